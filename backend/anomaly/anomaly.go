@@ -0,0 +1,67 @@
+// Package anomaly flags unusual daily counts against a rolling baseline using
+// the modified z-score of Iglewicz and Hoaglin, which is built on the median
+// and the median absolute deviation so that the odd bad day in the baseline
+// does not hide the next one.
+package anomaly
+
+import (
+	"math"
+	"sort"
+)
+
+// Threshold is the modified z-score beyond which a value is anomalous.
+const Threshold = 3.5
+
+// madScale turns a median absolute deviation into a standard deviation
+// estimate for normally distributed data.
+const madScale = 0.6745
+
+// meanADScale does the same for the mean absolute deviation, used when more
+// than half the baseline equals the median and the MAD is zero.
+const meanADScale = 0.7979
+
+// Result describes a value scored against its baseline.
+type Result struct {
+	Median float64
+	MAD    float64
+	Score  float64 // modified z-score; ±Inf when the baseline never varies and the value differs
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// Score compares x with baseline, which must not be empty.
+func Score(baseline []float64, x float64) Result {
+	r := Result{Median: median(baseline)}
+	deviations := make([]float64, len(baseline))
+	var sum float64
+	for i, v := range baseline {
+		deviations[i] = math.Abs(v - r.Median)
+		sum += deviations[i]
+	}
+	r.MAD = median(deviations)
+	switch {
+	case r.MAD > 0:
+		r.Score = madScale * (x - r.Median) / r.MAD
+	case sum > 0:
+		r.Score = meanADScale * (x - r.Median) / (sum / float64(len(baseline)))
+	case x != r.Median:
+		r.Score = math.Inf(1)
+		if x < r.Median {
+			r.Score = math.Inf(-1)
+		}
+	}
+	return r
+}
+
+// Anomalous reports whether the score is beyond Threshold.
+func (r Result) Anomalous() bool {
+	return math.Abs(r.Score) > Threshold
+}
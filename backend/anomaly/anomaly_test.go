@@ -0,0 +1,34 @@
+package anomaly
+
+import (
+	"math"
+	"testing"
+)
+
+func TestScore(t *testing.T) {
+	baseline := []float64{10, 12, 11, 9, 10, 13, 11, 10, 12, 0}
+	cases := []struct {
+		name      string
+		baseline  []float64
+		x         float64
+		anomalous bool
+	}{
+		{"normal day", baseline, 11, false},
+		{"sudden drop", baseline, 2, true},
+		{"spike", baseline, 25, true},
+		// One bad day in the baseline does not stretch the spread enough to hide another.
+		{"drop after an earlier drop", baseline, 4, true},
+		{"steady flock drops to zero", []float64{6, 6, 6, 6, 6, 6, 6}, 0, true},
+		{"steady flock unchanged", []float64{6, 6, 6, 6, 6, 6, 6}, 6, false},
+		{"mostly steady flock", []float64{6, 6, 6, 6, 6, 5, 7}, 5, false},
+	}
+	for _, tc := range cases {
+		r := Score(tc.baseline, tc.x)
+		if r.Anomalous() != tc.anomalous {
+			t.Errorf("%s: score %.2f, anomalous %v, want %v", tc.name, r.Score, r.Anomalous(), tc.anomalous)
+		}
+	}
+	if r := Score([]float64{6, 6, 6}, 0); !math.IsInf(r.Score, -1) || r.Median != 6 || r.MAD != 0 {
+		t.Errorf("unexpected result for a drop from a constant baseline: %+v", r)
+	}
+}
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"errors"
+	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -75,9 +76,14 @@ func AuthMiddleware() func(c *gin.Context) {
 		if len(tokenStr) > 7 && tokenStr[:7] == "Bearer " {
 			tokenStr = tokenStr[7:]
 		}
+		// EventSource cannot set headers, so event streams pass the token
+		// as ?access_token= instead.
+		if tokenStr == "" {
+			tokenStr = c.Query("access_token")
+		}
 		userID, err := ParseAccessToken(tokenStr)
 		if err != nil {
-			c.AbortWithStatus(401)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing or invalid access token", "code": "unauthorized"})
 			return
 		}
 		c.Set("user_id", userID)
@@ -2,7 +2,8 @@ package db
 
 import (
 	"database/sql"
-	"log"
+	"fmt"
+	"log/slog"
 
 	_ "github.com/mattn/go-sqlite3" // SQLite driver
 )
@@ -10,25 +11,22 @@ import (
 // InitializeDatabase ensures the necessary tables exist in the SQLite database
 // and seeds them with sample data if they are empty.
 func InitializeDatabase(dbPath string) error {
-	log.Printf("[DB Init] Opening SQLite database at %s for initialization...", dbPath)
+	logger := slog.With("component", "db", "path", dbPath)
+	logger.Info("Initializing database")
 	db, err := sql.Open("sqlite3", dbPath+"?_foreign_keys=on") // Enable foreign keys
 	if err != nil {
-		log.Printf("[DB Init] Error opening SQLite DB: %v", err)
-		return err
+		return fmt.Errorf("open sqlite: %w", err)
 	}
 	defer db.Close()
 
-	log.Println("[DB Init] Creating tables if they don't exist...")
 	if err := createTables(db); err != nil {
-		return err
+		return fmt.Errorf("create tables: %w", err)
 	}
-
-	log.Println("[DB Init] Seeding database with initial data if empty...")
-	if err := seedData(db); err != nil {
-		return err
+	if err := seedData(logger, db); err != nil {
+		return fmt.Errorf("seed data: %w", err)
 	}
 
-	log.Println("[DB Init] Database initialization complete.")
+	logger.Info("Database initialization complete")
 	return nil
 }
 
@@ -137,42 +135,36 @@ func createTables(db *sql.DB) error {
     `
 
 	_, err := db.Exec(schema)
-	if err != nil {
-		log.Printf("[DB Init] Error executing schema creation: %v", err)
-		return err
-	}
-	log.Println("[DB Init] Tables created successfully (if they didn't exist).")
-	return nil
+	return err
 }
 
 // seedData adds some initial data if tables are empty
-func seedData(db *sql.DB) error {
+func seedData(logger *slog.Logger, db *sql.DB) error {
 	tx, err := db.Begin()
 	if err != nil {
-		log.Printf("[DB Seed] Error beginning transaction: %v", err)
 		return err
 	}
 
 	// Seed Species if empty
-	if err := seedTableIfEmpty(tx, "species", "INSERT INTO species (name) VALUES (?), (?), (?)", "Chicken", "Duck", "Quail"); err != nil {
+	if err := seedTableIfEmpty(logger, tx, "species", "INSERT INTO species (name) VALUES (?), (?), (?)", "Chicken", "Duck", "Quail"); err != nil {
 		tx.Rollback()
 		return err
 	}
 
 	// Seed Coops if empty
-	if err := seedTableIfEmpty(tx, "coops", "INSERT INTO coops (name) VALUES (?), (?)", "Main Coop", "Duck House"); err != nil {
+	if err := seedTableIfEmpty(logger, tx, "coops", "INSERT INTO coops (name) VALUES (?), (?)", "Main Coop", "Duck House"); err != nil {
 		tx.Rollback()
 		return err
 	}
 
 	// Seed Egg Colors if empty
-	if err := seedTableIfEmpty(tx, "egg_colors", "INSERT INTO egg_colors (color) VALUES (?), (?), (?)", "Brown", "White", "Blue"); err != nil {
+	if err := seedTableIfEmpty(logger, tx, "egg_colors", "INSERT INTO egg_colors (color) VALUES (?), (?), (?)", "Brown", "White", "Blue"); err != nil {
 		tx.Rollback()
 		return err
 	}
 
 	// Seed Egg Sizes if empty
-	if err := seedTableIfEmpty(tx, "egg_sizes", "INSERT INTO egg_sizes (size) VALUES (?), (?), (?)", "Medium", "Large", "Small"); err != nil {
+	if err := seedTableIfEmpty(logger, tx, "egg_sizes", "INSERT INTO egg_sizes (size) VALUES (?), (?), (?)", "Medium", "Large", "Small"); err != nil {
 		tx.Rollback()
 		return err
 	}
@@ -181,36 +173,33 @@ func seedData(db *sql.DB) error {
 	var eggCount int
 	err = tx.QueryRow("SELECT COUNT(*) FROM eggs").Scan(&eggCount)
 	if err != nil {
-		log.Printf("[DB Seed] Error checking egg count: %v", err)
 		tx.Rollback()
-		return err
+		return fmt.Errorf("count eggs: %w", err)
 	}
 	if eggCount == 0 {
-		log.Println("[DB Seed] Seeding eggs table...")
+		logger.Info("Seeding table", "table", "eggs")
 		_, err = tx.Exec(`INSERT INTO eggs (species_id, coop_id, collection_date, quantity, color_id, size_id) VALUES
             (1, 1, '2025-05-01', 5, 1, 2), -- 5 Large Brown Chicken eggs from Main Coop
             (1, 1, '2025-05-02', 4, 3, 1), -- 4 Medium Blue Chicken eggs from Main Coop
             (2, 2, '2025-05-02', 2, 2, 2)  -- 2 Large White Duck eggs from Duck House
         `)
 		if err != nil {
-			log.Printf("[DB Seed] Error seeding eggs: %v", err)
 			tx.Rollback()
-			return err
+			return fmt.Errorf("seed eggs: %w", err)
 		}
 	} else {
-		log.Println("[DB Seed] Eggs table already has data, skipping seeding.")
+		logger.Debug("Table already has data, skipping seeding", "table", "eggs")
 	}
 
 	// Seed Inventory Actions if empty (Requires eggs table to be seeded first)
 	var actionCount int
 	err = tx.QueryRow("SELECT COUNT(*) FROM inventory_actions").Scan(&actionCount)
 	if err != nil {
-		log.Printf("[DB Seed] Error checking inventory_actions count: %v", err)
 		tx.Rollback()
-		return err
+		return fmt.Errorf("count inventory_actions: %w", err)
 	}
 	if actionCount == 0 && eggCount == 0 { // Only seed actions if we also seeded eggs
-		log.Println("[DB Seed] Seeding inventory_actions table...")
+		logger.Info("Seeding table", "table", "inventory_actions")
 		// Get the IDs of the eggs we just inserted (assuming they are 1, 2, 3)
 		_, err = tx.Exec(`INSERT INTO inventory_actions (egg_id, action_type, quantity, action_date) VALUES
             (1, 'collected', 5, '2025-05-01 08:00:00'),
@@ -219,36 +208,32 @@ func seedData(db *sql.DB) error {
             (1, 'sold', 2, '2025-05-02 10:00:00') -- Sold 2 of the first batch
         `)
 		if err != nil {
-			log.Printf("[DB Seed] Error seeding inventory_actions: %v", err)
 			tx.Rollback()
-			return err
+			return fmt.Errorf("seed inventory_actions: %w", err)
 		}
 	} else {
-		log.Println("[DB Seed] Inventory_actions table already has data or eggs table was not seeded, skipping seeding actions.")
+		logger.Debug("Table already has data or eggs were not seeded, skipping seeding", "table", "inventory_actions")
 	}
 
-	log.Println("[DB Seed] Committing seed data transaction.")
 	return tx.Commit()
 }
 
 // seedTableIfEmpty checks if a table is empty and executes the seed statement if it is.
-func seedTableIfEmpty(tx *sql.Tx, tableName, seedStmt string, args ...interface{}) error {
+func seedTableIfEmpty(logger *slog.Logger, tx *sql.Tx, tableName, seedStmt string, args ...interface{}) error {
 	var count int
 	err := tx.QueryRow("SELECT COUNT(*) FROM " + tableName).Scan(&count)
 	if err != nil {
-		log.Printf("[DB Seed] Error checking count for table %s: %v", tableName, err)
-		return err
+		return fmt.Errorf("count %s: %w", tableName, err)
 	}
 
 	if count == 0 {
-		log.Printf("[DB Seed] Seeding %s table...", tableName)
+		logger.Info("Seeding table", "table", tableName)
 		_, err = tx.Exec(seedStmt, args...)
 		if err != nil {
-			log.Printf("[DB Seed] Error seeding table %s: %v", tableName, err)
-			return err
+			return fmt.Errorf("seed %s: %w", tableName, err)
 		}
 	} else {
-		log.Printf("[DB Seed] Table %s already has data, skipping seeding.", tableName)
+		logger.Debug("Table already has data, skipping seeding", "table", tableName)
 	}
 	return nil
 }
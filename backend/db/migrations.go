@@ -45,6 +45,13 @@ func Migrate(db *sql.DB) error {
         action TEXT NOT NULL,
         notes TEXT,
         date DATE NOT NULL,
+        customer TEXT,
+        unit_price REAL,
+        weight_grams REAL, -- average weight per egg, collected actions only
+        lot_code TEXT, -- collected actions only, e.g. 20250501-MAIN-COOP-01
+        storage TEXT, -- collected actions only: refrigerated or counter
+        storage_unit_id INTEGER, -- collected actions only: the storage_units row holding the lot
+        expired_on DATE, -- set by the daily expiry job once the lot is past its shelf life
         created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
         updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
     );`
@@ -52,6 +59,59 @@ func Migrate(db *sql.DB) error {
 	if err != nil {
 		return fmt.Errorf("failed to create inventory_actions table: %w", err)
 	}
+	// Databases created by older versions lack these columns.
+	if err := addColumnIfMissing(db, "inventory_actions", "customer", "TEXT"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "inventory_actions", "unit_price", "REAL"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "inventory_actions", "weight_grams", "REAL"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "inventory_actions", "lot_code", "TEXT"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "inventory_actions", "storage", "TEXT"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "inventory_actions", "expired_on", "DATE"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(db, "inventory_actions", "storage_unit_id", "INTEGER"); err != nil {
+		return err
+	}
+	if _, err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_inventory_actions_lot_code ON inventory_actions (lot_code)"); err != nil {
+		return fmt.Errorf("failed to create lot code index: %w", err)
+	}
+
+	const shelfLifeTable = `
+    CREATE TABLE IF NOT EXISTS shelf_life (
+        species TEXT NOT NULL,
+        storage TEXT NOT NULL, -- refrigerated or counter
+        days INTEGER NOT NULL,
+        updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        PRIMARY KEY (species, storage)
+    );`
+	_, err = db.Exec(shelfLifeTable)
+	if err != nil {
+		return fmt.Errorf("failed to create shelf_life table: %w", err)
+	}
+
+	const lotDrawTable = `
+    CREATE TABLE IF NOT EXISTS lot_draws (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        action_id INTEGER NOT NULL, -- the outgoing inventory action
+        lot_action_id INTEGER NOT NULL, -- the collected action that created the lot
+        quantity INTEGER NOT NULL,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        FOREIGN KEY (action_id) REFERENCES inventory_actions(id),
+        FOREIGN KEY (lot_action_id) REFERENCES inventory_actions(id)
+    );`
+	_, err = db.Exec(lotDrawTable)
+	if err != nil {
+		return fmt.Errorf("failed to create lot_draws table: %w", err)
+	}
 
 	const speciesTable = `
     CREATE TABLE IF NOT EXISTS species (
@@ -92,6 +152,22 @@ func Migrate(db *sql.DB) error {
 		return fmt.Errorf("failed to create egg_sizes table: %w", err)
 	}
 
+	const eggWeightBandTable = `
+    CREATE TABLE IF NOT EXISTS egg_weight_bands (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        species TEXT NOT NULL,
+        egg_size TEXT NOT NULL, -- name of an egg_sizes option
+        min_grams REAL NOT NULL, -- inclusive
+        max_grams REAL, -- exclusive; NULL for the heaviest band
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE (species, egg_size)
+    );`
+	_, err = db.Exec(eggWeightBandTable)
+	if err != nil {
+		return fmt.Errorf("failed to create egg_weight_bands table: %w", err)
+	}
+
 	const coopTable = `
     CREATE TABLE IF NOT EXISTS coops (
         id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -105,11 +181,573 @@ func Migrate(db *sql.DB) error {
 		return fmt.Errorf("failed to create coops table: %w", err)
 	}
 
+	const storageUnitTable = `
+    CREATE TABLE IF NOT EXISTS storage_units (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        name TEXT NOT NULL UNIQUE,
+        active BOOLEAN NOT NULL DEFAULT 1,
+        min_temp_c REAL, -- safe range; NULL uses the default
+        max_temp_c REAL,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+    );`
+	_, err = db.Exec(storageUnitTable)
+	if err != nil {
+		return fmt.Errorf("failed to create storage_units table: %w", err)
+	}
+
+	const storageReadingTable = `
+    CREATE TABLE IF NOT EXISTS storage_readings (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        storage_unit_id INTEGER NOT NULL,
+        recorded_at DATETIME NOT NULL,
+        temp_c REAL NOT NULL,
+        source TEXT NOT NULL DEFAULT 'manual', -- manual or sensor
+        notes TEXT,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        FOREIGN KEY (storage_unit_id) REFERENCES storage_units(id)
+    );`
+	_, err = db.Exec(storageReadingTable)
+	if err != nil {
+		return fmt.Errorf("failed to create storage_readings table: %w", err)
+	}
+
+	const settingsTable = `
+    CREATE TABLE IF NOT EXISTS settings (
+        key TEXT PRIMARY KEY,
+        value TEXT NOT NULL,
+        updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+    );`
+	_, err = db.Exec(settingsTable)
+	if err != nil {
+		return fmt.Errorf("failed to create settings table: %w", err)
+	}
+
+	const birdTable = `
+    CREATE TABLE IF NOT EXISTS birds (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        name TEXT,
+        band_id TEXT UNIQUE,
+        species TEXT NOT NULL,
+        breed TEXT,
+        hatch_date DATE,
+        coop TEXT,
+        status TEXT NOT NULL DEFAULT 'active', -- active, sold, deceased
+        status_date DATE, -- when the bird left the flock
+        notes TEXT,
+        incubation_batch_id INTEGER, -- set for birds hatched on the farm
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+    );`
+	_, err = db.Exec(birdTable)
+	if err != nil {
+		return fmt.Errorf("failed to create birds table: %w", err)
+	}
+	if err := addColumnIfMissing(db, "birds", "incubation_batch_id", "INTEGER"); err != nil {
+		return err
+	}
+
+	const birdTransferTable = `
+    CREATE TABLE IF NOT EXISTS bird_transfers (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        bird_id INTEGER NOT NULL,
+        from_coop TEXT, -- NULL when the bird first joins the flock
+        to_coop TEXT NOT NULL,
+        date DATE NOT NULL,
+        notes TEXT,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        FOREIGN KEY (bird_id) REFERENCES birds(id)
+    );`
+	_, err = db.Exec(birdTransferTable)
+	if err != nil {
+		return fmt.Errorf("failed to create bird_transfers table: %w", err)
+	}
+
+	const healthEventTable = `
+    CREATE TABLE IF NOT EXISTS health_events (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        bird_id INTEGER, -- NULL for coop-wide events
+        coop TEXT NOT NULL,
+        event_type TEXT NOT NULL, -- illness, treatment, vaccination, death
+        date DATE NOT NULL,
+        description TEXT,
+        medication TEXT,
+        withdrawal_days INTEGER NOT NULL DEFAULT 0, -- eggs from the coop must not be sold for this many days
+        notes TEXT,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        FOREIGN KEY (bird_id) REFERENCES birds(id)
+    );`
+	_, err = db.Exec(healthEventTable)
+	if err != nil {
+		return fmt.Errorf("failed to create health_events table: %w", err)
+	}
+
+	const statusPeriodTable = `
+    CREATE TABLE IF NOT EXISTS bird_status_periods (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        bird_id INTEGER, -- NULL when the whole coop is affected
+        coop TEXT NOT NULL,
+        status TEXT NOT NULL, -- molting, broody, laying
+        start_date DATE NOT NULL,
+        end_date DATE, -- inclusive; NULL while ongoing
+        notes TEXT,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        FOREIGN KEY (bird_id) REFERENCES birds(id)
+    );`
+	_, err = db.Exec(statusPeriodTable)
+	if err != nil {
+		return fmt.Errorf("failed to create bird_status_periods table: %w", err)
+	}
+
+	const incubationProfileTable = `
+    CREATE TABLE IF NOT EXISTS incubation_profiles (
+        species TEXT PRIMARY KEY,
+        incubation_days INTEGER NOT NULL,
+        candling_days TEXT NOT NULL, -- comma-separated days after setting, e.g. '7,14,18'
+        updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+    );`
+	_, err = db.Exec(incubationProfileTable)
+	if err != nil {
+		return fmt.Errorf("failed to create incubation_profiles table: %w", err)
+	}
+
+	const incubationBatchTable = `
+    CREATE TABLE IF NOT EXISTS incubation_batches (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        species TEXT NOT NULL,
+        breed TEXT,
+        breeder_coop TEXT,
+        set_date DATE NOT NULL,
+        eggs_set INTEGER NOT NULL,
+        expected_hatch_date DATE NOT NULL,
+        inventory_action_id INTEGER, -- the 'incubated' action that took the eggs out of stock
+        status TEXT NOT NULL DEFAULT 'incubating', -- incubating, hatched, failed
+        hatch_date DATE,
+        hatched_count INTEGER,
+        notes TEXT,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        FOREIGN KEY (inventory_action_id) REFERENCES inventory_actions(id)
+    );`
+	_, err = db.Exec(incubationBatchTable)
+	if err != nil {
+		return fmt.Errorf("failed to create incubation_batches table: %w", err)
+	}
+
+	const candlingTable = `
+    CREATE TABLE IF NOT EXISTS candling_results (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        batch_id INTEGER NOT NULL,
+        day INTEGER NOT NULL, -- days after setting
+        date DATE NOT NULL,
+        fertile INTEGER NOT NULL,
+        infertile INTEGER NOT NULL DEFAULT 0,
+        dead INTEGER NOT NULL DEFAULT 0, -- embryos that stopped developing
+        notes TEXT,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE (batch_id, day),
+        FOREIGN KEY (batch_id) REFERENCES incubation_batches(id)
+    );`
+	_, err = db.Exec(candlingTable)
+	if err != nil {
+		return fmt.Errorf("failed to create candling_results table: %w", err)
+	}
+
+	const feedTypeTable = `
+    CREATE TABLE IF NOT EXISTS feed_types (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        name TEXT NOT NULL UNIQUE,
+        bag_weight_kg REAL, -- default weight of one bag, used when purchases give only a bag count
+        low_stock_kg REAL NOT NULL DEFAULT 0, -- warn when the balance drops below this
+        notes TEXT,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+    );`
+	_, err = db.Exec(feedTypeTable)
+	if err != nil {
+		return fmt.Errorf("failed to create feed_types table: %w", err)
+	}
+
+	const feedPurchaseTable = `
+    CREATE TABLE IF NOT EXISTS feed_purchases (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        feed_type_id INTEGER NOT NULL,
+        date DATE NOT NULL,
+        quantity INTEGER NOT NULL, -- bags
+        weight_kg REAL NOT NULL,
+        cost REAL,
+        supplier TEXT,
+        notes TEXT,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        FOREIGN KEY (feed_type_id) REFERENCES feed_types(id)
+    );`
+	_, err = db.Exec(feedPurchaseTable)
+	if err != nil {
+		return fmt.Errorf("failed to create feed_purchases table: %w", err)
+	}
+
+	const feedUsageTable = `
+    CREATE TABLE IF NOT EXISTS feed_usage (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        feed_type_id INTEGER NOT NULL,
+        coop TEXT NOT NULL,
+        date DATE NOT NULL,
+        weight_kg REAL NOT NULL,
+        notes TEXT,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        FOREIGN KEY (feed_type_id) REFERENCES feed_types(id)
+    );`
+	_, err = db.Exec(feedUsageTable)
+	if err != nil {
+		return fmt.Errorf("failed to create feed_usage table: %w", err)
+	}
+
+	const taskTable = `
+    CREATE TABLE IF NOT EXISTS tasks (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        title TEXT NOT NULL,
+        description TEXT,
+        rrule TEXT NOT NULL, -- RFC 5545 recurrence rule, e.g. FREQ=WEEKLY;BYDAY=SA
+        start_date DATE NOT NULL,
+        coop TEXT,
+        assigned_user_id INTEGER,
+        active BOOLEAN NOT NULL DEFAULT 1,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        FOREIGN KEY (assigned_user_id) REFERENCES users(id)
+    );`
+	_, err = db.Exec(taskTable)
+	if err != nil {
+		return fmt.Errorf("failed to create tasks table: %w", err)
+	}
+
+	const taskCompletionTable = `
+    CREATE TABLE IF NOT EXISTS task_completions (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        task_id INTEGER NOT NULL,
+        due_date DATE NOT NULL, -- the occurrence this completion covers
+        completed_on DATE NOT NULL,
+        user_id INTEGER,
+        notes TEXT,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE (task_id, due_date),
+        FOREIGN KEY (task_id) REFERENCES tasks(id),
+        FOREIGN KEY (user_id) REFERENCES users(id)
+    );`
+	_, err = db.Exec(taskCompletionTable)
+	if err != nil {
+		return fmt.Errorf("failed to create task_completions table: %w", err)
+	}
+
+	const attachmentTable = `
+    CREATE TABLE IF NOT EXISTS attachments (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        entity_type TEXT NOT NULL, -- 'inventory_action', 'bird' or 'feed_purchase'
+        entity_id INTEGER NOT NULL,
+        filename TEXT NOT NULL, -- name the file was uploaded with
+        content_type TEXT NOT NULL, -- sniffed from the file contents
+        size_bytes INTEGER NOT NULL,
+        storage_name TEXT NOT NULL UNIQUE, -- file name under the attachments directory
+        has_thumbnail BOOLEAN NOT NULL DEFAULT 0,
+        caption TEXT,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+    );`
+	_, err = db.Exec(attachmentTable)
+	if err != nil {
+		return fmt.Errorf("failed to create attachments table: %w", err)
+	}
+	if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_attachments_entity ON attachments (entity_type, entity_id)"); err != nil {
+		return fmt.Errorf("failed to create attachments entity index: %w", err)
+	}
+
+	const weatherDailyTable = `
+    CREATE TABLE IF NOT EXISTS weather_daily (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        date DATE NOT NULL,
+        station TEXT NOT NULL DEFAULT '', -- e.g. a GHCN station ID; '' for a farm's own readings
+        tmin_c REAL,
+        tmax_c REAL,
+        tavg_c REAL,
+        precip_mm REAL,
+        source TEXT NOT NULL, -- import format: 'ghcn', 'csv' or 'json'
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE (station, date)
+    );`
+	_, err = db.Exec(weatherDailyTable)
+	if err != nil {
+		return fmt.Errorf("failed to create weather_daily table: %w", err)
+	}
+
+	const lightingScheduleTable = `
+    CREATE TABLE IF NOT EXISTS lighting_schedules (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        coop TEXT NOT NULL,
+        start_date DATE NOT NULL,
+        end_date DATE, -- last day the schedule ran; NULL while it is still in use
+        lights_on TEXT NOT NULL, -- HH:MM in the farm timezone
+        lights_off TEXT NOT NULL,
+        notes TEXT,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+    );`
+	_, err = db.Exec(lightingScheduleTable)
+	if err != nil {
+		return fmt.Errorf("failed to create lighting_schedules table: %w", err)
+	}
+
+	const forecastRunTable = `
+    CREATE TABLE IF NOT EXISTS forecast_runs (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        as_of DATE NOT NULL, -- first forecast day
+        by_group TEXT NOT NULL, -- coop or species
+        period TEXT NOT NULL, -- day or week
+        weeks INTEGER NOT NULL,
+        level INTEGER NOT NULL, -- prediction interval, percent
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+    );`
+	_, err = db.Exec(forecastRunTable)
+	if err != nil {
+		return fmt.Errorf("failed to create forecast_runs table: %w", err)
+	}
+
+	const forecastPointTable = `
+    CREATE TABLE IF NOT EXISTS forecast_points (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        run_id INTEGER NOT NULL REFERENCES forecast_runs(id) ON DELETE CASCADE,
+        grp TEXT NOT NULL, -- coop or species name
+        model TEXT NOT NULL,
+        step INTEGER NOT NULL, -- periods ahead of as_of, from 1
+        start_date DATE NOT NULL,
+        end_date DATE NOT NULL, -- inclusive
+        predicted REAL NOT NULL,
+        lower REAL NOT NULL,
+        upper REAL NOT NULL
+    );`
+	_, err = db.Exec(forecastPointTable)
+	if err != nil {
+		return fmt.Errorf("failed to create forecast_points table: %w", err)
+	}
+	if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_forecast_points_run ON forecast_points (run_id)"); err != nil {
+		return fmt.Errorf("failed to create forecast_points run index: %w", err)
+	}
+
+	const alertTable = `
+    CREATE TABLE IF NOT EXISTS alerts (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        date DATE NOT NULL, -- the day whose collections were unusual
+        coop TEXT NOT NULL DEFAULT '',
+        species TEXT NOT NULL,
+        kind TEXT NOT NULL, -- 'drop' or 'spike'
+        eggs INTEGER NOT NULL,
+        baseline REAL NOT NULL, -- median daily collection over the baseline window
+        mad REAL NOT NULL, -- median absolute deviation of the baseline
+        score REAL, -- modified z-score; NULL when the baseline never varied
+        notified_at DATETIME,
+        acknowledged_at DATETIME,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE (date, coop, species)
+    );`
+	_, err = db.Exec(alertTable)
+	if err != nil {
+		return fmt.Errorf("failed to create alerts table: %w", err)
+	}
+
+	const notificationPreferenceTable = `
+    CREATE TABLE IF NOT EXISTS notification_preferences (
+        user_id INTEGER PRIMARY KEY,
+        enabled BOOLEAN NOT NULL DEFAULT 1,
+        kinds TEXT, -- comma-separated kinds subscribed to; NULL for all
+        quiet_start TEXT, -- HH:MM in the farm timezone; deliveries wait until quiet_end
+        quiet_end TEXT,
+        updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        FOREIGN KEY (user_id) REFERENCES users(id)
+    );`
+	_, err = db.Exec(notificationPreferenceTable)
+	if err != nil {
+		return fmt.Errorf("failed to create notification_preferences table: %w", err)
+	}
+
+	const notificationDeliveryTable = `
+    CREATE TABLE IF NOT EXISTS notification_deliveries (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        user_id INTEGER NOT NULL,
+        channel TEXT NOT NULL, -- e.g. 'email'
+        recipient TEXT NOT NULL,
+        kind TEXT NOT NULL,
+        subject TEXT NOT NULL,
+        body TEXT NOT NULL,
+        status TEXT NOT NULL DEFAULT 'pending', -- 'pending', 'sent' or 'failed'
+        attempts INTEGER NOT NULL DEFAULT 0,
+        next_attempt_at DATETIME NOT NULL,
+        last_error TEXT,
+        sent_at DATETIME,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        FOREIGN KEY (user_id) REFERENCES users(id)
+    );`
+	_, err = db.Exec(notificationDeliveryTable)
+	if err != nil {
+		return fmt.Errorf("failed to create notification_deliveries table: %w", err)
+	}
+	if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_notification_deliveries_status ON notification_deliveries (status)"); err != nil {
+		return fmt.Errorf("failed to create notification_deliveries status index: %w", err)
+	}
+
+	const eventTable = `
+    CREATE TABLE IF NOT EXISTS events (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        type TEXT NOT NULL, -- e.g. 'inventory.created'
+        payload TEXT NOT NULL, -- JSON
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+    );`
+	_, err = db.Exec(eventTable)
+	if err != nil {
+		return fmt.Errorf("failed to create events table: %w", err)
+	}
+
+	const webhookTable = `
+    CREATE TABLE IF NOT EXISTS webhooks (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        url TEXT NOT NULL,
+        secret TEXT NOT NULL,
+        event_types TEXT NOT NULL, -- comma-separated, or '*' for all
+        description TEXT,
+        active BOOLEAN NOT NULL DEFAULT 1,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+    );`
+	_, err = db.Exec(webhookTable)
+	if err != nil {
+		return fmt.Errorf("failed to create webhooks table: %w", err)
+	}
+
+	const webhookDeliveryTable = `
+    CREATE TABLE IF NOT EXISTS webhook_deliveries (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        webhook_id INTEGER NOT NULL,
+        event_id INTEGER NOT NULL,
+        status TEXT NOT NULL DEFAULT 'pending', -- 'pending', 'delivered' or 'failed'
+        attempts INTEGER NOT NULL DEFAULT 0,
+        next_attempt_at DATETIME NOT NULL,
+        response_status INTEGER,
+        last_error TEXT,
+        delivered_at DATETIME,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        FOREIGN KEY (webhook_id) REFERENCES webhooks(id),
+        FOREIGN KEY (event_id) REFERENCES events(id)
+    );`
+	_, err = db.Exec(webhookDeliveryTable)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook_deliveries table: %w", err)
+	}
+	if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status ON webhook_deliveries (status)"); err != nil {
+		return fmt.Errorf("failed to create webhook_deliveries status index: %w", err)
+	}
+	if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries (webhook_id)"); err != nil {
+		return fmt.Errorf("failed to create webhook_deliveries webhook index: %w", err)
+	}
+
+	const mqttMappingTable = `
+    CREATE TABLE IF NOT EXISTS mqtt_mappings (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        topic TEXT NOT NULL, -- subscription filter, may use + and #
+        kind TEXT NOT NULL, -- 'collection' or 'reading'
+        -- The fields below are text/template strings run against each message.
+        coop TEXT NOT NULL,
+        species TEXT, -- collection only
+        quantity TEXT, -- collection only
+        egg_color TEXT, -- collection only
+        egg_size TEXT, -- collection only
+        metric TEXT, -- reading only, e.g. temperature_c or door
+        value TEXT, -- reading only
+        timestamp TEXT, -- RFC 3339 or Unix seconds; when the message was received if NULL
+        dedupe_key TEXT, -- identifies a message; a hash of topic and payload if NULL
+        active BOOLEAN NOT NULL DEFAULT 1,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+    );`
+	_, err = db.Exec(mqttMappingTable)
+	if err != nil {
+		return fmt.Errorf("failed to create mqtt_mappings table: %w", err)
+	}
+
+	const mqttMessageTable = `
+    CREATE TABLE IF NOT EXISTS mqtt_messages (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        mapping_id INTEGER NOT NULL,
+        dedupe_key TEXT NOT NULL,
+        topic TEXT NOT NULL,
+        inventory_action_id INTEGER,
+        coop_reading_id INTEGER,
+        received_at DATETIME NOT NULL,
+        UNIQUE (mapping_id, dedupe_key),
+        FOREIGN KEY (mapping_id) REFERENCES mqtt_mappings(id)
+    );`
+	_, err = db.Exec(mqttMessageTable)
+	if err != nil {
+		return fmt.Errorf("failed to create mqtt_messages table: %w", err)
+	}
+
+	const coopReadingTable = `
+    CREATE TABLE IF NOT EXISTS coop_readings (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        coop TEXT NOT NULL,
+        metric TEXT NOT NULL,
+        value REAL NOT NULL,
+        recorded_at DATETIME NOT NULL,
+        source TEXT NOT NULL DEFAULT 'mqtt',
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+    );`
+	_, err = db.Exec(coopReadingTable)
+	if err != nil {
+		return fmt.Errorf("failed to create coop_readings table: %w", err)
+	}
+	if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_coop_readings_coop_metric ON coop_readings (coop, metric, recorded_at)"); err != nil {
+		return fmt.Errorf("failed to create coop_readings coop index: %w", err)
+	}
+
+	return nil
+}
+
+// addColumnIfMissing adds a column to an existing table so that databases
+// created by older versions pick up new fields without a rebuild.
+func addColumnIfMissing(db *sql.DB, table, column, decl string) error {
+	rows, err := db.Query("PRAGMA table_info(" + table + ")")
+	if err != nil {
+		return fmt.Errorf("failed to inspect %s table: %w", table, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var cid, notnull, pk int
+		var name, ctype string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &ctype, &notnull, &dflt, &pk); err != nil {
+			return fmt.Errorf("failed to inspect %s table: %w", table, err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to inspect %s table: %w", table, err)
+	}
+	rows.Close()
+	if _, err := db.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + decl); err != nil {
+		return fmt.Errorf("failed to add %s.%s column: %w", table, column, err)
+	}
 	return nil
 }
 
+// InitDB opens the SQLite database at path with foreign keys enforced and
+// brings its schema up to date.
 func InitDB(path string) (*sql.DB, error) {
-	db, err := sql.Open("sqlite3", path)
+	db, err := sql.Open("sqlite3", path+"?_foreign_keys=on")
 	if err != nil {
 		return nil, err
 	}
@@ -2,8 +2,11 @@ package db
 
 import (
 	"database/sql"
+	"errors"
 	"os"
 	"testing"
+
+	"github.com/mattn/go-sqlite3"
 )
 
 func TestMigrate_CreatesUsersTable(t *testing.T) {
@@ -29,3 +32,46 @@ func TestMigrate_CreatesUsersTable(t *testing.T) {
 		t.Fatalf("expected table 'users', got '%s'", tableName)
 	}
 }
+
+func TestMigrate_AddsColumnsToExistingInventoryTable(t *testing.T) {
+	testDBPath := "test_migrate_columns.db"
+	defer os.Remove(testDBPath)
+
+	db, err := sql.Open("sqlite3", testDBPath)
+	if err != nil {
+		t.Fatalf("failed to open test db: %v", err)
+	}
+	defer db.Close()
+
+	// Table as created by earlier versions, before sales were tracked
+	if _, err := db.Exec(`CREATE TABLE inventory_actions (id INTEGER PRIMARY KEY AUTOINCREMENT, quantity INTEGER NOT NULL, species TEXT NOT NULL, coop TEXT, egg_color TEXT, egg_size TEXT, action TEXT NOT NULL, notes TEXT, date DATE NOT NULL, created_at DATETIME DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME DEFAULT CURRENT_TIMESTAMP)`); err != nil {
+		t.Fatalf("failed to create legacy table: %v", err)
+	}
+	if err := Migrate(db); err != nil {
+		t.Fatalf("migration failed: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO inventory_actions (quantity, species, action, date, customer, unit_price) VALUES (1, 'Chicken', 'sold', '2025-05-01', 'Jane', 0.5)`); err != nil {
+		t.Fatalf("expected customer and unit_price columns after migration: %v", err)
+	}
+	// Running again must be a no-op
+	if err := Migrate(db); err != nil {
+		t.Fatalf("second migration failed: %v", err)
+	}
+}
+
+func TestInitDB_EnforcesForeignKeys(t *testing.T) {
+	testDBPath := "test_init_db.db"
+	defer os.Remove(testDBPath)
+
+	db, err := InitDB(testDBPath)
+	if err != nil {
+		t.Fatalf("InitDB failed: %v", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec("INSERT INTO lot_draws (action_id, lot_action_id, quantity) VALUES (998, 999, 1)")
+	var se sqlite3.Error
+	if !errors.As(err, &se) || se.ExtendedCode != sqlite3.ErrConstraintForeignKey {
+		t.Fatalf("expected a foreign key violation, got %v", err)
+	}
+}
@@ -1,142 +1,167 @@
 package etl
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log" // Import log package
+	"log/slog"
+	"regexp"
 	"strings"
+	"time"
+
+	"egg-tracker/backend/logging"
+	"egg-tracker/backend/metrics"
 
 	_ "github.com/marcboeker/go-duckdb"
 	_ "github.com/mattn/go-sqlite3"
 )
 
+// coreTables must exist in SQLite; the ETL fails if any of them is missing.
+var coreTables = []string{"eggs", "inventory_actions", "species", "egg_colors", "egg_sizes", "coops"}
+
+// optionalTables were added by later migrations and are copied only when
+// present, so databases created by older versions still refresh cleanly.
+var optionalTables = []string{
+	"birds", "bird_transfers", "health_events", "bird_status_periods",
+	"incubation_batches", "candling_results",
+	"feed_types", "feed_purchases", "feed_usage", "lot_draws",
+	"storage_units", "storage_readings", "weather_daily",
+}
+
+// tablesToCopy returns the core tables plus any optional tables present in SQLite.
+func tablesToCopy(ctx context.Context, src *sql.DB) ([]string, error) {
+	tables := append([]string{}, coreTables...)
+	for _, tbl := range optionalTables {
+		var name string
+		err := src.QueryRow("SELECT name FROM sqlite_master WHERE type='table' AND name=?", tbl).Scan(&name)
+		if err == sql.ErrNoRows {
+			logging.FromContext(ctx).Info("Optional table not found in SQLite, skipping", "component", "etl", "table", tbl)
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("check table %s: %w", tbl, err)
+		}
+		tables = append(tables, tbl)
+	}
+	return tables, nil
+}
+
+// logRun logs the start of an ETL run and returns a func, to be deferred,
+// that logs how it ended and records it in metrics.
+func logRun(ctx context.Context, mode string, attrs ...any) func(err *error) {
+	logger := logging.FromContext(ctx).With("component", "etl", "mode", mode)
+	logger.Info("ETL started", attrs...)
+	started := time.Now()
+	return func(err *error) {
+		metrics.ObserveETL(mode, started, *err)
+		duration := slog.Int64("duration_ms", time.Since(started).Milliseconds())
+		if *err != nil {
+			logger.Error("ETL failed", duration, logging.Err(*err))
+			return
+		}
+		logger.Info("ETL completed", duration)
+	}
+}
+
 // FullRefresh copies all relevant tables from SQLite to DuckDB, replacing OLAP data.
-func FullRefresh(sqlitePath, duckdbPath string) error {
-	log.Printf("[ETL FullRefresh] Starting: SQLite='%s', DuckDB='%s'", sqlitePath, duckdbPath) // Add logging
+func FullRefresh(ctx context.Context, sqlitePath, duckdbPath string) (err error) {
+	defer logRun(ctx, "full", "sqlite", sqlitePath, "duckdb", duckdbPath)(&err)
 	sqliteDB, err := sql.Open("sqlite3", sqlitePath)
 	if err != nil {
-		log.Printf("[ETL FullRefresh] Error opening SQLite: %v", err) // Add logging
 		return fmt.Errorf("open sqlite: %w", err)
 	}
 	defer sqliteDB.Close()
 
 	duckDB, err := sql.Open("duckdb", duckdbPath)
 	if err != nil {
-		log.Printf("[ETL FullRefresh] Error opening DuckDB: %v", err) // Add logging
 		return fmt.Errorf("open duckdb: %w", err)
 	}
 	defer duckDB.Close()
 
-	// Ensure all required tables are included, especially inventory_actions
-	tables := []string{"eggs", "inventory_actions", "species", "egg_colors", "egg_sizes", "coops"}
-	log.Printf("[ETL FullRefresh] Tables to copy: %v", tables) // Add logging
+	tables, err := tablesToCopy(ctx, sqliteDB)
+	if err != nil {
+		return err
+	}
 	for _, tbl := range tables {
-		log.Printf("[ETL FullRefresh] Copying table: %s", tbl) // Add logging
-		if err := copyTable(sqliteDB, duckDB, tbl); err != nil {
-			log.Printf("[ETL FullRefresh] Error copying table %s: %v", tbl, err) // Add logging
+		if err := copyTable(ctx, sqliteDB, duckDB, tbl); err != nil {
 			return fmt.Errorf("copy table %s: %w", tbl, err)
 		}
-		log.Printf("[ETL FullRefresh] Successfully copied table: %s", tbl) // Add logging
 	}
-	log.Println("[ETL FullRefresh] Completed successfully.") // Add logging
 	return nil
 }
 
 // IncrementalRefresh copies only new or updated records from SQLite to DuckDB based on created_at/updated_at timestamps.
-func IncrementalRefresh(sqlitePath, duckdbPath string, since string) error {
-	log.Printf("[ETL IncrementalRefresh] Starting: SQLite='%s', DuckDB='%s', Since='%s'", sqlitePath, duckdbPath, since) // Add logging
+func IncrementalRefresh(ctx context.Context, sqlitePath, duckdbPath string, since string) (err error) {
+	defer logRun(ctx, "incremental", "sqlite", sqlitePath, "duckdb", duckdbPath, "since", since)(&err)
 	sqliteDB, err := sql.Open("sqlite3", sqlitePath)
 	if err != nil {
-		log.Printf("[ETL IncrementalRefresh] Error opening SQLite: %v", err) // Add logging
 		return fmt.Errorf("open sqlite: %w", err)
 	}
 	defer sqliteDB.Close()
 
 	duckDB, err := sql.Open("duckdb", duckdbPath)
 	if err != nil {
-		log.Printf("[ETL IncrementalRefresh] Error opening DuckDB: %v", err) // Add logging
 		return fmt.Errorf("open duckdb: %w", err)
 	}
 	defer duckDB.Close()
 
-	// Ensure all required tables are included, especially inventory_actions
-	tables := []string{"eggs", "inventory_actions", "species", "egg_colors", "egg_sizes", "coops"}
-	log.Printf("[ETL IncrementalRefresh] Tables to upsert: %v", tables) // Add logging
+	tables, err := tablesToCopy(ctx, sqliteDB)
+	if err != nil {
+		return err
+	}
 	for _, tbl := range tables {
 		// Check if table exists in DuckDB first for incremental, create if not
 		var exists int
 		err := duckDB.QueryRow("SELECT 1 FROM information_schema.tables WHERE table_name = ?", tbl).Scan(&exists)
 		if err != nil && err != sql.ErrNoRows {
-			log.Printf("[ETL IncrementalRefresh] Error checking if table %s exists in DuckDB: %v", tbl, err)
 			return fmt.Errorf("check table exists %s: %w", tbl, err)
 		}
 
 		if err == sql.ErrNoRows {
-			log.Printf("[ETL IncrementalRefresh] Table %s does not exist in DuckDB, performing initial copy.", tbl)
-			if err := copyTable(sqliteDB, duckDB, tbl); err != nil {
-				log.Printf("[ETL IncrementalRefresh] Error copying table %s during incremental setup: %v", tbl, err)
+			logging.FromContext(ctx).Info("Table missing from DuckDB, copying it in full", "component", "etl", "table", tbl)
+			if err := copyTable(ctx, sqliteDB, duckDB, tbl); err != nil {
 				return fmt.Errorf("initial copy table %s: %w", tbl, err)
 			}
-			log.Printf("[ETL IncrementalRefresh] Successfully performed initial copy for table: %s", tbl)
-		} else {
-			log.Printf("[ETL IncrementalRefresh] Upserting table: %s", tbl) // Add logging
-			if err := upsertTable(sqliteDB, duckDB, tbl, since); err != nil {
-				log.Printf("[ETL IncrementalRefresh] Error upserting table %s: %v", tbl, err) // Add logging
-				return fmt.Errorf("upsert table %s: %w", tbl, err)
-			}
-			log.Printf("[ETL IncrementalRefresh] Successfully upserted table: %s", tbl) // Add logging
+		} else if err := upsertTable(ctx, sqliteDB, duckDB, tbl, since); err != nil {
+			return fmt.Errorf("upsert table %s: %w", tbl, err)
 		}
 	}
-	log.Println("[ETL IncrementalRefresh] Completed successfully.") // Add logging
 	return nil
 }
 
-func copyTable(src, dst *sql.DB, table string) error {
+func copyTable(ctx context.Context, src, dst *sql.DB, table string) error {
+	logger := logging.FromContext(ctx).With("component", "etl", "table", table)
+
 	// Drop and recreate table in DuckDB
 	var schema string
-	log.Printf("[ETL copyTable] Getting schema for table: %s", table) // Add logging
 	row := src.QueryRow("SELECT sql FROM sqlite_master WHERE type='table' AND name=?", table)
 	if err := row.Scan(&schema); err != nil {
-		// If schema is missing (e.g., eggs table removed), log and skip? Or error out?
-		// For now, error out as the spec implies these tables exist.
-		log.Printf("[ETL copyTable] Could not get schema for table %s from sqlite_master: %v", table, err) // Use log package
 		return fmt.Errorf("get schema for %s: %w", table, err)
 	}
-	log.Printf("[ETL copyTable] Original schema for %s: %s", table, schema) // Add logging
+	logger.Debug("Read SQLite schema", "schema", schema)
 
 	// Clean schema for DuckDB compatibility
 	schema = cleanSchemaForDuckDB(schema)
-	log.Printf("[ETL copyTable] Cleaned schema for %s: %s", table, schema) // Add logging
-
-	log.Printf("[ETL copyTable] Dropping table %s if exists in DuckDB", table) // Add logging
+	logger.Debug("Recreating table in DuckDB", "schema", schema)
 	if _, err := dst.Exec("DROP TABLE IF EXISTS " + table); err != nil {
-		log.Printf("[ETL copyTable] Failed to drop table %s: %v", table, err) // Use log package
 		return fmt.Errorf("drop table %s: %w", table, err)
 	}
-
-	log.Printf("[ETL copyTable] Creating table %s in DuckDB with schema: %s", table, schema) // Use log package and show final schema
 	if _, err := dst.Exec(schema); err != nil {
-		log.Printf("[ETL copyTable] Failed to create table %s: %v\nSchema used: %s", table, err, schema) // Use log package
 		return fmt.Errorf("create table %s: %w", table, err)
 	}
-	log.Printf("[ETL copyTable] Successfully created table %s in DuckDB", table) // Add logging
 
 	// Copy data
-	log.Printf("[ETL copyTable] Selecting data from source table %s", table) // Add logging
 	rows, err := src.Query("SELECT * FROM " + table)
 	if err != nil {
-		log.Printf("[ETL copyTable] Failed to select data from source table %s: %v", table, err) // Use log package
 		return fmt.Errorf("select from %s: %w", table, err)
 	}
 	defer rows.Close()
 
 	cols, err := rows.Columns()
 	if err != nil {
-		log.Printf("[ETL copyTable] Failed to get columns for source table %s: %v", table, err)
 		return fmt.Errorf("get columns for %s: %w", table, err)
 	}
 	if len(cols) == 0 {
-		log.Printf("[ETL copyTable] No columns found for source table %s, skipping data copy.", table)
+		logger.Warn("No columns in SQLite table, skipping data copy")
 		return nil // Or return error? Table exists but is empty/unstructured?
 	}
 
@@ -154,7 +179,7 @@ func copyTable(src, dst *sql.DB, table string) error {
 		}
 		duckRows.Close()
 		if len(duckCols) != len(cols) {
-			log.Printf("[ETL copyTable] WARNING: DuckDB table %s column count (%d) does not match SQLite (%d)", table, len(duckCols), len(cols))
+			logger.Warn("DuckDB column count does not match SQLite", "duckdb_columns", len(duckCols), "sqlite_columns", len(cols))
 		}
 	}
 
@@ -165,17 +190,15 @@ func copyTable(src, dst *sql.DB, table string) error {
 	}
 
 	insertSQL := "INSERT INTO " + table + " (" + joinCols(cols) + ") VALUES (" + placeholders(len(cols)) + ")"
-	log.Printf("[ETL copyTable] Prepared insert statement for %s: %s", table, insertSQL) // Add logging
+	logger.Debug("Prepared insert", "sql", insertSQL)
 
 	rowCount := 0
 	tx, err := dst.Begin() // Use transaction for bulk insert
 	if err != nil {
-		log.Printf("[ETL copyTable] Failed to begin transaction for table %s: %v", table, err)
 		return fmt.Errorf("begin transaction for %s: %w", table, err)
 	}
 	stmt, err := tx.Prepare(insertSQL)
 	if err != nil {
-		log.Printf("[ETL copyTable] Failed to prepare insert statement for table %s: %v", table, err)
 		tx.Rollback()
 		return fmt.Errorf("prepare insert for %s: %w", table, err)
 	}
@@ -183,15 +206,13 @@ func copyTable(src, dst *sql.DB, table string) error {
 
 	for rows.Next() {
 		if err := rows.Scan(scanArgs...); err != nil {
-			log.Printf("[ETL copyTable] Failed to scan row for table %s: %v", table, err) // Use log package
 			tx.Rollback()
 			return fmt.Errorf("scan row %d for %s: %w", rowCount+1, table, err)
 		}
 		// Convert types if necessary (e.g., time.Time to string for DuckDB TIMESTAMP)
 		// For now, assume direct mapping works or DuckDB handles it.
 		if _, err := stmt.Exec(vals...); err != nil {
-			log.Printf("[ETL copyTable] Failed to execute prepared insert for row %d into table %s: %v", rowCount+1, table, err) // Use log package
-			log.Printf("[ETL copyTable] Failed data: %v", vals)                                                                  // Log the data
+			logger.Debug("Insert failed", "row", rowCount+1, "values", fmt.Sprint(vals), logging.Err(err))
 			tx.Rollback()
 			return fmt.Errorf("exec insert row %d for %s: %w", rowCount+1, table, err)
 		}
@@ -199,20 +220,21 @@ func copyTable(src, dst *sql.DB, table string) error {
 	}
 
 	if err := rows.Err(); err != nil { // Check for errors during iteration
-		log.Printf("[ETL copyTable] Error during row iteration for table %s: %v", table, err)
 		tx.Rollback()
 		return fmt.Errorf("rows iteration for %s: %w", table, err)
 	}
 
 	if err := tx.Commit(); err != nil {
-		log.Printf("[ETL copyTable] Failed to commit transaction for table %s: %v", table, err)
 		return fmt.Errorf("commit transaction for %s: %w", table, err)
 	}
 
-	log.Printf("[ETL copyTable] Successfully inserted %d rows into table %s", rowCount, table) // Add logging
+	logger.Debug("Copied table", "rows", rowCount)
+	metrics.AddETLRows(table, rowCount)
 	return nil
 }
 
+var foreignKeyClause = regexp.MustCompile(`(?i),\s*FOREIGN\s+KEY\s*\([^)]*\)\s*REFERENCES\s+\w+\s*\([^)]*\)`)
+
 // cleanSchemaForDuckDB attempts to convert SQLite schema syntax to be DuckDB compatible.
 func cleanSchemaForDuckDB(schema string) string {
 	// Remove AUTOINCREMENT (case-insensitive)
@@ -232,6 +254,10 @@ func cleanSchemaForDuckDB(schema string) string {
 		schema = replaceCaseInsensitive(schema, "INTEGER PRIMARY KEY", "BIGINT PRIMARY KEY")
 	}
 
+	// Drop foreign keys: the analytics copy is rebuilt wholesale and DuckDB
+	// rejects references between INTEGER and BIGINT columns.
+	schema = foreignKeyClause.ReplaceAllString(schema, "")
+
 	// Add more replacements as needed based on observed errors
 	// e.g., constraints, specific data types
 
@@ -258,29 +284,25 @@ func replaceCaseInsensitive(s, old, new string) string {
 
 // upsertTable inserts or updates records in DuckDB from SQLite where created_at or updated_at > since.
 // Uses DuckDB's INSERT ... ON CONFLICT DO UPDATE syntax.
-func upsertTable(src, dst *sql.DB, table, since string) error {
-	log.Printf("[ETL upsertTable] Starting upsert for table %s since %s", table, since) // Add logging
+func upsertTable(ctx context.Context, src, dst *sql.DB, table, since string) error {
+	logger := logging.FromContext(ctx).With("component", "etl", "table", table)
 
 	// Get columns from source table to ensure we handle the correct data
 	query := fmt.Sprintf("SELECT * FROM %s WHERE created_at > ? OR updated_at > ?", table)
-	log.Printf("[ETL upsertTable] Querying source: %s with since=%s", query, since) // Add logging
 	rows, err := src.Query(query, since, since)
 	if err != nil {
-		log.Printf("[ETL upsertTable] Failed to select data from source table %s: %v", table, err) // Add logging
 		return fmt.Errorf("select for upsert %s: %w", table, err)
 	}
 	defer rows.Close()
 
 	cols, err := rows.Columns()
 	if err != nil {
-		log.Printf("[ETL upsertTable] Failed to get columns for source table %s: %v", table, err) // Add logging
 		return fmt.Errorf("get columns for upsert %s: %w", table, err)
 	}
 	if len(cols) == 0 {
-		log.Printf("[ETL upsertTable] No columns found for source table %s, skipping upsert.", table)
+		logger.Warn("No columns in SQLite table, skipping upsert")
 		return nil
 	}
-	log.Printf("[ETL upsertTable] Columns for %s: %v", table, cols) // Add logging
 
 	// Find the 'id' column index
 	idColIndex := -1
@@ -294,7 +316,6 @@ func upsertTable(src, dst *sql.DB, table, since string) error {
 		}
 	}
 	if idColIndex == -1 {
-		log.Printf("[ETL upsertTable] No 'id' column found in table %s, cannot perform upsert.", table) // Add logging
 		return fmt.Errorf("no id column in %s for upsert", table)
 	}
 
@@ -315,17 +336,15 @@ func upsertTable(src, dst *sql.DB, table, since string) error {
 		idColName,                      // Conflict target column
 		strings.Join(setClauses, ", "), // Update clauses
 	)
-	log.Printf("[ETL upsertTable] Prepared upsert statement for %s: %s", table, upsertSQL) // Add logging
+	logger.Debug("Prepared upsert", "sql", upsertSQL)
 
 	// Execute upsert within a transaction
 	tx, err := dst.Begin()
 	if err != nil {
-		log.Printf("[ETL upsertTable] Failed to begin transaction for table %s: %v", table, err)
 		return fmt.Errorf("begin upsert transaction for %s: %w", table, err)
 	}
 	stmt, err := tx.Prepare(upsertSQL)
 	if err != nil {
-		log.Printf("[ETL upsertTable] Failed to prepare upsert statement for table %s: %v", table, err)
 		tx.Rollback()
 		return fmt.Errorf("prepare upsert for %s: %w", table, err)
 	}
@@ -340,15 +359,13 @@ func upsertTable(src, dst *sql.DB, table, since string) error {
 	rowCount := 0
 	for rows.Next() {
 		if err := rows.Scan(scanArgs...); err != nil {
-			log.Printf("[ETL upsertTable] Failed to scan row for table %s: %v", table, err) // Add logging
 			tx.Rollback()
 			return fmt.Errorf("scan upsert row %d for %s: %w", rowCount+1, table, err)
 		}
 
 		// Execute the prepared upsert statement
 		if _, err := stmt.Exec(vals...); err != nil {
-			log.Printf("[ETL upsertTable] Failed to execute upsert for row %d into table %s: %v", rowCount+1, table, err) // Add logging
-			log.Printf("[ETL upsertTable] Failed data: %v", vals)                                                         // Add logging
+			logger.Debug("Upsert failed", "row", rowCount+1, "values", fmt.Sprint(vals), logging.Err(err))
 			tx.Rollback()
 			return fmt.Errorf("exec upsert row %d for %s: %w", rowCount+1, table, err)
 		}
@@ -356,17 +373,16 @@ func upsertTable(src, dst *sql.DB, table, since string) error {
 	}
 
 	if err := rows.Err(); err != nil { // Check for errors during iteration
-		log.Printf("[ETL upsertTable] Error during row iteration for table %s: %v", table, err)
 		tx.Rollback()
 		return fmt.Errorf("rows iteration for upsert %s: %w", table, err)
 	}
 
 	if err := tx.Commit(); err != nil {
-		log.Printf("[ETL upsertTable] Failed to commit upsert transaction for table %s: %v", table, err)
 		return fmt.Errorf("commit upsert transaction for %s: %w", table, err)
 	}
 
-	log.Printf("[ETL upsertTable] Successfully upserted %d rows into table %s", rowCount, table) // Add logging
+	logger.Debug("Upserted table", "rows", rowCount)
+	metrics.AddETLRows(table, rowCount)
 	return nil
 }
 
@@ -1,9 +1,11 @@
 package etl
 
 import (
+	"context"
 	"database/sql"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 
 	_ "github.com/marcboeker/go-duckdb"
@@ -57,7 +59,7 @@ func TestFullRefresh_MatchesOLTP(t *testing.T) {
 	}
 
 	// Run ETL
-	if err := FullRefresh(sqlitePath, duckdbPath); err != nil {
+	if err := FullRefresh(context.Background(), sqlitePath, duckdbPath); err != nil {
 		t.Fatalf("etl: %v", err)
 	}
 
@@ -117,7 +119,7 @@ func TestIncrementalRefresh_OnlyNewOrChangedRecords(t *testing.T) {
 	}
 
 	// Initial full refresh
-	if err := FullRefresh(sqlitePath, duckdbPath); err != nil {
+	if err := FullRefresh(context.Background(), sqlitePath, duckdbPath); err != nil {
 		t.Fatalf("etl: %v", err)
 	}
 
@@ -130,7 +132,7 @@ func TestIncrementalRefresh_OnlyNewOrChangedRecords(t *testing.T) {
 	}
 
 	// Incremental refresh since '2024-05-02T00:00:00'
-	if err := IncrementalRefresh(sqlitePath, duckdbPath, "2024-05-02T00:00:00"); err != nil {
+	if err := IncrementalRefresh(context.Background(), sqlitePath, duckdbPath, "2024-05-02T00:00:00"); err != nil {
 		t.Fatalf("incremental etl: %v", err)
 	}
 
@@ -218,3 +220,20 @@ func typesOfRows(rows [][]interface{}) [][]string {
 	}
 	return types
 }
+
+func TestCleanSchemaForDuckDB_StripsForeignKeys(t *testing.T) {
+	schema := `CREATE TABLE bird_transfers (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        bird_id INTEGER NOT NULL,
+        to_coop TEXT NOT NULL,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        FOREIGN KEY (bird_id) REFERENCES birds(id)
+    )`
+	got := cleanSchemaForDuckDB(schema)
+	if strings.Contains(strings.ToUpper(got), "FOREIGN KEY") {
+		t.Errorf("expected foreign key to be removed, got: %s", got)
+	}
+	if !strings.Contains(got, "created_at TIMESTAMP") {
+		t.Errorf("expected remaining columns to be kept, got: %s", got)
+	}
+}
@@ -0,0 +1,166 @@
+// Package forecast fits additive Holt-Winters models to production series and
+// projects them forward with prediction intervals. Series too short for a
+// seasonal fit fall back to Holt's linear trend, then to the mean.
+package forecast
+
+import "math"
+
+// Model kinds, from most to least structured.
+const (
+	HoltWinters = "holt-winters"
+	Holt        = "holt"
+	Mean        = "mean"
+)
+
+// Point is one forecast value with its prediction interval.
+type Point struct {
+	Value float64
+	Lower float64
+	Upper float64
+}
+
+// Model is a fitted forecast model.
+type Model struct {
+	Kind   string
+	Alpha  float64 // level smoothing
+	Beta   float64 // trend smoothing
+	Gamma  float64 // seasonal smoothing
+	Season int     // season length in periods, 0 without seasonality
+	Sigma  float64 // standard deviation of the one-step-ahead errors
+
+	level, trend float64
+	seasonal     []float64 // the last Season seasonal terms, oldest first
+}
+
+// grid holds the smoothing values tried when fitting.
+var grid = []float64{0.05, 0.15, 0.25, 0.35, 0.45, 0.55, 0.65, 0.75, 0.85, 0.95}
+
+// run smooths y with the given parameters and returns the final state and
+// the sum of squared one-step-ahead errors.
+func run(y []float64, season int, alpha, beta, gamma float64) (level, trend float64, seasonal []float64, sse float64) {
+	start := 0
+	switch {
+	case season > 0:
+		var first, second float64
+		for i := 0; i < season; i++ {
+			first += y[i]
+			second += y[season+i]
+		}
+		first /= float64(season)
+		second /= float64(season)
+		level, trend = first, (second-first)/float64(season)
+		seasonal = make([]float64, season)
+		for i := range seasonal {
+			seasonal[i] = y[i] - first
+		}
+		start = season
+		// The first season only initialises the state; smoothing starts
+		// from the level at its end.
+		level += trend * float64(season-1)
+	default:
+		level, trend = y[0], y[1]-y[0]
+		start = 1
+	}
+	for t := start; t < len(y); t++ {
+		s := 0.0
+		if season > 0 {
+			s = seasonal[0]
+		}
+		forecast := level + trend + s
+		err := y[t] - forecast
+		sse += err * err
+		prevLevel := level
+		level = alpha*(y[t]-s) + (1-alpha)*(level+trend)
+		trend = beta*(level-prevLevel) + (1-beta)*trend
+		if season > 0 {
+			next := gamma*(y[t]-level) + (1-gamma)*s
+			seasonal = append(seasonal[1:], next)
+		}
+	}
+	return level, trend, seasonal, sse
+}
+
+// Fit chooses the model for y and its smoothing parameters by minimising
+// the one-step-ahead squared error. season is the seasonal period, e.g. 7
+// for daily data; at least two full seasons are needed to use it.
+func Fit(y []float64, season int) Model {
+	switch {
+	case season > 1 && len(y) >= 2*season+1:
+	case len(y) >= 3:
+		season = 0
+	default:
+		m := Model{Kind: Mean}
+		var sum float64
+		for _, v := range y {
+			sum += v
+		}
+		if len(y) > 0 {
+			m.level = sum / float64(len(y))
+		}
+		var ss float64
+		for _, v := range y {
+			ss += (v - m.level) * (v - m.level)
+		}
+		if len(y) > 1 {
+			m.Sigma = math.Sqrt(ss / float64(len(y)-1))
+		}
+		return m
+	}
+
+	best := Model{Kind: Holt, Season: season}
+	if season > 0 {
+		best.Kind = HoltWinters
+	}
+	bestSSE := math.Inf(1)
+	gammas := grid
+	if season == 0 {
+		gammas = []float64{0}
+	}
+	for _, a := range grid {
+		for _, b := range grid {
+			for _, g := range gammas {
+				level, trend, seasonal, sse := run(y, season, a, b, g)
+				if sse < bestSSE {
+					bestSSE = sse
+					best.Alpha, best.Beta, best.Gamma = a, b, g
+					best.level, best.trend, best.seasonal = level, trend, seasonal
+				}
+			}
+		}
+	}
+	start := 1
+	if season > 0 {
+		start = season
+	}
+	best.Sigma = math.Sqrt(bestSSE / float64(len(y)-start))
+	return best
+}
+
+// Forecast projects the model h periods ahead. Intervals are value ± z
+// standard errors, using the additive Holt-Winters error variance; values
+// and bounds are kept at zero or above since counts cannot go negative.
+func (m Model) Forecast(h int, z float64) []Point {
+	points := make([]Point, h)
+	var cumulative float64 // sum of squared error coefficients for steps before this one
+	for i := 1; i <= h; i++ {
+		v := m.level
+		if m.Kind != Mean {
+			v += float64(i) * m.trend
+		}
+		if m.Season > 0 {
+			v += m.seasonal[(i-1)%m.Season]
+		}
+		se := m.Sigma * math.Sqrt(1+cumulative)
+		if m.Kind == Mean {
+			se = m.Sigma
+		}
+		points[i-1] = Point{Value: math.Max(v, 0), Lower: math.Max(v-z*se, 0), Upper: math.Max(v+z*se, 0)}
+
+		c := m.Alpha * (1 + float64(i)*m.Beta)
+		if m.Season > 0 && i%m.Season == 0 {
+			c += m.Gamma
+		}
+		cumulative += c * c
+	}
+	return points
+}
@@ -0,0 +1,60 @@
+package forecast
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFitRecoversWeeklySeason(t *testing.T) {
+	pattern := []float64{10, 12, 11, 13, 9, 4, 5}
+	// Eight weeks of the pattern on a trend of half an egg a week.
+	var y []float64
+	for t := 0; t < 56; t++ {
+		y = append(y, pattern[t%7]+0.5*float64(t)/7)
+	}
+	m := Fit(y, 7)
+	if m.Kind != HoltWinters || m.Season != 7 {
+		t.Fatalf("expected a seasonal model, got %+v", m)
+	}
+	points := m.Forecast(14, 1.96)
+	for i, p := range points {
+		want := pattern[i%7] + 0.5*float64(56+i)/7
+		if math.Abs(p.Value-want) > 0.5 {
+			t.Errorf("step %d: predicted %.2f, want about %.2f", i+1, p.Value, want)
+		}
+		if p.Lower > p.Value || p.Upper < p.Value {
+			t.Errorf("step %d: interval %.2f-%.2f does not contain %.2f", i+1, p.Lower, p.Upper, p.Value)
+		}
+	}
+	// Intervals widen with the horizon.
+	if w1, w14 := points[0].Upper-points[0].Lower, points[13].Upper-points[13].Lower; w14 < w1 {
+		t.Errorf("interval narrowed from %.2f to %.2f", w1, w14)
+	}
+}
+
+func TestFitFallsBack(t *testing.T) {
+	if m := Fit([]float64{1, 2, 3, 4, 5}, 7); m.Kind != Holt {
+		t.Errorf("short series: got %s, want %s", m.Kind, Holt)
+	} else if p := m.Forecast(1, 1.28); math.Abs(p[0].Value-6) > 0.5 {
+		t.Errorf("linear trend: predicted %.2f, want about 6", p[0].Value)
+	}
+	m := Fit([]float64{4, 6}, 7)
+	if m.Kind != Mean {
+		t.Fatalf("two points: got %s, want %s", m.Kind, Mean)
+	}
+	if p := m.Forecast(3, 1.28); p[2].Value != 5 || p[2].Lower >= 5 {
+		t.Errorf("mean model: got %+v", p[2])
+	}
+	if p := Fit(nil, 7).Forecast(1, 1.28); p[0].Value != 0 || p[0].Upper != 0 {
+		t.Errorf("empty series: got %+v", p[0])
+	}
+}
+
+func TestForecastNeverNegative(t *testing.T) {
+	y := []float64{20, 18, 15, 12, 9, 6, 3, 1, 0, 0}
+	for _, p := range Fit(y, 0).Forecast(10, 1.96) {
+		if p.Value < 0 || p.Lower < 0 || p.Upper < 0 {
+			t.Fatalf("negative forecast: %+v", p)
+		}
+	}
+}
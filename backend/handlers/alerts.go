@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"time"
+
+	"egg-tracker/backend/anomaly"
+	"egg-tracker/backend/logging"
+	"egg-tracker/backend/models"
+	"egg-tracker/backend/notify"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// anomalyWindowDays is how many days before the checked day form its baseline.
+	anomalyWindowDays = 28
+	// anomalyMinBaselineDays is the least history a coop needs before it is checked.
+	anomalyMinBaselineDays = 14
+	// anomalyMinEggs is the smallest difference from the baseline worth an
+	// alert, so that a small flock laying one egg fewer is not flagged.
+	anomalyMinEggs = 2
+)
+
+type DetectionResult struct {
+	Date    string         `json:"date"`
+	Checked int            `json:"checked"` // coop and species pairs with enough history
+	Alerts  []models.Alert `json:"alerts"`  // alerts raised by this run
+}
+
+// DetectAnomalies scores each coop and species' collections on day against
+// the previous anomalyWindowDays days, counting days without collections as
+// zero from the pair's first collection, and stores an alert for each
+// anomalous one. Alerts not yet notified, including ones a failed earlier
+// run left behind, are then queued as notifications.
+func DetectAnomalies(db *sql.DB, day time.Time) (DetectionResult, error) {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	result := DetectionResult{Date: day.Format("2006-01-02"), Alerts: []models.Alert{}}
+	windowStart := day.AddDate(0, 0, -anomalyWindowDays)
+	rows, err := db.Query(`
+	SELECT COALESCE(coop, ''), species, date(date), SUM(quantity),
+		(SELECT MIN(date(f.date)) FROM inventory_actions f
+		 WHERE f.action = 'collected' AND COALESCE(f.coop, '') = COALESCE(ia.coop, '') AND f.species = ia.species)
+	FROM inventory_actions ia
+	WHERE action = 'collected' AND date(date) BETWEEN date(?) AND date(?)
+	GROUP BY 1, 2, 3 ORDER BY 1, 2, 3`, windowStart.Format("2006-01-02"), result.Date)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+	type series struct {
+		coop, species string
+		first         time.Time
+		eggs          map[string]float64
+	}
+	var pairs []*series
+	var cur *series
+	for rows.Next() {
+		var coop, species, date, first string
+		var eggs float64
+		if err := rows.Scan(&coop, &species, &date, &eggs, &first); err != nil {
+			return result, err
+		}
+		if cur == nil || cur.coop != coop || cur.species != species {
+			f, err := time.Parse("2006-01-02", first)
+			if err != nil {
+				return result, err
+			}
+			cur = &series{coop: coop, species: species, first: f, eggs: map[string]float64{}}
+			pairs = append(pairs, cur)
+		}
+		cur.eggs[date] = eggs
+	}
+	if err := rows.Err(); err != nil {
+		return result, err
+	}
+	rows.Close()
+
+	for _, p := range pairs {
+		start := windowStart
+		if p.first.After(start) {
+			start = p.first
+		}
+		var baseline []float64
+		for d := start; d.Before(day); d = d.AddDate(0, 0, 1) {
+			baseline = append(baseline, p.eggs[d.Format("2006-01-02")])
+		}
+		if len(baseline) < anomalyMinBaselineDays {
+			continue
+		}
+		result.Checked++
+		x := p.eggs[result.Date]
+		r := anomaly.Score(baseline, x)
+		if !r.Anomalous() || math.Abs(x-r.Median) < anomalyMinEggs {
+			continue
+		}
+		a := models.Alert{Date: day, Coop: p.coop, Species: p.species, Kind: "drop", Eggs: int(x), Baseline: r.Median, MAD: r.MAD}
+		if x > r.Median {
+			a.Kind = "spike"
+		}
+		if !math.IsInf(r.Score, 0) {
+			a.Score = &r.Score
+		}
+		res, err := db.Exec(
+			"INSERT OR IGNORE INTO alerts (date, coop, species, kind, eggs, baseline, mad, score) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			a.Date, a.Coop, a.Species, a.Kind, a.Eggs, a.Baseline, a.MAD, a.Score,
+		)
+		if err != nil {
+			return result, err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue
+		}
+		a.ID, _ = res.LastInsertId()
+		result.Alerts = append(result.Alerts, a)
+	}
+	slog.Info("Anomaly detection finished", "component", "DetectAnomalies", "date", result.Date, "checked", result.Checked, "alerts", len(result.Alerts))
+	if err := queueAlerts(db); err != nil {
+		slog.Warn("Queueing alert notifications failed, will retry next run", "component", "DetectAnomalies", logging.Err(err))
+	}
+	return result, nil
+}
+
+// queueAlerts queues notifications for unacknowledged alerts that have not
+// had one yet and marks them as notified.
+func queueAlerts(db *sql.DB) error {
+	alerts, err := loadAlerts(db, "WHERE notified_at IS NULL AND acknowledged_at IS NULL", nil)
+	if err != nil {
+		return err
+	}
+	for _, a := range alerts {
+		data := struct {
+			models.Alert
+			WindowDays int
+		}{a, anomalyWindowDays}
+		if _, err := queueNotification(db, notify.KindAnomaly, data); err != nil {
+			return fmt.Errorf("queue alert %d: %w", a.ID, err)
+		}
+		if _, err := db.Exec("UPDATE alerts SET notified_at = CURRENT_TIMESTAMP WHERE id = ?", a.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loadAlerts(db *sql.DB, where string, args []interface{}) ([]models.Alert, error) {
+	rows, err := db.Query(
+		"SELECT id, date, coop, species, kind, eggs, baseline, mad, score, notified_at, acknowledged_at, created_at FROM alerts "+where+" ORDER BY date DESC, coop ASC, species ASC",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	alerts := []models.Alert{}
+	for rows.Next() {
+		var a models.Alert
+		var score sql.NullFloat64
+		var notified, acknowledged sql.NullTime
+		if err := rows.Scan(&a.ID, &a.Date, &a.Coop, &a.Species, &a.Kind, &a.Eggs, &a.Baseline, &a.MAD, &score, &notified, &acknowledged, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		if score.Valid {
+			a.Score = &score.Float64
+		}
+		if notified.Valid {
+			a.NotifiedAt = &notified.Time
+		}
+		if acknowledged.Valid {
+			a.AcknowledgedAt = &acknowledged.Time
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, rows.Err()
+}
+
+// ListAlertsHandler lists alerts, newest first. Query parameters: from/to as
+// YYYY-MM-DD, coop, species, acknowledged=true|false.
+func ListAlertsHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		where := "WHERE 1 = 1"
+		var args []interface{}
+		for _, p := range []struct{ param, cond string }{{"from", "date(date) >= date(?)"}, {"to", "date(date) <= date(?)"}} {
+			if s := c.Query(p.param); s != "" {
+				if _, err := time.Parse("2006-01-02", s); err != nil {
+					respondError(c, http.StatusBadRequest, "invalid "+p.param+" date")
+					return
+				}
+				where += " AND " + p.cond
+				args = append(args, s)
+			}
+		}
+		if coop, ok := c.GetQuery("coop"); ok {
+			where += " AND coop = ?"
+			args = append(args, coop)
+		}
+		if species := c.Query("species"); species != "" {
+			where += " AND species = ?"
+			args = append(args, species)
+		}
+		switch c.Query("acknowledged") {
+		case "":
+		case "true":
+			where += " AND acknowledged_at IS NOT NULL"
+		case "false":
+			where += " AND acknowledged_at IS NULL"
+		default:
+			respondError(c, http.StatusBadRequest, "acknowledged must be true or false")
+			return
+		}
+		alerts, err := loadAlerts(db, where, args)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, alerts)
+	}
+}
+
+func AcknowledgeAlertHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		res, err := db.Exec("UPDATE alerts SET acknowledged_at = COALESCE(acknowledged_at, CURRENT_TIMESTAMP) WHERE id = ?", c.Param("id"))
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			respondError(c, http.StatusNotFound, "alert not found")
+			return
+		}
+		c.JSON(http.StatusOK, MessageResponse{Message: "updated"})
+	}
+}
+
+// DetectAnomaliesHandler runs anomaly detection for ?date= (YYYY-MM-DD,
+// default yesterday), e.g. to check a day again after late entries.
+func DetectAnomaliesHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		day := today().AddDate(0, 0, -1)
+		if s := c.Query("date"); s != "" {
+			d, err := time.Parse("2006-01-02", s)
+			if err != nil {
+				respondError(c, http.StatusBadRequest, "invalid date")
+				return
+			}
+			day = d
+		}
+		result, err := DetectAnomalies(db, day)
+		if err != nil {
+			requestLogger(c, "DetectAnomaliesHandler").Error("Detection failed", logging.Err(err))
+			respondError(c, http.StatusInternalServerError, "anomaly detection failed")
+			return
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"egg-tracker/backend/db"
+	"egg-tracker/backend/models"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupAlertsTestDB() (*sql.DB, func()) {
+	testDBPath := "test_alerts.db"
+	dbase, _ := sql.Open("sqlite3", testDBPath)
+	db.Migrate(dbase)
+	return dbase, func() {
+		dbase.Close()
+		os.Remove(testDBPath)
+	}
+}
+
+func TestDetectAnomalies(t *testing.T) {
+	dbase, cleanup := setupAlertsTestDB()
+	defer cleanup()
+	day := time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)
+	// Main Coop lays 10 or 11 a day for four weeks, then only 3 on the checked
+	// day; Back Barn keeps laying normally; Pond only started a week ago.
+	for d := day.AddDate(0, 0, -28); d.Before(day); d = d.AddDate(0, 0, 1) {
+		mustExec(t, dbase, "INSERT INTO inventory_actions (quantity, species, coop, action, date) VALUES (?, 'Chicken', 'Main Coop', 'collected', ?)", 10+d.Day()%2, d)
+		mustExec(t, dbase, "INSERT INTO inventory_actions (quantity, species, coop, action, date) VALUES (?, 'Chicken', 'Back Barn', 'collected', ?)", 5+d.Day()%3, d)
+	}
+	for d := day.AddDate(0, 0, -7); d.Before(day); d = d.AddDate(0, 0, 1) {
+		mustExec(t, dbase, "INSERT INTO inventory_actions (quantity, species, coop, action, date) VALUES (4, 'Duck', 'Pond', 'collected', ?)", d)
+	}
+	mustExec(t, dbase, `INSERT INTO inventory_actions (quantity, species, coop, action, date) VALUES
+		(3, 'Chicken', 'Main Coop', 'collected', ?), (6, 'Chicken', 'Back Barn', 'collected', ?)`, day, day)
+
+	mustExec(t, dbase, "INSERT INTO users (id, email, password_hash) VALUES (1, 'keeper@farm.test', 'x')")
+
+	result, err := DetectAnomalies(dbase, day)
+	if err != nil {
+		t.Fatalf("detect: %v", err)
+	}
+	if result.Checked != 2 || len(result.Alerts) != 1 {
+		t.Fatalf("expected one alert from two checked coops, got %+v", result)
+	}
+	a := result.Alerts[0]
+	if a.Coop != "Main Coop" || a.Kind != "drop" || a.Eggs != 3 || a.Baseline < 10 || a.Baseline > 11 || a.Score == nil || *a.Score > -3.5 {
+		t.Errorf("unexpected alert: %+v", a)
+	}
+
+	// Running again neither duplicates the alert nor notifies it twice.
+	result, err = DetectAnomalies(dbase, day)
+	if err != nil || len(result.Alerts) != 0 {
+		t.Fatalf("expected no new alerts, got %+v, %v", result, err)
+	}
+	var subject string
+	var queued int
+	dbase.QueryRow("SELECT COUNT(*), MAX(subject) FROM notification_deliveries WHERE kind = 'anomaly' AND user_id = 1").Scan(&queued, &subject)
+	if queued != 1 || !strings.Contains(subject, "Main Coop") {
+		t.Errorf("expected one anomaly notification, got %d: %q", queued, subject)
+	}
+}
+
+func TestAlertsEndpoints(t *testing.T) {
+	dbase, cleanup := setupAlertsTestDB()
+	defer cleanup()
+	mustExec(t, dbase, `INSERT INTO alerts (id, date, coop, species, kind, eggs, baseline, mad, score) VALUES
+		(1, '2025-06-29', 'Main Coop', 'Chicken', 'drop', 0, 10, 1, NULL),
+		(2, '2025-06-30', 'Pond', 'Duck', 'spike', 12, 4, 0.5, 10.8)`)
+
+	router := gin.Default()
+	router.GET("/api/alerts", ListAlertsHandler(dbase))
+	router.POST("/api/alerts/:id/acknowledge", AcknowledgeAlertHandler(dbase))
+	router.POST("/api/alerts/detect", DetectAnomaliesHandler(dbase))
+
+	if w := doJSON(router, "POST", "/api/alerts/1/acknowledge", nil); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := doJSON(router, "POST", "/api/alerts/9/acknowledge", nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+
+	var alerts []models.Alert
+	w := doJSON(router, "GET", "/api/alerts?acknowledged=false", nil)
+	json.Unmarshal(w.Body.Bytes(), &alerts)
+	if len(alerts) != 1 || alerts[0].ID != 2 || alerts[0].Score == nil || *alerts[0].Score != 10.8 {
+		t.Fatalf("unexpected unacknowledged alerts: %s", w.Body.String())
+	}
+	w = doJSON(router, "GET", "/api/alerts?from=2025-06-01&to=2025-06-29&coop=Main%20Coop", nil)
+	alerts = nil
+	json.Unmarshal(w.Body.Bytes(), &alerts)
+	if len(alerts) != 1 || alerts[0].ID != 1 || alerts[0].AcknowledgedAt == nil || alerts[0].Score != nil {
+		t.Fatalf("unexpected filtered alerts: %s", w.Body.String())
+	}
+	for _, q := range []string{"from=June", "acknowledged=maybe"} {
+		if w := doJSON(router, "GET", "/api/alerts?"+q, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", q, w.Code)
+		}
+	}
+
+	w = doJSON(router, "POST", "/api/alerts/detect?date=2025-07-01", nil)
+	var result DetectionResult
+	json.Unmarshal(w.Body.Bytes(), &result)
+	if w.Code != http.StatusOK || result.Date != "2025-07-01" || result.Checked != 0 {
+		t.Errorf("unexpected detection result %d: %s", w.Code, w.Body.String())
+	}
+}
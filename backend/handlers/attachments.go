@@ -0,0 +1,377 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"egg-tracker/backend/logging"
+	"egg-tracker/backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// attachmentsDir holds uploaded files next to the databases in the data dir.
+var attachmentsDir = "/app/data/attachments"
+
+const (
+	maxAttachmentBytes = 10 << 20
+	thumbnailSize      = 256      // longest edge in pixels
+	maxThumbnailPixels = 50 << 20 // larger images are stored without a thumbnail
+)
+
+// attachmentEntities maps the records attachments can belong to onto their tables.
+var attachmentEntities = map[string]string{
+	"inventory_action": "inventory_actions",
+	"bird":             "birds",
+	"feed_purchase":    "feed_purchases",
+}
+
+// attachmentTypes are the sniffed content types accepted for upload, with the
+// extension files are stored under.
+var attachmentTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+func thumbnailPath(storageName string) string {
+	return filepath.Join(attachmentsDir, "thumbs", storageName+".jpg")
+}
+
+// makeThumbnail scales an image to fit within thumbnailSize, averaging the
+// source pixels behind each thumbnail pixel, and encodes it as JPEG.
+func makeThumbnail(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width*cfg.Height > maxThumbnailPixels {
+		return nil, errors.New("image too large for a thumbnail")
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+	b := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	tw, th := w, h
+	if w > thumbnailSize || h > thumbnailSize {
+		if w >= h {
+			tw, th = thumbnailSize, max(1, h*thumbnailSize/w)
+		} else {
+			tw, th = max(1, w*thumbnailSize/h), thumbnailSize
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := y*h/th, max((y+1)*h/th, y*h/th+1)
+		for x := 0; x < tw; x++ {
+			x0, x1 := x*w/tw, max((x+1)*w/tw, x*w/tw+1)
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					for i := 0; i < 4; i++ {
+						sum[i] += int(row[sx*4+i])
+					}
+				}
+			}
+			n := (y1 - y0) * (x1 - x0)
+			off := y*dst.Stride + x*4
+			for i := 0; i < 4; i++ {
+				dst.Pix[off+i] = uint8(sum[i] / n)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// saveAttachmentFile writes an upload into attachmentsDir under a random name.
+func saveAttachmentFile(r io.Reader, ext string) (string, error) {
+	if err := os.MkdirAll(attachmentsDir, 0755); err != nil {
+		return "", err
+	}
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	name := hex.EncodeToString(raw) + ext
+	tmp, err := os.CreateTemp(attachmentsDir, ".upload-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	return name, os.Rename(tmp.Name(), filepath.Join(attachmentsDir, name))
+}
+
+// removeAttachmentFiles deletes stored files and thumbnails. Failures are
+// only logged: the metadata is already gone.
+func removeAttachmentFiles(storageNames []string) {
+	for _, name := range storageNames {
+		for _, p := range []string{filepath.Join(attachmentsDir, name), thumbnailPath(name)} {
+			if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+				slog.Warn("Failed to remove file", "component", "attachments", "path", p, logging.Err(err))
+			}
+		}
+	}
+}
+
+// deleteAttachments removes the attachment rows of a record and returns the
+// stored file names, to be passed to removeAttachmentFiles once committed.
+func deleteAttachments(q sqlExecutor, entityType string, entityID interface{}) ([]string, error) {
+	rows, err := q.Query("SELECT storage_name FROM attachments WHERE entity_type = ? AND entity_id = ?", entityType, entityID)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	_, err = q.Exec("DELETE FROM attachments WHERE entity_type = ? AND entity_id = ?", entityType, entityID)
+	return names, err
+}
+
+const attachmentColumns = "id, entity_type, entity_id, filename, content_type, size_bytes, has_thumbnail, caption, created_at"
+
+func scanAttachment(row rowScanner) (models.Attachment, error) {
+	var a models.Attachment
+	var caption sql.NullString
+	if err := row.Scan(&a.ID, &a.EntityType, &a.EntityID, &a.Filename, &a.ContentType, &a.SizeBytes, &a.HasThumbnail, &caption, &a.CreatedAt); err != nil {
+		return a, err
+	}
+	if caption.Valid {
+		a.Caption = &caption.String
+	}
+	return a, nil
+}
+
+// UploadAttachmentHandler stores a file from the multipart "file" field
+// against the :id record of entityType, with an optional "caption" field.
+// The type is sniffed from the contents; images get a JPEG thumbnail.
+func UploadAttachmentHandler(db *sql.DB, entityType string) gin.HandlerFunc {
+	table := attachmentEntities[entityType]
+	return func(c *gin.Context) {
+		var entityID int64
+		err := db.QueryRow("SELECT id FROM "+table+" WHERE id = ?", c.Param("id")).Scan(&entityID)
+		if err == sql.ErrNoRows {
+			respondError(c, http.StatusNotFound, "record not found")
+			return
+		} else if err != nil {
+			respondServerError(c, err)
+			return
+		}
+
+		// Leave room for the multipart framing and caption around the file.
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxAttachmentBytes+64<<10)
+		file, err := c.FormFile("file")
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				respondError(c, http.StatusRequestEntityTooLarge, "file too large")
+				return
+			}
+			respondError(c, http.StatusBadRequest, "missing file")
+			return
+		}
+		if file.Size > maxAttachmentBytes {
+			respondError(c, http.StatusRequestEntityTooLarge, "file too large")
+			return
+		}
+		f, err := file.Open()
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "could not read file")
+			return
+		}
+		defer f.Close()
+		head := make([]byte, 512)
+		n, err := io.ReadFull(f, head)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			respondError(c, http.StatusBadRequest, "could not read file")
+			return
+		}
+		contentType := http.DetectContentType(head[:n])
+		ext, ok := attachmentTypes[contentType]
+		if !ok {
+			respondError(c, http.StatusUnsupportedMediaType, "unsupported file type "+contentType)
+			return
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			respondError(c, http.StatusBadRequest, "could not read file")
+			return
+		}
+
+		name, err := saveAttachmentFile(f, ext)
+		if err != nil {
+			requestLogger(c, "UploadAttachmentHandler").Error("Failed to store file", logging.Err(err))
+			respondError(c, http.StatusInternalServerError, "failed to store file")
+			return
+		}
+		hasThumb := false
+		if ext == ".jpg" || ext == ".png" || ext == ".gif" {
+			thumb, err := makeThumbnail(filepath.Join(attachmentsDir, name))
+			if err == nil {
+				err = os.MkdirAll(filepath.Dir(thumbnailPath(name)), 0755)
+			}
+			if err == nil {
+				err = os.WriteFile(thumbnailPath(name), thumb, 0644)
+			}
+			if err != nil {
+				requestLogger(c, "UploadAttachmentHandler").Warn("No thumbnail", "filename", file.Filename, logging.Err(err))
+			}
+			hasThumb = err == nil
+		}
+
+		caption := nullIfEmpty(c.PostForm("caption"))
+		res, err := db.Exec(
+			"INSERT INTO attachments (entity_type, entity_id, filename, content_type, size_bytes, storage_name, has_thumbnail, caption) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			entityType, entityID, filepath.Base(file.Filename), contentType, file.Size, name, hasThumb, caption,
+		)
+		if err != nil {
+			removeAttachmentFiles([]string{name})
+			respondServerError(c, err)
+			return
+		}
+		id, _ := res.LastInsertId()
+		a, err := scanAttachment(db.QueryRow("SELECT "+attachmentColumns+" FROM attachments WHERE id = ?", id))
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, a)
+	}
+}
+
+// ListAttachmentsHandler lists the attachments of the :id record of entityType.
+func ListAttachmentsHandler(db *sql.DB, entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rows, err := db.Query(
+			"SELECT "+attachmentColumns+" FROM attachments WHERE entity_type = ? AND entity_id = ? ORDER BY created_at ASC, id ASC",
+			entityType, c.Param("id"),
+		)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		defer rows.Close()
+		attachments := []models.Attachment{}
+		for rows.Next() {
+			a, err := scanAttachment(rows)
+			if err != nil {
+				respondServerError(c, err)
+				return
+			}
+			attachments = append(attachments, a)
+		}
+		c.JSON(http.StatusOK, attachments)
+	}
+}
+
+// serveAttachment looks up the :id attachment and sends either the file or
+// its thumbnail inline.
+func serveAttachment(c *gin.Context, db *sql.DB, thumbnail bool) {
+	var filename, contentType, storageName string
+	var hasThumb bool
+	err := db.QueryRow("SELECT filename, content_type, storage_name, has_thumbnail FROM attachments WHERE id = ?", c.Param("id")).
+		Scan(&filename, &contentType, &storageName, &hasThumb)
+	if err == sql.ErrNoRows {
+		respondError(c, http.StatusNotFound, "attachment not found")
+		return
+	} else if err != nil {
+		respondServerError(c, err)
+		return
+	}
+	path := filepath.Join(attachmentsDir, storageName)
+	if thumbnail {
+		if !hasThumb {
+			respondError(c, http.StatusNotFound, "attachment has no thumbnail")
+			return
+		}
+		path, contentType = thumbnailPath(storageName), "image/jpeg"
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		respondError(c, http.StatusNotFound, "attachment file missing")
+		return
+	} else if err != nil {
+		respondServerError(c, err)
+		return
+	}
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": filename}))
+	c.Data(http.StatusOK, contentType, data)
+}
+
+func GetAttachmentFileHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) { serveAttachment(c, db, false) }
+}
+
+func GetAttachmentThumbnailHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) { serveAttachment(c, db, true) }
+}
+
+func DeleteAttachmentHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var storageName string
+		err := db.QueryRow("SELECT storage_name FROM attachments WHERE id = ?", c.Param("id")).Scan(&storageName)
+		if err == sql.ErrNoRows {
+			respondError(c, http.StatusNotFound, "attachment not found")
+			return
+		} else if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		if _, err := db.Exec("DELETE FROM attachments WHERE id = ?", c.Param("id")); err != nil {
+			respondServerError(c, err)
+			return
+		}
+		removeAttachmentFiles([]string{storageName})
+		c.JSON(http.StatusOK, MessageResponse{Message: "deleted"})
+	}
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"egg-tracker/backend/db"
+	"egg-tracker/backend/models"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupAttachmentsTestDB(t *testing.T) (*sql.DB, func()) {
+	testDBPath := "test_attachments.db"
+	dbase, _ := sql.Open("sqlite3", testDBPath)
+	db.Migrate(dbase)
+	prev := attachmentsDir
+	attachmentsDir = t.TempDir()
+	return dbase, func() {
+		attachmentsDir = prev
+		dbase.Close()
+		os.Remove(testDBPath)
+	}
+}
+
+func uploadFile(router *gin.Engine, path, filename string, data []byte) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("file", filename)
+	fw.Write(data)
+	mw.WriteField("caption", "Pecked comb")
+	mw.Close()
+	req, _ := http.NewRequest("POST", path, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAttachmentUploadThumbnailAndDelete(t *testing.T) {
+	dbase, cleanup := setupAttachmentsTestDB(t)
+	defer cleanup()
+	router := gin.Default()
+	router.POST("/api/birds/:id/attachments", UploadAttachmentHandler(dbase, "bird"))
+	router.GET("/api/birds/:id/attachments", ListAttachmentsHandler(dbase, "bird"))
+	router.DELETE("/api/birds/:id", DeleteBirdHandler(dbase))
+	router.GET("/api/attachments/:id/file", GetAttachmentFileHandler(dbase))
+	router.GET("/api/attachments/:id/thumbnail", GetAttachmentThumbnailHandler(dbase))
+	router.POST("/api/backup", BackupHandler(dbase))
+	mustExec(t, dbase, "INSERT INTO birds (id, name, species, status) VALUES (1, 'Henrietta', 'Chicken', 'active')")
+
+	img := image.NewRGBA(image.Rect(0, 0, 600, 300))
+	for x := 0; x < 600; x++ {
+		for y := 0; y < 300; y++ {
+			img.Set(x, y, color.RGBA{200, 40, 40, 255})
+		}
+	}
+	var pngData bytes.Buffer
+	png.Encode(&pngData, img)
+
+	// The type comes from the contents, not the file name
+	w := uploadFile(router, "/api/birds/1/attachments", "injury.txt", pngData.Bytes())
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var a models.Attachment
+	json.Unmarshal(w.Body.Bytes(), &a)
+	if a.ContentType != "image/png" || !a.HasThumbnail || a.Caption == nil || a.SizeBytes != int64(pngData.Len()) {
+		t.Errorf("unexpected attachment: %+v", a)
+	}
+
+	if w = uploadFile(router, "/api/birds/1/attachments", "notes.png", []byte("just some text")); w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("expected 415 for a text file, got %d", w.Code)
+	}
+	if w = uploadFile(router, "/api/birds/2/attachments", "x.png", pngData.Bytes()); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing bird, got %d", w.Code)
+	}
+	big := append(append([]byte{}, pngData.Bytes()...), make([]byte, maxAttachmentBytes)...)
+	if w = uploadFile(router, "/api/birds/1/attachments", "big.png", big); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for an oversized file, got %d", w.Code)
+	}
+
+	w = doJSON(router, "GET", "/api/attachments/"+itoa(a.ID)+"/file", nil)
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), pngData.Bytes()) || w.Header().Get("Content-Type") != "image/png" {
+		t.Errorf("expected the original file back, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	w = doJSON(router, "GET", "/api/attachments/"+itoa(a.ID)+"/thumbnail", nil)
+	thumb, err := jpeg.Decode(w.Body)
+	if err != nil || thumb.Bounds().Dx() != thumbnailSize || thumb.Bounds().Dy() != thumbnailSize/2 {
+		t.Errorf("expected a %dx%d JPEG thumbnail, got %v %v", thumbnailSize, thumbnailSize/2, err, thumb)
+	}
+
+	defer os.RemoveAll("backups")
+	w = doJSON(router, "POST", "/api/backup", nil)
+	matches, _ := filepath.Glob(filepath.Join("backups", "*_attachments", "thumbs", "*.jpg"))
+	if w.Code != http.StatusOK || len(matches) != 1 {
+		t.Errorf("expected attachments in the backup, got %d: %s", w.Code, w.Body.String())
+	}
+
+	doJSON(router, "DELETE", "/api/birds/1", nil)
+	w = doJSON(router, "GET", "/api/birds/1/attachments", nil)
+	if w.Body.String() != "[]" {
+		t.Errorf("expected attachments removed with the bird, got %s", w.Body.String())
+	}
+	if files, _ := filepath.Glob(filepath.Join(attachmentsDir, "*.png")); len(files) != 0 {
+		t.Errorf("expected stored files removed, got %v", files)
+	}
+}
@@ -1,52 +1,122 @@
 package handlers
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
 	"os"
 	"path/filepath"
 	"time"
 
+	"egg-tracker/backend/metrics"
+	"egg-tracker/backend/notify"
+
 	"github.com/gin-gonic/gin"
 )
 
-// BackupHandler copies SQLite and DuckDB files to /backups/ with timestamps.
-func BackupHandler() gin.HandlerFunc {
+// copyTree copies the files under src into dst, creating directories as
+// needed, and returns the number of bytes copied.
+func copyTree(src, dst string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.Create(target)
+		if err != nil {
+			return err
+		}
+		n, err := io.Copy(out, in)
+		if err != nil {
+			out.Close()
+			return err
+		}
+		size += n
+		return out.Close()
+	})
+	return size, err
+}
+
+// BackupResponse lists the files written by a backup.
+type BackupResponse struct {
+	Message string   `json:"message"`
+	Files   []string `json:"files"`
+}
+
+// BackupHandler copies SQLite and DuckDB files to /backups/ with timestamps,
+// along with the attachments directory. Failures send a backup_failed
+// notification. Durations and sizes are recorded as metrics.
+func BackupHandler(db *sql.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		started := time.Now()
+		fail := func(msg string) {
+			respondError(c, http.StatusInternalServerError, msg)
+			notifyFailure(db, notify.KindBackupFailed, started, msg)
+			metrics.ObserveBackup(started, 0, errors.New(msg))
+		}
 		sqlitePath := "eggtracker.db"
 		duckdbPath := "eggtracker.duckdb"
 		backupDir := "backups"
 		if err := os.MkdirAll(backupDir, 0755); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create backup dir"})
+			fail("failed to create backup dir")
 			return
 		}
-		timestamp := time.Now().Format("20060102_150405")
+		timestamp := started.Format("20060102_150405")
 		files := []string{sqlitePath, duckdbPath}
 		var backedUp []string
+		var size int64
 		for _, f := range files {
 			if _, err := os.Stat(f); err == nil {
 				backupName := filepath.Join(backupDir, fmt.Sprintf("%s_%s", timestamp, filepath.Base(f)))
 				src, err := os.Open(f)
 				if err != nil {
-					c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to open file: " + f})
+					fail("failed to open file: " + f)
 					return
 				}
 				defer src.Close()
 				dst, err := os.Create(backupName)
 				if err != nil {
-					c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create backup: " + backupName})
+					fail("failed to create backup: " + backupName)
 					return
 				}
-				if _, err := io.Copy(dst, src); err != nil {
+				n, err := io.Copy(dst, src)
+				if err != nil {
 					dst.Close()
-					c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to copy file: " + f})
+					fail("failed to copy file: " + f)
 					return
 				}
 				dst.Close()
+				size += n
 				backedUp = append(backedUp, backupName)
 			}
 		}
-		c.JSON(http.StatusOK, gin.H{"message": "backup complete", "files": backedUp})
+		if info, err := os.Stat(attachmentsDir); err == nil && info.IsDir() {
+			backupName := filepath.Join(backupDir, timestamp+"_attachments")
+			n, err := copyTree(attachmentsDir, backupName)
+			if err != nil {
+				fail("failed to copy attachments")
+				return
+			}
+			size += n
+			backedUp = append(backedUp, backupName)
+		}
+		metrics.ObserveBackup(started, size, nil)
+		c.JSON(http.StatusOK, BackupResponse{Message: "backup complete", Files: backedUp})
 	}
 }
@@ -24,8 +24,10 @@ func TestBackupHandler_CreatesBackupFiles(t *testing.T) {
 	defer os.Remove(sqliteFile)
 	defer os.Remove(duckdbFile)
 
+	dbase, cleanup := setupNotificationsTestDB()
+	defer cleanup()
 	r := gin.Default()
-	r.POST("/api/backup", BackupHandler())
+	r.POST("/api/backup", BackupHandler(dbase))
 
 	req, _ := http.NewRequest("POST", "/api/backup", nil)
 	w := httptest.NewRecorder()
@@ -0,0 +1,423 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"egg-tracker/backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BirdInput struct {
+	Name       string  `json:"name"`
+	BandID     *string `json:"band_id"`
+	Species    string  `json:"species" binding:"required"`
+	Breed      string  `json:"breed"`
+	HatchDate  string  `json:"hatch_date"` // ISO8601 date, optional
+	Coop       string  `json:"coop" binding:"required"`
+	Status     string  `json:"status"`      // defaults to "active"
+	StatusDate string  `json:"status_date"` // ISO8601 date, defaults to today when leaving the flock
+	Notes      *string `json:"notes"`
+	// ArrivalDate is when the bird joined its first coop; only used on create.
+	// Defaults to the hatch date, or today when that is unknown.
+	ArrivalDate string `json:"arrival_date"`
+}
+
+type BirdTransferInput struct {
+	Coop  string  `json:"coop" binding:"required"`
+	Date  string  `json:"date" binding:"required"` // ISO8601 date
+	Notes *string `json:"notes"`
+}
+
+var birdStatuses = map[string]bool{"active": true, "sold": true, "deceased": true}
+
+// parseOptionalDate parses an ISO8601 date, returning nil for an empty string.
+func parseOptionalDate(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func today() time.Time {
+	d, _ := time.Parse("2006-01-02", time.Now().Format("2006-01-02"))
+	return d
+}
+
+// validateBird normalises the input and parses its dates.
+func validateBird(input *BirdInput) (hatch, statusDate *time.Time, msg string) {
+	if input.Name == "" && (input.BandID == nil || *input.BandID == "") {
+		return nil, nil, "name or band_id is required"
+	}
+	if input.Status == "" {
+		input.Status = "active"
+	}
+	if !birdStatuses[input.Status] {
+		return nil, nil, "invalid status"
+	}
+	hatch, err := parseOptionalDate(input.HatchDate)
+	if err != nil {
+		return nil, nil, "invalid hatch_date"
+	}
+	statusDate, err = parseOptionalDate(input.StatusDate)
+	if err != nil {
+		return nil, nil, "invalid status_date"
+	}
+	if input.Status == "active" {
+		statusDate = nil
+	} else if statusDate == nil {
+		d := today()
+		statusDate = &d
+	}
+	return hatch, statusDate, ""
+}
+
+const birdColumns = "id, name, band_id, species, breed, hatch_date, coop, status, status_date, notes, incubation_batch_id, created_at, updated_at"
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanBird(row rowScanner) (models.Bird, error) {
+	var b models.Bird
+	var name, bandID, breed, coop, notes sql.NullString
+	var hatch, statusDate sql.NullTime
+	var batchID sql.NullInt64
+	if err := row.Scan(&b.ID, &name, &bandID, &b.Species, &breed, &hatch, &coop, &b.Status, &statusDate, &notes, &batchID, &b.CreatedAt, &b.UpdatedAt); err != nil {
+		return b, err
+	}
+	b.Name = name.String
+	b.Breed = breed.String
+	b.Coop = coop.String
+	if bandID.Valid {
+		b.BandID = &bandID.String
+	}
+	if hatch.Valid {
+		b.HatchDate = &hatch.Time
+	}
+	if statusDate.Valid {
+		b.StatusDate = &statusDate.Time
+	}
+	if notes.Valid {
+		b.Notes = &notes.String
+	}
+	if batchID.Valid {
+		b.IncubationBatchID = &batchID.Int64
+	}
+	return b, nil
+}
+
+// bandIDValue stores a missing or empty band ID as NULL, which any number of
+// birds may share.
+func bandIDValue(bandID *string) interface{} {
+	if bandID == nil {
+		return nil
+	}
+	return nullIfEmpty(*bandID)
+}
+
+// insertBird adds a bird and records its first placement in input.Coop.
+func insertBird(tx *sql.Tx, input BirdInput, hatch, statusDate *time.Time, arrival time.Time, batchID *int64) (int64, error) {
+	res, err := tx.Exec(
+		"INSERT INTO birds (name, band_id, species, breed, hatch_date, coop, status, status_date, notes, incubation_batch_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		input.Name, bandIDValue(input.BandID), input.Species, input.Breed, hatch, input.Coop, input.Status, statusDate, input.Notes, batchID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, _ := res.LastInsertId()
+	if _, err := tx.Exec(
+		"INSERT INTO bird_transfers (bird_id, from_coop, to_coop, date) VALUES (?, NULL, ?, ?)",
+		id, input.Coop, arrival,
+	); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func CreateBirdHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input BirdInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondBindError(c, err)
+			return
+		}
+		hatch, statusDate, msg := validateBird(&input)
+		if msg != "" {
+			respondError(c, http.StatusBadRequest, msg)
+			return
+		}
+		arrival, err := parseOptionalDate(input.ArrivalDate)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid arrival_date")
+			return
+		}
+		if arrival == nil {
+			arrival = hatch
+		}
+		if arrival == nil {
+			d := today()
+			arrival = &d
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		defer tx.Rollback()
+		id, err := insertBird(tx, input, hatch, statusDate, *arrival, nil)
+		if err != nil {
+			respondWriteError(c, err, "band_id already in use")
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			respondServerError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, IDResponse{ID: id})
+	}
+}
+
+// ListBirdsHandler lists birds, optionally filtered by ?status=, ?coop= and ?species=.
+func ListBirdsHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := "SELECT " + birdColumns + " FROM birds WHERE 1 = 1"
+		var args []interface{}
+		for _, f := range []string{"status", "coop", "species"} {
+			if v := c.Query(f); v != "" {
+				query += " AND " + f + " = ?"
+				args = append(args, v)
+			}
+		}
+		rows, err := db.Query(query+" ORDER BY name ASC, id ASC", args...)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		defer rows.Close()
+		birds := []models.Bird{}
+		for rows.Next() {
+			b, err := scanBird(rows)
+			if err != nil {
+				respondServerError(c, err)
+				return
+			}
+			birds = append(birds, b)
+		}
+		c.JSON(http.StatusOK, birds)
+	}
+}
+
+func GetBirdHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		b, err := scanBird(db.QueryRow("SELECT "+birdColumns+" FROM birds WHERE id = ?", c.Param("id")))
+		if err == sql.ErrNoRows {
+			respondError(c, http.StatusNotFound, "bird not found")
+			return
+		} else if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, b)
+	}
+}
+
+// UpdateBirdHandler replaces a bird's details. Changing the coop records a
+// transfer dated today; use the transfer endpoint to backdate a move.
+func UpdateBirdHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var input BirdInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondBindError(c, err)
+			return
+		}
+		hatch, statusDate, msg := validateBird(&input)
+		if msg != "" {
+			respondError(c, http.StatusBadRequest, msg)
+			return
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		defer tx.Rollback()
+		var birdID int64
+		var currentCoop sql.NullString
+		err = tx.QueryRow("SELECT id, coop FROM birds WHERE id = ?", id).Scan(&birdID, &currentCoop)
+		if err == sql.ErrNoRows {
+			respondError(c, http.StatusNotFound, "bird not found")
+			return
+		} else if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		if _, err := tx.Exec(
+			"UPDATE birds SET name = ?, band_id = ?, species = ?, breed = ?, hatch_date = ?, coop = ?, status = ?, status_date = ?, notes = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?",
+			input.Name, bandIDValue(input.BandID), input.Species, input.Breed, hatch, input.Coop, input.Status, statusDate, input.Notes, birdID,
+		); err != nil {
+			respondWriteError(c, err, "band_id already in use")
+			return
+		}
+		if currentCoop.String != input.Coop {
+			if _, err := tx.Exec(
+				"INSERT INTO bird_transfers (bird_id, from_coop, to_coop, date) VALUES (?, ?, ?, ?)",
+				birdID, currentCoop, input.Coop, today(),
+			); err != nil {
+				respondServerError(c, err)
+				return
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			respondServerError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, MessageResponse{Message: "updated"})
+	}
+}
+
+func DeleteBirdHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		tx, err := db.Begin()
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		defer tx.Rollback()
+		for _, table := range []string{"bird_transfers", "health_events", "bird_status_periods"} {
+			if _, err := tx.Exec("DELETE FROM "+table+" WHERE bird_id = ?", id); err != nil {
+				respondServerError(c, err)
+				return
+			}
+		}
+		files, err := deleteAttachments(tx, "bird", id)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		if _, err := tx.Exec("DELETE FROM birds WHERE id = ?", id); err != nil {
+			respondServerError(c, err)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			respondServerError(c, err)
+			return
+		}
+		removeAttachmentFiles(files)
+		c.JSON(http.StatusOK, MessageResponse{Message: "deleted"})
+	}
+}
+
+// TransferBirdHandler moves a bird to another coop on the given date. A
+// backdated transfer moves it out of the coop it was in on that date, and
+// only changes its current coop if it has not moved since.
+func TransferBirdHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var input BirdTransferInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondBindError(c, err)
+			return
+		}
+		date, err := time.Parse("2006-01-02", input.Date)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid date")
+			return
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		defer tx.Rollback()
+		var birdID int64
+		var currentCoop sql.NullString
+		err = tx.QueryRow("SELECT id, coop FROM birds WHERE id = ?", id).Scan(&birdID, &currentCoop)
+		if err == sql.ErrNoRows {
+			respondError(c, http.StatusNotFound, "bird not found")
+			return
+		} else if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		day := date.Format("2006-01-02")
+		fromCoop := currentCoop
+		err = tx.QueryRow(
+			"SELECT to_coop FROM bird_transfers WHERE bird_id = ? AND date(date) <= date(?) ORDER BY date DESC, id DESC LIMIT 1",
+			birdID, day,
+		).Scan(&fromCoop)
+		if err != nil && err != sql.ErrNoRows {
+			respondServerError(c, err)
+			return
+		}
+		if fromCoop.String == input.Coop {
+			respondError(c, http.StatusBadRequest, "bird is already in that coop")
+			return
+		}
+		var later int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM bird_transfers WHERE bird_id = ? AND date(date) > date(?)", birdID, day).Scan(&later); err != nil {
+			respondServerError(c, err)
+			return
+		}
+		res, err := tx.Exec(
+			"INSERT INTO bird_transfers (bird_id, from_coop, to_coop, date, notes) VALUES (?, ?, ?, ?, ?)",
+			birdID, fromCoop, input.Coop, date, input.Notes,
+		)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		if later == 0 {
+			if _, err := tx.Exec("UPDATE birds SET coop = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", input.Coop, birdID); err != nil {
+				respondServerError(c, err)
+				return
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			respondServerError(c, err)
+			return
+		}
+		transferID, _ := res.LastInsertId()
+		c.JSON(http.StatusCreated, IDResponse{ID: transferID})
+	}
+}
+
+func ListBirdTransfersHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rows, err := db.Query(
+			"SELECT id, bird_id, from_coop, to_coop, date, notes, created_at FROM bird_transfers WHERE bird_id = ? ORDER BY date ASC, id ASC",
+			c.Param("id"),
+		)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		defer rows.Close()
+		transfers := []models.BirdTransfer{}
+		for rows.Next() {
+			var t models.BirdTransfer
+			var from, notes sql.NullString
+			if err := rows.Scan(&t.ID, &t.BirdID, &from, &t.ToCoop, &t.Date, &notes, &t.CreatedAt); err != nil {
+				respondServerError(c, err)
+				return
+			}
+			if from.Valid {
+				t.FromCoop = &from.String
+			}
+			if notes.Valid {
+				t.Notes = &notes.String
+			}
+			transfers = append(transfers, t)
+		}
+		c.JSON(http.StatusOK, transfers)
+	}
+}
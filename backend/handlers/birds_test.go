@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+
+	"egg-tracker/backend/db"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupBirdsTestDB() (*sql.DB, func()) {
+	testDBPath := "test_birds.db"
+	dbase, _ := sql.Open("sqlite3", testDBPath)
+	db.Migrate(dbase)
+	return dbase, func() {
+		dbase.Close()
+		os.Remove(testDBPath)
+	}
+}
+
+func birdsRouter(dbase *sql.DB) *gin.Engine {
+	router := gin.Default()
+	router.POST("/api/birds", CreateBirdHandler(dbase))
+	router.GET("/api/birds", ListBirdsHandler(dbase))
+	router.GET("/api/birds/:id", GetBirdHandler(dbase))
+	router.PUT("/api/birds/:id", UpdateBirdHandler(dbase))
+	router.DELETE("/api/birds/:id", DeleteBirdHandler(dbase))
+	router.POST("/api/birds/:id/transfer", TransferBirdHandler(dbase))
+	router.GET("/api/birds/:id/transfers", ListBirdTransfersHandler(dbase))
+	return router
+}
+
+func doJSON(router *gin.Engine, method, path string, payload interface{}) *httptest.ResponseRecorder {
+	var body *bytes.Buffer
+	if payload != nil {
+		b, _ := json.Marshal(payload)
+		body = bytes.NewBuffer(b)
+	} else {
+		body = &bytes.Buffer{}
+	}
+	req, _ := http.NewRequest(method, path, body)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestBirdsCRUD(t *testing.T) {
+	dbase, cleanup := setupBirdsTestDB()
+	defer cleanup()
+	router := birdsRouter(dbase)
+
+	// Create
+	w := doJSON(router, "POST", "/api/birds", map[string]interface{}{
+		"name":       "Henrietta",
+		"band_id":    "A-001",
+		"species":    "Chicken",
+		"breed":      "Orpington",
+		"hatch_date": "2024-03-01",
+		"coop":       "Main Coop",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	idStr := strconv.Itoa(int(resp["id"].(float64)))
+
+	// Duplicate band ID
+	w = doJSON(router, "POST", "/api/birds", map[string]interface{}{"band_id": "A-001", "species": "Chicken", "coop": "Main Coop"})
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for duplicate band_id, got %d", w.Code)
+	}
+
+	// Update status
+	w = doJSON(router, "PUT", "/api/birds/"+idStr, map[string]interface{}{
+		"name":        "Henrietta",
+		"band_id":     "A-001",
+		"species":     "Chicken",
+		"breed":       "Orpington",
+		"hatch_date":  "2024-03-01",
+		"coop":        "Main Coop",
+		"status":      "sold",
+		"status_date": "2025-01-10",
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on update, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Get
+	w = doJSON(router, "GET", "/api/birds/"+idStr, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on get, got %d", w.Code)
+	}
+	var bird map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &bird)
+	if bird["status"] != "sold" {
+		t.Errorf("expected status sold, got %v", bird["status"])
+	}
+
+	// List with filter
+	w = doJSON(router, "GET", "/api/birds?status=active", nil)
+	var list []map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list) != 0 {
+		t.Errorf("expected no active birds, got %d", len(list))
+	}
+
+	// Delete
+	w = doJSON(router, "DELETE", "/api/birds/"+idStr, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on delete, got %d", w.Code)
+	}
+	w = doJSON(router, "GET", "/api/birds/"+idStr, nil)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 after delete, got %d", w.Code)
+	}
+}
+
+func TestBirdsInvalidInput(t *testing.T) {
+	dbase, cleanup := setupBirdsTestDB()
+	defer cleanup()
+	router := birdsRouter(dbase)
+
+	cases := []map[string]interface{}{
+		{"species": "Chicken", "coop": "Main Coop"},                                         // no name or band
+		{"name": "Hen", "species": "Chicken", "coop": "Main Coop", "status": "escaped"},     // bad status
+		{"name": "Hen", "species": "Chicken", "coop": "Main Coop", "hatch_date": "03/2024"}, // bad date
+		{"name": "Hen", "coop": "Main Coop"},                                                // missing species
+	}
+	for i, payload := range cases {
+		w := doJSON(router, "POST", "/api/birds", payload)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("case %d: expected 400, got %d", i, w.Code)
+		}
+	}
+}
+
+func TestBirdTransferHistory(t *testing.T) {
+	dbase, cleanup := setupBirdsTestDB()
+	defer cleanup()
+	router := birdsRouter(dbase)
+
+	w := doJSON(router, "POST", "/api/birds", map[string]interface{}{
+		"name": "Gertie", "species": "Goose", "coop": "Main Coop", "arrival_date": "2024-04-01",
+	})
+	var resp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	idStr := strconv.Itoa(int(resp["id"].(float64)))
+
+	w = doJSON(router, "POST", "/api/birds/"+idStr+"/transfer", map[string]interface{}{"coop": "Back Barn", "date": "2024-06-01"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 on transfer, got %d: %s", w.Code, w.Body.String())
+	}
+	w = doJSON(router, "POST", "/api/birds/"+idStr+"/transfer", map[string]interface{}{"coop": "Back Barn", "date": "2024-06-02"})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 transferring to current coop, got %d", w.Code)
+	}
+	w = doJSON(router, "POST", "/api/birds/9999/transfer", map[string]interface{}{"coop": "Back Barn", "date": "2024-06-02"})
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown bird, got %d", w.Code)
+	}
+
+	w = doJSON(router, "GET", "/api/birds/"+idStr+"/transfers", nil)
+	var transfers []map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &transfers)
+	if len(transfers) != 2 {
+		t.Fatalf("expected 2 transfers, got %d", len(transfers))
+	}
+	if transfers[0]["from_coop"] != nil || transfers[0]["to_coop"] != "Main Coop" {
+		t.Errorf("unexpected initial placement: %v", transfers[0])
+	}
+	if transfers[1]["from_coop"] != "Main Coop" || transfers[1]["to_coop"] != "Back Barn" {
+		t.Errorf("unexpected transfer: %v", transfers[1])
+	}
+
+	w = doJSON(router, "GET", "/api/birds/"+idStr, nil)
+	var bird map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &bird)
+	if bird["coop"] != "Back Barn" {
+		t.Errorf("expected bird in Back Barn, got %v", bird["coop"])
+	}
+
+	// A backdated transfer leaves the current coop alone
+	w = doJSON(router, "POST", "/api/birds/"+idStr+"/transfer", map[string]interface{}{"coop": "Brooder", "date": "2024-05-01"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 on backdated transfer, got %d: %s", w.Code, w.Body.String())
+	}
+	w = doJSON(router, "GET", "/api/birds/"+idStr, nil)
+	json.Unmarshal(w.Body.Bytes(), &bird)
+	if bird["coop"] != "Back Barn" {
+		t.Errorf("expected bird to stay in Back Barn, got %v", bird["coop"])
+	}
+	w = doJSON(router, "GET", "/api/birds/"+idStr+"/transfers", nil)
+	transfers = nil
+	json.Unmarshal(w.Body.Bytes(), &transfers)
+	if len(transfers) != 3 || transfers[1]["from_coop"] != "Main Coop" || transfers[1]["to_coop"] != "Brooder" {
+		t.Errorf("unexpected backdated transfer: %v", transfers)
+	}
+}
+
+func TestDeleteBirdRemovesHistory(t *testing.T) {
+	dbase, cleanup := setupBirdsTestDB()
+	defer cleanup()
+	router := birdsRouter(dbase)
+
+	w := doJSON(router, "POST", "/api/birds", map[string]interface{}{"name": "Gertie", "species": "Goose", "coop": "Main Coop"})
+	var resp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	idStr := strconv.Itoa(int(resp["id"].(float64)))
+	mustExec(t, dbase, "INSERT INTO health_events (bird_id, coop, event_type, date) VALUES (?, 'Main Coop', 'illness', '2024-05-01')", idStr)
+	mustExec(t, dbase, "INSERT INTO bird_status_periods (bird_id, coop, status, start_date) VALUES (?, 'Main Coop', 'molting', '2024-05-01')", idStr)
+
+	if w = doJSON(router, "DELETE", "/api/birds/"+idStr, nil); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on delete, got %d", w.Code)
+	}
+	for _, table := range []string{"bird_transfers", "health_events", "bird_status_periods"} {
+		var n int
+		dbase.QueryRow("SELECT COUNT(*) FROM "+table+" WHERE bird_id = ?", idStr).Scan(&n)
+		if n != 0 {
+			t.Errorf("expected the bird's %s to be deleted, got %d", table, n)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"egg-tracker/backend/models"
+	"egg-tracker/backend/sun"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxDaylightDays caps the range of a single daylight request.
+const maxDaylightDays = 3660
+
+// farmPosition returns the farm's latitude and longitude once both are set.
+func farmPosition(values map[string]string) (float64, float64, bool) {
+	lat, err1 := strconv.ParseFloat(values[settingLatitude], 64)
+	lon, err2 := strconv.ParseFloat(values[settingLongitude], 64)
+	return lat, lon, err1 == nil && err2 == nil
+}
+
+// farmTimezone is the configured timezone, UTC when unset or unknown.
+func farmTimezone(values map[string]string) *time.Location {
+	if loc, err := time.LoadLocation(values[settingTimezone]); err == nil && values[settingTimezone] != "" {
+		return loc
+	}
+	return time.UTC
+}
+
+func toDaylight(d sun.Day) models.Daylight {
+	return models.Daylight{
+		Date:           d.Date.Format("2006-01-02"),
+		Sunrise:        d.Sunrise,
+		Sunset:         d.Sunset,
+		DayLengthHours: d.DayLength.Hours(),
+		Polar:          d.Polar,
+	}
+}
+
+// clockOn places an HH:MM clock time on a date in loc.
+func clockOn(date time.Time, clock string, loc *time.Location) (time.Time, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return t, err
+	}
+	y, m, d := date.Date()
+	return time.Date(y, m, d, t.Hour(), t.Minute(), 0, 0, loc), nil
+}
+
+// effectiveLight returns the natural day length and the hours of light once
+// the schedules running in a coop that day are added, counting overlap with
+// daylight only once.
+func effectiveLight(day sun.Day, schedules []models.LightingSchedule, loc *time.Location) (float64, float64) {
+	type span struct{ start, end time.Time }
+	var spans []span
+	switch {
+	case day.Polar == "day":
+		return 24, 24
+	case day.Sunrise != nil:
+		spans = append(spans, span{*day.Sunrise, *day.Sunset})
+	}
+	for _, s := range schedules {
+		// Schedule dates are calendar dates, so compare them as such rather
+		// than as instants in different zones.
+		date := day.Date.Format("2006-01-02")
+		if date < s.StartDate.Format("2006-01-02") || (s.EndDate != nil && date > s.EndDate.Format("2006-01-02")) {
+			continue
+		}
+		on, err1 := clockOn(day.Date, s.LightsOn, loc)
+		off, err2 := clockOn(day.Date, s.LightsOff, loc)
+		if err1 == nil && err2 == nil && off.After(on) {
+			spans = append(spans, span{on, off})
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start.Before(spans[j].start) })
+	var total time.Duration
+	var cur *span
+	for i := range spans {
+		s := spans[i]
+		if cur != nil && !s.start.After(cur.end) {
+			if s.end.After(cur.end) {
+				cur.end = s.end
+			}
+			continue
+		}
+		if cur != nil {
+			total += cur.end.Sub(cur.start)
+		}
+		cur = &s
+	}
+	if cur != nil {
+		total += cur.end.Sub(cur.start)
+	}
+	return day.DayLength.Hours(), total.Hours()
+}
+
+// DaylightHandler returns sunrise, sunset and day length at the farm for each
+// date from ?from= to ?to= (YYYY-MM-DD, default the next seven days).
+func DaylightHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		values, err := loadSettings(db)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		lat, lon, ok := farmPosition(values)
+		if !ok {
+			respondError(c, http.StatusBadRequest, "farm latitude and longitude are not set")
+			return
+		}
+		from := today()
+		if s := c.Query("from"); s != "" {
+			if from, err = time.Parse("2006-01-02", s); err != nil {
+				respondError(c, http.StatusBadRequest, "invalid from date")
+				return
+			}
+		}
+		to := from.AddDate(0, 0, 6)
+		if s := c.Query("to"); s != "" {
+			if to, err = time.Parse("2006-01-02", s); err != nil {
+				respondError(c, http.StatusBadRequest, "invalid to date")
+				return
+			}
+		}
+		if from.After(to) || to.Sub(from).Hours()/24 >= maxDaylightDays {
+			respondError(c, http.StatusBadRequest, "invalid date range")
+			return
+		}
+		loc := farmTimezone(values)
+		days := []models.Daylight{}
+		for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+			days = append(days, toDaylight(sun.Times(d, lat, lon, loc)))
+		}
+		c.JSON(http.StatusOK, days)
+	}
+}
+
+type LightingScheduleInput struct {
+	Coop      string  `json:"coop" binding:"required"`
+	StartDate string  `json:"start_date" binding:"required"` // ISO8601 date
+	EndDate   string  `json:"end_date"`                      // ISO8601 date, inclusive; empty while in use
+	LightsOn  string  `json:"lights_on" binding:"required"`  // HH:MM
+	LightsOff string  `json:"lights_off" binding:"required"` // HH:MM, after lights_on
+	Notes     *string `json:"notes"`
+}
+
+func validateLightingSchedule(input LightingScheduleInput) (time.Time, *time.Time, string) {
+	start, err := time.Parse("2006-01-02", input.StartDate)
+	if err != nil {
+		return start, nil, "invalid date"
+	}
+	end, err := parseOptionalDate(input.EndDate)
+	if err != nil {
+		return start, nil, "invalid date"
+	}
+	if end != nil && end.Before(start) {
+		return start, nil, "end_date must not be before start_date"
+	}
+	on, err1 := time.Parse("15:04", input.LightsOn)
+	off, err2 := time.Parse("15:04", input.LightsOff)
+	if err1 != nil || err2 != nil {
+		return start, nil, "lights_on and lights_off must be HH:MM"
+	}
+	if !off.After(on) {
+		return start, nil, "lights_off must be after lights_on"
+	}
+	return start, end, ""
+}
+
+func CreateLightingScheduleHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input LightingScheduleInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondBindError(c, err)
+			return
+		}
+		start, end, msg := validateLightingSchedule(input)
+		if msg != "" {
+			respondError(c, http.StatusBadRequest, msg)
+			return
+		}
+		res, err := db.Exec(
+			"INSERT INTO lighting_schedules (coop, start_date, end_date, lights_on, lights_off, notes) VALUES (?, ?, ?, ?, ?, ?)",
+			input.Coop, start, end, input.LightsOn, input.LightsOff, input.Notes,
+		)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		id, _ := res.LastInsertId()
+		c.JSON(http.StatusCreated, IDResponse{ID: id})
+	}
+}
+
+// loadLightingSchedules returns lighting schedules, optionally for one coop.
+func loadLightingSchedules(db *sql.DB, coop string) ([]models.LightingSchedule, error) {
+	query := "SELECT id, coop, start_date, end_date, lights_on, lights_off, notes, created_at, updated_at FROM lighting_schedules"
+	var args []interface{}
+	if coop != "" {
+		query += " WHERE coop = ?"
+		args = append(args, coop)
+	}
+	rows, err := db.Query(query+" ORDER BY coop ASC, start_date ASC, id ASC", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	schedules := []models.LightingSchedule{}
+	for rows.Next() {
+		var s models.LightingSchedule
+		var end sql.NullTime
+		var notes sql.NullString
+		if err := rows.Scan(&s.ID, &s.Coop, &s.StartDate, &end, &s.LightsOn, &s.LightsOff, &notes, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if end.Valid {
+			s.EndDate = &end.Time
+		}
+		if notes.Valid {
+			s.Notes = &notes.String
+		}
+		schedules = append(schedules, s)
+	}
+	return schedules, rows.Err()
+}
+
+// ListLightingSchedulesHandler lists lighting schedules, optionally for one ?coop=.
+func ListLightingSchedulesHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		schedules, err := loadLightingSchedules(db, c.Query("coop"))
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, schedules)
+	}
+}
+
+func UpdateLightingScheduleHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input LightingScheduleInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondBindError(c, err)
+			return
+		}
+		start, end, msg := validateLightingSchedule(input)
+		if msg != "" {
+			respondError(c, http.StatusBadRequest, msg)
+			return
+		}
+		res, err := db.Exec(
+			"UPDATE lighting_schedules SET coop = ?, start_date = ?, end_date = ?, lights_on = ?, lights_off = ?, notes = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?",
+			input.Coop, start, end, input.LightsOn, input.LightsOff, input.Notes, c.Param("id"),
+		)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			respondError(c, http.StatusNotFound, "lighting schedule not found")
+			return
+		}
+		c.JSON(http.StatusOK, MessageResponse{Message: "updated"})
+	}
+}
+
+func DeleteLightingScheduleHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, err := db.Exec("DELETE FROM lighting_schedules WHERE id = ?", c.Param("id")); err != nil {
+			respondServerError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, MessageResponse{Message: "deleted"})
+	}
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"math"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"egg-tracker/backend/db"
+	"egg-tracker/backend/models"
+	"egg-tracker/backend/sun"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupDaylightTestDB() (*sql.DB, func()) {
+	testDBPath := "test_daylight.db"
+	dbase, _ := sql.Open("sqlite3", testDBPath)
+	db.Migrate(dbase)
+	return dbase, func() {
+		dbase.Close()
+		os.Remove(testDBPath)
+	}
+}
+
+func TestDaylightFromSettings(t *testing.T) {
+	dbase, cleanup := setupDaylightTestDB()
+	defer cleanup()
+	router := gin.Default()
+	router.GET("/api/settings", GetSettingsHandler(dbase))
+	router.PUT("/api/settings", UpdateSettingsHandler(dbase))
+	router.GET("/api/daylight", DaylightHandler(dbase))
+
+	if w := doJSON(router, "GET", "/api/daylight", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 before the farm position is set, got %d", w.Code)
+	}
+	if w := doJSON(router, "PUT", "/api/settings", map[string]interface{}{"latitude": 91}); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an impossible latitude, got %d", w.Code)
+	}
+	if w := doJSON(router, "PUT", "/api/settings", map[string]interface{}{"timezone": "Mars/Olympus"}); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown timezone, got %d", w.Code)
+	}
+	doJSON(router, "PUT", "/api/settings", map[string]interface{}{"latitude": 51.5074, "longitude": -0.1278, "timezone": "Europe/London"})
+	w := doJSON(router, "GET", "/api/settings", nil)
+	var settings models.Settings
+	json.Unmarshal(w.Body.Bytes(), &settings)
+	if settings.Latitude == nil || *settings.Latitude != 51.5074 || settings.Timezone != "Europe/London" {
+		t.Errorf("unexpected settings: %s", w.Body.String())
+	}
+
+	w = doJSON(router, "GET", "/api/daylight?from=2025-06-20&to=2025-06-22", nil)
+	var days []models.Daylight
+	json.Unmarshal(w.Body.Bytes(), &days)
+	if len(days) != 3 || days[1].Date != "2025-06-21" || math.Abs(days[1].DayLengthHours-16.63) > 0.05 {
+		t.Fatalf("unexpected daylight: %s", w.Body.String())
+	}
+	if _, offset := days[1].Sunrise.Zone(); offset != 3600 {
+		t.Errorf("expected sunrise in British Summer Time, got %s", days[1].Sunrise)
+	}
+}
+
+func TestEffectiveLightAndSchedules(t *testing.T) {
+	dbase, cleanup := setupDaylightTestDB()
+	defer cleanup()
+	router := gin.Default()
+	router.POST("/api/lighting", CreateLightingScheduleHandler(dbase))
+	router.GET("/api/lighting", ListLightingSchedulesHandler(dbase))
+
+	if w := doJSON(router, "POST", "/api/lighting", map[string]interface{}{"coop": "Main Coop", "start_date": "2025-11-01", "lights_on": "19:00", "lights_off": "05:00"}); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 when lights go off before they go on, got %d", w.Code)
+	}
+	doJSON(router, "POST", "/api/lighting", map[string]interface{}{"coop": "Main Coop", "start_date": "2025-11-01", "end_date": "2026-02-28", "lights_on": "04:00", "lights_off": "09:00"})
+	doJSON(router, "POST", "/api/lighting", map[string]interface{}{"coop": "Main Coop", "start_date": "2025-11-01", "lights_on": "15:00", "lights_off": "19:00"})
+	w := doJSON(router, "GET", "/api/lighting?coop=Main Coop", nil)
+	var schedules []models.LightingSchedule
+	json.Unmarshal(w.Body.Bytes(), &schedules)
+	if len(schedules) != 2 {
+		t.Fatalf("expected 2 schedules, got %s", w.Body.String())
+	}
+
+	// Midwinter in London: sun from about 08:04 to 15:54. Lights fill
+	// 04:00-08:04 and 15:54-19:00, for 15 hours in all.
+	london, _ := time.LoadLocation("Europe/London")
+	midwinter, _ := time.Parse("2006-01-02", "2025-12-21")
+	day := sun.Times(midwinter, 51.5074, -0.1278, london)
+	natural, effective := effectiveLight(day, schedules, london)
+	if math.Abs(natural-7.83) > 0.05 || math.Abs(effective-15) > 1e-9 {
+		t.Errorf("expected 15 effective hours from %.2f natural, got %.2f", natural, effective)
+	}
+	// After the morning schedule ends only the evening lights run
+	march, _ := time.Parse("2006-01-02", "2026-03-01")
+	day = sun.Times(march, 51.5074, -0.1278, london)
+	natural, effective = effectiveLight(day, schedules, london)
+	if sunset := day.Sunset.In(london); math.Abs(effective-(natural+19-float64(sunset.Hour())-float64(sunset.Minute())/60)) > 0.02 {
+		t.Errorf("expected the evening lights to extend the day to 19:00, got %.2f from %.2f", effective, natural)
+	}
+}
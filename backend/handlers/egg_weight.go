@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"egg-tracker/backend/models"
+	"egg-tracker/backend/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EggWeightBandInput struct {
+	Species  string   `json:"species" binding:"required"`
+	EggSize  string   `json:"egg_size" binding:"required"`
+	MinGrams float64  `json:"min_grams" binding:"min=0"`
+	MaxGrams *float64 `json:"max_grams"`
+}
+
+// eggWeightPresets are common grading standards for hen eggs, as minimum
+// grams per egg. Each band runs up to the next heavier one.
+var eggWeightPresets = map[string][]EggWeightBandInput{
+	// USDA classes by minimum net weight per dozen, converted to grams per egg.
+	"usda": {
+		{EggSize: "Peewee", MinGrams: 35.4},
+		{EggSize: "Small", MinGrams: 42.5},
+		{EggSize: "Medium", MinGrams: 49.6},
+		{EggSize: "Large", MinGrams: 56.7},
+		{EggSize: "Extra Large", MinGrams: 63.8},
+		{EggSize: "Jumbo", MinGrams: 70.9},
+	},
+	"eu": {
+		{EggSize: "S", MinGrams: 0},
+		{EggSize: "M", MinGrams: 53},
+		{EggSize: "L", MinGrams: 63},
+		{EggSize: "XL", MinGrams: 73},
+	},
+}
+
+// validateWeightBand checks the size is a known option and the range does
+// not overlap another band of the same species. It returns a status and
+// message on failure.
+func validateWeightBand(db *sql.DB, input EggWeightBandInput, excludeID string) (int, string) {
+	if input.MaxGrams != nil && *input.MaxGrams <= input.MinGrams {
+		return http.StatusBadRequest, "max_grams must be greater than min_grams"
+	}
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM egg_sizes WHERE name = ?", input.EggSize).Scan(&n); err != nil {
+		return http.StatusInternalServerError, "db error"
+	}
+	if n == 0 {
+		return http.StatusBadRequest, "unknown egg_size"
+	}
+	err := db.QueryRow(
+		`SELECT COUNT(*) FROM egg_weight_bands
+		WHERE species = ? AND CAST(id AS TEXT) <> ?
+			AND (max_grams IS NULL OR max_grams > ?)
+			AND (? IS NULL OR min_grams < ?)`,
+		input.Species, excludeID, input.MinGrams, input.MaxGrams, input.MaxGrams,
+	).Scan(&n)
+	if err != nil {
+		return http.StatusInternalServerError, "db error"
+	}
+	if n > 0 {
+		return http.StatusConflict, "weight band overlaps an existing band"
+	}
+	return 0, ""
+}
+
+func CreateEggWeightBandHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input EggWeightBandInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondBindError(c, err)
+			return
+		}
+		if status, msg := validateWeightBand(db, input, ""); msg != "" {
+			respondError(c, status, msg)
+			return
+		}
+		res, err := db.Exec(
+			"INSERT INTO egg_weight_bands (species, egg_size, min_grams, max_grams) VALUES (?, ?, ?, ?)",
+			input.Species, input.EggSize, input.MinGrams, input.MaxGrams,
+		)
+		if err != nil {
+			respondWriteError(c, err, "species already has a band for this size")
+			return
+		}
+		id, _ := res.LastInsertId()
+		c.JSON(http.StatusCreated, IDResponse{ID: id})
+	}
+}
+
+// ListEggWeightBandsHandler lists weight bands, optionally for one ?species=.
+func ListEggWeightBandsHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := "SELECT id, species, egg_size, min_grams, max_grams, created_at, updated_at FROM egg_weight_bands"
+		var args []interface{}
+		if sp := c.Query("species"); sp != "" {
+			query += " WHERE species = ?"
+			args = append(args, sp)
+		}
+		rows, err := db.Query(query+" ORDER BY species ASC, min_grams ASC", args...)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		defer rows.Close()
+		bands := []models.EggWeightBand{}
+		for rows.Next() {
+			var b models.EggWeightBand
+			var max sql.NullFloat64
+			if err := rows.Scan(&b.ID, &b.Species, &b.EggSize, &b.MinGrams, &max, &b.CreatedAt, &b.UpdatedAt); err != nil {
+				respondServerError(c, err)
+				return
+			}
+			if max.Valid {
+				b.MaxGrams = &max.Float64
+			}
+			bands = append(bands, b)
+		}
+		c.JSON(http.StatusOK, bands)
+	}
+}
+
+func UpdateEggWeightBandHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var input EggWeightBandInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondBindError(c, err)
+			return
+		}
+		if status, msg := validateWeightBand(db, input, id); msg != "" {
+			respondError(c, status, msg)
+			return
+		}
+		res, err := db.Exec(
+			"UPDATE egg_weight_bands SET species = ?, egg_size = ?, min_grams = ?, max_grams = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?",
+			input.Species, input.EggSize, input.MinGrams, input.MaxGrams, id,
+		)
+		if err != nil {
+			respondWriteError(c, err, "species already has a band for this size")
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			respondError(c, http.StatusNotFound, "weight band not found")
+			return
+		}
+		c.JSON(http.StatusOK, MessageResponse{Message: "updated"})
+	}
+}
+
+func DeleteEggWeightBandHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, err := db.Exec("DELETE FROM egg_weight_bands WHERE id = ?", c.Param("id")); err != nil {
+			respondServerError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, MessageResponse{Message: "deleted"})
+	}
+}
+
+// ApplyEggWeightPresetHandler replaces a species' weight bands with a grading
+// standard (?species=, default Chicken), adding any missing egg size options.
+func ApplyEggWeightPresetHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		preset, ok := eggWeightPresets[c.Param("standard")]
+		if !ok {
+			respondError(c, http.StatusBadRequest, "standard must be usda or eu")
+			return
+		}
+		species := c.DefaultQuery("species", "Chicken")
+		tx, err := db.Begin()
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		defer tx.Rollback()
+		if _, err := tx.Exec("DELETE FROM egg_weight_bands WHERE species = ?", species); err != nil {
+			respondServerError(c, err)
+			return
+		}
+		for i, band := range preset {
+			var max *float64
+			if i+1 < len(preset) {
+				max = &preset[i+1].MinGrams
+			}
+			if _, err := tx.Exec("INSERT OR IGNORE INTO egg_sizes (name, active) VALUES (?, 1)", band.EggSize); err != nil {
+				respondServerError(c, err)
+				return
+			}
+			if _, err := tx.Exec(
+				"INSERT INTO egg_weight_bands (species, egg_size, min_grams, max_grams) VALUES (?, ?, ?, ?)",
+				species, band.EggSize, band.MinGrams, max,
+			); err != nil {
+				respondServerError(c, err)
+				return
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			respondServerError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, MessageResponse{Message: "applied"})
+	}
+}
+
+// gradeEggs fills in the egg size from the species' weight bands when a
+// weight is given. Without a weight the hand-picked size is required.
+func gradeEggs(q sqlExecutor, input *InventoryInput) error {
+	if input.WeightGrams == nil {
+		if input.EggSize == "" {
+			return &openapi.ValidationError{Problems: []openapi.Problem{{Field: "egg_size", Message: "is required when weight_grams is not given"}}}
+		}
+		return nil
+	}
+	if input.Action != "collected" {
+		return &inputError{http.StatusBadRequest, "weight_grams can only be recorded on collected eggs"}
+	}
+	var size string
+	err := q.QueryRow(
+		`SELECT egg_size FROM egg_weight_bands
+		WHERE species = ? AND min_grams <= ? AND (max_grams IS NULL OR max_grams > ?)`,
+		input.Species, *input.WeightGrams, *input.WeightGrams,
+	).Scan(&size)
+	switch {
+	case err == sql.ErrNoRows && input.EggSize == "":
+		return &openapi.ValidationError{Problems: []openapi.Problem{{Field: "egg_size", Message: "is required when no weight band matches"}}}
+	case err == sql.ErrNoRows:
+		return nil
+	case err != nil:
+		return err
+	}
+	input.EggSize = size
+	return nil
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"egg-tracker/backend/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EggWeightTrend struct {
+	Period    string  `json:"period"`
+	Group     string  `json:"group"` // coop or species name, depending on ?by=
+	Eggs      int     `json:"eggs"`  // eggs in weighed collections
+	AvgGrams  float64 `json:"avg_grams"`
+	MinGrams  float64 `json:"min_grams"` // lightest collection average in the period
+	MaxGrams  float64 `json:"max_grams"` // heaviest collection average in the period
+	Weighings int     `json:"weighings"`
+}
+
+// queryEggWeights averages recorded egg weights per period, weighting each
+// collection by its egg count.
+func queryEggWeights(duck *sql.DB, from, to time.Time, by, period string) ([]EggWeightTrend, error) {
+	format := periodFormats[period]
+	rows, err := duck.Query(`
+		SELECT strftime(CAST(date AS DATE), '`+format+`') AS period, COALESCE(`+by+`, '') AS grp,
+			CAST(SUM(quantity) AS BIGINT),
+			CAST(SUM(weight_grams * quantity) / SUM(quantity) AS DOUBLE),
+			CAST(MIN(weight_grams) AS DOUBLE), CAST(MAX(weight_grams) AS DOUBLE), COUNT(*)
+		FROM inventory_actions
+		WHERE action = 'collected' AND weight_grams IS NOT NULL AND quantity > 0
+			AND CAST(date AS DATE) BETWEEN CAST(? AS DATE) AND CAST(? AS DATE)
+		GROUP BY 1, 2
+		ORDER BY period ASC, grp ASC
+	`, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	trends := []EggWeightTrend{}
+	for rows.Next() {
+		var t EggWeightTrend
+		if err := rows.Scan(&t.Period, &t.Group, &t.Eggs, &t.AvgGrams, &t.MinGrams, &t.MaxGrams, &t.Weighings); err != nil {
+			return nil, err
+		}
+		trends = append(trends, t)
+	}
+	return trends, rows.Err()
+}
+
+// EggWeightReport is the response of EggWeightReportHandler.
+type EggWeightReport struct {
+	By         string           `json:"by"`
+	Period     string           `json:"period"`
+	EggWeights []EggWeightTrend `json:"eggWeights"`
+}
+
+// EggWeightReportHandler returns average egg weight trends from DuckDB.
+// Query parameters: by=coop|species (default coop), period=day|week|month
+// (default week), from/to as YYYY-MM-DD (default: first collection to today).
+func EggWeightReportHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		by := c.DefaultQuery("by", "coop")
+		if by != "coop" && by != "species" {
+			respondError(c, http.StatusBadRequest, "by must be coop or species")
+			return
+		}
+		period := c.DefaultQuery("period", "week")
+		if _, ok := periodFormats[period]; !ok {
+			respondError(c, http.StatusBadRequest, "period must be day, week or month")
+			return
+		}
+
+		duckdb, err := sql.Open("duckdb", analyticsDBPath)
+		if err != nil {
+			requestLogger(c, "EggWeightReportHandler").Error("Failed to open DuckDB", logging.Err(err))
+			respondError(c, http.StatusInternalServerError, "failed to open DuckDB")
+			return
+		}
+		defer duckdb.Close()
+
+		from, to, err := reportRange(c, func() (time.Time, error) { return firstCollectionDate(duckdb) })
+		if err != nil {
+			respondInputError(c, err)
+			return
+		}
+		trends, err := queryEggWeights(duckdb, from, to, by, period)
+		if err != nil {
+			requestLogger(c, "EggWeightReportHandler").Error("Egg weight query failed", logging.Err(err))
+			respondError(c, http.StatusInternalServerError, "egg weight query failed")
+			return
+		}
+		c.JSON(http.StatusOK, EggWeightReport{By: by, Period: period, EggWeights: trends})
+	}
+}
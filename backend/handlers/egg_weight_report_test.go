@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestEggWeightReport_WeightedAverage(t *testing.T) {
+	cleanup := setupAnalyticsTestDB(t, func(dbase *sql.DB) {
+		mustExec(t, dbase, `INSERT INTO inventory_actions (quantity, species, coop, action, date, weight_grams) VALUES
+			(3, 'Chicken', 'Main Coop', 'collected', '2025-05-01', 60),
+			(1, 'Chicken', 'Main Coop', 'collected', '2025-05-02', 52),
+			(2, 'Duck', 'Pond', 'collected', '2025-05-02', 75),
+			(4, 'Chicken', 'Main Coop', 'collected', '2025-05-03', NULL)`)
+	})
+	defer cleanup()
+
+	router := gin.Default()
+	router.GET("/api/reports/egg-weight", EggWeightReportHandler())
+	req, _ := http.NewRequest("GET", "/api/reports/egg-weight?by=species&period=month&from=2025-05-01&to=2025-05-31", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		EggWeights []EggWeightTrend `json:"eggWeights"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.EggWeights) != 2 {
+		t.Fatalf("expected 2 rows, got %+v", resp.EggWeights)
+	}
+	chicken := resp.EggWeights[0]
+	if chicken.Group != "Chicken" || chicken.Eggs != 4 || chicken.AvgGrams != 58 || chicken.MinGrams != 52 || chicken.Weighings != 2 {
+		t.Errorf("unexpected Chicken row: %+v", chicken)
+	}
+}
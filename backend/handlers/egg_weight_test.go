@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+
+	"egg-tracker/backend/db"
+	"egg-tracker/backend/models"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupEggWeightTestDB() (*sql.DB, func()) {
+	testDBPath := "test_egg_weight.db"
+	dbase, _ := sql.Open("sqlite3", testDBPath)
+	db.Migrate(dbase)
+	return dbase, func() {
+		dbase.Close()
+		os.Remove(testDBPath)
+	}
+}
+
+func TestEggWeightBandsAndGrading(t *testing.T) {
+	dbase, cleanup := setupEggWeightTestDB()
+	defer cleanup()
+	router := gin.Default()
+	router.POST("/api/egg-weight-bands", CreateEggWeightBandHandler(dbase))
+	router.GET("/api/egg-weight-bands", ListEggWeightBandsHandler(dbase))
+	router.POST("/api/egg-weight-bands/presets/:standard", ApplyEggWeightPresetHandler(dbase))
+	router.POST("/api/inventory", CreateInventoryHandler(dbase))
+
+	w := doJSON(router, "POST", "/api/egg-weight-bands/presets/usda?species=Chicken", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 applying preset, got %d: %s", w.Code, w.Body.String())
+	}
+	w = doJSON(router, "GET", "/api/egg-weight-bands?species=Chicken", nil)
+	var bands []models.EggWeightBand
+	json.Unmarshal(w.Body.Bytes(), &bands)
+	if len(bands) != 6 || bands[5].EggSize != "Jumbo" || bands[5].MaxGrams != nil || *bands[0].MaxGrams != 42.5 {
+		t.Fatalf("unexpected USDA bands: %+v", bands)
+	}
+
+	// Overlapping and unknown-size bands are rejected
+	w = doJSON(router, "POST", "/api/egg-weight-bands", map[string]interface{}{"species": "Chicken", "egg_size": "Large", "min_grams": 60, "max_grams": 65})
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected 409 for overlapping band, got %d", w.Code)
+	}
+	w = doJSON(router, "POST", "/api/egg-weight-bands", map[string]interface{}{"species": "Duck", "egg_size": "Huge", "min_grams": 60})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unknown egg size, got %d", w.Code)
+	}
+
+	collect := map[string]interface{}{
+		"quantity": 6, "species": "Chicken", "coop": "Main Coop", "egg_color": "Brown",
+		"action": "collected", "date": "2025-05-01", "weight_grams": 58.2,
+	}
+	w = doJSON(router, "POST", "/api/inventory", collect)
+	var resp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusCreated || resp["egg_size"] != "Large" {
+		t.Fatalf("expected Large from 58.2 g, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// No band for ducks and no hand-picked size
+	collect["species"] = "Duck"
+	w = doJSON(router, "POST", "/api/inventory", collect)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without a matching band or size, got %d", w.Code)
+	}
+	collect["egg_size"] = "Large"
+	collect["action"] = "sold"
+	w = doJSON(router, "POST", "/api/inventory", collect)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for weight on a sale, got %d", w.Code)
+	}
+
+	// Without a weight the size names the field at fault
+	delete(collect, "weight_grams")
+	delete(collect, "egg_size")
+	w = doJSON(router, "POST", "/api/inventory", collect)
+	var failed ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &failed)
+	if w.Code != http.StatusBadRequest || failed.Code != CodeValidationFailed || failed.Error != "egg_size is required when weight_grams is not given" ||
+		len(failed.Details) != 1 || failed.Details[0].Field != "egg_size" {
+		t.Errorf("expected a field error on egg_size, got %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"egg-tracker/backend/logging"
+	"egg-tracker/backend/openapi"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Error codes, for clients to tell failures apart without parsing messages.
+const (
+	CodeBadRequest           = "bad_request"
+	CodeValidationFailed     = "validation_failed"
+	CodeUnauthorized         = "unauthorized"
+	CodeNotFound             = "not_found"
+	CodeConflict             = "conflict"
+	CodeDuplicate            = "duplicate"
+	CodeTooLarge             = "too_large"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeConstraintViolation  = "constraint_violation"
+	CodeInternal             = "internal"
+)
+
+var statusCodes = map[int]string{
+	http.StatusBadRequest:            CodeBadRequest,
+	http.StatusUnauthorized:          CodeUnauthorized,
+	http.StatusNotFound:              CodeNotFound,
+	http.StatusConflict:              CodeConflict,
+	http.StatusRequestEntityTooLarge: CodeTooLarge,
+	http.StatusUnsupportedMediaType:  CodeUnsupportedMediaType,
+	http.StatusUnprocessableEntity:   CodeConstraintViolation,
+	http.StatusInternalServerError:   CodeInternal,
+}
+
+func init() {
+	// Name fields in binding errors as clients send them.
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+	}
+}
+
+// respondError writes an error with the code for its status.
+func respondError(c *gin.Context, status int, message string) {
+	code, ok := statusCodes[status]
+	if !ok {
+		code = CodeBadRequest
+		if status >= 500 {
+			code = CodeInternal
+		}
+	}
+	c.JSON(status, ErrorResponse{Error: message, Code: code})
+}
+
+// RespondValidationError writes a 400 listing what is wrong with each field
+// of the request body.
+func RespondValidationError(c *gin.Context, problems []openapi.Problem) {
+	err := &openapi.ValidationError{Problems: problems}
+	c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error(), Code: CodeValidationFailed, Details: problems})
+}
+
+// respondBindError reports a request body that gin could not bind.
+func respondBindError(c *gin.Context, err error) {
+	RespondValidationError(c, bindProblems(err))
+}
+
+func bindProblems(err error) []openapi.Problem {
+	var verrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &verrs):
+		problems := make([]openapi.Problem, len(verrs))
+		for i, fe := range verrs {
+			// The namespace starts with the Go type's name.
+			_, field, _ := strings.Cut(fe.Namespace(), ".")
+			problems[i] = openapi.Problem{Field: field, Message: ruleMessage(fe)}
+		}
+		return problems
+	case errors.As(err, &typeErr):
+		return []openapi.Problem{{Field: typeErr.Field, Message: "must be " + jsonKind(typeErr.Type)}}
+	case errors.Is(err, io.EOF):
+		return []openapi.Problem{{Message: "body is required"}}
+	}
+	return []openapi.Problem{{Message: "body must be valid JSON"}}
+}
+
+// ruleMessage words a failed binding rule the way openapi.ValidateBody does.
+func ruleMessage(fe validator.FieldError) string {
+	// Lengths are counted for strings and items for slices and maps.
+	size := ""
+	switch fe.Kind() {
+	case reflect.String:
+		size = " characters"
+	case reflect.Slice, reflect.Map:
+		size = " items"
+	}
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be an email address"
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "min", "gte":
+		if size == " items" {
+			return "must have at least " + fe.Param() + size
+		}
+		return "must be at least " + fe.Param() + size
+	case "max", "lte":
+		if size == " items" {
+			return "must have at most " + fe.Param() + size
+		}
+		return "must be at most " + fe.Param() + size
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "lt":
+		return "must be less than " + fe.Param()
+	}
+	return "is invalid"
+}
+
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a whole number"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	return "an object"
+}
+
+// respondInputError reports an *inputError with the status it carries and
+// anything else as respondServerError.
+func respondInputError(c *gin.Context, err error) {
+	var ie *inputError
+	if errors.As(err, &ie) {
+		respondError(c, ie.status, ie.msg)
+		return
+	}
+	respondServerError(c, err)
+}
+
+// respondWriteError reports a failed insert or update: a unique violation is
+// a 409 with duplicate as its message, anything else as respondServerError.
+func respondWriteError(c *gin.Context, err error, duplicate string) {
+	var se sqlite3.Error
+	if errors.As(err, &se) && (se.ExtendedCode == sqlite3.ErrConstraintUnique || se.ExtendedCode == sqlite3.ErrConstraintPrimaryKey) {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: duplicate, Code: CodeDuplicate, Details: constraintProblems(se, "already exists")})
+		return
+	}
+	respondServerError(c, err)
+}
+
+// respondServerError reports an error the client cannot fix by changing its
+// request. SQLite constraint violations still are, and become a 409 or 422;
+// anything else is logged and reported without detail.
+func respondServerError(c *gin.Context, err error) {
+	var se sqlite3.Error
+	if errors.As(err, &se) && se.Code == sqlite3.ErrConstraint {
+		switch se.ExtendedCode {
+		case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+			c.JSON(http.StatusConflict, ErrorResponse{Error: "a record with these values already exists", Code: CodeDuplicate, Details: constraintProblems(se, "already exists")})
+		case sqlite3.ErrConstraintForeignKey:
+			c.JSON(http.StatusUnprocessableEntity, ErrorResponse{Error: "refers to a record that does not exist", Code: CodeConstraintViolation})
+		case sqlite3.ErrConstraintNotNull:
+			c.JSON(http.StatusUnprocessableEntity, ErrorResponse{Error: "a required value is missing", Code: CodeConstraintViolation, Details: constraintProblems(se, "is required")})
+		default:
+			c.JSON(http.StatusUnprocessableEntity, ErrorResponse{Error: "a value is not allowed", Code: CodeConstraintViolation})
+		}
+		return
+	}
+	requestLogger(c, handlerName(c)).Error("Request failed", logging.Err(err))
+	c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "internal server error", Code: CodeInternal})
+}
+
+// constraintProblems names the columns in a constraint error such as
+// "UNIQUE constraint failed: birds.band_id".
+func constraintProblems(se sqlite3.Error, message string) []openapi.Problem {
+	_, cols, ok := strings.Cut(se.Error(), "constraint failed: ")
+	if !ok {
+		return nil
+	}
+	var problems []openapi.Problem
+	for _, col := range strings.Split(cols, ", ") {
+		if _, name, ok := strings.Cut(col, "."); ok {
+			problems = append(problems, openapi.Problem{Field: name, Message: message})
+		}
+	}
+	return problems
+}
+
+// handlerName returns the name of the handler serving c, e.g.
+// CreateBirdHandler, for logs.
+func handlerName(c *gin.Context) string {
+	name := c.HandlerName()
+	name = name[strings.LastIndex(name, "/")+1:]
+	if parts := strings.Split(name, "."); len(parts) > 1 {
+		return parts[1]
+	}
+	return name
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"egg-tracker/backend/db"
+	"egg-tracker/backend/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupErrorsTestDB() (*sql.DB, func()) {
+	testDBPath := "test_errors.db"
+	// Opened the way the server opens its database, so constraint errors
+	// match production.
+	dbase, _ := db.InitDB(testDBPath)
+	return dbase, func() {
+		dbase.Close()
+		os.Remove(testDBPath)
+	}
+}
+
+func decodeError(t *testing.T, w *httptest.ResponseRecorder) ErrorResponse {
+	t.Helper()
+	var resp ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("expected an error body, got %q", w.Body.String())
+	}
+	return resp
+}
+
+func TestBindErrors(t *testing.T) {
+	dbase, cleanup := setupErrorsTestDB()
+	defer cleanup()
+	router := gin.Default()
+	router.POST("/api/inventory", CreateInventoryHandler(dbase))
+	router.POST("/api/signup", SignupHandler(dbase))
+
+	w := doJSON(router, "POST", "/api/inventory", map[string]interface{}{"quantity": 0, "species": "Chicken", "lots": []map[string]interface{}{{"lot_code": "A"}}})
+	resp := decodeError(t, w)
+	if w.Code != http.StatusBadRequest || resp.Code != CodeValidationFailed {
+		t.Fatalf("expected a validation error, got %d %s", w.Code, w.Body.String())
+	}
+	fields := map[string]string{}
+	for _, p := range resp.Details {
+		fields[p.Field] = p.Message
+	}
+	for field, want := range map[string]string{
+		"quantity":         "is required",
+		"coop":             "is required",
+		"lots[0].quantity": "is required",
+	} {
+		if fields[field] != want {
+			t.Errorf("%s: expected %q, got %q in %v", field, want, fields[field], resp.Details)
+		}
+	}
+	if !strings.Contains(resp.Error, "coop is required") {
+		t.Errorf("expected the message to list the problems, got %q", resp.Error)
+	}
+
+	for _, tc := range []struct {
+		body string
+		want string
+	}{
+		{`{"email": "hen@example.com", "password": 12345678}`, "password must be a string"},
+		{`{"email": "hen", "password": "short"}`, "email must be an email address; password must be at least 8 characters"},
+		{`{"email": `, "body must be valid JSON"},
+		{``, "body is required"},
+	} {
+		req, _ := http.NewRequest("POST", "/api/signup", bytes.NewBufferString(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if resp := decodeError(t, w); w.Code != http.StatusBadRequest || resp.Error != tc.want {
+			t.Errorf("%s: expected %q, got %d %q", tc.body, tc.want, w.Code, resp.Error)
+		}
+	}
+}
+
+func TestConstraintErrors(t *testing.T) {
+	dbase, cleanup := setupErrorsTestDB()
+	defer cleanup()
+	router := gin.Default()
+	router.POST("/api/options/:type", AddOptionHandler(dbase))
+	router.POST("/fk", func(c *gin.Context) {
+		_, err := dbase.Exec("INSERT INTO feed_usage (feed_type_id, coop, date, weight_kg) VALUES (999, 'Main', '2024-05-01', 1)")
+		respondServerError(c, err)
+	})
+	router.POST("/notnull", func(c *gin.Context) {
+		_, err := dbase.Exec("INSERT INTO feed_types (name) VALUES (NULL)")
+		respondServerError(c, err)
+	})
+
+	doJSON(router, "POST", "/api/options/species", map[string]string{"name": "Chicken"})
+	w := doJSON(router, "POST", "/api/options/species", map[string]string{"name": "Chicken"})
+	resp := decodeError(t, w)
+	if w.Code != http.StatusConflict || resp.Code != CodeDuplicate || resp.Error != "option already exists" {
+		t.Errorf("expected a duplicate error, got %d %s", w.Code, w.Body.String())
+	}
+	if len(resp.Details) != 1 || resp.Details[0].Field != "name" {
+		t.Errorf("expected the duplicate field, got %v", resp.Details)
+	}
+
+	w = doJSON(router, "POST", "/fk", nil)
+	if resp := decodeError(t, w); w.Code != http.StatusUnprocessableEntity || resp.Code != CodeConstraintViolation {
+		t.Errorf("expected a foreign key violation, got %d %s", w.Code, w.Body.String())
+	}
+	w = doJSON(router, "POST", "/notnull", nil)
+	if resp := decodeError(t, w); w.Code != http.StatusUnprocessableEntity || len(resp.Details) != 1 || resp.Details[0].Field != "name" {
+		t.Errorf("expected a not null violation on name, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestInternalErrorsNotLeaked(t *testing.T) {
+	var buf bytes.Buffer
+	logger, _ := logging.New(&buf, "json", "info")
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(logger)
+
+	dbase, cleanup := setupErrorsTestDB()
+	router := gin.New()
+	router.Use(logging.Middleware())
+	router.GET("/api/birds", ListBirdsHandler(dbase))
+	router.GET("/boom", func(c *gin.Context) {
+		respondServerError(c, errors.New("no such table: secrets"))
+	})
+	cleanup()
+
+	for path, logged := range map[string]string{
+		"/api/birds": `"component":"ListBirdsHandler"`,
+		"/boom":      `"error":"no such table: secrets"`,
+	} {
+		buf.Reset()
+		w := doJSON(router, "GET", path, nil)
+		resp := decodeError(t, w)
+		if w.Code != http.StatusInternalServerError || resp.Code != CodeInternal || resp.Error != "internal server error" {
+			t.Errorf("%s: expected a generic internal error, got %d %s", path, w.Code, w.Body.String())
+		}
+		if !strings.Contains(buf.String(), logged) || !strings.Contains(buf.String(), `"request_id":`) {
+			t.Errorf("%s: expected the error to be logged with %s, got %q", path, logged, buf.String())
+		}
+	}
+}
+
+func TestRespondErrorCodes(t *testing.T) {
+	router := gin.New()
+	router.GET("/:status", func(c *gin.Context) {
+		switch c.Param("status") {
+		case "404":
+			respondError(c, http.StatusNotFound, "bird not found")
+		case "413":
+			respondError(c, http.StatusRequestEntityTooLarge, "file too large")
+		}
+	})
+	for path, want := range map[string]string{"/404": CodeNotFound, "/413": CodeTooLarge} {
+		w := doJSON(router, "GET", path, nil)
+		if resp := decodeError(t, w); resp.Code != want {
+			t.Errorf("%s: expected code %s, got %s", path, want, resp.Code)
+		}
+	}
+}
@@ -1,16 +1,20 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"net/http"
 	"os"
+	"time"
 
 	"egg-tracker/backend/etl"
+	"egg-tracker/backend/notify"
 
 	"github.com/gin-gonic/gin"
 )
 
-// FullETLHandler triggers a full ETL refresh from SQLite to DuckDB.
+// FullETLHandler triggers a full ETL refresh from SQLite to DuckDB. Failures
+// send an etl_failed notification.
 func FullETLHandler(db *sql.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Use absolute paths to ensure correct files are used inside the container
@@ -19,15 +23,15 @@ func FullETLHandler(db *sql.DB) gin.HandlerFunc {
 		if _, err := os.Stat(duckdbPath); err == nil {
 			os.Remove(duckdbPath) // Remove old DuckDB file for clean rebuild
 		}
-		err := etl.FullRefresh(sqlitePath, duckdbPath)
+		started := time.Now()
+		err := etl.FullRefresh(c.Request.Context(), sqlitePath, duckdbPath)
 		if err != nil {
-			// Log error to backend log for debugging
-			logMsg := "[ETL ERROR] " + err.Error()
-			println(logMsg)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			notifyFailure(db, notify.KindETLFailed, started, err.Error())
+			respondError(c, http.StatusInternalServerError, "ETL full refresh failed")
 			return
 		}
-		c.JSON(http.StatusOK, gin.H{"message": "ETL full refresh complete"})
+		publishEvent(db, eventETLCompleted, gin.H{"started_at": started.UTC(), "duration_ms": time.Since(started).Milliseconds()})
+		c.JSON(http.StatusOK, MessageResponse{Message: "ETL full refresh complete"})
 	}
 }
 
@@ -35,10 +39,10 @@ func FullETLHandler(db *sql.DB) gin.HandlerFunc {
 func EtlFullRefreshFromMain() error {
 	sqlitePath := "/app/data/eggtracker.db"
 	duckdbPath := "/app/data/eggtracker.duckdb"
-	return etl.FullRefresh(sqlitePath, duckdbPath)
+	return etl.FullRefresh(context.Background(), sqlitePath, duckdbPath)
 }
 
 // EtlFullRefreshFromMainWithPaths runs a full ETL from SQLite to DuckDB using provided paths.
 func EtlFullRefreshFromMainWithPaths(sqlitePath, duckdbPath string) error {
-	return etl.FullRefresh(sqlitePath, duckdbPath)
+	return etl.FullRefresh(context.Background(), sqlitePath, duckdbPath)
 }
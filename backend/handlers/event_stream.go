@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"egg-tracker/backend/models"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// eventReplayLimit caps how many missed events a reconnecting client is
+	// sent; one that has been away longer should reload instead.
+	eventReplayLimit = 1000
+	// eventStreamBuffer is how far a client may fall behind before it is
+	// disconnected to catch up through replay.
+	eventStreamBuffer = 64
+)
+
+// eventHeartbeat is how often an idle stream sends a comment, so that
+// proxies do not close it and clients notice a dead connection.
+var eventHeartbeat = 25 * time.Second
+
+// eventFilter is what one client asked to receive.
+type eventFilter struct {
+	types []string // exact types, or prefixes such as "inventory." from "inventory.*"
+	coop  *string  // only inventory events for this coop
+}
+
+func parseEventFilter(c *gin.Context) (eventFilter, string) {
+	var f eventFilter
+	if s := c.Query("types"); s != "" {
+		for _, t := range strings.Split(s, ",") {
+			t = strings.TrimSpace(t)
+			if prefix := strings.TrimSuffix(t, "*"); prefix != t {
+				if !strings.HasSuffix(prefix, ".") || !hasEventPrefix(prefix) {
+					return f, "unknown event type: " + t
+				}
+				t = prefix
+			} else if !containsString(eventTypes, t) {
+				return f, "unknown event type: " + t
+			}
+			f.types = append(f.types, t)
+		}
+	}
+	if coop, ok := c.GetQuery("coop"); ok {
+		f.coop = &coop
+	}
+	return f, ""
+}
+
+func hasEventPrefix(prefix string) bool {
+	for _, t := range eventTypes {
+		if strings.HasPrefix(t, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f eventFilter) match(e models.Event) bool {
+	if len(f.types) > 0 {
+		found := false
+		for _, t := range f.types {
+			if e.Type == t || (strings.HasSuffix(t, ".") && strings.HasPrefix(e.Type, t)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.coop != nil && strings.HasPrefix(e.Type, "inventory.") {
+		var data struct {
+			Coop string `json:"coop"`
+		}
+		if json.Unmarshal(e.Data, &data) != nil || data.Coop != *f.coop {
+			return false
+		}
+	}
+	return true
+}
+
+// missedEvents returns events after lastID, oldest first, up to
+// eventReplayLimit of the newest of them.
+func missedEvents(db *sql.DB, lastID int64) ([]models.Event, error) {
+	rows, err := db.Query(`
+	SELECT id, type, payload, created_at FROM (
+		SELECT id, type, payload, created_at FROM events WHERE id > ? ORDER BY id DESC LIMIT ?
+	) ORDER BY id ASC`, lastID, eventReplayLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var events []models.Event
+	for rows.Next() {
+		var e models.Event
+		var payload string
+		if err := rows.Scan(&e.ID, &e.Type, &payload, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.Data = json.RawMessage(payload)
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// EventStreamHandler streams events to an authenticated client as
+// Server-Sent Events, each named after its type with the event as JSON
+// data. Query parameters: types (comma-separated, "inventory.*" for a
+// group), coop (inventory events for one coop only). A client reconnecting
+// with Last-Event-ID, or ?last_event_id=, is first sent what it missed.
+func EventStreamHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter, msg := parseEventFilter(c)
+		if msg != "" {
+			respondError(c, http.StatusBadRequest, msg)
+			return
+		}
+		lastID := int64(-1)
+		if s := c.GetHeader("Last-Event-ID"); s != "" {
+			if id, err := strconv.ParseInt(s, 10, 64); err == nil {
+				lastID = id
+			}
+		} else if s := c.Query("last_event_id"); s != "" {
+			id, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				respondError(c, http.StatusBadRequest, "last_event_id must be a number")
+				return
+			}
+			lastID = id
+		}
+
+		// Subscribe before reading the log so that nothing published in
+		// between is missed; live events the replay already covered are
+		// skipped by ID. Other live events are all sent: concurrent writers
+		// can publish them out of ID order.
+		live, unsubscribe := liveEvents.Subscribe(eventStreamBuffer)
+		defer unsubscribe()
+		var missed []models.Event
+		if lastID >= 0 {
+			var err error
+			if missed, err = missedEvents(db, lastID); err != nil {
+				respondServerError(c, err)
+				return
+			}
+		}
+
+		requestLogger(c, "EventStreamHandler").Info("Client connected", "user_id", c.GetInt64("user_id"), "replayed", len(missed))
+		c.Header("Content-Type", sse.ContentType)
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no") // stop nginx holding events back
+		c.Status(http.StatusOK)
+		send := func(e models.Event) bool {
+			if !filter.match(e) {
+				return true
+			}
+			body, _ := json.Marshal(e)
+			if err := sse.Encode(c.Writer, sse.Event{Id: strconv.FormatInt(e.ID, 10), Event: e.Type, Data: body}); err != nil {
+				return false
+			}
+			return true
+		}
+		replayed := lastID
+		for _, e := range missed {
+			if !send(e) {
+				return
+			}
+			replayed = e.ID
+		}
+		c.Writer.Flush()
+
+		heartbeat := time.NewTicker(eventHeartbeat)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case <-c.Request.Context().Done():
+				return
+			case e, ok := <-live:
+				if !ok {
+					return // fell behind; the client reconnects and replays
+				}
+				if e.ID <= replayed {
+					continue
+				}
+				if !send(e) {
+					return
+				}
+			case <-heartbeat.C:
+				if _, err := fmt.Fprint(c.Writer, ": heartbeat\n\n"); err != nil {
+					return
+				}
+			}
+			c.Writer.Flush()
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"egg-tracker/backend/auth"
+	"egg-tracker/backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sseMessage is one event or comment read from a stream.
+type sseMessage struct {
+	id, event, data, comment string
+}
+
+func readSSE(t *testing.T, r *bufio.Reader) sseMessage {
+	t.Helper()
+	var m sseMessage
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			return m
+		case strings.HasPrefix(line, ":"):
+			m.comment = strings.TrimSpace(line[1:])
+		case strings.HasPrefix(line, "id:"):
+			m.id = line[3:]
+		case strings.HasPrefix(line, "event:"):
+			m.event = line[6:]
+		case strings.HasPrefix(line, "data:"):
+			m.data = line[5:]
+		}
+	}
+}
+
+// readSSEEvents reads the next n events, skipping heartbeats, as "id type".
+func readSSEEvents(t *testing.T, r *bufio.Reader, n int) []string {
+	t.Helper()
+	var got []string
+	for len(got) < n {
+		if m := readSSE(t, r); m.comment == "" {
+			got = append(got, m.id+" "+m.event)
+		}
+	}
+	return got
+}
+
+func openStream(t *testing.T, ctx context.Context, url string, header http.Header) (*http.Response, *bufio.Reader) {
+	t.Helper()
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("open stream: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp, bufio.NewReader(resp.Body)
+}
+
+func TestEventStream(t *testing.T) {
+	dbase, cleanup := setupWebhooksTestDB()
+	defer cleanup()
+	defer func(d time.Duration) { eventHeartbeat = d }(eventHeartbeat)
+	eventHeartbeat = 100 * time.Millisecond
+	router := gin.Default()
+	router.GET("/api/events", auth.AuthMiddleware(), EventStreamHandler(dbase))
+	server := httptest.NewServer(router)
+	defer server.Close()
+	token, _ := auth.GenerateAccessToken(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if resp, _ := openStream(t, ctx, server.URL+"/api/events", nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", resp.StatusCode)
+	}
+	if resp, _ := openStream(t, ctx, server.URL+"/api/events?types=eggs.laid&access_token="+token, nil); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown type, got %d", resp.StatusCode)
+	}
+
+	publishEvent(dbase, eventInventoryCreated, gin.H{"id": 1, "coop": "North"})
+	publishEvent(dbase, eventInventoryCreated, gin.H{"id": 2, "coop": "South"})
+	publishEvent(dbase, eventETLCompleted, gin.H{"duration_ms": 5})
+
+	// Reconnecting after the first event replays the rest that match.
+	header := http.Header{"Authorization": {"Bearer " + token}, "Last-Event-Id": {"1"}}
+	resp, r := openStream(t, ctx, server.URL+"/api/events?types=inventory.*", header)
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		t.Fatalf("expected an event stream, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if m := readSSE(t, r); m.id != "2" || m.event != "inventory.created" || !strings.Contains(m.data, `"coop":"South"`) {
+		t.Errorf("expected replay of event 2, got %+v", m)
+	}
+	if m := readSSE(t, r); m.comment != "heartbeat" {
+		t.Errorf("expected a heartbeat, got %+v", m)
+	}
+
+	// Live events are filtered by type and coop.
+	_, north := openStream(t, ctx, server.URL+"/api/events?coop=North&access_token="+token, nil)
+	if m := readSSE(t, north); m.comment != "heartbeat" {
+		t.Fatalf("expected a heartbeat, got %+v", m)
+	}
+	publishEvent(dbase, eventETLCompleted, gin.H{"duration_ms": 7})
+	publishEvent(dbase, eventInventoryDeleted, gin.H{"id": 2, "coop": "South"})
+	publishEvent(dbase, eventInventoryDeleted, gin.H{"id": 1, "coop": "North"})
+	if got := readSSEEvents(t, r, 2); got[0] != "5 inventory.deleted" || got[1] != "6 inventory.deleted" {
+		t.Errorf("unexpected events for inventory.*: %v", got)
+	}
+	if got := readSSEEvents(t, north, 2); got[0] != "4 etl.completed" || got[1] != "6 inventory.deleted" {
+		t.Errorf("unexpected events for coop North: %v", got)
+	}
+
+	// Concurrent writers can publish out of ID order; neither event is lost.
+	data := json.RawMessage(`{"coop":"North"}`)
+	liveEvents.Publish(models.Event{ID: 8, Type: eventInventoryCreated, Data: data})
+	liveEvents.Publish(models.Event{ID: 7, Type: eventInventoryCreated, Data: data})
+	if got := readSSEEvents(t, north, 2); got[0] != "8 inventory.created" || got[1] != "7 inventory.created" {
+		t.Errorf("expected both out-of-order events, got %v", got)
+	}
+}
+
+func TestPruneEvents(t *testing.T) {
+	dbase, cleanup := setupWebhooksTestDB()
+	defer cleanup()
+	old := time.Now().UTC().AddDate(0, 0, -eventRetentionDays-1)
+	mustExec(t, dbase, "INSERT INTO webhooks (url, secret, event_types) VALUES ('http://example.com', 's', '*')")
+	for id, status := range map[int]string{1: "delivered", 2: "pending"} {
+		mustExec(t, dbase, "INSERT INTO events (id, type, payload, created_at) VALUES (?, 'etl.completed', '{}', ?)", id, old)
+		mustExec(t, dbase, "INSERT INTO webhook_deliveries (webhook_id, event_id, status, next_attempt_at) VALUES (1, ?, ?, ?)", id, status, old)
+	}
+	publishEvent(dbase, eventETLCompleted, gin.H{})
+
+	n, err := PruneEvents(dbase, time.Now())
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 event pruned, got %d, %v", n, err)
+	}
+	var events, deliveries int
+	dbase.QueryRow("SELECT COUNT(*) FROM events").Scan(&events)
+	dbase.QueryRow("SELECT COUNT(*) FROM webhook_deliveries").Scan(&deliveries)
+	if events != 2 || deliveries != 2 {
+		t.Errorf("expected the pending and recent events to stay, got %d events and %d deliveries", events, deliveries)
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"egg-tracker/backend/logging"
+	"egg-tracker/backend/models"
+	"egg-tracker/backend/stream"
+)
+
+// Event types published to webhooks and the event stream.
+const (
+	eventInventoryCreated  = "inventory.created"
+	eventInventoryUpdated  = "inventory.updated"
+	eventInventoryDeleted  = "inventory.deleted"
+	eventOptionCreated     = "option.created"
+	eventOptionUpdated     = "option.updated"
+	eventOptionDeactivated = "option.deactivated"
+	eventOptionReactivated = "option.reactivated"
+	eventETLCompleted      = "etl.completed"
+)
+
+var eventTypes = []string{
+	eventInventoryCreated, eventInventoryUpdated, eventInventoryDeleted,
+	eventOptionCreated, eventOptionUpdated, eventOptionDeactivated, eventOptionReactivated,
+	eventETLCompleted,
+}
+
+// eventRetentionDays is how long events are kept for stream replay and
+// webhook delivery history.
+const eventRetentionDays = 30
+
+// liveEvents passes recorded events on to open event streams.
+var liveEvents = stream.NewBroker()
+
+// publishEvent records an event, passes it to open event streams and queues
+// a delivery to every active webhook subscribed to its type. The change that
+// caused it has already been made, so failures are logged rather than
+// returned.
+func publishEvent(db *sql.DB, eventType string, data interface{}) {
+	if _, err := recordEvent(db, eventType, data); err != nil {
+		slog.Error("Could not record event", "component", "publishEvent", "type", eventType, logging.Err(err))
+	}
+}
+
+func recordEvent(db *sql.DB, eventType string, data interface{}) (int64, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now().UTC()
+	res, err := db.Exec("INSERT INTO events (type, payload, created_at) VALUES (?, ?, ?)", eventType, string(payload), now)
+	if err != nil {
+		return 0, err
+	}
+	id, _ := res.LastInsertId()
+	liveEvents.Publish(models.Event{ID: id, Type: eventType, Data: payload, CreatedAt: now})
+	_, err = db.Exec(`
+	INSERT INTO webhook_deliveries (webhook_id, event_id, next_attempt_at)
+	SELECT id, ?, ? FROM webhooks
+	WHERE active = 1 AND (event_types = '*' OR instr(',' || event_types || ',', ?) > 0)`,
+		id, now, ","+eventType+",",
+	)
+	return id, err
+}
+
+// PruneEvents deletes events older than eventRetentionDays, with their
+// finished webhook deliveries, so the event log stays bounded. Events still
+// waiting on a delivery are kept. It returns the number deleted.
+func PruneEvents(db *sql.DB, now time.Time) (int64, error) {
+	cutoff := now.UTC().AddDate(0, 0, -eventRetentionDays)
+	const old = "SELECT id FROM events WHERE created_at < ? AND id NOT IN (SELECT event_id FROM webhook_deliveries WHERE status = 'pending')"
+	if _, err := db.Exec("DELETE FROM webhook_deliveries WHERE event_id IN ("+old+")", cutoff); err != nil {
+		return 0, err
+	}
+	res, err := db.Exec("DELETE FROM events WHERE id IN ("+old+")", cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
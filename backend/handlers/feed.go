@@ -0,0 +1,393 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"egg-tracker/backend/logging"
+	"egg-tracker/backend/models"
+	"egg-tracker/backend/notify"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FeedTypeInput struct {
+	Name        string   `json:"name" binding:"required"`
+	BagWeightKg *float64 `json:"bag_weight_kg" binding:"omitempty,gt=0"`
+	LowStockKg  float64  `json:"low_stock_kg" binding:"min=0"`
+	Notes       *string  `json:"notes"`
+}
+
+type FeedPurchaseInput struct {
+	FeedTypeID int64    `json:"feed_type_id" binding:"required"`
+	Date       string   `json:"date" binding:"required"`            // ISO8601 date
+	Quantity   int      `json:"quantity" binding:"min=0"`           // bags
+	WeightKg   *float64 `json:"weight_kg" binding:"omitempty,gt=0"` // defaults to quantity x the type's bag weight
+	Cost       *float64 `json:"cost" binding:"omitempty,min=0"`
+	Supplier   *string  `json:"supplier"`
+	Notes      *string  `json:"notes"`
+}
+
+type FeedUsageInput struct {
+	FeedTypeID int64   `json:"feed_type_id" binding:"required"`
+	Coop       string  `json:"coop" binding:"required"`
+	Date       string  `json:"date" binding:"required"` // ISO8601 date
+	WeightKg   float64 `json:"weight_kg" binding:"required,gt=0"`
+	Notes      *string `json:"notes"`
+}
+
+func CreateFeedTypeHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input FeedTypeInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondBindError(c, err)
+			return
+		}
+		res, err := db.Exec(
+			"INSERT INTO feed_types (name, bag_weight_kg, low_stock_kg, notes) VALUES (?, ?, ?, ?)",
+			input.Name, input.BagWeightKg, input.LowStockKg, input.Notes,
+		)
+		if err != nil {
+			respondWriteError(c, err, "feed type already exists")
+			return
+		}
+		id, _ := res.LastInsertId()
+		c.JSON(http.StatusCreated, IDResponse{ID: id})
+	}
+}
+
+func ListFeedTypesHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rows, err := db.Query("SELECT id, name, bag_weight_kg, low_stock_kg, notes, created_at, updated_at FROM feed_types ORDER BY name ASC")
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		defer rows.Close()
+		types := []models.FeedType{}
+		for rows.Next() {
+			var ft models.FeedType
+			var bagWeight sql.NullFloat64
+			var notes sql.NullString
+			if err := rows.Scan(&ft.ID, &ft.Name, &bagWeight, &ft.LowStockKg, &notes, &ft.CreatedAt, &ft.UpdatedAt); err != nil {
+				respondServerError(c, err)
+				return
+			}
+			if bagWeight.Valid {
+				ft.BagWeightKg = &bagWeight.Float64
+			}
+			if notes.Valid {
+				ft.Notes = &notes.String
+			}
+			types = append(types, ft)
+		}
+		c.JSON(http.StatusOK, types)
+	}
+}
+
+func UpdateFeedTypeHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input FeedTypeInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondBindError(c, err)
+			return
+		}
+		res, err := db.Exec(
+			"UPDATE feed_types SET name = ?, bag_weight_kg = ?, low_stock_kg = ?, notes = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?",
+			input.Name, input.BagWeightKg, input.LowStockKg, input.Notes, c.Param("id"),
+		)
+		if err != nil {
+			respondWriteError(c, err, "feed type already exists")
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			respondError(c, http.StatusNotFound, "feed type not found")
+			return
+		}
+		c.JSON(http.StatusOK, MessageResponse{Message: "updated"})
+	}
+}
+
+// DeleteFeedTypeHandler refuses to delete feed types that have purchases or
+// usage recorded against them.
+func DeleteFeedTypeHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var refs int
+		err := db.QueryRow(
+			"SELECT (SELECT COUNT(*) FROM feed_purchases WHERE feed_type_id = ?) + (SELECT COUNT(*) FROM feed_usage WHERE feed_type_id = ?)",
+			id, id,
+		).Scan(&refs)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		if refs > 0 {
+			respondError(c, http.StatusConflict, "feed type has purchases or usage")
+			return
+		}
+		if _, err := db.Exec("DELETE FROM feed_types WHERE id = ?", id); err != nil {
+			respondServerError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, MessageResponse{Message: "deleted"})
+	}
+}
+
+// feedBalances returns the feed on hand per type, limited to one type when
+// typeID is non-zero.
+func feedBalances(db *sql.DB, typeID int64) ([]models.FeedBalance, error) {
+	rows, err := db.Query(`
+		SELECT ft.id, ft.name, ft.low_stock_kg,
+			COALESCE((SELECT SUM(weight_kg) FROM feed_purchases WHERE feed_type_id = ft.id), 0),
+			COALESCE((SELECT SUM(weight_kg) FROM feed_usage WHERE feed_type_id = ft.id), 0)
+		FROM feed_types ft
+		WHERE ? = 0 OR ft.id = ?
+		ORDER BY ft.name ASC`, typeID, typeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	balances := []models.FeedBalance{}
+	for rows.Next() {
+		var b models.FeedBalance
+		if err := rows.Scan(&b.FeedTypeID, &b.Name, &b.LowStockKg, &b.PurchasedKg, &b.UsedKg); err != nil {
+			return nil, err
+		}
+		b.OnHandKg = b.PurchasedKg - b.UsedKg
+		b.LowStock = b.OnHandKg < b.LowStockKg
+		balances = append(balances, b)
+	}
+	return balances, rows.Err()
+}
+
+// FeedBalanceHandler returns feed on hand per type. ?low_stock=true lists
+// only the types below their warning threshold.
+func FeedBalanceHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		balances, err := feedBalances(db, 0)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		if c.Query("low_stock") == "true" {
+			low := []models.FeedBalance{}
+			for _, b := range balances {
+				if b.LowStock {
+					low = append(low, b)
+				}
+			}
+			balances = low
+		}
+		c.JSON(http.StatusOK, balances)
+	}
+}
+
+// FeedPurchaseCreated is returned for a new purchase, with the weight
+// worked out from the bag weight when none was given.
+type FeedPurchaseCreated struct {
+	ID       int64   `json:"id"`
+	WeightKg float64 `json:"weight_kg"`
+}
+
+func CreateFeedPurchaseHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input FeedPurchaseInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondBindError(c, err)
+			return
+		}
+		date, err := time.Parse("2006-01-02", input.Date)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid date")
+			return
+		}
+		var bagWeight sql.NullFloat64
+		err = db.QueryRow("SELECT bag_weight_kg FROM feed_types WHERE id = ?", input.FeedTypeID).Scan(&bagWeight)
+		if err == sql.ErrNoRows {
+			respondError(c, http.StatusBadRequest, "feed type not found")
+			return
+		} else if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		if input.WeightKg == nil {
+			if !bagWeight.Valid || input.Quantity == 0 {
+				respondError(c, http.StatusBadRequest, "weight_kg is required without a bag count and bag weight")
+				return
+			}
+			weight := float64(input.Quantity) * bagWeight.Float64
+			input.WeightKg = &weight
+		}
+		res, err := db.Exec(
+			"INSERT INTO feed_purchases (feed_type_id, date, quantity, weight_kg, cost, supplier, notes) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			input.FeedTypeID, date, input.Quantity, *input.WeightKg, input.Cost, input.Supplier, input.Notes,
+		)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		id, _ := res.LastInsertId()
+		c.JSON(http.StatusCreated, FeedPurchaseCreated{ID: id, WeightKg: *input.WeightKg})
+	}
+}
+
+// ListFeedPurchasesHandler lists purchases, newest first, optionally for one ?feed_type_id=.
+func ListFeedPurchasesHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := "SELECT id, feed_type_id, date, quantity, weight_kg, cost, supplier, notes, created_at FROM feed_purchases"
+		var args []interface{}
+		if t := c.Query("feed_type_id"); t != "" {
+			query += " WHERE feed_type_id = ?"
+			args = append(args, t)
+		}
+		rows, err := db.Query(query+" ORDER BY date DESC, id DESC", args...)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		defer rows.Close()
+		purchases := []models.FeedPurchase{}
+		for rows.Next() {
+			var p models.FeedPurchase
+			var cost sql.NullFloat64
+			var supplier, notes sql.NullString
+			if err := rows.Scan(&p.ID, &p.FeedTypeID, &p.Date, &p.Quantity, &p.WeightKg, &cost, &supplier, &notes, &p.CreatedAt); err != nil {
+				respondServerError(c, err)
+				return
+			}
+			if cost.Valid {
+				p.Cost = &cost.Float64
+			}
+			if supplier.Valid {
+				p.Supplier = &supplier.String
+			}
+			if notes.Valid {
+				p.Notes = &notes.String
+			}
+			purchases = append(purchases, p)
+		}
+		c.JSON(http.StatusOK, purchases)
+	}
+}
+
+func DeleteFeedPurchaseHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		files, err := deleteAttachments(db, "feed_purchase", c.Param("id"))
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		if _, err := db.Exec("DELETE FROM feed_purchases WHERE id = ?", c.Param("id")); err != nil {
+			respondServerError(c, err)
+			return
+		}
+		removeAttachmentFiles(files)
+		c.JSON(http.StatusOK, MessageResponse{Message: "deleted"})
+	}
+}
+
+// FeedUsageCreated is returned for a new usage log with the feed type's
+// balance after it. Warning is set once the feed is running low.
+type FeedUsageCreated struct {
+	ID      int64              `json:"id"`
+	Balance models.FeedBalance `json:"balance"`
+	Warning string             `json:"warning,omitempty"`
+}
+
+// CreateFeedUsageHandler logs feed put out for a coop. The response carries
+// the remaining balance and a warning once it drops below the low-stock level;
+// crossing that level also sends a low_stock notification.
+func CreateFeedUsageHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input FeedUsageInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondBindError(c, err)
+			return
+		}
+		date, err := time.Parse("2006-01-02", input.Date)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid date")
+			return
+		}
+		balances, err := feedBalances(db, input.FeedTypeID)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		if len(balances) == 0 {
+			respondError(c, http.StatusBadRequest, "feed type not found")
+			return
+		}
+		res, err := db.Exec(
+			"INSERT INTO feed_usage (feed_type_id, coop, date, weight_kg, notes) VALUES (?, ?, ?, ?, ?)",
+			input.FeedTypeID, input.Coop, date, input.WeightKg, input.Notes,
+		)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		id, _ := res.LastInsertId()
+		balance := balances[0]
+		wasLow := balance.LowStock
+		balance.UsedKg += input.WeightKg
+		balance.OnHandKg -= input.WeightKg
+		balance.LowStock = balance.OnHandKg < balance.LowStockKg
+		if balance.LowStock && !wasLow {
+			if _, err := queueNotification(db, notify.KindLowStock, balance); err != nil {
+				requestLogger(c, "CreateFeedUsageHandler").Error("Could not queue low stock notification", logging.Err(err))
+			}
+		}
+		resp := FeedUsageCreated{ID: id, Balance: balance}
+		if balance.LowStock {
+			resp.Warning = "feed is running low"
+		}
+		c.JSON(http.StatusCreated, resp)
+	}
+}
+
+// ListFeedUsageHandler lists usage logs, newest first, optionally filtered by
+// ?feed_type_id= and ?coop=.
+func ListFeedUsageHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := "SELECT id, feed_type_id, coop, date, weight_kg, notes, created_at FROM feed_usage WHERE 1 = 1"
+		var args []interface{}
+		for _, f := range []string{"feed_type_id", "coop"} {
+			if v := c.Query(f); v != "" {
+				query += " AND " + f + " = ?"
+				args = append(args, v)
+			}
+		}
+		rows, err := db.Query(query+" ORDER BY date DESC, id DESC", args...)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		defer rows.Close()
+		usage := []models.FeedUsage{}
+		for rows.Next() {
+			var u models.FeedUsage
+			var notes sql.NullString
+			if err := rows.Scan(&u.ID, &u.FeedTypeID, &u.Coop, &u.Date, &u.WeightKg, &notes, &u.CreatedAt); err != nil {
+				respondServerError(c, err)
+				return
+			}
+			if notes.Valid {
+				u.Notes = &notes.String
+			}
+			usage = append(usage, u)
+		}
+		c.JSON(http.StatusOK, usage)
+	}
+}
+
+func DeleteFeedUsageHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, err := db.Exec("DELETE FROM feed_usage WHERE id = ?", c.Param("id")); err != nil {
+			respondServerError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, MessageResponse{Message: "deleted"})
+	}
+}
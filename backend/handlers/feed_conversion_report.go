@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"egg-tracker/backend/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FeedConversion struct {
+	Month        string   `json:"month"`
+	Coop         string   `json:"coop"`
+	FeedKg       float64  `json:"feed_kg"`
+	Eggs         int      `json:"eggs"`
+	KgPerDozen   *float64 `json:"kg_per_dozen"`   // null when no eggs were collected
+	FeedCost     *float64 `json:"feed_cost"`      // usage priced at each feed type's average purchase cost per kg
+	CostPerDozen *float64 `json:"cost_per_dozen"` // null without eggs or priced purchases
+}
+
+// queryFeedConversion relates feed used to eggs collected per coop and month.
+func queryFeedConversion(duck *sql.DB, from, to time.Time) ([]FeedConversion, error) {
+	rows, err := duck.Query(`
+	WITH price AS (
+		SELECT feed_type_id, SUM(cost) / SUM(weight_kg) AS per_kg
+		FROM feed_purchases WHERE cost IS NOT NULL
+		GROUP BY 1
+	),
+	feed AS (
+		SELECT strftime(CAST(u.date AS DATE), '%Y-%m') AS month, u.coop,
+			SUM(u.weight_kg) AS kg, SUM(u.weight_kg * p.per_kg) AS cost
+		FROM feed_usage u LEFT JOIN price p ON p.feed_type_id = u.feed_type_id
+		WHERE CAST(u.date AS DATE) BETWEEN CAST(? AS DATE) AND CAST(? AS DATE)
+		GROUP BY 1, 2
+	),
+	eggs AS (
+		SELECT strftime(CAST(date AS DATE), '%Y-%m') AS month, COALESCE(coop, '') AS coop, SUM(quantity) AS eggs
+		FROM inventory_actions
+		WHERE action = 'collected' AND CAST(date AS DATE) BETWEEN CAST(? AS DATE) AND CAST(? AS DATE)
+		GROUP BY 1, 2
+	)
+	SELECT COALESCE(f.month, e.month) AS month, COALESCE(f.coop, e.coop) AS coop,
+		CAST(COALESCE(f.kg, 0) AS DOUBLE), CAST(COALESCE(e.eggs, 0) AS BIGINT), CAST(f.cost AS DOUBLE)
+	FROM feed f FULL OUTER JOIN eggs e ON f.month = e.month AND f.coop = e.coop
+	ORDER BY month ASC, coop ASC
+	`, from.Format("2006-01-02"), to.Format("2006-01-02"), from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	results := []FeedConversion{}
+	for rows.Next() {
+		var fc FeedConversion
+		var cost sql.NullFloat64
+		if err := rows.Scan(&fc.Month, &fc.Coop, &fc.FeedKg, &fc.Eggs, &cost); err != nil {
+			return nil, err
+		}
+		if cost.Valid {
+			fc.FeedCost = &cost.Float64
+		}
+		if fc.Eggs > 0 {
+			dozens := float64(fc.Eggs) / 12
+			perDozen := fc.FeedKg / dozens
+			fc.KgPerDozen = &perDozen
+			if fc.FeedCost != nil {
+				costPerDozen := *fc.FeedCost / dozens
+				fc.CostPerDozen = &costPerDozen
+			}
+		}
+		results = append(results, fc)
+	}
+	return results, rows.Err()
+}
+
+// FeedConversionReport is the response of FeedConversionReportHandler.
+type FeedConversionReport struct {
+	FeedConversion []FeedConversion `json:"feedConversion"`
+}
+
+// FeedConversionReportHandler returns kilograms of feed per dozen eggs by coop
+// and month from DuckDB. Query parameters: from/to as YYYY-MM-DD (default:
+// first collection to today).
+func FeedConversionReportHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		duckdb, err := sql.Open("duckdb", analyticsDBPath)
+		if err != nil {
+			requestLogger(c, "FeedConversionReportHandler").Error("Failed to open DuckDB", logging.Err(err))
+			respondError(c, http.StatusInternalServerError, "failed to open DuckDB")
+			return
+		}
+		defer duckdb.Close()
+
+		from, to, err := reportRange(c, func() (time.Time, error) { return firstCollectionDate(duckdb) })
+		if err != nil {
+			respondInputError(c, err)
+			return
+		}
+		results, err := queryFeedConversion(duckdb, from, to)
+		if err != nil {
+			requestLogger(c, "FeedConversionReportHandler").Error("Feed conversion query failed", logging.Err(err))
+			respondError(c, http.StatusInternalServerError, "feed conversion query failed")
+			return
+		}
+		c.JSON(http.StatusOK, FeedConversionReport{FeedConversion: results})
+	}
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestFeedConversionReport_KgPerDozen(t *testing.T) {
+	cleanup := setupAnalyticsTestDB(t, func(dbase *sql.DB) {
+		mustExec(t, dbase, "INSERT INTO feed_types (id, name) VALUES (1, 'Layer Pellets')")
+		mustExec(t, dbase, "INSERT INTO feed_purchases (feed_type_id, date, quantity, weight_kg, cost) VALUES (1, '2025-05-01', 2, 40, 50)")
+		mustExec(t, dbase, `INSERT INTO feed_usage (feed_type_id, coop, date, weight_kg) VALUES
+			(1, 'Main Coop', '2025-05-01', 6),
+			(1, 'Main Coop', '2025-05-15', 3),
+			(1, 'Back Barn', '2025-06-01', 4)`)
+		mustExec(t, dbase, `INSERT INTO inventory_actions (quantity, species, coop, action, date) VALUES
+			(24, 'Chicken', 'Main Coop', 'collected', '2025-05-02'),
+			(12, 'Chicken', 'Main Coop', 'collected', '2025-05-20'),
+			(5, 'Chicken', 'Main Coop', 'sold', '2025-05-21')`)
+	})
+	defer cleanup()
+
+	router := gin.Default()
+	router.GET("/api/reports/feed-conversion", FeedConversionReportHandler())
+	req, _ := http.NewRequest("GET", "/api/reports/feed-conversion?from=2025-05-01&to=2025-06-30", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		FeedConversion []FeedConversion `json:"feedConversion"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.FeedConversion) != 2 {
+		t.Fatalf("expected 2 rows, got %+v", resp.FeedConversion)
+	}
+	may, june := resp.FeedConversion[0], resp.FeedConversion[1]
+	if may.Month != "2025-05" || may.FeedKg != 9 || may.Eggs != 36 || *may.KgPerDozen != 3 || *may.CostPerDozen != 3.75 {
+		t.Errorf("unexpected May row: %+v", may)
+	}
+	if june.Coop != "Back Barn" || june.KgPerDozen != nil || *june.FeedCost != 5 {
+		t.Errorf("expected June feed without eggs, got %+v", june)
+	}
+}
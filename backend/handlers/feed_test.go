@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+
+	"egg-tracker/backend/db"
+	"egg-tracker/backend/models"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupFeedTestDB() (*sql.DB, func()) {
+	testDBPath := "test_feed.db"
+	dbase, _ := sql.Open("sqlite3", testDBPath)
+	db.Migrate(dbase)
+	return dbase, func() {
+		dbase.Close()
+		os.Remove(testDBPath)
+	}
+}
+
+func TestFeedBalanceAndLowStock(t *testing.T) {
+	dbase, cleanup := setupFeedTestDB()
+	defer cleanup()
+	router := gin.Default()
+	router.POST("/api/feed/types", CreateFeedTypeHandler(dbase))
+	router.DELETE("/api/feed/types/:id", DeleteFeedTypeHandler(dbase))
+	router.POST("/api/feed/purchases", CreateFeedPurchaseHandler(dbase))
+	router.POST("/api/feed/usage", CreateFeedUsageHandler(dbase))
+	router.GET("/api/feed/balance", FeedBalanceHandler(dbase))
+
+	w := doJSON(router, "POST", "/api/feed/types", map[string]interface{}{"name": "Layer Pellets", "bag_weight_kg": 20, "low_stock_kg": 15})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	w = doJSON(router, "POST", "/api/feed/types", map[string]interface{}{"name": "Layer Pellets"})
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected 409 for duplicate feed type, got %d", w.Code)
+	}
+
+	// Weight comes from the bag count when omitted
+	w = doJSON(router, "POST", "/api/feed/purchases", map[string]interface{}{"feed_type_id": 1, "date": "2025-05-01", "quantity": 2, "cost": 40})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 for purchase, got %d: %s", w.Code, w.Body.String())
+	}
+	var purchase map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &purchase)
+	if purchase["weight_kg"] != 40.0 {
+		t.Errorf("expected 40 kg from two 20 kg bags, got %v", purchase["weight_kg"])
+	}
+
+	w = doJSON(router, "POST", "/api/feed/usage", map[string]interface{}{"feed_type_id": 1, "coop": "Main Coop", "date": "2025-05-02", "weight_kg": 20})
+	var usage map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &usage)
+	if w.Code != http.StatusCreated || usage["warning"] != nil {
+		t.Fatalf("expected usage without warning, got %d: %s", w.Code, w.Body.String())
+	}
+	w = doJSON(router, "POST", "/api/feed/usage", map[string]interface{}{"feed_type_id": 1, "coop": "Main Coop", "date": "2025-05-09", "weight_kg": 10})
+	usage = nil
+	json.Unmarshal(w.Body.Bytes(), &usage)
+	if usage["warning"] == nil {
+		t.Errorf("expected low stock warning, got %s", w.Body.String())
+	}
+
+	w = doJSON(router, "GET", "/api/feed/balance?low_stock=true", nil)
+	var balances []models.FeedBalance
+	json.Unmarshal(w.Body.Bytes(), &balances)
+	if len(balances) != 1 || balances[0].OnHandKg != 10 || !balances[0].LowStock {
+		t.Errorf("expected 10 kg on hand and low, got %+v", balances)
+	}
+
+	w = doJSON(router, "DELETE", "/api/feed/types/1", nil)
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected 409 deleting a feed type in use, got %d", w.Code)
+	}
+}
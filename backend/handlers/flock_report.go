@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"egg-tracker/backend/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// birdDaysSQL expands bird_transfers into one row per bird per day spent in a
+// coop between the two date parameters. A stint ends at the bird's next
+// transfer or, for sold/deceased birds, at their status date.
+const birdDaysSQL = `
+	WITH stints AS (
+		SELECT t.bird_id, t.to_coop AS coop, CAST(t.date AS DATE) AS start_date,
+			LEAD(CAST(t.date AS DATE)) OVER (PARTITION BY t.bird_id ORDER BY t.date, t.id) AS next_move
+		FROM bird_transfers t
+	),
+	spans AS (
+		SELECT s.bird_id, s.coop, b.species, CAST(b.hatch_date AS DATE) AS hatch_date, s.start_date,
+			LEAST(
+				COALESCE(s.next_move, DATE '9999-12-31'),
+				CASE WHEN b.status <> 'active' AND b.status_date IS NOT NULL THEN CAST(b.status_date AS DATE) ELSE DATE '9999-12-31' END
+			) AS end_date
+		FROM stints s JOIN birds b ON b.id = s.bird_id
+	),
+	days AS (
+		SELECT CAST(d AS DATE) AS day FROM generate_series(CAST(? AS DATE), CAST(? AS DATE), INTERVAL 1 DAY) AS g(d)
+	),
+	bird_days AS (
+		SELECT days.day, spans.bird_id, spans.coop, spans.species, spans.hatch_date
+		FROM days JOIN spans ON spans.start_date <= days.day AND days.day < spans.end_date
+	)
+`
+
+type FlockSize struct {
+	Date  string `json:"date"`
+	Coop  string `json:"coop"`
+	Birds int    `json:"birds"`
+}
+
+// queryFlockSizes returns the number of birds housed in each coop for every
+// day in [from, to].
+func queryFlockSizes(duck *sql.DB, from, to time.Time) ([]FlockSize, error) {
+	// go-duckdb cannot bind time.Time into CAST(? AS DATE), so pass ISO strings.
+	rows, err := duck.Query(birdDaysSQL+`
+		SELECT strftime(day, '%Y-%m-%d'), coop, COUNT(*)
+		FROM bird_days
+		GROUP BY day, coop
+		ORDER BY day ASC, coop ASC
+	`, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	sizes := []FlockSize{}
+	for rows.Next() {
+		var fs FlockSize
+		if err := rows.Scan(&fs.Date, &fs.Coop, &fs.Birds); err != nil {
+			return nil, err
+		}
+		sizes = append(sizes, fs)
+	}
+	return sizes, rows.Err()
+}
+
+// reportRange reads ?from= and ?to= (YYYY-MM-DD). When from is missing the
+// earliest date returned by defaultFrom is used; to defaults to today. Bad
+// parameters are *inputError; defaultFrom's errors are returned as they are.
+func reportRange(c *gin.Context, defaultFrom func() (time.Time, error)) (time.Time, time.Time, error) {
+	to := today()
+	if s := c.Query("to"); s != "" {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			return time.Time{}, time.Time{}, &inputError{http.StatusBadRequest, "invalid to date"}
+		}
+		to = d
+	}
+	var from time.Time
+	if s := c.Query("from"); s != "" {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			return time.Time{}, time.Time{}, &inputError{http.StatusBadRequest, "invalid from date"}
+		}
+		from = d
+	} else {
+		d, err := defaultFrom()
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		from = d
+	}
+	if from.After(to) {
+		return time.Time{}, time.Time{}, &inputError{http.StatusBadRequest, "from must not be after to"}
+	}
+	return from, to, nil
+}
+
+// FlockReport is the response of FlockReportHandler.
+type FlockReport struct {
+	FlockSizes []FlockSize `json:"flockSizes"`
+}
+
+// FlockReportHandler returns daily flock size per coop from DuckDB.
+func FlockReportHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		duckdb, err := sql.Open("duckdb", analyticsDBPath)
+		if err != nil {
+			requestLogger(c, "FlockReportHandler").Error("Failed to open DuckDB", logging.Err(err))
+			respondError(c, http.StatusInternalServerError, "failed to open DuckDB")
+			return
+		}
+		defer duckdb.Close()
+
+		from, to, err := reportRange(c, func() (time.Time, error) {
+			var first sql.NullTime
+			if err := duckdb.QueryRow("SELECT MIN(CAST(date AS DATE)) FROM bird_transfers").Scan(&first); err != nil {
+				return time.Time{}, err
+			}
+			if !first.Valid {
+				return today(), nil
+			}
+			return first.Time, nil
+		})
+		if err != nil {
+			respondInputError(c, err)
+			return
+		}
+		sizes, err := queryFlockSizes(duckdb, from, to)
+		if err != nil {
+			requestLogger(c, "FlockReportHandler").Error("Flock size query failed", logging.Err(err))
+			respondError(c, http.StatusInternalServerError, "flock size query failed")
+			return
+		}
+		c.JSON(http.StatusOK, FlockReport{FlockSizes: sizes})
+	}
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"egg-tracker/backend/db"
+	"egg-tracker/backend/etl"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/marcboeker/go-duckdb"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// setupAnalyticsTestDB migrates a fresh SQLite database, lets seed insert
+// rows, then runs the ETL and points the report handlers at the result.
+func setupAnalyticsTestDB(t *testing.T, seed func(*sql.DB)) func() {
+	sqlitePath := "test_analytics.db"
+	duckdbPath := "test_analytics.duckdb"
+	os.Remove(sqlitePath)
+	os.Remove(duckdbPath)
+	dbase, err := sql.Open("sqlite3", sqlitePath)
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.Migrate(dbase); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	seed(dbase)
+	dbase.Close()
+	if err := etl.FullRefresh(context.Background(), sqlitePath, duckdbPath); err != nil {
+		t.Fatalf("etl: %v", err)
+	}
+	oldPath := analyticsDBPath
+	analyticsDBPath = duckdbPath
+	return func() {
+		analyticsDBPath = oldPath
+		os.Remove(sqlitePath)
+		os.Remove(duckdbPath)
+	}
+}
+
+func mustExec(t *testing.T, dbase *sql.DB, query string, args ...interface{}) {
+	t.Helper()
+	if _, err := dbase.Exec(query, args...); err != nil {
+		t.Fatalf("exec %q: %v", query, err)
+	}
+}
+
+func TestFlockReport_SizesFollowTransfersAndStatus(t *testing.T) {
+	cleanup := setupAnalyticsTestDB(t, func(dbase *sql.DB) {
+		mustExec(t, dbase, `INSERT INTO birds (id, name, species, coop, status, status_date) VALUES
+			(1, 'A', 'Chicken', 'Back Barn', 'active', NULL),
+			(2, 'B', 'Chicken', 'Main Coop', 'deceased', '2025-05-03')`)
+		mustExec(t, dbase, `INSERT INTO bird_transfers (bird_id, from_coop, to_coop, date) VALUES
+			(1, NULL, 'Main Coop', '2025-05-01'),
+			(1, 'Main Coop', 'Back Barn', '2025-05-03'),
+			(2, NULL, 'Main Coop', '2025-05-01')`)
+	})
+	defer cleanup()
+
+	router := gin.Default()
+	router.GET("/api/reports/flock", FlockReportHandler())
+	req, _ := http.NewRequest("GET", "/api/reports/flock?from=2025-05-01&to=2025-05-03", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		FlockSizes []FlockSize `json:"flockSizes"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	want := []FlockSize{
+		{Date: "2025-05-01", Coop: "Main Coop", Birds: 2},
+		{Date: "2025-05-02", Coop: "Main Coop", Birds: 2},
+		{Date: "2025-05-03", Coop: "Back Barn", Birds: 1},
+	}
+	if len(resp.FlockSizes) != len(want) {
+		t.Fatalf("expected %v, got %v", want, resp.FlockSizes)
+	}
+	for i := range want {
+		if resp.FlockSizes[i] != want[i] {
+			t.Errorf("row %d: expected %v, got %v", i, want[i], resp.FlockSizes[i])
+		}
+	}
+}
+
+func TestFlockReport_InvalidRange(t *testing.T) {
+	cleanup := setupAnalyticsTestDB(t, func(*sql.DB) {})
+	defer cleanup()
+
+	router := gin.Default()
+	router.GET("/api/reports/flock", FlockReportHandler())
+	req, _ := http.NewRequest("GET", "/api/reports/flock?from=2025-05-03&to=2025-05-01", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestFlockReport_DefaultFromFailure(t *testing.T) {
+	// An analytics database without tables makes the default from date fail.
+	oldPath := analyticsDBPath
+	analyticsDBPath = "test_analytics_empty.duckdb"
+	defer func() {
+		analyticsDBPath = oldPath
+		os.Remove("test_analytics_empty.duckdb")
+	}()
+
+	router := gin.Default()
+	router.GET("/api/reports/flock", FlockReportHandler())
+	req, _ := http.NewRequest("GET", "/api/reports/flock", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,388 @@
+package handlers
+
+import (
+	"database/sql"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"egg-tracker/backend/forecast"
+	"egg-tracker/backend/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// forecastSeasons is the seasonal period of each ?period=: a weekly cycle in
+// daily data and a yearly one in weekly data.
+var forecastSeasons = map[string]int{
+	"day":  7,
+	"week": 52,
+}
+
+// forecastZ maps the supported ?level= interval percentages to normal quantiles.
+var forecastZ = map[int]float64{
+	80: 1.2816,
+	95: 1.9600,
+}
+
+// maxForecastWeeks caps how far ahead a forecast may reach.
+const maxForecastWeeks = 52
+
+type ForecastPoint struct {
+	Start     string  `json:"start"` // first day of the period, YYYY-MM-DD
+	End       string  `json:"end"`   // last day of the period, inclusive
+	Predicted float64 `json:"predicted"`
+	Lower     float64 `json:"lower"`
+	Upper     float64 `json:"upper"`
+}
+
+type GroupForecast struct {
+	Group   string          `json:"group"` // coop or species name, depending on ?by=
+	Model   string          `json:"model"` // holt-winters, holt or mean
+	Alpha   float64         `json:"alpha"`
+	Beta    float64         `json:"beta"`
+	Gamma   float64         `json:"gamma"`
+	Sigma   float64         `json:"sigma"`   // standard deviation of one-step-ahead errors
+	History int             `json:"history"` // periods the model was fitted on
+	Points  []ForecastPoint `json:"points"`
+}
+
+// ForecastOptions selects what a forecast covers.
+type ForecastOptions struct {
+	By     string    // coop or species
+	Period string    // day or week
+	Weeks  int       // how far ahead to forecast
+	Level  int       // prediction interval, 80 or 95 percent
+	AsOf   time.Time // first day to forecast; history stops at the last complete period before it
+}
+
+// weekStart returns the Monday on or before d.
+func weekStart(d time.Time) time.Time {
+	return d.AddDate(0, 0, -((int(d.Weekday()) + 6) % 7))
+}
+
+// forecastOptions reads and validates the forecast query parameters.
+func forecastOptions(c *gin.Context) (ForecastOptions, string) {
+	opts := ForecastOptions{By: c.DefaultQuery("by", "coop"), Period: c.DefaultQuery("period", "week"), AsOf: today()}
+	if opts.By != "coop" && opts.By != "species" {
+		return opts, "by must be coop or species"
+	}
+	if _, ok := forecastSeasons[opts.Period]; !ok {
+		return opts, "period must be day or week"
+	}
+	weeks, err := strconv.Atoi(c.DefaultQuery("weeks", "4"))
+	if err != nil || weeks < 1 || weeks > maxForecastWeeks {
+		return opts, "weeks must be between 1 and " + strconv.Itoa(maxForecastWeeks)
+	}
+	opts.Weeks = weeks
+	level, err := strconv.Atoi(c.DefaultQuery("level", "80"))
+	if _, ok := forecastZ[level]; err != nil || !ok {
+		return opts, "level must be 80 or 95"
+	}
+	opts.Level = level
+	return opts, ""
+}
+
+// queryForecasts fits a model per coop or species to collections from
+// DuckDB and projects it forward. Days without collections count as zero
+// between a group's first collection and the end of the history; groups that
+// start after it are skipped.
+func queryForecasts(duck *sql.DB, opts ForecastOptions) ([]GroupForecast, error) {
+	asOf := opts.AsOf
+	step, steps := 1, 7*opts.Weeks
+	if opts.Period == "week" {
+		asOf = weekStart(asOf)
+		step, steps = 7, opts.Weeks
+	}
+	rows, err := duck.Query(`
+	SELECT COALESCE(`+opts.By+`, '') AS grp, CAST(date AS DATE) AS d, CAST(SUM(quantity) AS BIGINT)
+	FROM inventory_actions
+	WHERE action = 'collected' AND CAST(date AS DATE) < CAST(? AS DATE)
+	GROUP BY 1, 2 ORDER BY 1, 2`, asOf.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	type series struct {
+		first time.Time
+		eggs  map[string]float64
+	}
+	groups := map[string]*series{}
+	var names []string
+	for rows.Next() {
+		var grp string
+		var d time.Time
+		var n int64
+		if err := rows.Scan(&grp, &d, &n); err != nil {
+			return nil, err
+		}
+		s, ok := groups[grp]
+		if !ok {
+			s = &series{first: d, eggs: map[string]float64{}}
+			if opts.Period == "week" {
+				s.first = weekStart(d)
+			}
+			groups[grp] = s
+			names = append(names, grp)
+		}
+		key := d.Format("2006-01-02")
+		if opts.Period == "week" {
+			key = weekStart(d).Format("2006-01-02")
+		}
+		s.eggs[key] += float64(n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	z := forecastZ[opts.Level]
+	forecasts := []GroupForecast{}
+	for _, name := range names {
+		s := groups[name]
+		var y []float64
+		for d := s.first; d.Before(asOf); d = d.AddDate(0, 0, step) {
+			y = append(y, s.eggs[d.Format("2006-01-02")])
+		}
+		if len(y) == 0 {
+			continue
+		}
+		model := forecast.Fit(y, forecastSeasons[opts.Period])
+		gf := GroupForecast{
+			Group: name, Model: model.Kind, Alpha: model.Alpha, Beta: model.Beta, Gamma: model.Gamma,
+			Sigma: model.Sigma, History: len(y), Points: []ForecastPoint{},
+		}
+		for i, p := range model.Forecast(steps, z) {
+			start := asOf.AddDate(0, 0, i*step)
+			gf.Points = append(gf.Points, ForecastPoint{
+				Start:     start.Format("2006-01-02"),
+				End:       start.AddDate(0, 0, step-1).Format("2006-01-02"),
+				Predicted: p.Value,
+				Lower:     p.Lower,
+				Upper:     p.Upper,
+			})
+		}
+		forecasts = append(forecasts, gf)
+	}
+	return forecasts, nil
+}
+
+// ForecastReport is the response of ForecastReportHandler.
+type ForecastReport struct {
+	By        string          `json:"by"`
+	Period    string          `json:"period"`
+	Level     int             `json:"level"`
+	Forecasts []GroupForecast `json:"forecasts"`
+}
+
+// ForecastReportHandler forecasts collections per coop or species with
+// additive Holt-Winters models fitted over DuckDB aggregates. Query
+// parameters: by=coop|species (default coop), period=day|week (default
+// week), weeks=how far ahead (default 4), level=80|95 for the prediction
+// intervals (default 80).
+func ForecastReportHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		opts, msg := forecastOptions(c)
+		if msg != "" {
+			respondError(c, http.StatusBadRequest, msg)
+			return
+		}
+		duckdb, err := sql.Open("duckdb", analyticsDBPath)
+		if err != nil {
+			requestLogger(c, "ForecastReportHandler").Error("Failed to open DuckDB", logging.Err(err))
+			respondError(c, http.StatusInternalServerError, "failed to open DuckDB")
+			return
+		}
+		defer duckdb.Close()
+		forecasts, err := queryForecasts(duckdb, opts)
+		if err != nil {
+			requestLogger(c, "ForecastReportHandler").Error("Forecast query failed", logging.Err(err))
+			respondError(c, http.StatusInternalServerError, "forecast query failed")
+			return
+		}
+		c.JSON(http.StatusOK, ForecastReport{By: opts.By, Period: opts.Period, Level: opts.Level, Forecasts: forecasts})
+	}
+}
+
+// RecordForecast computes a forecast and stores its points so that their
+// accuracy can be measured once the periods are over. It returns the run id.
+func RecordForecast(db *sql.DB, opts ForecastOptions) (int64, error) {
+	duckdb, err := sql.Open("duckdb", analyticsDBPath)
+	if err != nil {
+		return 0, err
+	}
+	defer duckdb.Close()
+	forecasts, err := queryForecasts(duckdb, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec(
+		"INSERT INTO forecast_runs (as_of, by_group, period, weeks, level) VALUES (?, ?, ?, ?, ?)",
+		opts.AsOf, opts.By, opts.Period, opts.Weeks, opts.Level,
+	)
+	if err != nil {
+		return 0, err
+	}
+	runID, _ := res.LastInsertId()
+	for _, gf := range forecasts {
+		for i, p := range gf.Points {
+			_, err := tx.Exec(
+				"INSERT INTO forecast_points (run_id, grp, model, step, start_date, end_date, predicted, lower, upper) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+				runID, gf.Group, gf.Model, i+1, p.Start, p.End, p.Predicted, p.Lower, p.Upper,
+			)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+	return runID, tx.Commit()
+}
+
+// RecordForecastHandler stores a forecast for later accuracy tracking. It
+// takes the same query parameters as ForecastReportHandler.
+func RecordForecastHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		opts, msg := forecastOptions(c)
+		if msg != "" {
+			respondError(c, http.StatusBadRequest, msg)
+			return
+		}
+		id, err := RecordForecast(db, opts)
+		if err != nil {
+			requestLogger(c, "RecordForecastHandler").Error("Failed to record forecast", logging.Err(err))
+			respondError(c, http.StatusInternalServerError, "failed to record forecast")
+			return
+		}
+		c.JSON(http.StatusCreated, IDResponse{ID: id})
+	}
+}
+
+type ForecastAccuracy struct {
+	Points   int      `json:"points"`
+	MAE      float64  `json:"mae"`      // mean absolute error in eggs
+	Bias     float64  `json:"bias"`     // mean of predicted minus actual
+	MAPE     *float64 `json:"mape"`     // percent, over periods with eggs collected; null when there were none
+	Coverage float64  `json:"coverage"` // percent of actuals inside the prediction interval
+}
+
+type GroupAccuracy struct {
+	Group string `json:"group"`
+	ForecastAccuracy
+}
+
+type StepAccuracy struct {
+	Step int `json:"step"` // periods ahead of the forecast date
+	ForecastAccuracy
+}
+
+type accuracyTotals struct {
+	points, nonZero        int
+	absErr, err, pct, hits float64
+}
+
+func (t *accuracyTotals) add(predicted, lower, upper, actual float64) {
+	t.points++
+	t.absErr += math.Abs(predicted - actual)
+	t.err += predicted - actual
+	if actual != 0 {
+		t.nonZero++
+		t.pct += math.Abs(predicted-actual) / actual * 100
+	}
+	if actual >= lower && actual <= upper {
+		t.hits++
+	}
+}
+
+func (t *accuracyTotals) result() ForecastAccuracy {
+	a := ForecastAccuracy{
+		Points:   t.points,
+		MAE:      t.absErr / float64(t.points),
+		Bias:     t.err / float64(t.points),
+		Coverage: t.hits / float64(t.points) * 100,
+	}
+	if t.nonZero > 0 {
+		mape := t.pct / float64(t.nonZero)
+		a.MAPE = &mape
+	}
+	return a
+}
+
+// ForecastAccuracyReport is the response of ForecastAccuracyHandler.
+type ForecastAccuracyReport struct {
+	By     string          `json:"by"`
+	Period string          `json:"period"`
+	Level  int             `json:"level"`
+	Groups []GroupAccuracy `json:"groups"`
+	Steps  []StepAccuracy  `json:"steps"`
+}
+
+// ForecastAccuracyHandler scores recorded forecasts against what was
+// actually collected, per group and per number of periods ahead. Only
+// periods that have ended are scored. Query parameters: by=coop|species
+// (default coop), period=day|week (default week), level=80|95 (default 80).
+func ForecastAccuracyHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		opts, msg := forecastOptions(c)
+		if msg != "" {
+			respondError(c, http.StatusBadRequest, msg)
+			return
+		}
+		rows, err := db.Query(`
+		SELECT p.grp, p.step, p.predicted, p.lower, p.upper,
+			(SELECT COALESCE(SUM(ia.quantity), 0) FROM inventory_actions ia
+			 WHERE ia.action = 'collected' AND COALESCE(ia.`+opts.By+`, '') = p.grp
+			   AND date(ia.date) BETWEEN date(p.start_date) AND date(p.end_date))
+		FROM forecast_points p JOIN forecast_runs r ON r.id = p.run_id
+		WHERE r.by_group = ? AND r.period = ? AND r.level = ? AND date(p.end_date) < date(?)`,
+			opts.By, opts.Period, opts.Level, today().Format("2006-01-02"))
+		if err != nil {
+			requestLogger(c, "ForecastAccuracyHandler").Error("Accuracy query failed", logging.Err(err))
+			respondError(c, http.StatusInternalServerError, "db error")
+			return
+		}
+		defer rows.Close()
+		byGroup := map[string]*accuracyTotals{}
+		byStep := map[int]*accuracyTotals{}
+		for rows.Next() {
+			var grp string
+			var step int
+			var predicted, lower, upper, actual float64
+			if err := rows.Scan(&grp, &step, &predicted, &lower, &upper, &actual); err != nil {
+				respondServerError(c, err)
+				return
+			}
+			if byGroup[grp] == nil {
+				byGroup[grp] = &accuracyTotals{}
+			}
+			if byStep[step] == nil {
+				byStep[step] = &accuracyTotals{}
+			}
+			byGroup[grp].add(predicted, lower, upper, actual)
+			byStep[step].add(predicted, lower, upper, actual)
+		}
+		if err := rows.Err(); err != nil {
+			respondServerError(c, err)
+			return
+		}
+
+		groups := []GroupAccuracy{}
+		for grp, t := range byGroup {
+			groups = append(groups, GroupAccuracy{Group: grp, ForecastAccuracy: t.result()})
+		}
+		sort.Slice(groups, func(i, j int) bool { return groups[i].Group < groups[j].Group })
+		steps := []StepAccuracy{}
+		for step, t := range byStep {
+			steps = append(steps, StepAccuracy{Step: step, ForecastAccuracy: t.result()})
+		}
+		sort.Slice(steps, func(i, j int) bool { return steps[i].Step < steps[j].Step })
+		c.JSON(http.StatusOK, ForecastAccuracyReport{By: opts.By, Period: opts.Period, Level: opts.Level, Groups: groups, Steps: steps})
+	}
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestForecastReport(t *testing.T) {
+	// Five weeks of collections up to yesterday: 6 eggs on weekdays, 2 at weekends.
+	eggsOn := func(d time.Time) int {
+		if d.Weekday() == time.Saturday || d.Weekday() == time.Sunday {
+			return 2
+		}
+		return 6
+	}
+	cleanup := setupAnalyticsTestDB(t, func(dbase *sql.DB) {
+		for d := today().AddDate(0, 0, -35); d.Before(today()); d = d.AddDate(0, 0, 1) {
+			mustExec(t, dbase, "INSERT INTO inventory_actions (quantity, species, coop, action, date) VALUES (?, 'Chicken', 'Main Coop', 'collected', ?)", eggsOn(d), d)
+		}
+	})
+	defer cleanup()
+
+	router := gin.Default()
+	router.GET("/api/reports/forecast", ForecastReportHandler())
+	for _, q := range []string{"by=flock", "period=month", "weeks=0", "level=90"} {
+		req, _ := http.NewRequest("GET", "/api/reports/forecast?"+q, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", q, w.Code)
+		}
+	}
+
+	req, _ := http.NewRequest("GET", "/api/reports/forecast?period=day&weeks=1&level=95", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Forecasts []GroupForecast `json:"forecasts"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Forecasts) != 1 || resp.Forecasts[0].Group != "Main Coop" || resp.Forecasts[0].Model != "holt-winters" {
+		t.Fatalf("unexpected forecasts: %s", w.Body.String())
+	}
+	f := resp.Forecasts[0]
+	if len(f.Points) != 7 || f.Points[0].Start != today().Format("2006-01-02") {
+		t.Fatalf("expected seven days from today, got %+v", f.Points)
+	}
+	for _, p := range f.Points {
+		d, _ := parseOptionalDate(p.Start)
+		want := float64(eggsOn(*d))
+		if p.Predicted < want-1 || p.Predicted > want+1 || p.Lower > p.Predicted || p.Upper < p.Predicted {
+			t.Errorf("%s: got %+v, want about %.0f", p.Start, p, want)
+		}
+	}
+
+	req, _ = http.NewRequest("GET", "/api/reports/forecast?by=species&weeks=2", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	// Weekly forecasts start with the current week, fitted on complete weeks only.
+	if len(resp.Forecasts) != 1 || resp.Forecasts[0].Group != "Chicken" || resp.Forecasts[0].Model != "holt" || len(resp.Forecasts[0].Points) != 2 {
+		t.Fatalf("unexpected weekly forecast: %s", w.Body.String())
+	}
+	if p := resp.Forecasts[0].Points[0]; p.Start != weekStart(today()).Format("2006-01-02") || p.End != weekStart(today()).AddDate(0, 0, 6).Format("2006-01-02") || p.Predicted <= 0 {
+		t.Errorf("unexpected first week: %+v", p)
+	}
+}
+
+func TestRecordForecastAndAccuracy(t *testing.T) {
+	cleanup := setupAnalyticsTestDB(t, func(dbase *sql.DB) {
+		mustExec(t, dbase, `INSERT INTO inventory_actions (quantity, species, coop, action, date) VALUES
+			(10, 'Chicken', 'Main Coop', 'collected', '2025-03-03'), (2, 'Chicken', 'Main Coop', 'collected', '2025-03-04'),
+			(8, 'Duck', 'Pond', 'collected', '2025-03-10')`)
+	})
+	defer cleanup()
+	dbase, err := sql.Open("sqlite3", "test_analytics.db")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer dbase.Close()
+
+	router := gin.Default()
+	router.POST("/api/forecasts", RecordForecastHandler(dbase))
+	router.GET("/api/reports/forecast-accuracy", ForecastAccuracyHandler(dbase))
+
+	req, _ := http.NewRequest("POST", "/api/forecasts?weeks=3", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var points int
+	dbase.QueryRow("SELECT COUNT(*) FROM forecast_points").Scan(&points)
+	if points != 6 {
+		t.Errorf("expected three weeks for two coops, got %d points", points)
+	}
+
+	// An older run whose weeks are over: Main Coop collected 12 against 10
+	// predicted, then nothing against 4; Pond collected 8 against 8.
+	mustExec(t, dbase, "INSERT INTO forecast_runs (id, as_of, by_group, period, weeks, level) VALUES (100, '2025-03-03', 'coop', 'week', 2, 80)")
+	mustExec(t, dbase, `INSERT INTO forecast_points (run_id, grp, model, step, start_date, end_date, predicted, lower, upper) VALUES
+		(100, 'Main Coop', 'holt', 1, '2025-03-03', '2025-03-09', 10, 8, 13),
+		(100, 'Main Coop', 'holt', 2, '2025-03-10', '2025-03-16', 4, 1, 7),
+		(100, 'Pond', 'mean', 2, '2025-03-10', '2025-03-16', 8, 6, 10)`)
+
+	req, _ = http.NewRequest("GET", "/api/reports/forecast-accuracy", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Groups []GroupAccuracy `json:"groups"`
+		Steps  []StepAccuracy  `json:"steps"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Groups) != 2 || len(resp.Steps) != 2 {
+		t.Fatalf("unexpected accuracy: %s", w.Body.String())
+	}
+	main := resp.Groups[0]
+	if main.Group != "Main Coop" || main.Points != 2 || main.MAE != 3 || main.Bias != 1 || main.Coverage != 50 || main.MAPE == nil || *main.MAPE < 16.6 || *main.MAPE > 16.7 {
+		t.Errorf("unexpected Main Coop accuracy: %+v", main)
+	}
+	if step := resp.Steps[1]; step.Step != 2 || step.Points != 2 || step.MAE != 2 || step.Coverage != 50 {
+		t.Errorf("unexpected two-week accuracy: %+v", step)
+	}
+}
@@ -0,0 +1,340 @@
+package handlers
+
+import (
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"egg-tracker/backend/logging"
+	"egg-tracker/backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultShelfLifeDays apply to any species without a saved shelf life.
+var defaultShelfLifeDays = map[string]int{
+	"refrigerated": 35,
+	"counter":      21,
+}
+
+// expiredLotPolicy is the configured expiry job behaviour, "flag" unless set to "spoil".
+func expiredLotPolicy(values map[string]string) string {
+	if values[settingExpiredLots] == "spoil" {
+		return "spoil"
+	}
+	return "flag"
+}
+
+// loadShelfLives returns saved shelf lives keyed by species and storage.
+func loadShelfLives(q sqlExecutor) (map[[2]string]int, error) {
+	rows, err := q.Query("SELECT species, storage, days FROM shelf_life")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	days := map[[2]string]int{}
+	for rows.Next() {
+		var species, storage string
+		var d int
+		if err := rows.Scan(&species, &storage, &d); err != nil {
+			return nil, err
+		}
+		days[[2]string{species, storage}] = d
+	}
+	return days, rows.Err()
+}
+
+func shelfLifeDays(saved map[[2]string]int, species, storage string) int {
+	if d, ok := saved[[2]string{species, storage}]; ok {
+		return d
+	}
+	return defaultShelfLifeDays[storage]
+}
+
+// loadLotStatuses lists lots with eggs left, the ones expiring soonest first,
+// optionally for a single species.
+func loadLotStatuses(db *sql.DB, now time.Time, species string) ([]models.LotStatus, error) {
+	saved, err := loadShelfLives(db)
+	if err != nil {
+		return nil, err
+	}
+	query := `
+		SELECT a.id, a.lot_code, a.species, COALESCE(a.coop, ''), COALESCE(a.storage, 'refrigerated'), a.date, a.expired_on,
+			a.quantity - COALESCE((SELECT SUM(d.quantity) FROM lot_draws d WHERE d.lot_action_id = a.id), 0) AS remaining
+		FROM inventory_actions a
+		WHERE a.action = 'collected' AND a.lot_code IS NOT NULL`
+	var args []interface{}
+	if species != "" {
+		query += " AND a.species = ?"
+		args = append(args, species)
+	}
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	lots := []models.LotStatus{}
+	for rows.Next() {
+		var l models.LotStatus
+		var flagged sql.NullTime
+		if err := rows.Scan(&l.ActionID, &l.LotCode, &l.Species, &l.Coop, &l.Storage, &l.CollectedOn, &flagged, &l.Remaining); err != nil {
+			return nil, err
+		}
+		if l.Remaining <= 0 {
+			continue
+		}
+		if flagged.Valid {
+			l.FlaggedOn = &flagged.Time
+		}
+		collected := time.Date(l.CollectedOn.Year(), l.CollectedOn.Month(), l.CollectedOn.Day(), 0, 0, 0, 0, time.UTC)
+		l.AgeDays = int(day.Sub(collected).Hours() / 24)
+		l.ShelfLifeDays = shelfLifeDays(saved, l.Species, l.Storage)
+		l.ExpiresOn = collected.AddDate(0, 0, l.ShelfLifeDays)
+		l.DaysLeft = l.ShelfLifeDays - l.AgeDays
+		l.Expired = l.DaysLeft <= 0
+		lots = append(lots, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(lots, func(i, j int) bool {
+		if !lots[i].ExpiresOn.Equal(lots[j].ExpiresOn) {
+			return lots[i].ExpiresOn.Before(lots[j].ExpiresOn)
+		}
+		return lots[i].ActionID < lots[j].ActionID
+	})
+	return lots, nil
+}
+
+// ListLotsHandler lists lots on hand with their age and expiry, soonest to
+// expire first. Query parameters: species, expired=true|false.
+func ListLotsHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lots, err := loadLotStatuses(db, time.Now(), c.Query("species"))
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		if exp := c.Query("expired"); exp != "" {
+			want := exp == "true"
+			filtered := []models.LotStatus{}
+			for _, l := range lots {
+				if l.Expired == want {
+					filtered = append(filtered, l)
+				}
+			}
+			lots = filtered
+		}
+		c.JSON(http.StatusOK, lots)
+	}
+}
+
+// LotSuggestions is the response of LotSuggestionsHandler. Shortfall is
+// how many eggs the lots on hand cannot cover.
+type LotSuggestions struct {
+	Lots      []models.LotDraw `json:"lots"`
+	Shortfall int              `json:"shortfall"`
+}
+
+// LotSuggestionsHandler suggests which unexpired lots to sell or use first to
+// fill ?quantity= eggs of a ?species=, soonest to expire first.
+func LotSuggestionsHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		species := c.Query("species")
+		quantity, err := strconv.Atoi(c.Query("quantity"))
+		if species == "" || err != nil || quantity < 1 {
+			respondError(c, http.StatusBadRequest, "species and a positive quantity are required")
+			return
+		}
+		lots, err := loadLotStatuses(db, time.Now(), species)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		picks := []models.LotDraw{}
+		left := quantity
+		for _, l := range lots {
+			if left == 0 {
+				break
+			}
+			if l.Expired {
+				continue
+			}
+			n := l.Remaining
+			if n > left {
+				n = left
+			}
+			picks = append(picks, models.LotDraw{LotCode: l.LotCode, Quantity: n})
+			left -= n
+		}
+		c.JSON(http.StatusOK, LotSuggestions{Lots: picks, Shortfall: left})
+	}
+}
+
+type ShelfLifeInput struct {
+	Days int `json:"days" binding:"required,min=1"`
+}
+
+// ListShelfLifeHandler returns the shelf life for every active species and
+// storage method, including built-in defaults.
+func ListShelfLifeHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		saved, err := loadShelfLives(db)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		rows, err := db.Query("SELECT name FROM species WHERE active = 1 ORDER BY name ASC")
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		defer rows.Close()
+		listed := map[string]bool{}
+		var species []string
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				respondServerError(c, err)
+				return
+			}
+			species = append(species, name)
+			listed[name] = true
+		}
+		for key := range saved {
+			if !listed[key[0]] {
+				species = append(species, key[0])
+				listed[key[0]] = true
+			}
+		}
+		sort.Strings(species)
+		lives := []models.ShelfLife{}
+		for _, sp := range species {
+			for _, storage := range []string{"refrigerated", "counter"} {
+				lives = append(lives, models.ShelfLife{Species: sp, Storage: storage, Days: shelfLifeDays(saved, sp, storage)})
+			}
+		}
+		c.JSON(http.StatusOK, lives)
+	}
+}
+
+func UpdateShelfLifeHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		storage := c.Param("storage")
+		if _, ok := defaultShelfLifeDays[storage]; !ok {
+			respondError(c, http.StatusBadRequest, "storage must be refrigerated or counter")
+			return
+		}
+		var input ShelfLifeInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondBindError(c, err)
+			return
+		}
+		_, err := db.Exec(
+			`INSERT INTO shelf_life (species, storage, days) VALUES (?, ?, ?)
+			ON CONFLICT(species, storage) DO UPDATE SET days = excluded.days, updated_at = CURRENT_TIMESTAMP`,
+			c.Param("species"), storage, input.Days,
+		)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, MessageResponse{Message: "updated"})
+	}
+}
+
+// ExpiryResult lists the lots handled by one run of ExpireLots.
+type ExpiryResult struct {
+	Policy  string   `json:"policy"`
+	Flagged []string `json:"flagged"`
+	Spoiled []string `json:"spoiled"`
+}
+
+// ExpireLots flags lots past their shelf life, or records "spoiled" actions
+// for their remaining eggs when the expired_lots setting is "spoil". Lots
+// already flagged are skipped in flag mode.
+func ExpireLots(db *sql.DB, now time.Time) (ExpiryResult, error) {
+	values, err := loadSettings(db)
+	if err != nil {
+		return ExpiryResult{}, err
+	}
+	result := ExpiryResult{Policy: expiredLotPolicy(values), Flagged: []string{}, Spoiled: []string{}}
+	lots, err := loadLotStatuses(db, now, "")
+	if err != nil {
+		return result, err
+	}
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	for _, l := range lots {
+		if !l.Expired || (result.Policy == "flag" && l.FlaggedOn != nil) {
+			continue
+		}
+		if result.Policy == "flag" {
+			if _, err := db.Exec("UPDATE inventory_actions SET expired_on = ? WHERE id = ?", day, l.ActionID); err != nil {
+				return result, err
+			}
+			result.Flagged = append(result.Flagged, l.LotCode)
+			continue
+		}
+		if err := spoilLot(db, l, day); err != nil {
+			return result, err
+		}
+		result.Spoiled = append(result.Spoiled, l.LotCode)
+	}
+	slog.Info("Lot expiry finished", "component", "ExpireLots", "date", day.Format("2006-01-02"), "flagged", len(result.Flagged), "spoiled", len(result.Spoiled))
+	return result, nil
+}
+
+// spoilLot writes off the remaining eggs of an expired lot.
+func spoilLot(db *sql.DB, l models.LotStatus, date time.Time) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec(
+		`INSERT INTO inventory_actions (quantity, species, coop, egg_color, egg_size, action, notes, date)
+		SELECT ?, species, coop, egg_color, egg_size, 'spoiled', ?, ? FROM inventory_actions WHERE id = ?`,
+		l.Remaining, "Expired lot "+l.LotCode, date, l.ActionID,
+	)
+	if err != nil {
+		return err
+	}
+	id, _ := res.LastInsertId()
+	if _, _, err := drawLots(tx, id, drawRequest{Action: "spoiled", Date: date, Species: l.Species, Coop: l.Coop, Quantity: l.Remaining, Lots: []LotDrawInput{{LotCode: l.LotCode, Quantity: l.Remaining}}}); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE inventory_actions SET expired_on = ? WHERE id = ?", date, l.ActionID); err != nil {
+		return err
+	}
+	// The lot's own fields are copied as they are rather than re-checked
+	// like a new action, so lots recorded before color and size were
+	// required still expire.
+	spoiled := InventoryInput{Quantity: l.Remaining, Action: "spoiled", Date: date.Format("2006-01-02")}
+	if err := tx.QueryRow(
+		"SELECT species, COALESCE(coop, ''), COALESCE(egg_color, ''), COALESCE(egg_size, '') FROM inventory_actions WHERE id = ?", id,
+	).Scan(&spoiled.Species, &spoiled.Coop, &spoiled.EggColor, &spoiled.EggSize); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	publishEvent(db, eventInventoryCreated, inventoryEvent(id, spoiled))
+	return nil
+}
+
+// ExpireLotsHandler runs the expiry job immediately.
+func ExpireLotsHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result, err := ExpireLots(db, time.Now())
+		if err != nil {
+			requestLogger(c, "ExpireLotsHandler").Error("Expiry failed", logging.Err(err))
+			respondError(c, http.StatusInternalServerError, "db error")
+			return
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
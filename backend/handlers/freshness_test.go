@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"egg-tracker/backend/db"
+	"egg-tracker/backend/models"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupFreshnessTestDB() (*sql.DB, func()) {
+	testDBPath := "test_freshness.db"
+	dbase, _ := sql.Open("sqlite3", testDBPath)
+	db.Migrate(dbase)
+	return dbase, func() {
+		dbase.Close()
+		os.Remove(testDBPath)
+	}
+}
+
+func freshnessRouter(dbase *sql.DB) *gin.Engine {
+	router := gin.Default()
+	router.POST("/api/inventory", CreateInventoryHandler(dbase))
+	router.GET("/api/lots", ListLotsHandler(dbase))
+	router.GET("/api/lots/suggestions", LotSuggestionsHandler(dbase))
+	router.PUT("/api/shelf-life/:species/:storage", UpdateShelfLifeHandler(dbase))
+	return router
+}
+
+func collectDaysAgo(router *gin.Engine, days, quantity int, storage string) {
+	payload := lotAction("collected", time.Now().AddDate(0, 0, -days).Format("2006-01-02"), quantity)
+	payload["storage"] = storage
+	doJSON(router, "POST", "/api/inventory", payload)
+}
+
+func TestLotFreshnessAndSuggestions(t *testing.T) {
+	dbase, cleanup := setupFreshnessTestDB()
+	defer cleanup()
+	router := freshnessRouter(dbase)
+
+	if w := doJSON(router, "PUT", "/api/shelf-life/Chicken/counter", map[string]interface{}{"days": 14}); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	collectDaysAgo(router, 40, 6, "refrigerated") // expired
+	collectDaysAgo(router, 10, 6, "refrigerated") // 25 days left
+	collectDaysAgo(router, 5, 6, "counter")       // 9 days left, so it goes first
+
+	w := doJSON(router, "GET", "/api/lots", nil)
+	var lots []models.LotStatus
+	json.Unmarshal(w.Body.Bytes(), &lots)
+	if len(lots) != 3 || !lots[0].Expired || lots[1].Storage != "counter" || lots[1].DaysLeft != 9 || lots[2].AgeDays != 10 {
+		t.Fatalf("unexpected lot statuses: %+v", lots)
+	}
+
+	w = doJSON(router, "GET", "/api/lots/suggestions?species=Chicken&quantity=8", nil)
+	var resp struct {
+		Lots      []models.LotDraw `json:"lots"`
+		Shortfall int              `json:"shortfall"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Lots) != 2 || resp.Lots[0].LotCode != lots[1].LotCode || resp.Lots[1].Quantity != 2 || resp.Shortfall != 0 {
+		t.Errorf("unexpected suggestions: %s", w.Body.String())
+	}
+
+	payload := lotAction("collected", "2025-05-01", 6)
+	payload["storage"] = "cellar"
+	if w = doJSON(router, "POST", "/api/inventory", payload); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unknown storage, got %d", w.Code)
+	}
+}
+
+func TestExpireLots(t *testing.T) {
+	dbase, cleanup := setupFreshnessTestDB()
+	defer cleanup()
+	router := freshnessRouter(dbase)
+	collectDaysAgo(router, 40, 6, "refrigerated")
+	collectDaysAgo(router, 1, 6, "refrigerated")
+
+	// Flag mode marks the lot once
+	result, err := ExpireLots(dbase, time.Now())
+	if err != nil || result.Policy != "flag" || len(result.Flagged) != 1 {
+		t.Fatalf("expected one flagged lot, got %+v (%v)", result, err)
+	}
+	if result, _ = ExpireLots(dbase, time.Now()); len(result.Flagged) != 0 {
+		t.Errorf("expected flagged lots to be skipped, got %+v", result)
+	}
+
+	// Spoil mode writes off what is left
+	saveSetting(dbase, settingExpiredLots, "spoil")
+	result, err = ExpireLots(dbase, time.Now())
+	if err != nil || len(result.Spoiled) != 1 {
+		t.Fatalf("expected one spoiled lot, got %+v (%v)", result, err)
+	}
+	var spoiled int
+	dbase.QueryRow("SELECT quantity FROM inventory_actions WHERE action = 'spoiled'").Scan(&spoiled)
+	lots, _ := loadLotStatuses(dbase, time.Now(), "")
+	if spoiled != 6 || len(lots) != 1 {
+		t.Errorf("expected 6 eggs spoiled and one lot left, got %d and %+v", spoiled, lots)
+	}
+	var published int
+	dbase.QueryRow("SELECT COUNT(*) FROM events WHERE type = ? AND payload LIKE '%\"action\":\"spoiled\"%'", eventInventoryCreated).Scan(&published)
+	if published != 1 {
+		t.Errorf("expected an inventory.created event for the spoilage, got %d", published)
+	}
+}
+
+func TestSalesDrawSoonestToExpire(t *testing.T) {
+	dbase, cleanup := setupFreshnessTestDB()
+	defer cleanup()
+	router := freshnessRouter(dbase)
+
+	doJSON(router, "PUT", "/api/shelf-life/Chicken/counter", map[string]interface{}{"days": 14})
+	collectDaysAgo(router, 40, 6, "refrigerated") // expired
+	collectDaysAgo(router, 10, 6, "refrigerated") // 25 days left
+	collectDaysAgo(router, 5, 6, "counter")       // 9 days left
+	w := doJSON(router, "GET", "/api/lots", nil)
+	var lots []models.LotStatus
+	json.Unmarshal(w.Body.Bytes(), &lots)
+
+	today := time.Now().Format("2006-01-02")
+	w = doJSON(router, "POST", "/api/inventory", lotAction("sold", today, 8))
+	var resp struct {
+		Lots     []models.LotDraw `json:"lots"`
+		Untraced int              `json:"untraced"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusCreated || len(resp.Lots) != 2 || resp.Lots[0].LotCode != lots[1].LotCode || resp.Lots[1].LotCode != lots[2].LotCode {
+		t.Fatalf("expected the counter lot then the fresher refrigerated lot, got %d %s", w.Code, w.Body.String())
+	}
+
+	sale := lotAction("sold", today, 2)
+	sale["lots"] = []map[string]interface{}{{"lot_code": lots[0].LotCode, "quantity": 2}}
+	if w = doJSON(router, "POST", "/api/inventory", sale); w.Code != http.StatusConflict {
+		t.Errorf("expected 409 selling an expired lot, got %d %s", w.Code, w.Body.String())
+	}
+	spoiled := lotAction("spoiled", today, 2)
+	spoiled["lots"] = sale["lots"]
+	if w = doJSON(router, "POST", "/api/inventory", spoiled); w.Code != http.StatusCreated {
+		t.Errorf("expected an expired lot to be spoiled, got %d %s", w.Code, w.Body.String())
+	}
+}
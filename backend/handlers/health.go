@@ -0,0 +1,361 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"egg-tracker/backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthEventInput struct {
+	BirdID         *int64  `json:"bird_id"`
+	Coop           string  `json:"coop"` // defaults to the bird's coop for bird events
+	EventType      string  `json:"event_type" binding:"required"`
+	Date           string  `json:"date" binding:"required"` // ISO8601 date
+	Description    *string `json:"description"`
+	Medication     *string `json:"medication"`
+	WithdrawalDays int     `json:"withdrawal_days" binding:"min=0"`
+	Notes          *string `json:"notes"`
+}
+
+var healthEventTypes = map[string]bool{"illness": true, "treatment": true, "vaccination": true, "death": true}
+
+const healthEventColumns = "id, bird_id, coop, event_type, date, description, medication, withdrawal_days, notes, created_at, updated_at"
+
+func scanHealthEvent(row rowScanner) (models.HealthEvent, error) {
+	var e models.HealthEvent
+	var birdID sql.NullInt64
+	var description, medication, notes sql.NullString
+	if err := row.Scan(&e.ID, &birdID, &e.Coop, &e.EventType, &e.Date, &description, &medication, &e.WithdrawalDays, &notes, &e.CreatedAt, &e.UpdatedAt); err != nil {
+		return e, err
+	}
+	if birdID.Valid {
+		e.BirdID = &birdID.Int64
+	}
+	if description.Valid {
+		e.Description = &description.String
+	}
+	if medication.Valid {
+		e.Medication = &medication.String
+	}
+	if notes.Valid {
+		e.Notes = &notes.String
+	}
+	if e.WithdrawalDays > 0 {
+		ends := e.Date.AddDate(0, 0, e.WithdrawalDays)
+		e.WithdrawalEnds = &ends
+	}
+	return e, nil
+}
+
+// activeWithdrawals returns the health events whose egg withdrawal period
+// covers the given coop on the given day.
+func activeWithdrawals(q sqlExecutor, coop string, day time.Time) ([]models.HealthEvent, error) {
+	rows, err := q.Query(
+		`SELECT `+healthEventColumns+` FROM health_events
+		WHERE coop = ? AND withdrawal_days > 0
+			AND date(date) <= date(?)
+			AND date(date, '+' || withdrawal_days || ' days') > date(?)
+		ORDER BY date ASC`,
+		coop, day.Format("2006-01-02"), day.Format("2006-01-02"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	events := []models.HealthEvent{}
+	for rows.Next() {
+		e, err := scanHealthEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// resolveHealthEvent validates the input and fills in the coop from the bird.
+// Inputs the client has to correct are *inputError.
+func resolveHealthEvent(q sqlExecutor, input *HealthEventInput) (time.Time, error) {
+	if !healthEventTypes[input.EventType] {
+		return time.Time{}, &inputError{http.StatusBadRequest, "invalid event_type"}
+	}
+	date, err := time.Parse("2006-01-02", input.Date)
+	if err != nil {
+		return time.Time{}, &inputError{http.StatusBadRequest, "invalid date"}
+	}
+	if input.BirdID != nil && input.Coop == "" {
+		var coop sql.NullString
+		err := q.QueryRow("SELECT coop FROM birds WHERE id = ?", *input.BirdID).Scan(&coop)
+		if err == sql.ErrNoRows {
+			return time.Time{}, &inputError{http.StatusBadRequest, "bird not found"}
+		} else if err != nil {
+			return time.Time{}, err
+		}
+		input.Coop = coop.String
+	}
+	if input.Coop == "" {
+		return time.Time{}, &inputError{http.StatusBadRequest, "bird_id or coop is required"}
+	}
+	return date, nil
+}
+
+// syncBirdDeath keeps a bird's registry status in step with its recorded
+// deaths: deceased from its earliest death event, or active again once no
+// death event is left for a bird marked deceased.
+func syncBirdDeath(q sqlExecutor, birdID int64) error {
+	var died time.Time
+	err := q.QueryRow("SELECT date FROM health_events WHERE bird_id = ? AND event_type = 'death' ORDER BY date(date) ASC LIMIT 1", birdID).Scan(&died)
+	if err == sql.ErrNoRows {
+		_, err = q.Exec("UPDATE birds SET status = 'active', status_date = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND status = 'deceased'", birdID)
+		return err
+	} else if err != nil {
+		return err
+	}
+	_, err = q.Exec("UPDATE birds SET status = 'deceased', status_date = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", died, birdID)
+	return err
+}
+
+// deathBird is the bird a death event is about, if any.
+func deathBird(eventType string, birdID *int64) *int64 {
+	if eventType != "death" {
+		return nil
+	}
+	return birdID
+}
+
+// loadHealthEventBird returns the bird an existing event marks dead, if any.
+func loadHealthEventBird(q sqlExecutor, id string) (*int64, error) {
+	var birdID sql.NullInt64
+	var eventType string
+	if err := q.QueryRow("SELECT bird_id, event_type FROM health_events WHERE id = ?", id).Scan(&birdID, &eventType); err != nil {
+		return nil, err
+	}
+	if !birdID.Valid {
+		return nil, nil
+	}
+	return deathBird(eventType, &birdID.Int64), nil
+}
+
+func CreateHealthEventHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input HealthEventInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondBindError(c, err)
+			return
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		defer tx.Rollback()
+		date, err := resolveHealthEvent(tx, &input)
+		if err != nil {
+			respondInputError(c, err)
+			return
+		}
+		res, err := tx.Exec(
+			"INSERT INTO health_events (bird_id, coop, event_type, date, description, medication, withdrawal_days, notes) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			input.BirdID, input.Coop, input.EventType, date, input.Description, input.Medication, input.WithdrawalDays, input.Notes,
+		)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		if bird := deathBird(input.EventType, input.BirdID); bird != nil {
+			if err := syncBirdDeath(tx, *bird); err != nil {
+				respondServerError(c, err)
+				return
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			respondServerError(c, err)
+			return
+		}
+		id, _ := res.LastInsertId()
+		c.JSON(http.StatusCreated, IDResponse{ID: id})
+	}
+}
+
+// ListHealthEventsHandler lists events, optionally filtered by ?bird_id=, ?coop= and ?event_type=.
+func ListHealthEventsHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := "SELECT " + healthEventColumns + " FROM health_events WHERE 1 = 1"
+		var args []interface{}
+		for _, f := range []string{"bird_id", "coop", "event_type"} {
+			if v := c.Query(f); v != "" {
+				query += " AND " + f + " = ?"
+				args = append(args, v)
+			}
+		}
+		rows, err := db.Query(query+" ORDER BY date DESC, id DESC", args...)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		defer rows.Close()
+		events := []models.HealthEvent{}
+		for rows.Next() {
+			e, err := scanHealthEvent(rows)
+			if err != nil {
+				respondServerError(c, err)
+				return
+			}
+			events = append(events, e)
+		}
+		c.JSON(http.StatusOK, events)
+	}
+}
+
+func UpdateHealthEventHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var input HealthEventInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondBindError(c, err)
+			return
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		defer tx.Rollback()
+		oldBird, err := loadHealthEventBird(tx, id)
+		if err == sql.ErrNoRows {
+			respondError(c, http.StatusNotFound, "health event not found")
+			return
+		} else if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		date, err := resolveHealthEvent(tx, &input)
+		if err != nil {
+			respondInputError(c, err)
+			return
+		}
+		_, err = tx.Exec(
+			"UPDATE health_events SET bird_id = ?, coop = ?, event_type = ?, date = ?, description = ?, medication = ?, withdrawal_days = ?, notes = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?",
+			input.BirdID, input.Coop, input.EventType, date, input.Description, input.Medication, input.WithdrawalDays, input.Notes, id,
+		)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		// The bird that was marked dead may no longer be, and a new one may be.
+		for _, bird := range []*int64{oldBird, deathBird(input.EventType, input.BirdID)} {
+			if bird == nil {
+				continue
+			}
+			if err := syncBirdDeath(tx, *bird); err != nil {
+				respondServerError(c, err)
+				return
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			respondServerError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, MessageResponse{Message: "updated"})
+	}
+}
+
+func DeleteHealthEventHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		tx, err := db.Begin()
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		defer tx.Rollback()
+		bird, err := loadHealthEventBird(tx, id)
+		if err != nil && err != sql.ErrNoRows {
+			respondServerError(c, err)
+			return
+		}
+		res, err := tx.Exec("DELETE FROM health_events WHERE id = ?", id)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			respondError(c, http.StatusNotFound, "health event not found")
+			return
+		}
+		if bird != nil {
+			if err := syncBirdDeath(tx, *bird); err != nil {
+				respondServerError(c, err)
+				return
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			respondServerError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, MessageResponse{Message: "deleted"})
+	}
+}
+
+// CoopWithdrawal is a coop whose eggs cannot be sold until Until, because
+// of the health events listed.
+type CoopWithdrawal struct {
+	Coop   string               `json:"coop"`
+	Until  time.Time            `json:"until"`
+	Events []models.HealthEvent `json:"events"`
+}
+
+// ActiveWithdrawalsHandler lists coops whose eggs are under a withdrawal
+// period on ?date= (default today).
+func ActiveWithdrawalsHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		day := today()
+		if s := c.Query("date"); s != "" {
+			d, err := time.Parse("2006-01-02", s)
+			if err != nil {
+				respondError(c, http.StatusBadRequest, "invalid date")
+				return
+			}
+			day = d
+		}
+		rows, err := db.Query("SELECT DISTINCT coop FROM health_events WHERE withdrawal_days > 0 ORDER BY coop ASC")
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		var coops []string
+		for rows.Next() {
+			var coop string
+			if err := rows.Scan(&coop); err != nil {
+				rows.Close()
+				respondServerError(c, err)
+				return
+			}
+			coops = append(coops, coop)
+		}
+		rows.Close()
+		withdrawals := []CoopWithdrawal{}
+		for _, coop := range coops {
+			events, err := activeWithdrawals(db, coop, day)
+			if err != nil {
+				respondServerError(c, err)
+				return
+			}
+			if len(events) == 0 {
+				continue
+			}
+			until := *events[0].WithdrawalEnds
+			for _, e := range events[1:] {
+				if e.WithdrawalEnds.After(until) {
+					until = *e.WithdrawalEnds
+				}
+			}
+			withdrawals = append(withdrawals, CoopWithdrawal{Coop: coop, Until: until, Events: events})
+		}
+		c.JSON(http.StatusOK, withdrawals)
+	}
+}
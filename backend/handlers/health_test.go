@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"testing"
+
+	"egg-tracker/backend/db"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupHealthTestDB() (*sql.DB, func()) {
+	testDBPath := "test_health.db"
+	dbase, _ := sql.Open("sqlite3", testDBPath)
+	db.Migrate(dbase)
+	return dbase, func() {
+		dbase.Close()
+		os.Remove(testDBPath)
+	}
+}
+
+func healthRouter(dbase *sql.DB) *gin.Engine {
+	router := gin.Default()
+	router.POST("/api/health-events", CreateHealthEventHandler(dbase))
+	router.GET("/api/health-events", ListHealthEventsHandler(dbase))
+	router.PUT("/api/health-events/:id", UpdateHealthEventHandler(dbase))
+	router.DELETE("/api/health-events/:id", DeleteHealthEventHandler(dbase))
+	router.GET("/api/withdrawals", ActiveWithdrawalsHandler(dbase))
+	router.POST("/api/inventory", CreateInventoryHandler(dbase))
+	return router
+}
+
+func TestHealthEventsCRUD(t *testing.T) {
+	dbase, cleanup := setupHealthTestDB()
+	defer cleanup()
+	router := healthRouter(dbase)
+	mustExec(t, dbase, "INSERT INTO birds (id, name, species, coop, status) VALUES (1, 'Hen', 'Chicken', 'Main Coop', 'active')")
+
+	// Bird event inherits the bird's coop
+	w := doJSON(router, "POST", "/api/health-events", map[string]interface{}{
+		"bird_id": 1, "event_type": "illness", "date": "2025-05-01", "description": "sneezing",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	idStr := strconv.Itoa(int(resp["id"].(float64)))
+
+	w = doJSON(router, "GET", "/api/health-events?bird_id=1", nil)
+	var events []map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &events)
+	if len(events) != 1 || events[0]["coop"] != "Main Coop" {
+		t.Fatalf("unexpected events: %v", events)
+	}
+
+	// Recording a death updates the registry
+	w = doJSON(router, "PUT", "/api/health-events/"+idStr, map[string]interface{}{
+		"bird_id": 1, "event_type": "death", "date": "2025-05-04",
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on update, got %d: %s", w.Code, w.Body.String())
+	}
+	var status string
+	dbase.QueryRow("SELECT status FROM birds WHERE id = 1").Scan(&status)
+	if status != "deceased" {
+		t.Errorf("expected bird deceased, got %s", status)
+	}
+
+	// Editing the death into something else brings the bird back
+	w = doJSON(router, "PUT", "/api/health-events/"+idStr, map[string]interface{}{
+		"bird_id": 1, "event_type": "illness", "date": "2025-05-04",
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on update, got %d: %s", w.Code, w.Body.String())
+	}
+	var statusDate sql.NullTime
+	dbase.QueryRow("SELECT status, status_date FROM birds WHERE id = 1").Scan(&status, &statusDate)
+	if status != "active" || statusDate.Valid {
+		t.Errorf("expected bird active again, got %s %v", status, statusDate)
+	}
+	doJSON(router, "PUT", "/api/health-events/"+idStr, map[string]interface{}{
+		"bird_id": 1, "event_type": "death", "date": "2025-05-04",
+	})
+
+	w = doJSON(router, "DELETE", "/api/health-events/"+idStr, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on delete, got %d", w.Code)
+	}
+	dbase.QueryRow("SELECT status FROM birds WHERE id = 1").Scan(&status)
+	if status != "active" {
+		t.Errorf("expected deleting the death to bring the bird back, got %s", status)
+	}
+	if w = doJSON(router, "DELETE", "/api/health-events/"+idStr, nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 deleting a missing event, got %d", w.Code)
+	}
+	if w = doJSON(router, "PUT", "/api/health-events/"+idStr, map[string]interface{}{"coop": "Main Coop", "event_type": "illness", "date": "2025-05-01"}); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 updating a missing event, got %d", w.Code)
+	}
+
+	// Validation
+	for i, payload := range []map[string]interface{}{
+		{"coop": "Main Coop", "event_type": "haircut", "date": "2025-05-01"},
+		{"event_type": "illness", "date": "2025-05-01"},
+		{"bird_id": 99, "event_type": "illness", "date": "2025-05-01"},
+		{"coop": "Main Coop", "event_type": "treatment", "date": "2025-05-01", "withdrawal_days": -1},
+	} {
+		w = doJSON(router, "POST", "/api/health-events", payload)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("case %d: expected 400, got %d", i, w.Code)
+		}
+	}
+}
+
+func TestWithdrawalBlocksSales(t *testing.T) {
+	dbase, cleanup := setupHealthTestDB()
+	defer cleanup()
+	router := healthRouter(dbase)
+
+	w := doJSON(router, "POST", "/api/health-events", map[string]interface{}{
+		"coop": "Main Coop", "event_type": "treatment", "date": "2025-05-01", "medication": "Tylosin", "withdrawal_days": 7,
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	sale := func(coop, date string, override bool) int {
+		return doJSON(router, "POST", "/api/inventory", map[string]interface{}{
+			"quantity": 6, "species": "Chicken", "coop": coop, "egg_color": "Brown", "egg_size": "Large",
+			"action": "sold", "date": date, "override_withdrawal": override,
+		}).Code
+	}
+	if code := sale("Main Coop", "2025-05-07", false); code != http.StatusConflict {
+		t.Errorf("expected 409 inside withdrawal window, got %d", code)
+	}
+	if code := sale("Main Coop", "2025-05-07", true); code != http.StatusCreated {
+		t.Errorf("expected 201 with override, got %d", code)
+	}
+	if code := sale("Main Coop", "2025-05-08", false); code != http.StatusCreated {
+		t.Errorf("expected 201 after withdrawal ends, got %d", code)
+	}
+	if code := sale("Back Barn", "2025-05-03", false); code != http.StatusCreated {
+		t.Errorf("expected 201 for unaffected coop, got %d", code)
+	}
+
+	w = doJSON(router, "GET", "/api/withdrawals?date=2025-05-03", nil)
+	var active []map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &active)
+	if len(active) != 1 || active[0]["coop"] != "Main Coop" {
+		t.Fatalf("expected Main Coop under withdrawal, got %v", active)
+	}
+	w = doJSON(router, "GET", "/api/withdrawals?date=2025-05-09", nil)
+	active = nil
+	json.Unmarshal(w.Body.Bytes(), &active)
+	if len(active) != 0 {
+		t.Fatalf("expected no withdrawals, got %v", active)
+	}
+}
+
+func TestWithdrawalFollowsLots(t *testing.T) {
+	dbase, cleanup := setupHealthTestDB()
+	defer cleanup()
+	router := healthRouter(dbase)
+
+	collect := func(date string) string {
+		w := doJSON(router, "POST", "/api/inventory", map[string]interface{}{
+			"quantity": 6, "species": "Chicken", "coop": "Main Coop", "egg_color": "Brown", "egg_size": "Large",
+			"action": "collected", "date": date,
+		})
+		var resp struct {
+			LotCode string `json:"lot_code"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp.LotCode
+	}
+	withdrawn := collect("2025-05-03")
+	clean := collect("2025-05-09")
+	doJSON(router, "POST", "/api/health-events", map[string]interface{}{
+		"coop": "Main Coop", "event_type": "treatment", "date": "2025-05-01", "medication": "Tylosin", "withdrawal_days": 7,
+	})
+
+	sale := map[string]interface{}{
+		"quantity": 2, "species": "Chicken", "coop": "Main Coop", "egg_color": "Brown", "egg_size": "Large",
+		"action": "sold", "date": "2025-05-10",
+		"lots": []map[string]interface{}{{"lot_code": withdrawn, "quantity": 2}},
+	}
+	if w := doJSON(router, "POST", "/api/inventory", sale); w.Code != http.StatusConflict {
+		t.Errorf("expected 409 selling a lot laid during the withdrawal, got %d %s", w.Code, w.Body.String())
+	}
+	sale["override_withdrawal"] = true
+	if w := doJSON(router, "POST", "/api/inventory", sale); w.Code != http.StatusCreated {
+		t.Errorf("expected 201 with override, got %d %s", w.Code, w.Body.String())
+	}
+
+	// FIFO passes over the older, withdrawn lot
+	delete(sale, "lots")
+	delete(sale, "override_withdrawal")
+	w := doJSON(router, "POST", "/api/inventory", sale)
+	var resp struct {
+		Lots []map[string]interface{} `json:"lots"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusCreated || len(resp.Lots) != 1 || resp.Lots[0]["lot_code"] != clean {
+		t.Errorf("expected the sale to draw from %s, got %d %s", clean, w.Code, w.Body.String())
+	}
+}
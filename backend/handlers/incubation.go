@@ -0,0 +1,556 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"egg-tracker/backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultIncubationProfiles are used for species without a saved profile.
+var defaultIncubationProfiles = map[string]models.IncubationProfile{
+	"Chicken":     {Species: "Chicken", IncubationDays: 21, CandlingDays: []int{7, 14, 18}},
+	"Duck":        {Species: "Duck", IncubationDays: 28, CandlingDays: []int{7, 14, 25}},
+	"Goose":       {Species: "Goose", IncubationDays: 30, CandlingDays: []int{7, 14, 27}},
+	"Guinea Fowl": {Species: "Guinea Fowl", IncubationDays: 28, CandlingDays: []int{7, 14, 24}},
+	"Quail":       {Species: "Quail", IncubationDays: 17, CandlingDays: []int{5, 10, 14}},
+	"Turkey":      {Species: "Turkey", IncubationDays: 28, CandlingDays: []int{7, 14, 25}},
+}
+
+type IncubationProfileInput struct {
+	IncubationDays int   `json:"incubation_days" binding:"required,min=1"`
+	CandlingDays   []int `json:"candling_days" binding:"required"`
+}
+
+type IncubationBatchInput struct {
+	Species     string  `json:"species" binding:"required"`
+	Breed       string  `json:"breed"`
+	BreederCoop string  `json:"breeder_coop" binding:"required"`
+	SetDate     string  `json:"set_date" binding:"required"` // ISO8601 date
+	EggsSet     int     `json:"eggs_set" binding:"required,min=1"`
+	EggColor    string  `json:"egg_color" binding:"required"`
+	EggSize     string  `json:"egg_size" binding:"required"`
+	Notes       *string `json:"notes"`
+	// Lots optionally picks the lots the eggs come from; defaults to the
+	// unexpired lots of the breeder coop expiring soonest.
+	Lots []LotDrawInput `json:"lots" binding:"omitempty,dive"`
+}
+
+type CandlingInput struct {
+	Day       int     `json:"day" binding:"required,min=1"`
+	Date      string  `json:"date"` // ISO8601 date, defaults to set date + day
+	Fertile   int     `json:"fertile" binding:"min=0"`
+	Infertile int     `json:"infertile" binding:"min=0"`
+	Dead      int     `json:"dead" binding:"min=0"`
+	Notes     *string `json:"notes"`
+}
+
+type ChickInput struct {
+	Name   string  `json:"name"`
+	BandID *string `json:"band_id"`
+}
+
+type HatchInput struct {
+	HatchDate    string       `json:"hatch_date" binding:"required"` // ISO8601 date
+	HatchedCount int          `json:"hatched_count" binding:"min=0"`
+	Coop         string       `json:"coop"`   // brooder the chicks go to; required when chicks hatched
+	Chicks       []ChickInput `json:"chicks"` // optional names/bands; unnamed chicks are numbered
+}
+
+func parseCandlingDays(s string) []int {
+	days := []int{}
+	for _, part := range strings.Split(s, ",") {
+		if d, err := strconv.Atoi(strings.TrimSpace(part)); err == nil {
+			days = append(days, d)
+		}
+	}
+	return days
+}
+
+func formatCandlingDays(days []int) string {
+	parts := make([]string, len(days))
+	for i, d := range days {
+		parts[i] = strconv.Itoa(d)
+	}
+	return strings.Join(parts, ",")
+}
+
+// loadIncubationProfile returns the saved profile for a species, falling
+// back to the built-in defaults. ok is false when neither exists.
+func loadIncubationProfile(db *sql.DB, species string) (models.IncubationProfile, bool, error) {
+	var days int
+	var candling string
+	err := db.QueryRow("SELECT incubation_days, candling_days FROM incubation_profiles WHERE species = ?", species).Scan(&days, &candling)
+	if err == sql.ErrNoRows {
+		p, ok := defaultIncubationProfiles[species]
+		return p, ok, nil
+	} else if err != nil {
+		return models.IncubationProfile{}, false, err
+	}
+	return models.IncubationProfile{Species: species, IncubationDays: days, CandlingDays: parseCandlingDays(candling)}, true, nil
+}
+
+func ListIncubationProfilesHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		profiles := map[string]models.IncubationProfile{}
+		for sp, p := range defaultIncubationProfiles {
+			profiles[sp] = p
+		}
+		rows, err := db.Query("SELECT species, incubation_days, candling_days FROM incubation_profiles")
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var p models.IncubationProfile
+			var candling string
+			if err := rows.Scan(&p.Species, &p.IncubationDays, &candling); err != nil {
+				respondServerError(c, err)
+				return
+			}
+			p.CandlingDays = parseCandlingDays(candling)
+			profiles[p.Species] = p
+		}
+		list := make([]models.IncubationProfile, 0, len(profiles))
+		for _, p := range profiles {
+			list = append(list, p)
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].Species < list[j].Species })
+		c.JSON(http.StatusOK, list)
+	}
+}
+
+func UpdateIncubationProfileHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		species := c.Param("species")
+		var input IncubationProfileInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondBindError(c, err)
+			return
+		}
+		days := append([]int{}, input.CandlingDays...)
+		sort.Ints(days)
+		for i, d := range days {
+			if d < 1 || d >= input.IncubationDays || (i > 0 && days[i-1] == d) {
+				respondError(c, http.StatusBadRequest, "candling days must be distinct and within the incubation period")
+				return
+			}
+		}
+		_, err := db.Exec(
+			`INSERT INTO incubation_profiles (species, incubation_days, candling_days) VALUES (?, ?, ?)
+			ON CONFLICT(species) DO UPDATE SET incubation_days = excluded.incubation_days, candling_days = excluded.candling_days, updated_at = CURRENT_TIMESTAMP`,
+			species, input.IncubationDays, formatCandlingDays(days),
+		)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, MessageResponse{Message: "updated"})
+	}
+}
+
+const incubationBatchColumns = "id, species, breed, breeder_coop, set_date, eggs_set, expected_hatch_date, inventory_action_id, status, hatch_date, hatched_count, notes, created_at, updated_at"
+
+func scanIncubationBatch(row rowScanner) (models.IncubationBatch, error) {
+	var b models.IncubationBatch
+	var breed, coop, notes sql.NullString
+	var actionID, hatched sql.NullInt64
+	var hatchDate sql.NullTime
+	if err := row.Scan(&b.ID, &b.Species, &breed, &coop, &b.SetDate, &b.EggsSet, &b.ExpectedHatchDate, &actionID, &b.Status, &hatchDate, &hatched, &notes, &b.CreatedAt, &b.UpdatedAt); err != nil {
+		return b, err
+	}
+	b.Breed = breed.String
+	b.BreederCoop = coop.String
+	if actionID.Valid {
+		b.InventoryActionID = &actionID.Int64
+	}
+	if hatchDate.Valid {
+		b.HatchDate = &hatchDate.Time
+	}
+	if hatched.Valid {
+		n := int(hatched.Int64)
+		b.HatchedCount = &n
+	}
+	if notes.Valid {
+		b.Notes = &notes.String
+	}
+	return b, nil
+}
+
+// IncubationBatchCreated is returned for a new batch with the inventory
+// action that took its eggs and the lots they came from.
+type IncubationBatchCreated struct {
+	ID                int64            `json:"id"`
+	InventoryActionID int64            `json:"inventory_action_id"`
+	Lots              []models.LotDraw `json:"lots"`
+	Untraced          int              `json:"untraced"`
+}
+
+// CreateIncubationBatchHandler sets eggs in the incubator, recording an
+// "incubated" inventory action that takes them out of stock.
+func CreateIncubationBatchHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input IncubationBatchInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondBindError(c, err)
+			return
+		}
+		setDate, err := time.Parse("2006-01-02", input.SetDate)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid set_date")
+			return
+		}
+		profile, ok, err := loadIncubationProfile(db, input.Species)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		if !ok {
+			respondError(c, http.StatusBadRequest, "no incubation profile for species")
+			return
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		defer tx.Rollback()
+		notes := "Set for incubation"
+		action := InventoryInput{
+			Quantity: input.EggsSet, Species: input.Species, Coop: input.BreederCoop,
+			EggColor: input.EggColor, EggSize: input.EggSize, Action: "incubated",
+			Notes: &notes, Date: input.SetDate, Lots: input.Lots,
+		}
+		created, err := insertInventoryAction(tx, &action)
+		if err != nil {
+			respondInventoryError(c, err)
+			return
+		}
+		res, err := tx.Exec(
+			"INSERT INTO incubation_batches (species, breed, breeder_coop, set_date, eggs_set, expected_hatch_date, inventory_action_id, notes) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			input.Species, input.Breed, input.BreederCoop, setDate, input.EggsSet, setDate.AddDate(0, 0, profile.IncubationDays), created.ID, input.Notes,
+		)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			respondServerError(c, err)
+			return
+		}
+		publishEvent(db, eventInventoryCreated, inventoryEvent(created.ID, action))
+		id, _ := res.LastInsertId()
+		c.JSON(http.StatusCreated, IncubationBatchCreated{ID: id, InventoryActionID: created.ID, Lots: created.Lots, Untraced: *created.Untraced})
+	}
+}
+
+// ListIncubationBatchesHandler lists batches, optionally filtered by ?status= and ?species=.
+func ListIncubationBatchesHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := "SELECT " + incubationBatchColumns + " FROM incubation_batches WHERE 1 = 1"
+		var args []interface{}
+		for _, f := range []string{"status", "species"} {
+			if v := c.Query(f); v != "" {
+				query += " AND " + f + " = ?"
+				args = append(args, v)
+			}
+		}
+		rows, err := db.Query(query+" ORDER BY set_date DESC, id DESC", args...)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		defer rows.Close()
+		batches := []models.IncubationBatch{}
+		for rows.Next() {
+			b, err := scanIncubationBatch(rows)
+			if err != nil {
+				respondServerError(c, err)
+				return
+			}
+			batches = append(batches, b)
+		}
+		c.JSON(http.StatusOK, batches)
+	}
+}
+
+func loadCandlings(db *sql.DB, batchID int64) ([]models.CandlingResult, error) {
+	rows, err := db.Query("SELECT id, batch_id, day, date, fertile, infertile, dead, notes, created_at FROM candling_results WHERE batch_id = ? ORDER BY day ASC", batchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	results := []models.CandlingResult{}
+	for rows.Next() {
+		var r models.CandlingResult
+		var notes sql.NullString
+		if err := rows.Scan(&r.ID, &r.BatchID, &r.Day, &r.Date, &r.Fertile, &r.Infertile, &r.Dead, &notes, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		if notes.Valid {
+			r.Notes = &notes.String
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// GetIncubationBatchHandler returns a batch with its candling results and schedule.
+func GetIncubationBatchHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		b, err := scanIncubationBatch(db.QueryRow("SELECT "+incubationBatchColumns+" FROM incubation_batches WHERE id = ?", c.Param("id")))
+		if err == sql.ErrNoRows {
+			respondError(c, http.StatusNotFound, "batch not found")
+			return
+		} else if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		b.Candlings, err = loadCandlings(db, b.ID)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		profile, _, err := loadIncubationProfile(db, b.Species)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		done := map[int]bool{}
+		for _, r := range b.Candlings {
+			done[r.Day] = true
+		}
+		for _, day := range profile.CandlingDays {
+			b.Schedule = append(b.Schedule, models.CandlingPlanned{Day: day, Date: b.SetDate.AddDate(0, 0, day), Done: done[day]})
+		}
+		c.JSON(http.StatusOK, b)
+	}
+}
+
+// DeleteIncubationBatchHandler removes a batch and its "incubated" inventory
+// action. Birds hatched from it stay in the registry, unlinked.
+func DeleteIncubationBatchHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		tx, err := db.Begin()
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		defer tx.Rollback()
+		var actionID sql.NullInt64
+		err = tx.QueryRow("SELECT inventory_action_id FROM incubation_batches WHERE id = ?", id).Scan(&actionID)
+		if err == sql.ErrNoRows {
+			respondError(c, http.StatusNotFound, "batch not found")
+			return
+		} else if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		// The batch refers to its action, so it has to go first.
+		for _, stmt := range []string{
+			"DELETE FROM candling_results WHERE batch_id = ?",
+			"UPDATE birds SET incubation_batch_id = NULL WHERE incubation_batch_id = ?",
+			"DELETE FROM incubation_batches WHERE id = ?",
+		} {
+			if _, err := tx.Exec(stmt, id); err != nil {
+				respondServerError(c, err)
+				return
+			}
+		}
+		var deleted InventoryInput
+		var found bool
+		var files []string
+		if actionID.Valid {
+			deleted, found, files, err = deleteInventoryAction(tx, strconv.FormatInt(actionID.Int64, 10))
+			if err != nil {
+				respondInventoryError(c, err)
+				return
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			respondServerError(c, err)
+			return
+		}
+		removeAttachmentFiles(files)
+		if found {
+			publishEvent(db, eventInventoryDeleted, inventoryEvent(actionID.Int64, deleted))
+		}
+		c.JSON(http.StatusOK, MessageResponse{Message: "deleted"})
+	}
+}
+
+// checkBatchAction rejects edits to the "incubated" inventory action of an
+// incubation batch, which only changes with its batch.
+func checkBatchAction(q sqlExecutor, actionID string) error {
+	var batchID int64
+	err := q.QueryRow("SELECT id FROM incubation_batches WHERE inventory_action_id = ?", actionID).Scan(&batchID)
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return &inputError{http.StatusConflict, fmt.Sprintf("this action belongs to incubation batch %d; change or delete the batch instead", batchID)}
+}
+
+// loadOpenBatch fetches a batch that is still incubating, writing an error
+// response and returning false otherwise.
+func loadOpenBatch(c *gin.Context, db *sql.DB) (models.IncubationBatch, bool) {
+	b, err := scanIncubationBatch(db.QueryRow("SELECT "+incubationBatchColumns+" FROM incubation_batches WHERE id = ?", c.Param("id")))
+	if err == sql.ErrNoRows {
+		respondError(c, http.StatusNotFound, "batch not found")
+		return b, false
+	} else if err != nil {
+		respondServerError(c, err)
+		return b, false
+	}
+	if b.Status != "incubating" {
+		respondError(c, http.StatusConflict, "batch has already finished")
+		return b, false
+	}
+	return b, true
+}
+
+// RecordCandlingHandler records a candling on one of the species' candling days.
+func RecordCandlingHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input CandlingInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondBindError(c, err)
+			return
+		}
+		b, ok := loadOpenBatch(c, db)
+		if !ok {
+			return
+		}
+		profile, _, err := loadIncubationProfile(db, b.Species)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		scheduled := false
+		for _, d := range profile.CandlingDays {
+			scheduled = scheduled || d == input.Day
+		}
+		if !scheduled {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("day %d is not a candling day for %s", input.Day, b.Species))
+			return
+		}
+		if input.Fertile+input.Infertile+input.Dead > b.EggsSet {
+			respondError(c, http.StatusBadRequest, "candled more eggs than were set")
+			return
+		}
+		date := b.SetDate.AddDate(0, 0, input.Day)
+		if input.Date != "" {
+			date, err = time.Parse("2006-01-02", input.Date)
+			if err != nil {
+				respondError(c, http.StatusBadRequest, "invalid date")
+				return
+			}
+		}
+		res, err := db.Exec(
+			"INSERT INTO candling_results (batch_id, day, date, fertile, infertile, dead, notes) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			b.ID, input.Day, date, input.Fertile, input.Infertile, input.Dead, input.Notes,
+		)
+		if err != nil {
+			respondWriteError(c, err, "candling already recorded for that day")
+			return
+		}
+		id, _ := res.LastInsertId()
+		c.JSON(http.StatusCreated, IDResponse{ID: id})
+	}
+}
+
+// HatchResult is the response of RecordHatchHandler: the batch's new
+// status and the birds added for the chicks.
+type HatchResult struct {
+	Status  string  `json:"status"`
+	BirdIDs []int64 `json:"bird_ids"`
+}
+
+// RecordHatchHandler closes a batch and adds every hatched chick to the bird
+// registry, linked to the batch. A hatch of zero marks the batch failed.
+func RecordHatchHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input HatchInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondBindError(c, err)
+			return
+		}
+		hatchDate, err := time.Parse("2006-01-02", input.HatchDate)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid hatch_date")
+			return
+		}
+		b, ok := loadOpenBatch(c, db)
+		if !ok {
+			return
+		}
+		if input.HatchedCount > b.EggsSet {
+			respondError(c, http.StatusBadRequest, "hatched more chicks than eggs were set")
+			return
+		}
+		if len(input.Chicks) > input.HatchedCount {
+			respondError(c, http.StatusBadRequest, "more chicks listed than hatched")
+			return
+		}
+		if input.HatchedCount > 0 && input.Coop == "" {
+			respondError(c, http.StatusBadRequest, "coop is required for hatched chicks")
+			return
+		}
+		status := "hatched"
+		if input.HatchedCount == 0 {
+			status = "failed"
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		defer tx.Rollback()
+		if _, err := tx.Exec(
+			"UPDATE incubation_batches SET status = ?, hatch_date = ?, hatched_count = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?",
+			status, hatchDate, input.HatchedCount, b.ID,
+		); err != nil {
+			respondServerError(c, err)
+			return
+		}
+		birdIDs := []int64{}
+		for i := 0; i < input.HatchedCount; i++ {
+			chick := BirdInput{
+				Name:    fmt.Sprintf("Batch %d chick %d", b.ID, i+1),
+				Species: b.Species,
+				Breed:   b.Breed,
+				Coop:    input.Coop,
+				Status:  "active",
+			}
+			if i < len(input.Chicks) {
+				if input.Chicks[i].Name != "" {
+					chick.Name = input.Chicks[i].Name
+				}
+				chick.BandID = input.Chicks[i].BandID
+			}
+			id, err := insertBird(tx, chick, &hatchDate, nil, hatchDate, &b.ID)
+			if err != nil {
+				respondWriteError(c, err, "band_id already in use")
+				return
+			}
+			birdIDs = append(birdIDs, id)
+		}
+		if err := tx.Commit(); err != nil {
+			respondServerError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, HatchResult{Status: status, BirdIDs: birdIDs})
+	}
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"egg-tracker/backend/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IncubationRate struct {
+	Group          string   `json:"group"` // species, breed or breeder coop, depending on ?by=
+	Batches        int      `json:"batches"`
+	EggsSet        int      `json:"eggs_set"`
+	Hatched        int      `json:"hatched"`
+	Fertility      *float64 `json:"fertility"`        // percent fertile at first candling; null without candled batches
+	HatchOfSet     *float64 `json:"hatch_of_set"`     // percent of eggs set that hatched, finished batches only
+	HatchOfFertile *float64 `json:"hatch_of_fertile"` // percent of fertile eggs that hatched, finished and candled batches only
+}
+
+// queryIncubationRates aggregates fertility and hatch rates per group. Eggs
+// found dead at the first candling were fertile, so they count towards
+// fertility. Batches still incubating only contribute to fertility.
+func queryIncubationRates(duck *sql.DB, by string) ([]IncubationRate, error) {
+	rows, err := duck.Query(`
+	WITH first_candling AS (
+		SELECT batch_id, fertile + dead AS fertile_eggs
+		FROM (SELECT *, ROW_NUMBER() OVER (PARTITION BY batch_id ORDER BY day) AS rn FROM candling_results)
+		WHERE rn = 1
+	),
+	b AS (
+		SELECT COALESCE(ib.` + by + `, '') AS grp, ib.eggs_set, ib.status <> 'incubating' AS done,
+			COALESCE(ib.hatched_count, 0) AS hatched, fc.fertile_eggs
+		FROM incubation_batches ib LEFT JOIN first_candling fc ON fc.batch_id = ib.id
+	)
+	SELECT grp, COUNT(*), CAST(SUM(eggs_set) AS BIGINT),
+		CAST(SUM(CASE WHEN done THEN hatched ELSE 0 END) AS BIGINT),
+		CAST(SUM(CASE WHEN fertile_eggs IS NOT NULL THEN eggs_set ELSE 0 END) AS BIGINT),
+		CAST(COALESCE(SUM(fertile_eggs), 0) AS BIGINT),
+		CAST(SUM(CASE WHEN done THEN eggs_set ELSE 0 END) AS BIGINT),
+		CAST(SUM(CASE WHEN done AND fertile_eggs IS NOT NULL THEN hatched ELSE 0 END) AS BIGINT),
+		CAST(SUM(CASE WHEN done AND fertile_eggs IS NOT NULL THEN fertile_eggs ELSE 0 END) AS BIGINT)
+	FROM b GROUP BY grp ORDER BY grp ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	rates := []IncubationRate{}
+	for rows.Next() {
+		var r IncubationRate
+		var candledSet, fertile, doneSet, candledHatched, candledFertile int
+		if err := rows.Scan(&r.Group, &r.Batches, &r.EggsSet, &r.Hatched, &candledSet, &fertile, &doneSet, &candledHatched, &candledFertile); err != nil {
+			return nil, err
+		}
+		r.Fertility = percent(fertile, candledSet)
+		r.HatchOfSet = percent(r.Hatched, doneSet)
+		r.HatchOfFertile = percent(candledHatched, candledFertile)
+		rates = append(rates, r)
+	}
+	return rates, rows.Err()
+}
+
+func percent(n, d int) *float64 {
+	if d == 0 {
+		return nil
+	}
+	p := float64(n) * 100 / float64(d)
+	return &p
+}
+
+// IncubationReport is the response of IncubationReportHandler.
+type IncubationReport struct {
+	By    string           `json:"by"`
+	Rates []IncubationRate `json:"rates"`
+}
+
+// IncubationReportHandler returns fertility and hatch rates from DuckDB.
+// Query parameters: by=species|breed|breeder_coop (default species).
+func IncubationReportHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		by := c.DefaultQuery("by", "species")
+		if by != "species" && by != "breed" && by != "breeder_coop" {
+			respondError(c, http.StatusBadRequest, "by must be species, breed or breeder_coop")
+			return
+		}
+
+		duckdb, err := sql.Open("duckdb", analyticsDBPath)
+		if err != nil {
+			requestLogger(c, "IncubationReportHandler").Error("Failed to open DuckDB", logging.Err(err))
+			respondError(c, http.StatusInternalServerError, "failed to open DuckDB")
+			return
+		}
+		defer duckdb.Close()
+
+		rates, err := queryIncubationRates(duckdb, by)
+		if err != nil {
+			requestLogger(c, "IncubationReportHandler").Error("Incubation query failed", logging.Err(err))
+			respondError(c, http.StatusInternalServerError, "incubation query failed")
+			return
+		}
+		c.JSON(http.StatusOK, IncubationReport{By: by, Rates: rates})
+	}
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestIncubationReport_RatesBySpecies(t *testing.T) {
+	cleanup := setupAnalyticsTestDB(t, func(dbase *sql.DB) {
+		mustExec(t, dbase, `INSERT INTO incubation_batches (id, species, breeder_coop, set_date, eggs_set, expected_hatch_date, status, hatched_count) VALUES
+			(1, 'Goose', 'Pond', '2025-04-01', 10, '2025-05-01', 'hatched', 6),
+			(2, 'Goose', 'Pond', '2025-04-10', 10, '2025-05-10', 'hatched', 5),
+			(3, 'Goose', 'Pond', '2025-05-01', 10, '2025-05-31', 'incubating', NULL)`)
+		// First candling counts dead embryos as fertile; the later one is ignored
+		mustExec(t, dbase, `INSERT INTO candling_results (batch_id, day, date, fertile, infertile, dead) VALUES
+			(1, 7, '2025-04-08', 7, 2, 1),
+			(1, 14, '2025-04-15', 6, 2, 2),
+			(3, 7, '2025-05-08', 4, 6, 0)`)
+	})
+	defer cleanup()
+
+	router := gin.Default()
+	router.GET("/api/reports/incubation", IncubationReportHandler())
+	req, _ := http.NewRequest("GET", "/api/reports/incubation?by=species", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Rates []IncubationRate `json:"rates"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Rates) != 1 {
+		t.Fatalf("expected one species, got %+v", resp.Rates)
+	}
+	r := resp.Rates[0]
+	if r.Batches != 3 || r.EggsSet != 30 || r.Hatched != 11 {
+		t.Errorf("unexpected totals: %+v", r)
+	}
+	// (8 + 4) fertile of 20 candled; 11 of 20 finished hatched; 6 of 8 fertile hatched
+	if *r.Fertility != 60 || *r.HatchOfSet != 55 || *r.HatchOfFertile != 75 {
+		t.Errorf("unexpected rates: fertility %v, of set %v, of fertile %v", *r.Fertility, *r.HatchOfSet, *r.HatchOfFertile)
+	}
+
+	req, _ = http.NewRequest("GET", "/api/reports/incubation?by=color", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unknown grouping, got %d", w.Code)
+	}
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+
+	"egg-tracker/backend/db"
+	"egg-tracker/backend/models"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupIncubationTestDB() (*sql.DB, func()) {
+	testDBPath := "test_incubation.db"
+	dbase, _ := sql.Open("sqlite3", testDBPath)
+	db.Migrate(dbase)
+	return dbase, func() {
+		dbase.Close()
+		os.Remove(testDBPath)
+	}
+}
+
+func incubationRouter(dbase *sql.DB) *gin.Engine {
+	router := gin.Default()
+	router.GET("/api/incubation/profiles", ListIncubationProfilesHandler(dbase))
+	router.PUT("/api/incubation/profiles/:species", UpdateIncubationProfileHandler(dbase))
+	router.POST("/api/incubation/batches", CreateIncubationBatchHandler(dbase))
+	router.GET("/api/incubation/batches", ListIncubationBatchesHandler(dbase))
+	router.GET("/api/incubation/batches/:id", GetIncubationBatchHandler(dbase))
+	router.DELETE("/api/incubation/batches/:id", DeleteIncubationBatchHandler(dbase))
+	router.POST("/api/incubation/batches/:id/candling", RecordCandlingHandler(dbase))
+	router.POST("/api/incubation/batches/:id/hatch", RecordHatchHandler(dbase))
+	router.PUT("/api/inventory/:id", UpdateInventoryHandler(dbase))
+	router.DELETE("/api/inventory/:id", DeleteInventoryHandler(dbase))
+	return router
+}
+
+func TestIncubationBatchLifecycle(t *testing.T) {
+	dbase, cleanup := setupIncubationTestDB()
+	defer cleanup()
+	router := incubationRouter(dbase)
+
+	w := doJSON(router, "POST", "/api/incubation/batches", map[string]interface{}{
+		"species": "Goose", "breed": "Toulouse", "breeder_coop": "Pond", "set_date": "2025-04-01", "eggs_set": 6,
+		"egg_color": "White", "egg_size": "Large",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	id := itoa(int64(created["id"].(float64)))
+
+	// Setting eggs takes them out of stock
+	var action string
+	var qty int
+	dbase.QueryRow("SELECT action, quantity FROM inventory_actions WHERE id = ?", created["inventory_action_id"]).Scan(&action, &qty)
+	if action != "incubated" || qty != 6 {
+		t.Errorf("expected incubated action of 6, got %q %d", action, qty)
+	}
+	var published int
+	dbase.QueryRow("SELECT COUNT(*) FROM events WHERE type = ?", eventInventoryCreated).Scan(&published)
+	if published != 1 {
+		t.Errorf("expected an inventory.created event, got %d", published)
+	}
+
+	// The action only changes with its batch
+	actionPath := "/api/inventory/" + itoa(int64(created["inventory_action_id"].(float64)))
+	w = doJSON(router, "PUT", actionPath, map[string]interface{}{
+		"quantity": 2, "species": "Goose", "coop": "Pond", "egg_color": "White", "egg_size": "Large", "action": "incubated", "date": "2025-04-01",
+	})
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected 409 editing a batch's action, got %d: %s", w.Code, w.Body.String())
+	}
+	w = doJSON(router, "DELETE", actionPath, nil)
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected 409 deleting a batch's action, got %d: %s", w.Code, w.Body.String())
+	}
+	mustExec(t, dbase, "INSERT INTO attachments (entity_type, entity_id, filename, content_type, size_bytes, storage_name) VALUES ('inventory_action', ?, 'set.jpg', 'image/jpeg', 1, 'incubation-test.jpg')", created["inventory_action_id"])
+
+	w = doJSON(router, "GET", "/api/incubation/batches/"+id, nil)
+	var batch models.IncubationBatch
+	json.Unmarshal(w.Body.Bytes(), &batch)
+	if batch.ExpectedHatchDate.Format("2006-01-02") != "2025-05-01" {
+		t.Errorf("expected hatch on 2025-05-01 (30 days), got %v", batch.ExpectedHatchDate)
+	}
+	if len(batch.Schedule) != 3 || batch.Schedule[0].Date.Format("2006-01-02") != "2025-04-08" {
+		t.Errorf("unexpected candling schedule: %+v", batch.Schedule)
+	}
+
+	// Day 8 is not a goose candling day
+	w = doJSON(router, "POST", "/api/incubation/batches/"+id+"/candling", map[string]interface{}{"day": 8, "fertile": 5})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unscheduled day, got %d", w.Code)
+	}
+	w = doJSON(router, "POST", "/api/incubation/batches/"+id+"/candling", map[string]interface{}{"day": 7, "fertile": 5, "infertile": 2})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 when candling more eggs than set, got %d", w.Code)
+	}
+	w = doJSON(router, "POST", "/api/incubation/batches/"+id+"/candling", map[string]interface{}{"day": 7, "fertile": 5, "infertile": 1})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 for candling, got %d: %s", w.Code, w.Body.String())
+	}
+	w = doJSON(router, "POST", "/api/incubation/batches/"+id+"/candling", map[string]interface{}{"day": 7, "fertile": 5, "infertile": 1})
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected 409 for duplicate candling day, got %d", w.Code)
+	}
+
+	band := "G-1"
+	w = doJSON(router, "POST", "/api/incubation/batches/"+id+"/hatch", map[string]interface{}{
+		"hatch_date": "2025-05-01", "hatched_count": 4, "coop": "Brooder",
+		"chicks": []map[string]interface{}{{"name": "Goslina", "band_id": band}, {"band_id": ""}, {"band_id": ""}},
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 for hatch, got %d: %s", w.Code, w.Body.String())
+	}
+	var birds int
+	var named string
+	dbase.QueryRow("SELECT COUNT(*) FROM birds WHERE incubation_batch_id = ? AND coop = 'Brooder' AND breed = 'Toulouse'", id).Scan(&birds)
+	dbase.QueryRow("SELECT name FROM birds WHERE band_id = ?", band).Scan(&named)
+	if birds != 4 || named != "Goslina" {
+		t.Errorf("expected 4 linked goslings including Goslina, got %d (%q)", birds, named)
+	}
+
+	// Finished batches accept no more results
+	w = doJSON(router, "POST", "/api/incubation/batches/"+id+"/candling", map[string]interface{}{"day": 14, "fertile": 4})
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected 409 on finished batch, got %d", w.Code)
+	}
+
+	w = doJSON(router, "DELETE", "/api/incubation/batches/"+id, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on delete, got %d", w.Code)
+	}
+	var actions, linked int
+	dbase.QueryRow("SELECT COUNT(*) FROM inventory_actions").Scan(&actions)
+	dbase.QueryRow("SELECT COUNT(*) FROM birds WHERE incubation_batch_id IS NOT NULL").Scan(&linked)
+	if actions != 0 || linked != 0 {
+		t.Errorf("expected action removed and birds unlinked, got %d actions, %d linked birds", actions, linked)
+	}
+	var attachments int
+	dbase.QueryRow("SELECT COUNT(*) FROM attachments").Scan(&attachments)
+	dbase.QueryRow("SELECT COUNT(*) FROM events WHERE type = ?", eventInventoryDeleted).Scan(&published)
+	if attachments != 0 || published != 1 {
+		t.Errorf("expected the action's attachments removed and an inventory.deleted event, got %d attachments, %d events", attachments, published)
+	}
+	w = doJSON(router, "DELETE", "/api/incubation/batches/"+id, nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 deleting a missing batch, got %d", w.Code)
+	}
+}
+
+func TestIncubationProfiles(t *testing.T) {
+	dbase, cleanup := setupIncubationTestDB()
+	defer cleanup()
+	router := incubationRouter(dbase)
+
+	w := doJSON(router, "PUT", "/api/incubation/profiles/Guinea Fowl", map[string]interface{}{"incubation_days": 27, "candling_days": []int{10, 5}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	w = doJSON(router, "PUT", "/api/incubation/profiles/Emu", map[string]interface{}{"incubation_days": 50, "candling_days": []int{50}})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for candling day outside incubation, got %d", w.Code)
+	}
+
+	w = doJSON(router, "GET", "/api/incubation/profiles", nil)
+	var profiles []models.IncubationProfile
+	json.Unmarshal(w.Body.Bytes(), &profiles)
+	for _, p := range profiles {
+		if p.Species == "Guinea Fowl" && (p.IncubationDays != 27 || len(p.CandlingDays) != 2 || p.CandlingDays[0] != 5) {
+			t.Errorf("expected saved Guinea Fowl profile, got %+v", p)
+		}
+	}
+	if len(profiles) != len(defaultIncubationProfiles) {
+		t.Errorf("expected %d profiles, got %d", len(defaultIncubationProfiles), len(profiles))
+	}
+
+	w = doJSON(router, "POST", "/api/incubation/batches", map[string]interface{}{
+		"species": "Emu", "breeder_coop": "Paddock", "set_date": "2025-04-01", "eggs_set": 2,
+		"egg_color": "Green", "egg_size": "Jumbo",
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for species without profile, got %d", w.Code)
+	}
+}
@@ -2,12 +2,18 @@ package handlers
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"egg-tracker/backend/models"
+	"egg-tracker/backend/openapi"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
 type InventoryInput struct {
@@ -15,42 +21,208 @@ type InventoryInput struct {
 	Species  string  `json:"species" binding:"required"`
 	Coop     string  `json:"coop" binding:"required"`
 	EggColor string  `json:"egg_color" binding:"required"`
-	EggSize  string  `json:"egg_size" binding:"required"`
+	EggSize  string  `json:"egg_size"` // assigned from weight bands when weight_grams is given
 	Action   string  `json:"action" binding:"required"`
 	Notes    *string `json:"notes"`
 	Date     string  `json:"date" binding:"required"` // ISO8601 date
+	// Customer and UnitPrice are only meaningful for "sold" actions.
+	Customer  *string  `json:"customer"`
+	UnitPrice *float64 `json:"unit_price"`
+	// WeightGrams is the average weight per egg, only for "collected" actions.
+	WeightGrams *float64 `json:"weight_grams" binding:"omitempty,gt=0"`
+	// Storage is where a collected lot is kept: "refrigerated" (default) or "counter".
+	Storage string `json:"storage"`
+	// StorageUnitID is the refrigerator or cooler holding a collected lot.
+	StorageUnitID *int64 `json:"storage_unit_id"`
+	// Lots optionally picks the lots an outgoing action draws from; by
+	// default the coop's unexpired lots expiring soonest are used first.
+	Lots []LotDrawInput `json:"lots" binding:"omitempty,dive"`
+	// OverrideWithdrawal allows selling eggs from a coop that is still
+	// within a medication withdrawal period.
+	OverrideWithdrawal bool `json:"override_withdrawal"`
+}
+
+// InventoryCreatedResponse is returned for a new inventory action. EggSize
+// is the size given or the one assigned from weight bands.
+type InventoryCreatedResponse struct {
+	ID      int64  `json:"id"`
+	EggSize string `json:"egg_size"`
+	LotResult
+}
+
+// InventoryUpdatedResponse is returned for an edited inventory action.
+type InventoryUpdatedResponse struct {
+	Message string `json:"message"`
+	LotResult
+}
+
+// WithdrawalConflict is the error for selling eggs under a withdrawal
+// period, with the health events that caused it.
+type WithdrawalConflict struct {
+	ErrorResponse
+	Withdrawals []models.HealthEvent `json:"withdrawals"`
+}
+
+// withdrawalError is a sale from a coop under a withdrawal period.
+type withdrawalError struct {
+	events []models.HealthEvent
+}
+
+func (e *withdrawalError) Error() string {
+	return "eggs from this coop are within a medication withdrawal period"
+}
+
+// respondInventoryError writes the response for an inventory action that
+// could not be saved.
+func respondInventoryError(c *gin.Context, err error) {
+	var ie *inputError
+	var ve *openapi.ValidationError
+	var we *withdrawalError
+	switch {
+	case errors.As(err, &ve):
+		RespondValidationError(c, ve.Problems)
+	case errors.As(err, &ie):
+		respondError(c, ie.status, ie.msg)
+	case errors.As(err, &we):
+		c.JSON(http.StatusConflict, WithdrawalConflict{
+			ErrorResponse: ErrorResponse{Error: we.Error(), Code: CodeConflict},
+			Withdrawals:   we.events,
+		})
+	default:
+		respondServerError(c, err)
+	}
+}
+
+// checkWithdrawal blocks "sold" actions for coops under an active egg
+// withdrawal period unless explicitly overridden; drawLots also keeps sales
+// from lots laid during one.
+func checkWithdrawal(q sqlExecutor, input InventoryInput, date time.Time) error {
+	if input.Action != "sold" || input.OverrideWithdrawal {
+		return nil
+	}
+	events, err := activeWithdrawals(q, input.Coop, date)
+	if err != nil {
+		return err
+	}
+	if len(events) > 0 {
+		return &withdrawalError{events}
+	}
+	return nil
+}
+
+// checkStorage defaults the storage of collected eggs and rejects unknown
+// methods or units. Other actions do not record storage.
+func checkStorage(q sqlExecutor, input *InventoryInput) error {
+	if input.Action != "collected" {
+		input.Storage = ""
+		input.StorageUnitID = nil
+		return nil
+	}
+	if input.Storage == "" {
+		input.Storage = "refrigerated"
+	}
+	if _, ok := defaultShelfLifeDays[input.Storage]; !ok {
+		return &inputError{http.StatusBadRequest, "storage must be refrigerated or counter"}
+	}
+	if input.StorageUnitID != nil {
+		if _, err := loadStorageUnit(q, *input.StorageUnitID); err == sql.ErrNoRows {
+			return &inputError{http.StatusBadRequest, "storage unit not found"}
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prepareInventoryAction checks an inventory action, filling in its egg size
+// and storage, and returns its date. Inputs that were not bound from a
+// request body, such as MQTT messages, are held to the same binding rules.
+func prepareInventoryAction(q sqlExecutor, input *InventoryInput) (time.Time, error) {
+	if err := binding.Validator.ValidateStruct(input); err != nil {
+		return time.Time{}, &openapi.ValidationError{Problems: bindProblems(err)}
+	}
+	date, err := time.Parse("2006-01-02", input.Date)
+	if err != nil {
+		return time.Time{}, &inputError{http.StatusBadRequest, "invalid date"}
+	}
+	if err := gradeEggs(q, input); err != nil {
+		return date, err
+	}
+	if err := checkStorage(q, input); err != nil {
+		return date, err
+	}
+	return date, checkWithdrawal(q, *input, date)
+}
+
+// insertInventoryAction checks and saves a new inventory action, with its
+// lot code or the lots it draws from, in tx.
+func insertInventoryAction(tx *sql.Tx, input *InventoryInput) (InventoryCreatedResponse, error) {
+	date, err := prepareInventoryAction(tx, input)
+	if err != nil {
+		return InventoryCreatedResponse{}, err
+	}
+	res, err := tx.Exec(
+		"INSERT INTO inventory_actions (quantity, species, coop, egg_color, egg_size, action, notes, date, customer, unit_price, weight_grams, storage, storage_unit_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		input.Quantity, input.Species, input.Coop, input.EggColor, input.EggSize, input.Action, input.Notes, date, input.Customer, input.UnitPrice, input.WeightGrams, nullIfEmpty(input.Storage), input.StorageUnitID,
+	)
+	if err != nil {
+		return InventoryCreatedResponse{}, err
+	}
+	id, _ := res.LastInsertId()
+	resp := InventoryCreatedResponse{ID: id, EggSize: input.EggSize}
+	if err := recordLots(tx, id, *input, date, &resp.LotResult); err != nil {
+		return InventoryCreatedResponse{}, err
+	}
+	return resp, nil
+}
+
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// inventoryEvent is the data of inventory events.
+func inventoryEvent(id int64, input InventoryInput) gin.H {
+	return gin.H{
+		"id": id, "quantity": input.Quantity, "species": input.Species, "coop": input.Coop,
+		"egg_color": input.EggColor, "egg_size": input.EggSize, "action": input.Action, "date": input.Date,
+	}
 }
 
 func CreateInventoryHandler(db *sql.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var input InventoryInput
 		if err := c.ShouldBindJSON(&input); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+			respondBindError(c, err)
 			return
 		}
-		date, err := time.Parse("2006-01-02", input.Date)
+		tx, err := db.Begin()
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date"})
+			respondServerError(c, err)
 			return
 		}
-		res, err := db.Exec(
-			"INSERT INTO inventory_actions (quantity, species, coop, egg_color, egg_size, action, notes, date) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
-			input.Quantity, input.Species, input.Coop, input.EggColor, input.EggSize, input.Action, input.Notes, date,
-		)
+		defer tx.Rollback()
+		resp, err := insertInventoryAction(tx, &input)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondInventoryError(c, err)
 			return
 		}
-		id, _ := res.LastInsertId()
-		c.JSON(http.StatusCreated, gin.H{"id": id})
+		if err := tx.Commit(); err != nil {
+			respondServerError(c, err)
+			return
+		}
+		publishEvent(db, eventInventoryCreated, inventoryEvent(resp.ID, input))
+		c.JSON(http.StatusCreated, resp)
 	}
 }
 
 func ListInventoryHandler(db *sql.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		rows, err := db.Query("SELECT id, quantity, species, coop, egg_color, egg_size, action, notes, date, created_at, updated_at FROM inventory_actions")
+		rows, err := db.Query("SELECT id, quantity, species, coop, egg_color, egg_size, action, notes, date, customer, unit_price, weight_grams, lot_code, storage, storage_unit_id, created_at, updated_at FROM inventory_actions")
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+			respondServerError(c, err)
 			return
 		}
 		defer rows.Close()
@@ -58,9 +230,11 @@ func ListInventoryHandler(db *sql.DB) gin.HandlerFunc {
 		for rows.Next() {
 			var act models.InventoryAction
 			var notes sql.NullString
-			var coop, eggColor, eggSize sql.NullString
-			if err := rows.Scan(&act.ID, &act.Quantity, &act.Species, &coop, &eggColor, &eggSize, &act.Action, &notes, &act.Date, &act.CreatedAt, &act.UpdatedAt); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+			var coop, eggColor, eggSize, customer, lotCode, storage sql.NullString
+			var unitPrice, weight sql.NullFloat64
+			var storageUnit sql.NullInt64
+			if err := rows.Scan(&act.ID, &act.Quantity, &act.Species, &coop, &eggColor, &eggSize, &act.Action, &notes, &act.Date, &customer, &unitPrice, &weight, &lotCode, &storage, &storageUnit, &act.CreatedAt, &act.UpdatedAt); err != nil {
+				respondServerError(c, err)
 				return
 			}
 			if notes.Valid {
@@ -75,6 +249,24 @@ func ListInventoryHandler(db *sql.DB) gin.HandlerFunc {
 			if eggSize.Valid {
 				act.EggSize = eggSize.String
 			}
+			if customer.Valid {
+				act.Customer = &customer.String
+			}
+			if unitPrice.Valid {
+				act.UnitPrice = &unitPrice.Float64
+			}
+			if weight.Valid {
+				act.WeightGrams = &weight.Float64
+			}
+			if lotCode.Valid {
+				act.LotCode = &lotCode.String
+			}
+			if storage.Valid {
+				act.Storage = &storage.String
+			}
+			if storageUnit.Valid {
+				act.StorageUnitID = &storageUnit.Int64
+			}
 			actions = append(actions, act)
 		}
 		c.JSON(http.StatusOK, actions)
@@ -86,34 +278,144 @@ func UpdateInventoryHandler(db *sql.DB) gin.HandlerFunc {
 		id := c.Param("id")
 		var input InventoryInput
 		if err := c.ShouldBindJSON(&input); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+			respondBindError(c, err)
+			return
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		defer tx.Rollback()
+		if err := checkBatchAction(tx, id); err != nil {
+			respondInventoryError(c, err)
 			return
 		}
-		date, err := time.Parse("2006-01-02", input.Date)
+		date, err := prepareInventoryAction(tx, &input)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date"})
+			respondInventoryError(c, err)
 			return
 		}
-		_, err = db.Exec(
-			"UPDATE inventory_actions SET quantity = ?, species = ?, coop = ?, egg_color = ?, egg_size = ?, action = ?, notes = ?, date = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?",
-			input.Quantity, input.Species, input.Coop, input.EggColor, input.EggSize, input.Action, input.Notes, date, id,
+		// A lot that outgoing actions have drawn from must keep enough eggs,
+		// and its code must go on naming the day and coop they came from.
+		drawn, err := lotDrawnCount(tx, id)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		if drawn > 0 && (input.Action != "collected" || input.Quantity < drawn) {
+			respondError(c, http.StatusConflict, fmt.Sprintf("%d eggs have already been drawn from this lot", drawn))
+			return
+		}
+		if drawn > 0 {
+			var code string
+			if err := tx.QueryRow("SELECT COALESCE(lot_code, '') FROM inventory_actions WHERE id = ?", id).Scan(&code); err != nil {
+				respondServerError(c, err)
+				return
+			}
+			if !strings.HasPrefix(code, lotCodePrefix(date, input.Coop)) {
+				respondError(c, http.StatusConflict, fmt.Sprintf("%d eggs have already been drawn from this lot, so its date and coop cannot change", drawn))
+				return
+			}
+		}
+		res, err := tx.Exec(
+			"UPDATE inventory_actions SET quantity = ?, species = ?, coop = ?, egg_color = ?, egg_size = ?, action = ?, notes = ?, date = ?, customer = ?, unit_price = ?, weight_grams = ?, storage = ?, storage_unit_id = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?",
+			input.Quantity, input.Species, input.Coop, input.EggColor, input.EggSize, input.Action, input.Notes, date, input.Customer, input.UnitPrice, input.WeightGrams, nullIfEmpty(input.Storage), input.StorageUnitID, id,
 		)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+			respondServerError(c, err)
 			return
 		}
-		c.JSON(http.StatusOK, gin.H{"message": "updated"})
+		resp := InventoryUpdatedResponse{Message: "updated"}
+		updated, _ := res.RowsAffected()
+		actionID, _ := strconv.ParseInt(id, 10, 64)
+		if updated > 0 {
+			if _, err := tx.Exec("DELETE FROM lot_draws WHERE action_id = ?", id); err != nil {
+				respondServerError(c, err)
+				return
+			}
+			if input.Action != "collected" {
+				if _, err := tx.Exec("UPDATE inventory_actions SET lot_code = NULL WHERE id = ?", id); err != nil {
+					respondServerError(c, err)
+					return
+				}
+			}
+			if err := recordLots(tx, actionID, input, date, &resp.LotResult); err != nil {
+				respondInventoryError(c, err)
+				return
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			respondServerError(c, err)
+			return
+		}
+		if updated > 0 {
+			publishEvent(db, eventInventoryUpdated, inventoryEvent(actionID, input))
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// deleteInventoryAction removes an inventory action with its lot draws and
+// attachments in tx. It returns the deleted action, whether there was one,
+// and the attachment files to remove once tx commits. Lots that outgoing
+// actions have drawn from are *inputError.
+func deleteInventoryAction(tx *sql.Tx, id string) (InventoryInput, bool, []string, error) {
+	var deleted InventoryInput
+	drawn, err := lotDrawnCount(tx, id)
+	if err != nil {
+		return deleted, false, nil, err
+	}
+	if drawn > 0 {
+		return deleted, false, nil, &inputError{http.StatusConflict, fmt.Sprintf("%d eggs have already been drawn from this lot", drawn)}
+	}
+	err = tx.QueryRow(
+		"SELECT quantity, species, COALESCE(coop, ''), COALESCE(egg_color, ''), COALESCE(egg_size, ''), action, date(date) FROM inventory_actions WHERE id = ?", id,
+	).Scan(&deleted.Quantity, &deleted.Species, &deleted.Coop, &deleted.EggColor, &deleted.EggSize, &deleted.Action, &deleted.Date)
+	found := err == nil
+	if err != nil && err != sql.ErrNoRows {
+		return deleted, false, nil, err
 	}
+	if _, err := tx.Exec("DELETE FROM lot_draws WHERE action_id = ?", id); err != nil {
+		return deleted, false, nil, err
+	}
+	files, err := deleteAttachments(tx, "inventory_action", id)
+	if err != nil {
+		return deleted, false, nil, err
+	}
+	if _, err := tx.Exec("DELETE FROM inventory_actions WHERE id = ?", id); err != nil {
+		return deleted, false, nil, err
+	}
+	return deleted, found, files, nil
 }
 
 func DeleteInventoryHandler(db *sql.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
-		_, err := db.Exec("DELETE FROM inventory_actions WHERE id = ?", id)
+		tx, err := db.Begin()
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+			respondServerError(c, err)
 			return
 		}
-		c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+		defer tx.Rollback()
+		if err := checkBatchAction(tx, id); err != nil {
+			respondInventoryError(c, err)
+			return
+		}
+		deleted, found, files, err := deleteInventoryAction(tx, id)
+		if err != nil {
+			respondInventoryError(c, err)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			respondServerError(c, err)
+			return
+		}
+		removeAttachmentFiles(files)
+		if found {
+			actionID, _ := strconv.ParseInt(id, 10, 64)
+			publishEvent(db, eventInventoryDeleted, inventoryEvent(actionID, deleted))
+		}
+		c.JSON(http.StatusOK, MessageResponse{Message: "deleted"})
 	}
 }
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"egg-tracker/backend/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultPointOfLayDays is the age in days at which each species starts
+// laying. Younger birds are left out of hen-day counts; birds with no
+// recorded hatch date are assumed to be laying.
+var defaultPointOfLayDays = map[string]int{
+	"Chicken":     126,
+	"Duck":        140,
+	"Goose":       270,
+	"Guinea Fowl": 210,
+	"Quail":       49,
+	"Turkey":      210,
+}
+
+// fallbackPointOfLayDays applies to species missing from defaultPointOfLayDays.
+const fallbackPointOfLayDays = 140
+
+// periodFormats maps the ?period= values to DuckDB strftime formats. Weeks use
+// the same format as eggsByWeek in ReportsHandler.
+var periodFormats = map[string]string{
+	"day":   "%Y-%m-%d",
+	"week":  "%Y-%W",
+	"month": "%Y-%m",
+}
+
+type LayRate struct {
+	Period  string   `json:"period"`
+	Group   string   `json:"group"` // coop or species name, depending on ?by=
+	Eggs    int      `json:"eggs"`
+	HenDays int      `json:"hen_days"`
+	LayRate *float64 `json:"lay_rate"` // percent; null when no laying hens were present
+}
+
+// pointOfLaySQL renders the point-of-lay table as a VALUES list with its arguments.
+func pointOfLaySQL() (string, []interface{}) {
+	species := make([]string, 0, len(defaultPointOfLayDays))
+	for sp := range defaultPointOfLayDays {
+		species = append(species, sp)
+	}
+	sort.Strings(species)
+	values := make([]string, len(species))
+	args := make([]interface{}, 0, 2*len(species))
+	for i, sp := range species {
+		values[i] = "(?, CAST(? AS INTEGER))"
+		args = append(args, sp, defaultPointOfLayDays[sp])
+	}
+	return "pol(species, days) AS (VALUES " + strings.Join(values, ", ") + ")", args
+}
+
+// queryLayRates computes hen-day lay percentage (eggs collected / laying
+// hen-days) grouped by period and by coop or species.
+func queryLayRates(duck *sql.DB, from, to time.Time, by, period string) ([]LayRate, error) {
+	format := periodFormats[period]
+	polSQL, polArgs := pointOfLaySQL()
+	query := birdDaysSQL + `,
+	` + polSQL + `,
+	hens AS (
+		SELECT bd.day, bd.` + by + ` AS grp
+		FROM bird_days bd LEFT JOIN pol ON pol.species = bd.species
+		WHERE bd.hatch_date IS NULL OR date_diff('day', bd.hatch_date, bd.day) >= COALESCE(pol.days, ` + strconv.Itoa(fallbackPointOfLayDays) + `)
+	),
+	hen_days AS (
+		SELECT strftime(day, '` + format + `') AS period, grp, COUNT(*) AS hen_days
+		FROM hens GROUP BY 1, 2
+	),
+	eggs AS (
+		SELECT strftime(CAST(date AS DATE), '` + format + `') AS period, COALESCE(` + by + `, '') AS grp, SUM(quantity) AS eggs
+		FROM inventory_actions
+		WHERE action = 'collected' AND CAST(date AS DATE) BETWEEN CAST(? AS DATE) AND CAST(? AS DATE)
+		GROUP BY 1, 2
+	)
+	SELECT COALESCE(h.period, e.period) AS period, COALESCE(h.grp, e.grp) AS grp,
+		CAST(COALESCE(e.eggs, 0) AS BIGINT), COALESCE(h.hen_days, 0)
+	FROM hen_days h FULL OUTER JOIN eggs e ON h.period = e.period AND h.grp = e.grp
+	ORDER BY period ASC, grp ASC
+	`
+	fromStr, toStr := from.Format("2006-01-02"), to.Format("2006-01-02")
+	args := append([]interface{}{fromStr, toStr}, polArgs...)
+	args = append(args, fromStr, toStr)
+	rows, err := duck.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	rates := []LayRate{}
+	for rows.Next() {
+		var lr LayRate
+		if err := rows.Scan(&lr.Period, &lr.Group, &lr.Eggs, &lr.HenDays); err != nil {
+			return nil, err
+		}
+		if lr.HenDays > 0 {
+			rate := float64(lr.Eggs) / float64(lr.HenDays) * 100
+			lr.LayRate = &rate
+		}
+		rates = append(rates, lr)
+	}
+	return rates, rows.Err()
+}
+
+// firstCollectionDate is the default start of collection-based reports.
+func firstCollectionDate(duck *sql.DB) (time.Time, error) {
+	var first sql.NullTime
+	if err := duck.QueryRow("SELECT MIN(CAST(date AS DATE)) FROM inventory_actions WHERE action = 'collected'").Scan(&first); err != nil {
+		return time.Time{}, err
+	}
+	if !first.Valid {
+		return today(), nil
+	}
+	return first.Time, nil
+}
+
+// LayRateReport is the response of LayRateReportHandler.
+type LayRateReport struct {
+	By       string    `json:"by"`
+	Period   string    `json:"period"`
+	LayRates []LayRate `json:"layRates"`
+}
+
+// LayRateReportHandler returns hen-day lay percentages from DuckDB.
+// Query parameters: by=coop|species (default coop), period=day|week|month
+// (default week), from/to as YYYY-MM-DD (default: first collection to today).
+func LayRateReportHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		by := c.DefaultQuery("by", "coop")
+		if by != "coop" && by != "species" {
+			respondError(c, http.StatusBadRequest, "by must be coop or species")
+			return
+		}
+		period := c.DefaultQuery("period", "week")
+		if _, ok := periodFormats[period]; !ok {
+			respondError(c, http.StatusBadRequest, "period must be day, week or month")
+			return
+		}
+
+		duckdb, err := sql.Open("duckdb", analyticsDBPath)
+		if err != nil {
+			requestLogger(c, "LayRateReportHandler").Error("Failed to open DuckDB", logging.Err(err))
+			respondError(c, http.StatusInternalServerError, "failed to open DuckDB")
+			return
+		}
+		defer duckdb.Close()
+
+		from, to, err := reportRange(c, func() (time.Time, error) { return firstCollectionDate(duckdb) })
+		if err != nil {
+			respondInputError(c, err)
+			return
+		}
+		rates, err := queryLayRates(duckdb, from, to, by, period)
+		if err != nil {
+			requestLogger(c, "LayRateReportHandler").Error("Lay rate query failed", logging.Err(err))
+			respondError(c, http.StatusInternalServerError, "lay rate query failed")
+			return
+		}
+		c.JSON(http.StatusOK, LayRateReport{By: by, Period: period, LayRates: rates})
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func seedLayRateData(t *testing.T, dbase *sql.DB) {
+	// Main Coop: two laying hens and one pullet hatched too recently to lay.
+	// Back Barn: one laying hen.
+	mustExec(t, dbase, `INSERT INTO birds (id, name, species, hatch_date, coop, status) VALUES
+		(1, 'A', 'Chicken', '2024-01-01', 'Main Coop', 'active'),
+		(2, 'B', 'Chicken', NULL, 'Main Coop', 'active'),
+		(3, 'C', 'Chicken', '2025-04-01', 'Main Coop', 'active'),
+		(4, 'D', 'Chicken', '2024-01-01', 'Back Barn', 'active')`)
+	mustExec(t, dbase, `INSERT INTO bird_transfers (bird_id, from_coop, to_coop, date) VALUES
+		(1, NULL, 'Main Coop', '2025-05-01'),
+		(2, NULL, 'Main Coop', '2025-05-01'),
+		(3, NULL, 'Main Coop', '2025-05-01'),
+		(4, NULL, 'Back Barn', '2025-05-01')`)
+	mustExec(t, dbase, `INSERT INTO inventory_actions (quantity, species, coop, action, date) VALUES
+		(2, 'Chicken', 'Main Coop', 'collected', '2025-05-01'),
+		(1, 'Chicken', 'Main Coop', 'collected', '2025-05-02'),
+		(1, 'Chicken', 'Back Barn', 'collected', '2025-05-01'),
+		(5, 'Chicken', 'Back Barn', 'sold', '2025-05-02')`)
+}
+
+func TestLayRateReport_ByCoop(t *testing.T) {
+	cleanup := setupAnalyticsTestDB(t, func(dbase *sql.DB) { seedLayRateData(t, dbase) })
+	defer cleanup()
+
+	router := gin.Default()
+	router.GET("/api/reports/lay-rate", LayRateReportHandler())
+	req, _ := http.NewRequest("GET", "/api/reports/lay-rate?by=coop&period=month&from=2025-05-01&to=2025-05-02", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		LayRates []LayRate `json:"layRates"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.LayRates) != 2 {
+		t.Fatalf("expected 2 rows, got %v", resp.LayRates)
+	}
+	// Back Barn: 1 egg over 2 hen-days
+	back := resp.LayRates[0]
+	if back.Group != "Back Barn" || back.Eggs != 1 || back.HenDays != 2 || back.LayRate == nil || *back.LayRate != 50 {
+		t.Errorf("unexpected Back Barn row: %+v", back)
+	}
+	// Main Coop: 3 eggs over 4 hen-days; the pullet is excluded
+	main := resp.LayRates[1]
+	if main.Group != "Main Coop" || main.Eggs != 3 || main.HenDays != 4 || main.LayRate == nil || *main.LayRate != 75 {
+		t.Errorf("unexpected Main Coop row: %+v", main)
+	}
+}
+
+func TestLayRateReport_BySpeciesPerDay(t *testing.T) {
+	cleanup := setupAnalyticsTestDB(t, func(dbase *sql.DB) { seedLayRateData(t, dbase) })
+	defer cleanup()
+
+	router := gin.Default()
+	router.GET("/api/reports/lay-rate", LayRateReportHandler())
+	req, _ := http.NewRequest("GET", "/api/reports/lay-rate?by=species&period=day&from=2025-05-01&to=2025-05-02", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var resp struct {
+		LayRates []LayRate `json:"layRates"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.LayRates) != 2 {
+		t.Fatalf("expected 2 rows, got %v", resp.LayRates)
+	}
+	if r := resp.LayRates[0]; r.Period != "2025-05-01" || r.Eggs != 3 || r.HenDays != 3 {
+		t.Errorf("unexpected first day: %+v", r)
+	}
+	if r := resp.LayRates[1]; r.Period != "2025-05-02" || r.Eggs != 1 || r.HenDays != 3 {
+		t.Errorf("unexpected second day: %+v", r)
+	}
+}
+
+func TestLayRateReport_InvalidParams(t *testing.T) {
+	router := gin.Default()
+	router.GET("/api/reports/lay-rate", LayRateReportHandler())
+	for _, q := range []string{"?by=breed", "?period=year"} {
+		req, _ := http.NewRequest("GET", "/api/reports/lay-rate"+q, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", q, w.Code)
+		}
+	}
+}
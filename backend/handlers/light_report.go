@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"egg-tracker/backend/logging"
+	"egg-tracker/backend/models"
+	"egg-tracker/backend/sun"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LightLayRate struct {
+	Period            string   `json:"period"`
+	Coop              string   `json:"coop"`
+	NaturalHours      float64  `json:"natural_hours"`      // average day length
+	SupplementalHours float64  `json:"supplemental_hours"` // average light added by the coop's schedules
+	EffectiveHours    float64  `json:"effective_hours"`
+	Eggs              int      `json:"eggs"`
+	HenDays           int      `json:"hen_days"`
+	LayRate           *float64 `json:"lay_rate"` // percent; null when no laying hens were present
+}
+
+// periodKey formats a date like the DuckDB strftime formats in periodFormats;
+// %W counts Monday-based weeks, with days before the first Monday in week 00.
+func periodKey(d time.Time, period string) string {
+	switch period {
+	case "day":
+		return d.Format("2006-01-02")
+	case "month":
+		return d.Format("2006-01")
+	}
+	week := (d.YearDay() - 1 + 7 - (int(d.Weekday())+6)%7) / 7
+	return fmt.Sprintf("%d-%02d", d.Year(), week)
+}
+
+// LightLayRateReport is the response of LightLayRateReportHandler.
+type LightLayRateReport struct {
+	Period        string         `json:"period"`
+	LightLayRates []LightLayRate `json:"lightLayRates"`
+}
+
+// LightLayRateReportHandler sets average daily light hours per coop, natural
+// and with supplemental lighting, against hen-day lay rates from DuckDB.
+// Query parameters: period=day|week|month (default week), from/to as
+// YYYY-MM-DD (default: first collection to today).
+func LightLayRateReportHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		period := c.DefaultQuery("period", "week")
+		if _, ok := periodFormats[period]; !ok {
+			respondError(c, http.StatusBadRequest, "period must be day, week or month")
+			return
+		}
+		values, err := loadSettings(db)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		lat, lon, ok := farmPosition(values)
+		if !ok {
+			respondError(c, http.StatusBadRequest, "farm latitude and longitude are not set")
+			return
+		}
+		schedules, err := loadLightingSchedules(db, "")
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		byCoop := map[string][]models.LightingSchedule{}
+		for _, s := range schedules {
+			byCoop[s.Coop] = append(byCoop[s.Coop], s)
+		}
+
+		duckdb, err := sql.Open("duckdb", analyticsDBPath)
+		if err != nil {
+			requestLogger(c, "LightLayRateReportHandler").Error("Failed to open DuckDB", logging.Err(err))
+			respondError(c, http.StatusInternalServerError, "failed to open DuckDB")
+			return
+		}
+		defer duckdb.Close()
+		from, to, err := reportRange(c, func() (time.Time, error) { return firstCollectionDate(duckdb) })
+		if err != nil {
+			respondInputError(c, err)
+			return
+		}
+		rates, err := queryLayRates(duckdb, from, to, "coop", period)
+		if err != nil {
+			requestLogger(c, "LightLayRateReportHandler").Error("Lay rate query failed", logging.Err(err))
+			respondError(c, http.StatusInternalServerError, "lay rate query failed")
+			return
+		}
+
+		// Sum light hours per coop and period, one day at a time.
+		type hours struct {
+			natural, effective float64
+			days               int
+		}
+		coops := map[string]bool{}
+		for _, r := range rates {
+			coops[r.Group] = true
+		}
+		totals := map[[2]string]*hours{}
+		loc := farmTimezone(values)
+		for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+			day := sun.Times(d, lat, lon, loc)
+			key := periodKey(d, period)
+			for coop := range coops {
+				natural, effective := effectiveLight(day, byCoop[coop], loc)
+				h, ok := totals[[2]string{key, coop}]
+				if !ok {
+					h = &hours{}
+					totals[[2]string{key, coop}] = h
+				}
+				h.natural += natural
+				h.effective += effective
+				h.days++
+			}
+		}
+
+		results := []LightLayRate{}
+		for _, r := range rates {
+			lr := LightLayRate{Period: r.Period, Coop: r.Group, Eggs: r.Eggs, HenDays: r.HenDays, LayRate: r.LayRate}
+			if h, ok := totals[[2]string{r.Period, r.Group}]; ok && h.days > 0 {
+				lr.NaturalHours = h.natural / float64(h.days)
+				lr.EffectiveHours = h.effective / float64(h.days)
+				lr.SupplementalHours = lr.EffectiveHours - lr.NaturalHours
+			}
+			results = append(results, lr)
+		}
+		c.JSON(http.StatusOK, LightLayRateReport{Period: period, LightLayRates: results})
+	}
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestLightLayRateReport(t *testing.T) {
+	cleanup := setupAnalyticsTestDB(t, func(dbase *sql.DB) {
+		mustExec(t, dbase, "INSERT INTO birds (id, name, species, coop, status) VALUES (1, 'Hen', 'Chicken', 'Main Coop', 'active'), (2, 'Pen', 'Chicken', 'Main Coop', 'active')")
+		mustExec(t, dbase, "INSERT INTO bird_transfers (bird_id, to_coop, date) VALUES (1, 'Main Coop', '2025-01-01'), (2, 'Main Coop', '2025-01-01')")
+		mustExec(t, dbase, `INSERT INTO inventory_actions (quantity, species, coop, action, date) VALUES
+			(2, 'Chicken', 'Main Coop', 'collected', '2025-06-21'), (1, 'Chicken', 'Main Coop', 'collected', '2025-06-22')`)
+		mustExec(t, dbase, "INSERT INTO lighting_schedules (coop, start_date, lights_on, lights_off) VALUES ('Main Coop', '2025-06-01', '03:00', '05:00')")
+		mustExec(t, dbase, `INSERT INTO settings (key, value) VALUES ('farm_latitude', '51.5074'), ('farm_longitude', '-0.1278'), ('farm_timezone', 'Europe/London')`)
+	})
+	defer cleanup()
+	dbase, err := sql.Open("sqlite3", "test_analytics.db")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer dbase.Close()
+
+	router := gin.Default()
+	router.GET("/api/reports/light-lay-rate", LightLayRateReportHandler(dbase))
+	req, _ := http.NewRequest("GET", "/api/reports/light-lay-rate?period=day&from=2025-06-21&to=2025-06-22", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		LightLayRates []LightLayRate `json:"lightLayRates"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.LightLayRates) != 2 {
+		t.Fatalf("expected two days, got %s", w.Body.String())
+	}
+	// Lights from 03:00 add the time before a sunrise at about 04:43
+	first := resp.LightLayRates[0]
+	if first.Period != "2025-06-21" || first.SupplementalHours < 1.65 || first.SupplementalHours > 1.8 || first.LayRate == nil || *first.LayRate != 100 {
+		t.Errorf("unexpected first day: %+v", first)
+	}
+	if second := resp.LightLayRates[1]; second.LayRate == nil || *second.LayRate != 50 {
+		t.Errorf("unexpected second day: %+v", second)
+	}
+}
+
+func TestPeriodKeyMatchesDuckDBWeeks(t *testing.T) {
+	cases := map[string]string{"2025-01-01": "2025-00", "2025-01-06": "2025-01", "2025-12-31": "2025-52", "2024-01-01": "2024-01"}
+	for date, want := range cases {
+		d, _ := parseOptionalDate(date)
+		if got := periodKey(*d, "week"); got != want {
+			t.Errorf("periodKey(%s) = %s, want %s", date, got, want)
+		}
+	}
+}
@@ -19,7 +19,7 @@ func LoginHandler(db *sql.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req LoginRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+			respondBindError(c, err)
 			return
 		}
 
@@ -27,30 +27,30 @@ func LoginHandler(db *sql.DB) gin.HandlerFunc {
 		var hash string
 		err := db.QueryRow("SELECT id, password_hash FROM users WHERE email = ?", req.Email).Scan(&id, &hash)
 		if err == sql.ErrNoRows {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+			respondError(c, http.StatusUnauthorized, "invalid credentials")
 			return
 		} else if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
+			respondServerError(c, err)
 			return
 		}
 
 		if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(req.Password)); err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+			respondError(c, http.StatusUnauthorized, "invalid credentials")
 			return
 		}
 
 		accessToken, err := auth.GenerateAccessToken(id)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "token error"})
+			respondServerError(c, err)
 			return
 		}
 		refreshToken, err := auth.GenerateRefreshToken(id)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "token error"})
+			respondServerError(c, err)
 			return
 		}
 
 		c.SetCookie("refresh_token", refreshToken, 7*24*3600, "/", "", false, true) // HTTPOnly
-		c.JSON(http.StatusOK, gin.H{"access_token": accessToken})
+		c.JSON(http.StatusOK, TokenResponse{AccessToken: accessToken})
 	}
 }
@@ -0,0 +1,399 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"egg-tracker/backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// outgoingActions take eggs out of stock and draw them from lots.
+var outgoingActions = map[string]bool{"sold": true, "consumed": true, "gifted": true, "spoiled": true, "incubated": true}
+
+// sqlExecutor is satisfied by both *sql.DB and *sql.Tx.
+type sqlExecutor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+type LotDrawInput struct {
+	LotCode  string `json:"lot_code" binding:"required"`
+	Quantity int    `json:"quantity" binding:"required,min=1"`
+}
+
+// inputError is a request the client has to correct, such as an inventory
+// action, a lot selection or a report range.
+type inputError struct {
+	status int
+	msg    string
+}
+
+func (e *inputError) Error() string { return e.msg }
+
+// lotCodePrefix is the part of a lot code naming the collection day and coop.
+func lotCodePrefix(date time.Time, coop string) string {
+	coopPart := strings.ToUpper(slug(coop))
+	if coopPart == "" {
+		coopPart = "NOCOOP"
+	}
+	return date.Format("20060102") + "-" + coopPart + "-"
+}
+
+// nextLotCode numbers lots per coop and collection day, e.g. 20250501-MAIN-COOP-02.
+func nextLotCode(q sqlExecutor, date time.Time, coop string) (string, error) {
+	prefix := lotCodePrefix(date, coop)
+	rows, err := q.Query("SELECT lot_code FROM inventory_actions WHERE lot_code LIKE ?", prefix+"%")
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	seq := 0
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return "", err
+		}
+		if n, err := strconv.Atoi(strings.TrimPrefix(code, prefix)); err == nil && n > seq {
+			seq = n
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%02d", prefix, seq+1), nil
+}
+
+// lotRemainingSQL lists lots with the eggs not yet drawn from them, and
+// whether they were laid while their coop was under a medication withdrawal.
+const lotRemainingSQL = `
+	SELECT id, lot_code, species, coop, storage, date, expired_on, remaining, withdrawn FROM (
+		SELECT a.id, a.lot_code, a.species, COALESCE(a.coop, '') AS coop, COALESCE(a.storage, 'refrigerated') AS storage, a.date, a.expired_on,
+			a.quantity - COALESCE((SELECT SUM(d.quantity) FROM lot_draws d WHERE d.lot_action_id = a.id), 0) AS remaining,
+			EXISTS (SELECT 1 FROM health_events h
+				WHERE h.coop = a.coop AND h.withdrawal_days > 0
+					AND date(h.date) <= date(a.date)
+					AND date(h.date, '+' || h.withdrawal_days || ' days') > date(a.date)) AS withdrawn
+		FROM inventory_actions a
+		WHERE a.action = 'collected' AND a.lot_code IS NOT NULL
+	)`
+
+// drawableLot is a row of lotRemainingSQL.
+type drawableLot struct {
+	id        int64
+	code      string
+	species   string
+	coop      string
+	storage   string
+	date      time.Time
+	flagged   bool
+	remaining int
+	withdrawn bool
+	expiresOn time.Time
+}
+
+func scanDrawableLot(row rowScanner, saved map[[2]string]int) (drawableLot, error) {
+	var l drawableLot
+	var flagged sql.NullTime
+	if err := row.Scan(&l.id, &l.code, &l.species, &l.coop, &l.storage, &l.date, &flagged, &l.remaining, &l.withdrawn); err != nil {
+		return l, err
+	}
+	l.flagged = flagged.Valid
+	collected := time.Date(l.date.Year(), l.date.Month(), l.date.Day(), 0, 0, 0, 0, time.UTC)
+	l.expiresOn = collected.AddDate(0, 0, shelfLifeDays(saved, l.species, l.storage))
+	return l, nil
+}
+
+// expired reports whether the lot is flagged or past its shelf life on day.
+func (l drawableLot) expired(day time.Time) bool {
+	return l.flagged || !day.Before(l.expiresOn)
+}
+
+// drawRequest is an outgoing action taking eggs from lots.
+type drawRequest struct {
+	Action   string
+	Date     time.Time
+	Species  string
+	Coop     string
+	Quantity int
+	Lots     []LotDrawInput // lots picked by the client, if any
+	// OverrideWithdrawal allows selling lots laid during a withdrawal period.
+	OverrideWithdrawal bool
+}
+
+// drawLots records which lots an outgoing action took its eggs from. Lots
+// must hold the action's species and come from its coop, and, as with
+// checkWithdrawal, sales cannot take eggs laid during a withdrawal period
+// unless overridden. Only "spoiled" actions take expired lots. Requested lots
+// must cover the whole quantity; otherwise the lots expiring soonest are used
+// first. Eggs that no lot can account for (stock collected before lots
+// existed) are returned as untraced.
+func drawLots(q sqlExecutor, actionID int64, req drawRequest) ([]models.LotDraw, int, error) {
+	saved, err := loadShelfLives(q)
+	if err != nil {
+		return nil, 0, err
+	}
+	day := time.Date(req.Date.Year(), req.Date.Month(), req.Date.Day(), 0, 0, 0, 0, time.UTC)
+	allowWithdrawn := req.Action != "sold" || req.OverrideWithdrawal
+	allowExpired := req.Action == "spoiled"
+	var plan []models.LotDraw
+	var ids []int64
+	if len(req.Lots) > 0 {
+		total := 0
+		for _, r := range req.Lots {
+			l, err := scanDrawableLot(q.QueryRow(lotRemainingSQL+" WHERE lot_code = ?", r.LotCode), saved)
+			if err == sql.ErrNoRows {
+				return nil, 0, &inputError{http.StatusBadRequest, "unknown lot " + r.LotCode}
+			} else if err != nil {
+				return nil, 0, err
+			}
+			if l.species != req.Species {
+				return nil, 0, &inputError{http.StatusBadRequest, "lot " + r.LotCode + " holds " + l.species + " eggs"}
+			}
+			if l.coop != req.Coop {
+				return nil, 0, &inputError{http.StatusBadRequest, "lot " + r.LotCode + " was collected from " + l.coop}
+			}
+			if l.withdrawn && !allowWithdrawn {
+				return nil, 0, &inputError{http.StatusConflict, "lot " + r.LotCode + " was laid during a medication withdrawal period"}
+			}
+			if l.expired(day) && !allowExpired {
+				return nil, 0, &inputError{http.StatusConflict, "lot " + r.LotCode + " has expired"}
+			}
+			if l.remaining < r.Quantity {
+				return nil, 0, &inputError{http.StatusConflict, fmt.Sprintf("lot %s has only %d eggs left", r.LotCode, l.remaining)}
+			}
+			plan = append(plan, models.LotDraw{LotCode: l.code, Quantity: r.Quantity})
+			ids = append(ids, l.id)
+			total += r.Quantity
+		}
+		if total != req.Quantity {
+			return nil, 0, &inputError{http.StatusBadRequest, "lot quantities must add up to the action quantity"}
+		}
+	} else {
+		rows, err := q.Query(lotRemainingSQL+" WHERE species = ? AND coop = ? AND remaining > 0 ORDER BY date ASC, id ASC", req.Species, req.Coop)
+		if err != nil {
+			return nil, 0, err
+		}
+		var lots []drawableLot
+		for rows.Next() {
+			l, err := scanDrawableLot(rows, saved)
+			if err != nil {
+				rows.Close()
+				return nil, 0, err
+			}
+			if (l.withdrawn && !allowWithdrawn) || (l.expired(day) && !allowExpired) {
+				continue
+			}
+			lots = append(lots, l)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, 0, err
+		}
+		sort.SliceStable(lots, func(i, j int) bool { return lots[i].expiresOn.Before(lots[j].expiresOn) })
+		left := req.Quantity
+		for _, l := range lots {
+			if left == 0 {
+				break
+			}
+			n := l.remaining
+			if n > left {
+				n = left
+			}
+			plan = append(plan, models.LotDraw{LotCode: l.code, Quantity: n})
+			ids = append(ids, l.id)
+			left -= n
+		}
+	}
+
+	drawn := 0
+	for i, d := range plan {
+		if _, err := q.Exec("INSERT INTO lot_draws (action_id, lot_action_id, quantity) VALUES (?, ?, ?)", actionID, ids[i], d.Quantity); err != nil {
+			return nil, 0, err
+		}
+		drawn += d.Quantity
+	}
+	if plan == nil {
+		plan = []models.LotDraw{}
+	}
+	return plan, req.Quantity - drawn, nil
+}
+
+// LotResult is what saving an inventory action did to lots: collections
+// get a lot code and outgoing actions draw from lots.
+type LotResult struct {
+	LotCode  string           `json:"lot_code,omitempty"`
+	Lots     []models.LotDraw `json:"lots,omitempty"`
+	Untraced *int             `json:"untraced,omitempty"` // eggs not drawn from any lot
+}
+
+// recordLots gives collected actions a lot code, or a new one when their day
+// or coop has changed, and draws outgoing actions from lots, adding the
+// results to res. Lot selections the client has to correct are *inputError.
+func recordLots(q sqlExecutor, id int64, input InventoryInput, date time.Time, res *LotResult) error {
+	switch {
+	case input.Action == "collected":
+		var existing sql.NullString
+		if err := q.QueryRow("SELECT lot_code FROM inventory_actions WHERE id = ?", id).Scan(&existing); err != nil {
+			return err
+		}
+		code := existing.String
+		if !existing.Valid || !strings.HasPrefix(code, lotCodePrefix(date, input.Coop)) {
+			var err error
+			if code, err = nextLotCode(q, date, input.Coop); err != nil {
+				return err
+			}
+			if _, err := q.Exec("UPDATE inventory_actions SET lot_code = ? WHERE id = ?", code, id); err != nil {
+				return err
+			}
+		}
+		res.LotCode = code
+	case outgoingActions[input.Action]:
+		drawn, untraced, err := drawLots(q, id, drawRequest{Action: input.Action, Date: date, Species: input.Species, Coop: input.Coop, Quantity: input.Quantity, Lots: input.Lots, OverrideWithdrawal: input.OverrideWithdrawal})
+		if err != nil {
+			return err
+		}
+		res.Lots = drawn
+		res.Untraced = &untraced
+	}
+	return nil
+}
+
+// lotDrawnCount is how many eggs outgoing actions have taken from a collected action.
+func lotDrawnCount(q sqlExecutor, lotActionID string) (int, error) {
+	var n int
+	err := q.QueryRow("SELECT COALESCE(SUM(quantity), 0) FROM lot_draws WHERE lot_action_id = ?", lotActionID).Scan(&n)
+	return n, err
+}
+
+// TraceLotHandler returns where a lot came from and everyone who received eggs from it.
+func TraceLotHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		trace := models.LotTrace{LotCode: c.Param("lot")}
+		src := &trace.Source
+		var coop, eggColor, eggSize sql.NullString
+		var weight sql.NullFloat64
+		var storage sql.NullString
+		var unitID sql.NullInt64
+		err := db.QueryRow(
+			"SELECT id, quantity, species, coop, egg_color, egg_size, action, date, weight_grams, lot_code, storage, storage_unit_id, created_at, updated_at FROM inventory_actions WHERE lot_code = ?",
+			trace.LotCode,
+		).Scan(&src.ID, &src.Quantity, &src.Species, &coop, &eggColor, &eggSize, &src.Action, &src.Date, &weight, &src.LotCode, &storage, &unitID, &src.CreatedAt, &src.UpdatedAt)
+		if err == sql.ErrNoRows {
+			respondError(c, http.StatusNotFound, "lot not found")
+			return
+		} else if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		src.Coop, src.EggColor, src.EggSize = coop.String, eggColor.String, eggSize.String
+		if weight.Valid {
+			src.WeightGrams = &weight.Float64
+		}
+		if storage.Valid {
+			src.Storage = &storage.String
+		}
+		if unitID.Valid {
+			src.StorageUnitID = &unitID.Int64
+		}
+
+		day := src.Date.Format("2006-01-02")
+		rows, err := db.Query(
+			`SELECT `+birdColumns+` FROM birds b
+			WHERE (SELECT t.to_coop FROM bird_transfers t WHERE t.bird_id = b.id AND date(t.date) <= date(?)
+					ORDER BY t.date DESC, t.id DESC LIMIT 1) = ?
+				AND (b.status = 'active' OR b.status_date IS NULL OR date(b.status_date) > date(?))
+			ORDER BY b.name ASC`,
+			day, src.Coop, day,
+		)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		defer rows.Close()
+		trace.Birds = []models.Bird{}
+		for rows.Next() {
+			b, err := scanBird(rows)
+			if err != nil {
+				respondServerError(c, err)
+				return
+			}
+			trace.Birds = append(trace.Birds, b)
+		}
+		rows.Close()
+
+		rows, err = db.Query(
+			`SELECT a.id, a.date, a.action, d.quantity, a.customer
+			FROM lot_draws d JOIN inventory_actions a ON a.id = d.action_id
+			WHERE d.lot_action_id = ?
+			ORDER BY a.date ASC, a.id ASC`,
+			src.ID,
+		)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		defer rows.Close()
+		trace.History = []models.LotMovement{}
+		trace.Customers = []models.LotCustomer{}
+		customers := map[string]int{}
+		trace.Remaining = src.Quantity
+		for rows.Next() {
+			var m models.LotMovement
+			var customer sql.NullString
+			if err := rows.Scan(&m.ActionID, &m.Date, &m.Action, &m.Quantity, &customer); err != nil {
+				respondServerError(c, err)
+				return
+			}
+			trace.Remaining -= m.Quantity
+			if customer.Valid {
+				m.Customer = &customer.String
+			}
+			trace.History = append(trace.History, m)
+			if m.Action != "sold" {
+				continue
+			}
+			name := customer.String
+			if i, ok := customers[name]; ok {
+				trace.Customers[i].Quantity += m.Quantity
+				trace.Customers[i].LastSale = m.Date
+				continue
+			}
+			customers[name] = len(trace.Customers)
+			trace.Customers = append(trace.Customers, models.LotCustomer{Customer: name, Quantity: m.Quantity, FirstSale: m.Date, LastSale: m.Date})
+		}
+		rows.Close()
+
+		// Excursions while the lot was in storage: from collection until the
+		// last eggs left, or until now while some remain.
+		trace.Excursions = []models.Excursion{}
+		if src.StorageUnitID != nil {
+			unit, err := loadStorageUnit(db, *src.StorageUnitID)
+			if err != nil && err != sql.ErrNoRows {
+				respondServerError(c, err)
+				return
+			}
+			if err == nil {
+				trace.StorageUnit = &unit.Name
+				until := time.Now()
+				if trace.Remaining <= 0 && len(trace.History) > 0 {
+					until = trace.History[len(trace.History)-1].Date.AddDate(0, 0, 1)
+				}
+				readings, err := loadReadings(db, unit.ID, src.Date, until)
+				if err != nil {
+					respondServerError(c, err)
+					return
+				}
+				trace.Excursions = detectExcursions(unit, readings)
+			}
+		}
+		c.JSON(http.StatusOK, trace)
+	}
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+
+	"egg-tracker/backend/db"
+	"egg-tracker/backend/models"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupLotsTestDB() (*sql.DB, func()) {
+	testDBPath := "test_lots.db"
+	dbase, _ := sql.Open("sqlite3", testDBPath)
+	db.Migrate(dbase)
+	return dbase, func() {
+		dbase.Close()
+		os.Remove(testDBPath)
+	}
+}
+
+func lotAction(action, date string, quantity int) map[string]interface{} {
+	return map[string]interface{}{
+		"quantity": quantity, "species": "Chicken", "coop": "Main Coop", "egg_color": "Brown",
+		"egg_size": "Large", "action": action, "date": date,
+	}
+}
+
+func TestLotsFIFOAndTrace(t *testing.T) {
+	dbase, cleanup := setupLotsTestDB()
+	defer cleanup()
+	router := gin.Default()
+	router.POST("/api/inventory", CreateInventoryHandler(dbase))
+	router.PUT("/api/inventory/:id", UpdateInventoryHandler(dbase))
+	router.DELETE("/api/inventory/:id", DeleteInventoryHandler(dbase))
+	router.GET("/api/trace/:lot", TraceLotHandler(dbase))
+	mustExec(t, dbase, "INSERT INTO birds (id, name, species, coop, status) VALUES (1, 'Henrietta', 'Chicken', 'Main Coop', 'active')")
+	mustExec(t, dbase, "INSERT INTO bird_transfers (bird_id, from_coop, to_coop, date) VALUES (1, NULL, 'Main Coop', '2025-04-01')")
+
+	codes := []string{}
+	for _, date := range []string{"2025-05-01", "2025-05-01", "2025-05-02"} {
+		w := doJSON(router, "POST", "/api/inventory", lotAction("collected", date, 6))
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		codes = append(codes, resp["lot_code"].(string))
+	}
+	if codes[0] != "20250501-MAIN-COOP-01" || codes[1] != "20250501-MAIN-COOP-02" || codes[2] != "20250502-MAIN-COOP-01" {
+		t.Fatalf("unexpected lot codes: %v", codes)
+	}
+
+	// FIFO spans the two oldest lots
+	sale := lotAction("sold", "2025-05-03", 8)
+	sale["customer"] = "Corner Shop"
+	w := doJSON(router, "POST", "/api/inventory", sale)
+	var resp struct {
+		Lots     []models.LotDraw `json:"lots"`
+		Untraced int              `json:"untraced"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusCreated || len(resp.Lots) != 2 || resp.Lots[0].Quantity != 6 || resp.Lots[1].Quantity != 2 || resp.Untraced != 0 {
+		t.Fatalf("unexpected FIFO draw: %d %s", w.Code, w.Body.String())
+	}
+
+	// Explicit lot choice, and over-drawing a lot
+	gift := lotAction("gifted", "2025-05-03", 2)
+	gift["lots"] = []map[string]interface{}{{"lot_code": codes[2], "quantity": 2}}
+	if w = doJSON(router, "POST", "/api/inventory", gift); w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 for explicit lot, got %d: %s", w.Code, w.Body.String())
+	}
+	gift["lots"] = []map[string]interface{}{{"lot_code": codes[1], "quantity": 2}}
+	gift["quantity"] = 5
+	if w = doJSON(router, "POST", "/api/inventory", gift); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 when lots do not cover the quantity, got %d", w.Code)
+	}
+	gift["lots"] = []map[string]interface{}{{"lot_code": codes[1], "quantity": 5}}
+	if w = doJSON(router, "POST", "/api/inventory", gift); w.Code != http.StatusConflict {
+		t.Errorf("expected 409 when a lot runs short, got %d", w.Code)
+	}
+
+	// Drawn lots cannot be deleted or shrunk below what left them
+	if w = doJSON(router, "DELETE", "/api/inventory/1", nil); w.Code != http.StatusConflict {
+		t.Errorf("expected 409 deleting a drawn lot, got %d", w.Code)
+	}
+	if w = doJSON(router, "PUT", "/api/inventory/1", lotAction("collected", "2025-05-01", 4)); w.Code != http.StatusConflict {
+		t.Errorf("expected 409 shrinking a drawn lot, got %d", w.Code)
+	}
+	if w = doJSON(router, "PUT", "/api/inventory/1", lotAction("collected", "2025-04-30", 6)); w.Code != http.StatusConflict {
+		t.Errorf("expected 409 moving a drawn lot to another day, got %d", w.Code)
+	}
+
+	// An undrawn lot moved to another day gets a code for that day
+	doJSON(router, "POST", "/api/inventory", lotAction("collected", "2025-05-04", 3))
+	w = doJSON(router, "PUT", "/api/inventory/6", lotAction("collected", "2025-05-05", 3))
+	var updated struct {
+		LotCode string `json:"lot_code"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &updated)
+	if w.Code != http.StatusOK || updated.LotCode != "20250505-MAIN-COOP-01" {
+		t.Errorf("expected a new lot code, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = doJSON(router, "GET", "/api/trace/"+codes[1], nil)
+	var trace models.LotTrace
+	json.Unmarshal(w.Body.Bytes(), &trace)
+	if w.Code != http.StatusOK || trace.Source.Coop != "Main Coop" || trace.Remaining != 4 {
+		t.Fatalf("unexpected trace: %d %s", w.Code, w.Body.String())
+	}
+	if len(trace.Customers) != 1 || trace.Customers[0].Customer != "Corner Shop" || trace.Customers[0].Quantity != 2 {
+		t.Errorf("expected Corner Shop to have 2 eggs, got %+v", trace.Customers)
+	}
+	if len(trace.Birds) != 1 || trace.Birds[0].Name != "Henrietta" {
+		t.Errorf("expected Henrietta as a source bird, got %+v", trace.Birds)
+	}
+
+	if w = doJSON(router, "GET", "/api/trace/NOPE", nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown lot, got %d", w.Code)
+	}
+}
+
+func TestLotsDrawFromActionCoop(t *testing.T) {
+	dbase, cleanup := setupLotsTestDB()
+	defer cleanup()
+	router := gin.Default()
+	router.POST("/api/inventory", CreateInventoryHandler(dbase))
+
+	w := doJSON(router, "POST", "/api/inventory", lotAction("collected", "2025-05-01", 6))
+	var collected struct {
+		LotCode string `json:"lot_code"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &collected)
+	barn := lotAction("collected", "2025-05-02", 6)
+	barn["coop"] = "Barn"
+	doJSON(router, "POST", "/api/inventory", barn)
+
+	// FIFO skips the older Main Coop lot for a sale from the barn
+	sale := lotAction("sold", "2025-05-03", 8)
+	sale["coop"] = "Barn"
+	w = doJSON(router, "POST", "/api/inventory", sale)
+	var resp struct {
+		Lots     []models.LotDraw `json:"lots"`
+		Untraced int              `json:"untraced"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusCreated || len(resp.Lots) != 1 || resp.Lots[0].LotCode != "20250502-BARN-01" || resp.Untraced != 2 {
+		t.Fatalf("expected only the barn lot to be drawn, got %d %s", w.Code, w.Body.String())
+	}
+
+	sale["quantity"] = 2
+	sale["lots"] = []map[string]interface{}{{"lot_code": collected.LotCode, "quantity": 2}}
+	if w = doJSON(router, "POST", "/api/inventory", sale); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 drawing another coop's lot, got %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"database/sql"
+	"log/slog"
+	"strings"
+	"time"
+
+	"egg-tracker/backend/logging"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// farmCollector reports farm figures from SQLite each time metrics are
+// scraped, so they are never stale.
+type farmCollector struct {
+	db             *sql.DB
+	onHand         *prometheus.Desc
+	collectedToday *prometheus.Desc
+}
+
+// NewFarmCollector returns a collector for eggs on hand per species and
+// eggs collected today, in the farm timezone, per coop.
+func NewFarmCollector(db *sql.DB) prometheus.Collector {
+	return &farmCollector{
+		db: db,
+		onHand: prometheus.NewDesc("eggtracker_eggs_on_hand",
+			"Eggs collected less eggs sold, consumed, gifted, spoiled or incubated.", []string{"species"}, nil),
+		collectedToday: prometheus.NewDesc("eggtracker_eggs_collected_today",
+			"Eggs collected today in the farm timezone.", []string{"coop"}, nil),
+	}
+}
+
+func (f *farmCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- f.onHand
+	ch <- f.collectedToday
+}
+
+func (f *farmCollector) Collect(ch chan<- prometheus.Metric) {
+	var outgoing []string
+	for a := range outgoingActions {
+		outgoing = append(outgoing, "'"+a+"'")
+	}
+	f.collect(ch, f.onHand, `
+	SELECT species, SUM(CASE WHEN action = 'collected' THEN quantity ELSE -quantity END)
+	FROM inventory_actions WHERE action = 'collected' OR action IN (`+strings.Join(outgoing, ", ")+`)
+	GROUP BY species`)
+
+	values, err := loadSettings(f.db)
+	if err != nil {
+		slog.Error("Could not load settings", "component", "farmCollector", logging.Err(err))
+		ch <- prometheus.NewInvalidMetric(f.collectedToday, err)
+		return
+	}
+	day := time.Now().In(farmTimezone(values)).Format("2006-01-02")
+	f.collect(ch, f.collectedToday, `
+	SELECT COALESCE(coop, ''), SUM(quantity) FROM inventory_actions
+	WHERE action = 'collected' AND date(date) = date(?) GROUP BY 1`, day)
+}
+
+// collect sends a gauge for each label and value row of query.
+func (f *farmCollector) collect(ch chan<- prometheus.Metric, desc *prometheus.Desc, query string, args ...interface{}) {
+	rows, err := f.db.Query(query, args...)
+	if err != nil {
+		slog.Error("Query failed", "component", "farmCollector", logging.Err(err))
+		ch <- prometheus.NewInvalidMetric(desc, err)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var label string
+		var value float64
+		if err := rows.Scan(&label, &value); err != nil {
+			ch <- prometheus.NewInvalidMetric(desc, err)
+			return
+		}
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, label)
+	}
+	if err := rows.Err(); err != nil {
+		ch <- prometheus.NewInvalidMetric(desc, err)
+	}
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestFarmCollector(t *testing.T) {
+	dbase, cleanup := setupWebhooksTestDB()
+	defer cleanup()
+	day := time.Now().Format("2006-01-02")
+	mustExec(t, dbase, `INSERT INTO inventory_actions (quantity, species, coop, action, date) VALUES
+		(12, 'Chicken', 'North', 'collected', ?), (5, 'Chicken', 'South', 'collected', ?),
+		(4, 'Duck', 'Pond', 'collected', '2020-01-01'), (6, 'Chicken', NULL, 'sold', ?), (1, 'Duck', NULL, 'spoiled', ?)`,
+		day, day, day, day)
+
+	want := `
+# HELP eggtracker_eggs_collected_today Eggs collected today in the farm timezone.
+# TYPE eggtracker_eggs_collected_today gauge
+eggtracker_eggs_collected_today{coop="North"} 12
+eggtracker_eggs_collected_today{coop="South"} 5
+# HELP eggtracker_eggs_on_hand Eggs collected less eggs sold, consumed, gifted, spoiled or incubated.
+# TYPE eggtracker_eggs_on_hand gauge
+eggtracker_eggs_on_hand{species="Chicken"} 11
+eggtracker_eggs_on_hand{species="Duck"} 3
+`
+	if err := testutil.CollectAndCompare(NewFarmCollector(dbase), strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,498 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"egg-tracker/backend/ingest"
+	"egg-tracker/backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mqttMessageRetentionDays is how long dedupe keys are remembered.
+const mqttMessageRetentionDays = 30
+
+type MQTTMappingInput struct {
+	Topic     string  `json:"topic" binding:"required"`
+	Kind      string  `json:"kind" binding:"required"`
+	Coop      string  `json:"coop" binding:"required"`
+	Species   *string `json:"species"`
+	Quantity  *string `json:"quantity"`
+	EggColor  *string `json:"egg_color"`
+	EggSize   *string `json:"egg_size"`
+	Metric    *string `json:"metric"`
+	Value     *string `json:"value"`
+	Timestamp *string `json:"timestamp"`
+	DedupeKey *string `json:"dedupe_key"`
+	Active    *bool   `json:"active"` // default true
+}
+
+// validate checks the topic, that the kind has the templates it needs and
+// that every template parses, returning a message for the client.
+func (in MQTTMappingInput) validate() string {
+	if !ingest.ValidFilter(in.Topic) {
+		return "invalid topic filter"
+	}
+	required := map[string][]*string{
+		"collection": {in.Species, in.Quantity, in.EggColor, in.EggSize},
+		"reading":    {in.Metric, in.Value},
+	}
+	fields, ok := required[in.Kind]
+	if !ok {
+		return "kind must be collection or reading"
+	}
+	for _, f := range fields {
+		if f == nil || *f == "" {
+			if in.Kind == "collection" {
+				return "collection mappings need species, quantity, egg_color and egg_size"
+			}
+			return "reading mappings need metric and value"
+		}
+	}
+	templates := map[string]*string{
+		"coop": &in.Coop, "species": in.Species, "quantity": in.Quantity, "egg_color": in.EggColor, "egg_size": in.EggSize,
+		"metric": in.Metric, "value": in.Value, "timestamp": in.Timestamp, "dedupe_key": in.DedupeKey,
+	}
+	for name, t := range templates {
+		if t == nil {
+			continue
+		}
+		if _, err := ingest.Parse(*t); err != nil {
+			return "invalid " + name + " template: " + err.Error()
+		}
+	}
+	return ""
+}
+
+const mqttMappingColumns = "id, topic, kind, coop, species, quantity, egg_color, egg_size, metric, value, timestamp, dedupe_key, active, created_at, updated_at"
+
+func loadMQTTMappings(db *sql.DB, where string, args ...interface{}) ([]models.MQTTMapping, error) {
+	rows, err := db.Query("SELECT "+mqttMappingColumns+" FROM mqtt_mappings "+where+" ORDER BY id ASC", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	mappings := []models.MQTTMapping{}
+	for rows.Next() {
+		var m models.MQTTMapping
+		var species, quantity, eggColor, eggSize, metric, value, timestamp, dedupeKey sql.NullString
+		if err := rows.Scan(&m.ID, &m.Topic, &m.Kind, &m.Coop, &species, &quantity, &eggColor, &eggSize, &metric, &value, &timestamp, &dedupeKey, &m.Active, &m.CreatedAt, &m.UpdatedAt); err != nil {
+			return nil, err
+		}
+		for _, f := range []struct {
+			src sql.NullString
+			dst **string
+		}{{species, &m.Species}, {quantity, &m.Quantity}, {eggColor, &m.EggColor}, {eggSize, &m.EggSize}, {metric, &m.Metric}, {value, &m.Value}, {timestamp, &m.Timestamp}, {dedupeKey, &m.DedupeKey}} {
+			if f.src.Valid {
+				s := f.src.String
+				*f.dst = &s
+			}
+		}
+		mappings = append(mappings, m)
+	}
+	return mappings, rows.Err()
+}
+
+// MQTTTopics returns the topic filters of active mappings, for the MQTT
+// client to subscribe to.
+func MQTTTopics(db *sql.DB) ([]string, error) {
+	rows, err := db.Query("SELECT DISTINCT topic FROM mqtt_mappings WHERE active = 1")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var topics []string
+	for rows.Next() {
+		var t string
+		if err := rows.Scan(&t); err != nil {
+			return nil, err
+		}
+		topics = append(topics, t)
+	}
+	return topics, rows.Err()
+}
+
+// IngestMQTTMessage applies every active mapping whose topic matches to a
+// message, recording a collected inventory action or a coop reading for
+// each. A message a mapping has already handled, by its dedupe key, is
+// skipped; if every matching mapping skipped it, ingest.ErrDuplicate is
+// returned.
+func IngestMQTTMessage(db *sql.DB, topic string, payload []byte, now time.Time) error {
+	mappings, err := loadMQTTMappings(db, "WHERE active = 1")
+	if err != nil {
+		return err
+	}
+	msg := ingest.NewMessage(topic, payload)
+	matched, duplicates := 0, 0
+	for _, m := range mappings {
+		if !ingest.Match(m.Topic, topic) {
+			continue
+		}
+		matched++
+		err := ingestWithMapping(db, m, msg, payload, now)
+		if err == ingest.ErrDuplicate {
+			duplicates++
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("mapping %d: %w", m.ID, err)
+		}
+	}
+	if matched == 0 {
+		return fmt.Errorf("no active mapping matches topic %s", topic)
+	}
+	if duplicates == matched {
+		return ingest.ErrDuplicate
+	}
+	return nil
+}
+
+func ingestWithMapping(db *sql.DB, m models.MQTTMapping, msg ingest.Message, payload []byte, now time.Time) error {
+	render := func(t *string) (string, error) {
+		if t == nil {
+			return "", nil
+		}
+		return ingest.Render(*t, msg)
+	}
+	key, err := render(m.DedupeKey)
+	if err != nil {
+		return fmt.Errorf("dedupe_key: %w", err)
+	}
+	if key == "" {
+		sum := sha256.Sum256(append([]byte(msg.Topic+"\n"), payload...))
+		key = hex.EncodeToString(sum[:])
+	}
+	coop, err := render(&m.Coop)
+	if err != nil {
+		return fmt.Errorf("coop: %w", err)
+	}
+	at := now
+	if m.Timestamp != nil {
+		s, err := render(m.Timestamp)
+		if err != nil {
+			return fmt.Errorf("timestamp: %w", err)
+		}
+		if at, err = parseMQTTTime(s); err != nil {
+			return err
+		}
+	}
+	values, err := loadSettings(db)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec(
+		"INSERT OR IGNORE INTO mqtt_messages (mapping_id, dedupe_key, topic, received_at) VALUES (?, ?, ?, ?)",
+		m.ID, key, msg.Topic, now.UTC(),
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ingest.ErrDuplicate
+	}
+	messageID, _ := res.LastInsertId()
+
+	if m.Kind == "reading" {
+		metric, err := render(m.Metric)
+		if err != nil {
+			return fmt.Errorf("metric: %w", err)
+		}
+		s, err := render(m.Value)
+		if err != nil {
+			return fmt.Errorf("value: %w", err)
+		}
+		value, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			b, berr := strconv.ParseBool(s)
+			if berr != nil {
+				return fmt.Errorf("value %q is not a number", s)
+			}
+			value = 0
+			if b {
+				value = 1
+			}
+		}
+		res, err := tx.Exec("INSERT INTO coop_readings (coop, metric, value, recorded_at) VALUES (?, ?, ?, ?)", coop, metric, value, at.UTC())
+		if err != nil {
+			return err
+		}
+		readingID, _ := res.LastInsertId()
+		if _, err := tx.Exec("UPDATE mqtt_messages SET coop_reading_id = ? WHERE id = ?", readingID, messageID); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	// Collections go through the same checks as ones entered by hand.
+	input := InventoryInput{Coop: coop, Action: "collected"}
+	for _, f := range []struct {
+		name string
+		t    *string
+		dst  *string
+	}{{"species", m.Species, &input.Species}, {"egg_color", m.EggColor, &input.EggColor}, {"egg_size", m.EggSize, &input.EggSize}} {
+		if *f.dst, err = render(f.t); err != nil {
+			return fmt.Errorf("%s: %w", f.name, err)
+		}
+	}
+	s, err := render(m.Quantity)
+	if err != nil {
+		return fmt.Errorf("quantity: %w", err)
+	}
+	if s == "" {
+		return fmt.Errorf("quantity rendered empty")
+	}
+	if input.Quantity, err = strconv.Atoi(s); err != nil {
+		return fmt.Errorf("quantity %q is not a whole number", s)
+	}
+	if input.Quantity <= 0 {
+		// Counters report empty nests too; there is nothing to record.
+		return tx.Commit()
+	}
+	input.Date = at.In(farmTimezone(values)).Format("2006-01-02")
+	notes := "Recorded from MQTT topic " + msg.Topic
+	input.Notes = &notes
+	created, err := insertInventoryAction(tx, &input)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE mqtt_messages SET inventory_action_id = ? WHERE id = ?", created.ID, messageID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	publishEvent(db, eventInventoryCreated, inventoryEvent(created.ID, input))
+	return nil
+}
+
+// parseMQTTTime reads a rendered timestamp as RFC 3339 or Unix seconds.
+func parseMQTTTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Unix(0, int64(secs*float64(time.Second))), nil
+	}
+	return time.Time{}, fmt.Errorf("timestamp %q is neither RFC 3339 nor Unix seconds", s)
+}
+
+// PruneMQTTMessages forgets dedupe keys older than mqttMessageRetentionDays.
+func PruneMQTTMessages(db *sql.DB, now time.Time) (int64, error) {
+	res, err := db.Exec("DELETE FROM mqtt_messages WHERE received_at < ?", now.UTC().AddDate(0, 0, -mqttMessageRetentionDays))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func ListMQTTMappingsHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		mappings, err := loadMQTTMappings(db, "")
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, mappings)
+	}
+}
+
+func CreateMQTTMappingHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input MQTTMappingInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondBindError(c, err)
+			return
+		}
+		if msg := input.validate(); msg != "" {
+			respondError(c, http.StatusBadRequest, msg)
+			return
+		}
+		res, err := db.Exec(
+			"INSERT INTO mqtt_mappings (topic, kind, coop, species, quantity, egg_color, egg_size, metric, value, timestamp, dedupe_key, active) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			input.Topic, input.Kind, input.Coop, input.Species, input.Quantity, input.EggColor, input.EggSize, input.Metric, input.Value, input.Timestamp, input.DedupeKey, input.Active == nil || *input.Active,
+		)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		id, _ := res.LastInsertId()
+		c.JSON(http.StatusCreated, IDResponse{ID: id})
+	}
+}
+
+func UpdateMQTTMappingHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input MQTTMappingInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondBindError(c, err)
+			return
+		}
+		if msg := input.validate(); msg != "" {
+			respondError(c, http.StatusBadRequest, msg)
+			return
+		}
+		res, err := db.Exec(
+			`UPDATE mqtt_mappings SET topic = ?, kind = ?, coop = ?, species = ?, quantity = ?, egg_color = ?, egg_size = ?, metric = ?, value = ?,
+			timestamp = ?, dedupe_key = ?, active = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
+			input.Topic, input.Kind, input.Coop, input.Species, input.Quantity, input.EggColor, input.EggSize, input.Metric, input.Value,
+			input.Timestamp, input.DedupeKey, input.Active == nil || *input.Active, c.Param("id"),
+		)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			respondError(c, http.StatusNotFound, "mapping not found")
+			return
+		}
+		c.JSON(http.StatusOK, MessageResponse{Message: "updated"})
+	}
+}
+
+func DeleteMQTTMappingHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		if _, err := db.Exec("DELETE FROM mqtt_messages WHERE mapping_id = ?", id); err != nil {
+			respondServerError(c, err)
+			return
+		}
+		res, err := db.Exec("DELETE FROM mqtt_mappings WHERE id = ?", id)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			respondError(c, http.StatusNotFound, "mapping not found")
+			return
+		}
+		c.JSON(http.StatusOK, MessageResponse{Message: "deleted"})
+	}
+}
+
+// MQTTPreviewInput is a sample message to render a mapping against.
+type MQTTPreviewInput struct {
+	Topic   string `json:"topic" binding:"required"`
+	Payload string `json:"payload"`
+}
+
+// MQTTPreview is the response of PreviewMQTTMappingHandler: whether the
+// topic matches and each field rendered from the message, or why it could
+// not be.
+type MQTTPreview struct {
+	Matches   bool     `json:"matches"`
+	Coop      *string  `json:"coop,omitempty"`
+	Species   *string  `json:"species,omitempty"`
+	Quantity  *string  `json:"quantity,omitempty"`
+	EggColor  *string  `json:"egg_color,omitempty"`
+	EggSize   *string  `json:"egg_size,omitempty"`
+	Metric    *string  `json:"metric,omitempty"`
+	Value     *string  `json:"value,omitempty"`
+	Timestamp *string  `json:"timestamp,omitempty"`
+	DedupeKey *string  `json:"dedupe_key,omitempty"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// PreviewMQTTMappingHandler renders the :id mapping against a sample message,
+// {"topic": ..., "payload": ...}, without recording anything, to check
+// its templates.
+func PreviewMQTTMappingHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input MQTTPreviewInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondBindError(c, err)
+			return
+		}
+		mappings, err := loadMQTTMappings(db, "WHERE id = ?", c.Param("id"))
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		if len(mappings) == 0 {
+			respondError(c, http.StatusNotFound, "mapping not found")
+			return
+		}
+		m := mappings[0]
+		msg := ingest.NewMessage(input.Topic, []byte(input.Payload))
+		out := MQTTPreview{Matches: ingest.Match(m.Topic, input.Topic)}
+		fields := map[string]struct {
+			template *string
+			out      **string
+		}{
+			"coop": {&m.Coop, &out.Coop}, "species": {m.Species, &out.Species}, "quantity": {m.Quantity, &out.Quantity},
+			"egg_color": {m.EggColor, &out.EggColor}, "egg_size": {m.EggSize, &out.EggSize}, "metric": {m.Metric, &out.Metric},
+			"value": {m.Value, &out.Value}, "timestamp": {m.Timestamp, &out.Timestamp}, "dedupe_key": {m.DedupeKey, &out.DedupeKey},
+		}
+		for name, f := range fields {
+			if f.template == nil {
+				continue
+			}
+			s, err := ingest.Render(*f.template, msg)
+			if err != nil {
+				out.Errors = append(out.Errors, name+": "+err.Error())
+				continue
+			}
+			*f.out = &s
+		}
+		sort.Strings(out.Errors)
+		c.JSON(http.StatusOK, out)
+	}
+}
+
+// ListCoopReadingsHandler lists sensor readings, newest first. Query
+// parameters: coop, metric, from/to as YYYY-MM-DD, limit (default 500).
+func ListCoopReadingsHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := "SELECT id, coop, metric, value, recorded_at, source, created_at FROM coop_readings WHERE 1 = 1"
+		var args []interface{}
+		for _, f := range []string{"coop", "metric"} {
+			if v := c.Query(f); v != "" {
+				query += " AND " + f + " = ?"
+				args = append(args, v)
+			}
+		}
+		for _, p := range []struct{ param, cond string }{{"from", "date(recorded_at) >= date(?)"}, {"to", "date(recorded_at) <= date(?)"}} {
+			if s := c.Query(p.param); s != "" {
+				if _, err := time.Parse("2006-01-02", s); err != nil {
+					respondError(c, http.StatusBadRequest, "invalid "+p.param+" date")
+					return
+				}
+				query += " AND " + p.cond
+				args = append(args, s)
+			}
+		}
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "500"))
+		if err != nil || limit < 1 {
+			respondError(c, http.StatusBadRequest, "limit must be a positive number")
+			return
+		}
+		rows, err := db.Query(query+" ORDER BY recorded_at DESC, id DESC LIMIT ?", append(args, limit)...)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		defer rows.Close()
+		readings := []models.CoopReading{}
+		for rows.Next() {
+			var r models.CoopReading
+			if err := rows.Scan(&r.ID, &r.Coop, &r.Metric, &r.Value, &r.RecordedAt, &r.Source, &r.CreatedAt); err != nil {
+				respondServerError(c, err)
+				return
+			}
+			readings = append(readings, r)
+		}
+		c.JSON(http.StatusOK, readings)
+	}
+}
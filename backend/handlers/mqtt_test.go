@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"egg-tracker/backend/db"
+	"egg-tracker/backend/ingest"
+	"egg-tracker/backend/models"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupMQTTTestDB() (*sql.DB, func()) {
+	testDBPath := "test_mqtt.db"
+	dbase, _ := sql.Open("sqlite3", testDBPath)
+	db.Migrate(dbase)
+	return dbase, func() {
+		dbase.Close()
+		os.Remove(testDBPath)
+	}
+}
+
+func mqttRouter(dbase *sql.DB) *gin.Engine {
+	router := gin.Default()
+	router.GET("/api/mqtt/mappings", ListMQTTMappingsHandler(dbase))
+	router.POST("/api/mqtt/mappings", CreateMQTTMappingHandler(dbase))
+	router.PUT("/api/mqtt/mappings/:id", UpdateMQTTMappingHandler(dbase))
+	router.DELETE("/api/mqtt/mappings/:id", DeleteMQTTMappingHandler(dbase))
+	router.POST("/api/mqtt/mappings/:id/preview", PreviewMQTTMappingHandler(dbase))
+	router.GET("/api/coop-readings", ListCoopReadingsHandler(dbase))
+	return router
+}
+
+func TestMQTTMappingValidation(t *testing.T) {
+	dbase, cleanup := setupMQTTTestDB()
+	defer cleanup()
+	router := mqttRouter(dbase)
+
+	for _, body := range []gin.H{
+		{"topic": "farm/#/nest", "kind": "collection", "coop": "North", "species": "Chicken", "quantity": "1", "egg_color": "Brown", "egg_size": "Large"},
+		{"topic": "farm/nest", "kind": "weather", "coop": "North"},
+		{"topic": "farm/nest", "kind": "collection", "coop": "North", "species": "Chicken"},
+		{"topic": "farm/nest", "kind": "collection", "coop": "North", "species": "Chicken", "quantity": "1", "egg_size": "Large"},
+		{"topic": "farm/nest", "kind": "reading", "coop": "North", "metric": "temperature_c"},
+		{"topic": "farm/nest", "kind": "collection", "coop": "{{.Payload.coop", "species": "Chicken", "quantity": "1", "egg_color": "Brown", "egg_size": "Large"},
+	} {
+		if w := doJSON(router, "POST", "/api/mqtt/mappings", body); w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %v, got %d", body, w.Code)
+		}
+	}
+
+	w := doJSON(router, "POST", "/api/mqtt/mappings", gin.H{"topic": "farm/+/nest", "kind": "collection", "coop": "{{index .Segments 1}}", "species": "Chicken", "quantity": "{{.Payload.count}}", "egg_color": "Brown", "egg_size": "Large"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create mapping: %d %s", w.Code, w.Body.String())
+	}
+	w = doJSON(router, "POST", "/api/mqtt/mappings/1/preview", gin.H{"topic": "farm/north/nest", "payload": `{"count": 3}`})
+	var preview map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &preview)
+	if preview["matches"] != true || preview["coop"] != "north" || preview["quantity"] != "3" || preview["errors"] != nil {
+		t.Errorf("unexpected preview %s", w.Body.String())
+	}
+	w = doJSON(router, "POST", "/api/mqtt/mappings/1/preview", gin.H{"topic": "farm/north/door", "payload": `open`})
+	json.Unmarshal(w.Body.Bytes(), &preview)
+	if preview["matches"] != false || preview["errors"] == nil {
+		t.Errorf("expected no match and a quantity error, got %s", w.Body.String())
+	}
+
+	if w := doJSON(router, "PUT", "/api/mqtt/mappings/1", gin.H{"topic": "farm/+/nest", "kind": "collection", "coop": "North", "species": "Chicken", "quantity": "1", "egg_color": "Brown", "egg_size": "Large", "active": false}); w.Code != http.StatusOK {
+		t.Errorf("update mapping: %d %s", w.Code, w.Body.String())
+	}
+	if topics, _ := MQTTTopics(dbase); len(topics) != 0 {
+		t.Errorf("expected no topics for an inactive mapping, got %v", topics)
+	}
+	if w := doJSON(router, "DELETE", "/api/mqtt/mappings/1", nil); w.Code != http.StatusOK {
+		t.Errorf("delete mapping: %d", w.Code)
+	}
+	if w := doJSON(router, "DELETE", "/api/mqtt/mappings/1", nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 deleting twice, got %d", w.Code)
+	}
+}
+
+func TestIngestMQTTMessage(t *testing.T) {
+	dbase, cleanup := setupMQTTTestDB()
+	defer cleanup()
+	router := mqttRouter(dbase)
+	mustExec(t, dbase, "INSERT INTO settings (key, value) VALUES ('farm_timezone', 'America/New_York')")
+	doJSON(router, "POST", "/api/mqtt/mappings", gin.H{
+		"topic": "farm/+/nest", "kind": "collection", "coop": "{{index .Segments 1}}", "species": "Chicken", "egg_color": "Brown", "egg_size": "Large",
+		"quantity": "{{.Payload.count}}", "timestamp": "{{.Payload.ts}}", "dedupe_key": "{{.Payload.seq}}",
+	})
+	doJSON(router, "POST", "/api/mqtt/mappings", gin.H{
+		"topic": "farm/+/door", "kind": "reading", "coop": "{{index .Segments 1}}", "metric": "door_open", "value": `{{eq .Raw "open"}}`,
+	})
+	doJSON(router, "POST", "/api/mqtt/mappings", gin.H{
+		"topic": "farm/+/counter", "kind": "collection", "coop": "{{index .Segments 1}}", "species": "{{.Payload.species}}", "egg_color": "Brown",
+		"egg_size": "Large", "quantity": "{{.Payload.count}}", "dedupe_key": "{{.Payload.seq}}",
+	})
+	if topics, _ := MQTTTopics(dbase); len(topics) != 3 {
+		t.Fatalf("expected 2 topics, got %v", topics)
+	}
+	now := time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC)
+
+	// 02:30 UTC is still the evening before on the farm.
+	if err := IngestMQTTMessage(dbase, "farm/north/nest", []byte(`{"count": 2, "seq": 7, "ts": "2025-06-02T02:30:00Z"}`), now); err != nil {
+		t.Fatalf("ingest collection: %v", err)
+	}
+	if err := IngestMQTTMessage(dbase, "farm/north/nest", []byte(`{"count": 2, "seq": 7, "ts": "2025-06-02T02:30:00Z"}`), now); err != ingest.ErrDuplicate {
+		t.Errorf("expected a duplicate, got %v", err)
+	}
+	if err := IngestMQTTMessage(dbase, "farm/north/nest", []byte(`{"count": 0, "seq": 8, "ts": 1748865600}`), now); err != nil {
+		t.Errorf("ingest empty count: %v", err)
+	}
+	if err := IngestMQTTMessage(dbase, "farm/north/nest", []byte(`{"count": "lots", "seq": 9, "ts": 1748865600}`), now); err == nil {
+		t.Error("expected an error for a non-numeric count")
+	}
+	if err := IngestMQTTMessage(dbase, "farm/north/counter", []byte(`{"species": "", "count": 1, "seq": 1}`), now); err == nil || !strings.Contains(err.Error(), "species is required") {
+		t.Errorf("expected an error for an empty species, got %v", err)
+	}
+	if err := IngestMQTTMessage(dbase, "farm/north/counter", []byte(`{"species": "Chicken", "seq": 2}`), now); err == nil {
+		t.Error("expected an error for a missing count")
+	}
+	if err := IngestMQTTMessage(dbase, "farm/north/feeder", []byte(`{}`), now); err == nil {
+		t.Error("expected an error for a topic without a mapping")
+	}
+	var quantity int
+	var coop, date, lot, notes, color, storage string
+	err := dbase.QueryRow("SELECT SUM(quantity), MAX(coop), MAX(date(date)), MAX(lot_code), MAX(notes), MAX(egg_color), MAX(storage) FROM inventory_actions WHERE action = 'collected'").Scan(&quantity, &coop, &date, &lot, &notes, &color, &storage)
+	if err != nil || quantity != 2 || coop != "north" || date != "2025-06-01" || lot == "" || color != "Brown" || storage != "refrigerated" {
+		t.Errorf("expected one collection of 2 on 2025-06-01 with a lot, got %d %s %s %q %s %s, %v", quantity, coop, date, lot, color, storage, err)
+	}
+	// The failed message was not remembered, so it can be sent again once fixed.
+	var remembered int
+	dbase.QueryRow("SELECT COUNT(*) FROM mqtt_messages").Scan(&remembered)
+	if remembered != 2 {
+		t.Errorf("expected 2 remembered messages, got %d", remembered)
+	}
+
+	for _, payload := range []string{"open", "closed"} {
+		if err := IngestMQTTMessage(dbase, "farm/north/door", []byte(payload), now); err != nil {
+			t.Fatalf("ingest reading: %v", err)
+		}
+	}
+	w := doJSON(router, "GET", "/api/coop-readings?coop=north&metric=door_open", nil)
+	var readings []models.CoopReading
+	json.Unmarshal(w.Body.Bytes(), &readings)
+	if len(readings) != 2 || readings[0].Value != 0 || readings[1].Value != 1 || readings[0].Source != "mqtt" {
+		t.Errorf("unexpected readings %s", w.Body.String())
+	}
+
+	if n, err := PruneMQTTMessages(dbase, now.AddDate(0, 0, mqttMessageRetentionDays+1)); err != nil || n != 4 {
+		t.Errorf("expected 4 dedupe keys pruned, got %d, %v", n, err)
+	}
+}
@@ -0,0 +1,467 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"egg-tracker/backend/logging"
+	"egg-tracker/backend/models"
+	"egg-tracker/backend/notify"
+
+	"github.com/gin-gonic/gin"
+)
+
+// notificationChannelEmail is the channel deliveries use. Others can be added
+// alongside it in the channels passed to DeliverNotifications.
+const notificationChannelEmail = "email"
+
+// loadNotificationPreferences returns a user's preferences, or the defaults
+// when none are saved.
+func loadNotificationPreferences(db *sql.DB, userID int64) (models.NotificationPreferences, error) {
+	prefs := models.NotificationPreferences{UserID: userID, Enabled: true}
+	var kinds, quietStart, quietEnd sql.NullString
+	err := db.QueryRow(
+		"SELECT enabled, kinds, quiet_start, quiet_end FROM notification_preferences WHERE user_id = ?", userID,
+	).Scan(&prefs.Enabled, &kinds, &quietStart, &quietEnd)
+	if err == sql.ErrNoRows {
+		return prefs, nil
+	} else if err != nil {
+		return prefs, err
+	}
+	if kinds.Valid {
+		prefs.Kinds = splitKinds(kinds.String)
+	}
+	if quietStart.Valid {
+		prefs.QuietStart = &quietStart.String
+	}
+	if quietEnd.Valid {
+		prefs.QuietEnd = &quietEnd.String
+	}
+	return prefs, nil
+}
+
+func splitKinds(s string) []string {
+	kinds := []string{}
+	for _, k := range strings.Split(s, ",") {
+		if k != "" {
+			kinds = append(kinds, k)
+		}
+	}
+	return kinds
+}
+
+// wantsKind reports whether a subscription list (NULL for all) includes kind.
+func wantsKind(kinds sql.NullString, kind string) bool {
+	if !kinds.Valid || kind == notify.KindTest {
+		return true
+	}
+	for _, k := range splitKinds(kinds.String) {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// quietUntil reports whether now falls in the quiet hours from start to end
+// (HH:MM in loc, wrapping past midnight when end is earlier), and if so when
+// they finish.
+func quietUntil(now time.Time, start, end string, loc *time.Location) (time.Time, bool) {
+	local := now.In(loc)
+	s, err1 := clockOn(local, start, loc)
+	e, err2 := clockOn(local, end, loc)
+	if err1 != nil || err2 != nil || s.Equal(e) {
+		return time.Time{}, false
+	}
+	if s.Before(e) {
+		return e, !local.Before(s) && local.Before(e)
+	}
+	switch {
+	case !local.Before(s):
+		return e.AddDate(0, 0, 1), true
+	case local.Before(e):
+		return e, true
+	}
+	return time.Time{}, false
+}
+
+// queueNotification renders a message and queues it for every user who wants
+// this kind, or only for userIDs when given. It returns the deliveries queued.
+func queueNotification(db *sql.DB, kind string, data interface{}, userIDs ...int64) (int, error) {
+	m, err := notify.Render(kind, data)
+	if err != nil {
+		return 0, err
+	}
+	rows, err := db.Query(`
+	SELECT u.id, u.email, COALESCE(p.enabled, 1), p.kinds
+	FROM users u LEFT JOIN notification_preferences p ON p.user_id = u.id
+	ORDER BY u.id`)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	type recipient struct {
+		id    int64
+		email string
+	}
+	var recipients []recipient
+	for rows.Next() {
+		var r recipient
+		var enabled bool
+		var kinds sql.NullString
+		if err := rows.Scan(&r.id, &r.email, &enabled, &kinds); err != nil {
+			return 0, err
+		}
+		if len(userIDs) > 0 && !containsID(userIDs, r.id) {
+			continue
+		}
+		if (enabled || kind == notify.KindTest) && wantsKind(kinds, kind) {
+			recipients = append(recipients, r)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	now := time.Now().UTC()
+	for _, r := range recipients {
+		_, err := db.Exec(
+			"INSERT INTO notification_deliveries (user_id, channel, recipient, kind, subject, body, next_attempt_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			r.id, notificationChannelEmail, r.email, m.Kind, m.Subject, m.Body, now,
+		)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(recipients), nil
+}
+
+func containsID(ids []int64, id int64) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// notifyFailure queues a failure notification, logging rather than returning
+// errors since the caller is already handling a failure of its own.
+func notifyFailure(db *sql.DB, kind string, started time.Time, msg string) {
+	data := struct {
+		Time  time.Time
+		Error string
+	}{started, msg}
+	if _, err := queueNotification(db, kind, data); err != nil {
+		slog.Error("Could not queue notification", "component", "notifyFailure", "kind", kind, logging.Err(err))
+	}
+}
+
+// DeliverNotifications sends pending deliveries that are due over their
+// channel. Deliveries to someone in their quiet hours wait until the hours
+// end; failed ones are retried with exponential backoff until
+// notify.MaxAttempts. It returns the number sent.
+func DeliverNotifications(db *sql.DB, channels map[string]notify.Notifier, now time.Time) (int, error) {
+	values, err := loadSettings(db)
+	if err != nil {
+		return 0, err
+	}
+	loc := farmTimezone(values)
+	rows, err := db.Query(`
+	SELECT d.id, d.channel, d.recipient, d.kind, d.subject, d.body, d.attempts, d.next_attempt_at, p.quiet_start, p.quiet_end
+	FROM notification_deliveries d LEFT JOIN notification_preferences p ON p.user_id = d.user_id
+	WHERE d.status = 'pending'
+	ORDER BY d.next_attempt_at ASC, d.id ASC`)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	type pending struct {
+		id                 int64
+		channel, recipient string
+		message            notify.Message
+		attempts           int
+		due                time.Time
+		quietStart         sql.NullString
+		quietEnd           sql.NullString
+	}
+	var queue []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.channel, &p.recipient, &p.message.Kind, &p.message.Subject, &p.message.Body, &p.attempts, &p.due, &p.quietStart, &p.quietEnd); err != nil {
+			return 0, err
+		}
+		if !p.due.After(now) {
+			queue = append(queue, p)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	sent := 0
+	for _, p := range queue {
+		if p.quietStart.Valid && p.quietEnd.Valid {
+			if until, quiet := quietUntil(now, p.quietStart.String, p.quietEnd.String, loc); quiet {
+				if _, err := db.Exec("UPDATE notification_deliveries SET next_attempt_at = ? WHERE id = ?", until.UTC(), p.id); err != nil {
+					return sent, err
+				}
+				continue
+			}
+		}
+		err := errUnknownChannel
+		if n, ok := channels[p.channel]; ok {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			err = n.Notify(ctx, p.recipient, p.message)
+			cancel()
+		}
+		attempts := p.attempts + 1
+		switch {
+		case err == nil:
+			_, err = db.Exec("UPDATE notification_deliveries SET status = 'sent', attempts = ?, sent_at = ?, last_error = NULL WHERE id = ?", attempts, now.UTC(), p.id)
+			sent++
+		case attempts >= notify.MaxAttempts:
+			slog.Warn("Giving up on delivery", "component", "DeliverNotifications", "delivery_id", p.id, "recipient", p.recipient, logging.Err(err))
+			_, err = db.Exec("UPDATE notification_deliveries SET status = 'failed', attempts = ?, last_error = ? WHERE id = ?", attempts, err.Error(), p.id)
+		default:
+			_, err = db.Exec(
+				"UPDATE notification_deliveries SET attempts = ?, last_error = ?, next_attempt_at = ? WHERE id = ?",
+				attempts, err.Error(), now.Add(notify.Backoff(attempts)).UTC(), p.id,
+			)
+		}
+		if err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+// errUnknownChannel fails deliveries queued for a channel that is not set up.
+var errUnknownChannel = errors.New("notification channel not configured")
+
+// NotifyTasksDue tells each user about the tasks due or overdue on day that
+// are assigned to them or to nobody. It returns the deliveries queued.
+func NotifyTasksDue(db *sql.DB, day time.Time) (int, error) {
+	tasks, err := tasksDueOn(db, day, "", "")
+	if err != nil {
+		return 0, err
+	}
+	var pending []models.TaskDue
+	for _, t := range tasks {
+		if !t.Completed {
+			pending = append(pending, t)
+		}
+	}
+	if len(pending) == 0 {
+		return 0, nil
+	}
+	rows, err := db.Query("SELECT id FROM users ORDER BY id")
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	var users []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return 0, err
+		}
+		users = append(users, id)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	queued := 0
+	for _, id := range users {
+		var mine []models.TaskDue
+		for _, t := range pending {
+			if t.AssignedUserID == nil || *t.AssignedUserID == id {
+				mine = append(mine, t)
+			}
+		}
+		if len(mine) == 0 {
+			continue
+		}
+		data := struct {
+			Date  string
+			Tasks []models.TaskDue
+		}{day.Format("2006-01-02"), mine}
+		n, err := queueNotification(db, notify.KindTasksDue, data, id)
+		if err != nil {
+			return queued, err
+		}
+		queued += n
+	}
+	return queued, nil
+}
+
+type NotificationPreferencesInput struct {
+	Enabled    *bool    `json:"enabled"`
+	Kinds      []string `json:"kinds"`       // null or omitted for all kinds
+	QuietStart *string  `json:"quiet_start"` // HH:MM; set both or neither
+	QuietEnd   *string  `json:"quiet_end"`
+}
+
+// userExists answers 404 and returns false when the :id user is missing.
+func userExists(c *gin.Context, db *sql.DB) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusNotFound, "user not found")
+		return 0, false
+	}
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users WHERE id = ?", id).Scan(&n); err != nil {
+		respondServerError(c, err)
+		return 0, false
+	}
+	if n == 0 {
+		respondError(c, http.StatusNotFound, "user not found")
+		return 0, false
+	}
+	return id, true
+}
+
+func GetNotificationPreferencesHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := userExists(c, db)
+		if !ok {
+			return
+		}
+		prefs, err := loadNotificationPreferences(db, id)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, prefs)
+	}
+}
+
+func UpdateNotificationPreferencesHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := userExists(c, db)
+		if !ok {
+			return
+		}
+		var input NotificationPreferencesInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondBindError(c, err)
+			return
+		}
+		enabled := input.Enabled == nil || *input.Enabled
+		var kinds interface{}
+		if input.Kinds != nil {
+			for _, k := range input.Kinds {
+				if !containsKind(k) {
+					respondError(c, http.StatusBadRequest, "unknown notification kind: "+k)
+					return
+				}
+			}
+			kinds = strings.Join(input.Kinds, ",")
+		}
+		if (input.QuietStart == nil) != (input.QuietEnd == nil) {
+			respondError(c, http.StatusBadRequest, "quiet_start and quiet_end must be set together")
+			return
+		}
+		if input.QuietStart != nil {
+			_, err1 := time.Parse("15:04", *input.QuietStart)
+			_, err2 := time.Parse("15:04", *input.QuietEnd)
+			if err1 != nil || err2 != nil {
+				respondError(c, http.StatusBadRequest, "quiet_start and quiet_end must be HH:MM")
+				return
+			}
+		}
+		_, err := db.Exec(
+			`INSERT INTO notification_preferences (user_id, enabled, kinds, quiet_start, quiet_end) VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT(user_id) DO UPDATE SET enabled = excluded.enabled, kinds = excluded.kinds,
+				quiet_start = excluded.quiet_start, quiet_end = excluded.quiet_end, updated_at = CURRENT_TIMESTAMP`,
+			id, enabled, kinds, input.QuietStart, input.QuietEnd,
+		)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, MessageResponse{Message: "updated"})
+	}
+}
+
+func containsKind(kind string) bool {
+	for _, k := range notify.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// SendTestNotificationHandler queues a test message for the :id user,
+// regardless of their preferences, to check that notifications reach them.
+func SendTestNotificationHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := userExists(c, db)
+		if !ok {
+			return
+		}
+		if _, err := queueNotification(db, notify.KindTest, nil, id); err != nil {
+			requestLogger(c, "SendTestNotificationHandler").Error("Could not queue test notification", logging.Err(err))
+			respondError(c, http.StatusInternalServerError, "db error")
+			return
+		}
+		c.JSON(http.StatusAccepted, MessageResponse{Message: "queued"})
+	}
+}
+
+// ListNotificationDeliveriesHandler returns the delivery log, newest first.
+// Query parameters: status=pending|sent|failed, kind, user_id, limit
+// (default 100).
+func ListNotificationDeliveriesHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := `SELECT id, user_id, channel, recipient, kind, subject, body, status, attempts, next_attempt_at, last_error, sent_at, created_at
+			FROM notification_deliveries WHERE 1 = 1`
+		var args []interface{}
+		for _, f := range []string{"status", "kind", "user_id"} {
+			if v := c.Query(f); v != "" {
+				query += " AND " + f + " = ?"
+				args = append(args, v)
+			}
+		}
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+		if err != nil || limit < 1 {
+			respondError(c, http.StatusBadRequest, "limit must be a positive number")
+			return
+		}
+		rows, err := db.Query(query+" ORDER BY created_at DESC, id DESC LIMIT ?", append(args, limit)...)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		defer rows.Close()
+		deliveries := []models.NotificationDelivery{}
+		for rows.Next() {
+			var d models.NotificationDelivery
+			var lastError sql.NullString
+			var sentAt sql.NullTime
+			if err := rows.Scan(&d.ID, &d.UserID, &d.Channel, &d.Recipient, &d.Kind, &d.Subject, &d.Body, &d.Status, &d.Attempts, &d.NextAttemptAt, &lastError, &sentAt, &d.CreatedAt); err != nil {
+				respondServerError(c, err)
+				return
+			}
+			if lastError.Valid {
+				d.LastError = &lastError.String
+			}
+			if sentAt.Valid {
+				d.SentAt = &sentAt.Time
+			}
+			deliveries = append(deliveries, d)
+		}
+		c.JSON(http.StatusOK, deliveries)
+	}
+}
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"egg-tracker/backend/db"
+	"egg-tracker/backend/models"
+	"egg-tracker/backend/notify"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupNotificationsTestDB() (*sql.DB, func()) {
+	testDBPath := "test_notifications.db"
+	dbase, _ := sql.Open("sqlite3", testDBPath)
+	db.Migrate(dbase)
+	return dbase, func() {
+		dbase.Close()
+		os.Remove(testDBPath)
+	}
+}
+
+// fakeNotifier records what it sends, failing the first failures calls.
+type fakeNotifier struct {
+	failures int
+	sent     []string
+}
+
+func (f *fakeNotifier) Notify(ctx context.Context, to string, m notify.Message) error {
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("connection refused")
+	}
+	f.sent = append(f.sent, to+": "+m.Subject)
+	return nil
+}
+
+func loadDeliveries(t *testing.T, dbase *sql.DB) []models.NotificationDelivery {
+	t.Helper()
+	router := gin.Default()
+	router.GET("/api/notifications/deliveries", ListNotificationDeliveriesHandler(dbase))
+	w := doJSON(router, "GET", "/api/notifications/deliveries", nil)
+	var deliveries []models.NotificationDelivery
+	if err := json.Unmarshal(w.Body.Bytes(), &deliveries); err != nil {
+		t.Fatalf("decode deliveries: %v: %s", err, w.Body.String())
+	}
+	return deliveries
+}
+
+func TestQuietUntil(t *testing.T) {
+	loc, _ := time.LoadLocation("Europe/London")
+	at := func(s string) time.Time {
+		d, _ := time.ParseInLocation("2006-01-02 15:04", s, loc)
+		return d
+	}
+	cases := []struct {
+		now, start, end string
+		quiet           bool
+		until           string
+	}{
+		{"2025-06-30 23:30", "22:00", "06:30", true, "2025-07-01 06:30"},
+		{"2025-06-30 05:00", "22:00", "06:30", true, "2025-06-30 06:30"},
+		{"2025-06-30 12:00", "22:00", "06:30", false, ""},
+		{"2025-06-30 13:00", "12:00", "14:00", true, "2025-06-30 14:00"},
+		{"2025-06-30 14:00", "12:00", "14:00", false, ""},
+		{"2025-06-30 14:00", "14:00", "14:00", false, ""},
+	}
+	for _, tc := range cases {
+		until, quiet := quietUntil(at(tc.now).UTC(), tc.start, tc.end, loc)
+		if quiet != tc.quiet || (quiet && !until.Equal(at(tc.until))) {
+			t.Errorf("%s in %s-%s: got %v until %s, want %v until %s", tc.now, tc.start, tc.end, quiet, until, tc.quiet, tc.until)
+		}
+	}
+}
+
+func TestQueueAndDeliverNotifications(t *testing.T) {
+	dbase, cleanup := setupNotificationsTestDB()
+	defer cleanup()
+	mustExec(t, dbase, `INSERT INTO users (id, email, password_hash) VALUES
+		(1, 'all@farm.test', 'x'), (2, 'feed@farm.test', 'x'), (3, 'off@farm.test', 'x'), (4, 'night@farm.test', 'x')`)
+	clock := time.Now().UTC()
+	mustExec(t, dbase, `INSERT INTO notification_preferences (user_id, enabled, kinds, quiet_start, quiet_end) VALUES
+		(2, 1, 'low_stock', NULL, NULL), (3, 0, NULL, NULL, NULL), (4, 1, NULL, ?, ?)`,
+		clock.Add(-time.Hour).Format("15:04"), clock.Add(2*time.Hour).Format("15:04"))
+
+	n, err := queueNotification(dbase, notify.KindBackupFailed, struct {
+		Time  time.Time
+		Error string
+	}{time.Now(), "disk full"})
+	if err != nil || n != 2 {
+		t.Fatalf("expected deliveries for users 1 and 4, got %d, %v", n, err)
+	}
+
+	email := &fakeNotifier{failures: 1}
+	channels := map[string]notify.Notifier{"email": email}
+	now := time.Now()
+	if sent, err := DeliverNotifications(dbase, channels, now); err != nil || sent != 0 {
+		t.Fatalf("expected the first attempt to fail, got %d sent, %v", sent, err)
+	}
+	deliveries := loadDeliveries(t, dbase)
+	if len(deliveries) != 2 {
+		t.Fatalf("expected two deliveries, got %+v", deliveries)
+	}
+	for _, d := range deliveries {
+		switch d.Recipient {
+		case "all@farm.test":
+			if d.Status != "pending" || d.Attempts != 1 || d.LastError == nil || d.NextAttemptAt.Sub(now.UTC()) < 59*time.Second {
+				t.Errorf("expected a retry in a minute, got %+v", d)
+			}
+		case "night@farm.test":
+			if d.Attempts != 0 || !d.NextAttemptAt.After(now) {
+				t.Errorf("expected the delivery to wait for quiet hours, got %+v", d)
+			}
+		}
+	}
+
+	// Not due yet, then sent once the backoff has passed.
+	DeliverNotifications(dbase, channels, now.Add(30*time.Second))
+	if sent, err := DeliverNotifications(dbase, channels, now.Add(61*time.Second)); err != nil || sent != 1 {
+		t.Fatalf("expected the retry to be sent, got %d, %v", sent, err)
+	}
+	if len(email.sent) != 1 || email.sent[0] != "all@farm.test: Backup failed" {
+		t.Errorf("unexpected sent messages %v", email.sent)
+	}
+
+	// Deliveries on a channel that is not configured fail after MaxAttempts.
+	mustExec(t, dbase, "INSERT INTO notification_deliveries (user_id, channel, recipient, kind, subject, body, next_attempt_at) VALUES (1, 'sms', '+100', 'test', 'x', 'y', ?)", now.UTC())
+	at := now
+	for i := 0; i < notify.MaxAttempts; i++ {
+		at = at.Add(notify.Backoff(i + 1))
+		DeliverNotifications(dbase, channels, at)
+	}
+	var status string
+	var attempts int
+	dbase.QueryRow("SELECT status, attempts FROM notification_deliveries WHERE channel = 'sms'").Scan(&status, &attempts)
+	if status != "failed" || attempts != notify.MaxAttempts {
+		t.Errorf("expected the sms delivery to fail after %d attempts, got %s after %d", notify.MaxAttempts, status, attempts)
+	}
+}
+
+func TestNotificationPreferencesEndpoints(t *testing.T) {
+	dbase, cleanup := setupNotificationsTestDB()
+	defer cleanup()
+	mustExec(t, dbase, "INSERT INTO users (id, email, password_hash) VALUES (1, 'keeper@farm.test', 'x')")
+	router := gin.Default()
+	router.GET("/api/users/:id/notification-preferences", GetNotificationPreferencesHandler(dbase))
+	router.PUT("/api/users/:id/notification-preferences", UpdateNotificationPreferencesHandler(dbase))
+	router.POST("/api/users/:id/notifications/test", SendTestNotificationHandler(dbase))
+
+	var prefs models.NotificationPreferences
+	w := doJSON(router, "GET", "/api/users/1/notification-preferences", nil)
+	json.Unmarshal(w.Body.Bytes(), &prefs)
+	if w.Code != http.StatusOK || !prefs.Enabled || prefs.Kinds != nil {
+		t.Fatalf("expected default preferences, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := doJSON(router, "GET", "/api/users/9/notification-preferences", nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+	for _, body := range []map[string]interface{}{
+		{"kinds": []string{"weather"}},
+		{"quiet_start": "22:00"},
+		{"quiet_start": "10pm", "quiet_end": "06:00"},
+	} {
+		if w := doJSON(router, "PUT", "/api/users/1/notification-preferences", body); w.Code != http.StatusBadRequest {
+			t.Errorf("%v: expected 400, got %d", body, w.Code)
+		}
+	}
+	w = doJSON(router, "PUT", "/api/users/1/notification-preferences", map[string]interface{}{
+		"enabled": false, "kinds": []string{"anomaly", "tasks_due"}, "quiet_start": "21:00", "quiet_end": "07:00",
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	w = doJSON(router, "GET", "/api/users/1/notification-preferences", nil)
+	prefs = models.NotificationPreferences{}
+	json.Unmarshal(w.Body.Bytes(), &prefs)
+	if prefs.Enabled || len(prefs.Kinds) != 2 || prefs.Kinds[1] != "tasks_due" || prefs.QuietStart == nil || *prefs.QuietStart != "21:00" {
+		t.Errorf("unexpected saved preferences: %s", w.Body.String())
+	}
+
+	// Test messages reach users who have turned notifications off.
+	if w := doJSON(router, "POST", "/api/users/1/notifications/test", nil); w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", w.Code)
+	}
+	if n, _ := queueNotification(dbase, notify.KindAnomaly, struct {
+		models.Alert
+		WindowDays int
+	}{}); n != 0 {
+		t.Errorf("expected no anomaly delivery while disabled, got %d", n)
+	}
+	if d := loadDeliveries(t, dbase); len(d) != 1 || d[0].Kind != notify.KindTest || d[0].Subject != "Test notification" {
+		t.Errorf("unexpected deliveries %+v", d)
+	}
+}
+
+func TestNotificationSources(t *testing.T) {
+	dbase, cleanup := setupNotificationsTestDB()
+	defer cleanup()
+	mustExec(t, dbase, "INSERT INTO users (id, email, password_hash) VALUES (1, 'a@farm.test', 'x'), (2, 'b@farm.test', 'x')")
+
+	// Tasks due go to their assignee; unassigned ones to everyone.
+	day := time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)
+	mustExec(t, dbase, `INSERT INTO tasks (title, rrule, start_date, assigned_user_id, active) VALUES
+		('Collect eggs', 'FREQ=DAILY', ?, NULL, 1), ('Clean coop', 'FREQ=WEEKLY', ?, 2, 1)`, day, day)
+	if n, err := NotifyTasksDue(dbase, day); err != nil || n != 2 {
+		t.Fatalf("expected a delivery per user, got %d, %v", n, err)
+	}
+	var subjects []string
+	rows, _ := dbase.Query("SELECT subject FROM notification_deliveries WHERE kind = 'tasks_due' ORDER BY user_id")
+	for rows.Next() {
+		var s string
+		rows.Scan(&s)
+		subjects = append(subjects, s)
+	}
+	rows.Close()
+	if len(subjects) != 2 || subjects[0] != "1 task to do on 2025-06-30" || subjects[1] != "2 tasks to do on 2025-06-30" {
+		t.Errorf("unexpected task notifications %v", subjects)
+	}
+
+	// Low stock is notified when usage crosses the warning level, not after.
+	router := gin.Default()
+	router.POST("/api/feed/usage", CreateFeedUsageHandler(dbase))
+	mustExec(t, dbase, "INSERT INTO feed_types (id, name, low_stock_kg) VALUES (1, 'Layer pellets', 10)")
+	mustExec(t, dbase, "INSERT INTO feed_purchases (feed_type_id, date, quantity, weight_kg) VALUES (1, '2025-06-01', 1, 20)")
+	for _, kg := range []float64{5, 8, 2} {
+		w := doJSON(router, "POST", "/api/feed/usage", map[string]interface{}{"feed_type_id": 1, "coop": "Main Coop", "date": "2025-06-30", "weight_kg": kg})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+	var lowStock int
+	dbase.QueryRow("SELECT COUNT(*) FROM notification_deliveries WHERE kind = 'low_stock' AND subject = 'Layer pellets feed is running low'").Scan(&lowStock)
+	if lowStock != 2 {
+		t.Errorf("expected one low stock notification per user, got %d", lowStock)
+	}
+
+	// A failed backup is notified.
+	os.WriteFile("backups", []byte("not a directory"), 0644)
+	defer os.Remove("backups")
+	router.POST("/api/backup", BackupHandler(dbase))
+	if w := doJSON(router, "POST", "/api/backup", nil); w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", w.Code)
+	}
+	var backupFailed int
+	dbase.QueryRow("SELECT COUNT(*) FROM notification_deliveries WHERE kind = 'backup_failed'").Scan(&backupFailed)
+	if backupFailed != 2 {
+		t.Errorf("expected backup failure notifications, got %d", backupFailed)
+	}
+}
@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	_ "embed"
+	"net/http"
+	"sync"
+
+	"egg-tracker/backend/models"
+	"egg-tracker/backend/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed apidocs.html
+var apiDocsPage []byte
+
+var (
+	specOnce sync.Once
+	spec     *openapi.Document
+)
+
+// OpenAPISpec returns the OpenAPI document for every route registered in
+// main.go. Routes added there must be added here too; the route test in
+// main_test.go fails otherwise.
+func OpenAPISpec() *openapi.Document {
+	specOnce.Do(func() { spec = buildSpec() })
+	return spec
+}
+
+// OpenAPIHandler serves the OpenAPI document as JSON.
+func OpenAPIHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, OpenAPISpec())
+	}
+}
+
+// APIDocsHandler serves a page that lists the operations in the OpenAPI
+// document. It is self-contained so it works without internet access.
+func APIDocsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", apiDocsPage)
+	}
+}
+
+// Query parameters shared by several routes.
+var (
+	dateRange = []openapi.Param{
+		{Name: "from", Type: "date", Description: "First day, YYYY-MM-DD."},
+		{Name: "to", Type: "date", Description: "Last day, YYYY-MM-DD."},
+	}
+	reportPeriod = openapi.Param{Name: "period", Enum: []string{"day", "week", "month"}, Description: "Default week."}
+	reportBy     = openapi.Param{Name: "by", Enum: []string{"coop", "species"}, Description: "Default coop."}
+	forecastBy   = []openapi.Param{
+		reportBy,
+		{Name: "period", Enum: []string{"day", "week"}, Description: "Default week."},
+		{Name: "level", Type: "integer", Description: "Prediction interval, 80 or 95 percent; default 80."},
+	}
+	limitParam = func(def string) openapi.Param {
+		return openapi.Param{Name: "limit", Type: "integer", Description: "Default " + def + "."}
+	}
+)
+
+func with(params []openapi.Param, more ...openapi.Param) []openapi.Param {
+	return append(append([]openapi.Param{}, params...), more...)
+}
+
+func buildSpec() *openapi.Document {
+	d := openapi.New("Egg Tracker API", "1.0.0",
+		"Records eggs, birds, feed, storage and farm tasks, and reports on them. Errors have an \"error\" message.",
+		ErrorResponse{})
+	for _, r := range apiRoutes() {
+		d.Add(r)
+	}
+	return d
+}
+
+// attachmentRoutes documents the attachment routes of one kind of record.
+func attachmentRoutes(path, tag string) []openapi.Route {
+	return []openapi.Route{
+		{Method: "POST", Path: path, Tag: tag, Summary: "Upload an attachment; images get a thumbnail", Status: http.StatusCreated, Response: models.Attachment{},
+			Form: []openapi.Param{{Name: "file", Type: "file", Required: true}, {Name: "caption"}}},
+		{Method: "GET", Path: path, Tag: tag, Summary: "List attachments", Response: []models.Attachment{}},
+	}
+}
+
+func apiRoutes() []openapi.Route {
+	routes := []openapi.Route{
+		{Method: "GET", Path: "/metrics", Tag: "System", Summary: "Prometheus metrics", ContentType: "text/plain"},
+		{Method: "GET", Path: "/api/health", Tag: "System", Summary: "Server and MQTT broker health", Response: HealthResponse{}},
+		{Method: "GET", Path: "/api/openapi.json", Tag: "System", Summary: "This OpenAPI document", Response: map[string]any{}},
+		{Method: "GET", Path: "/api/docs", Tag: "System", Summary: "API documentation page", ContentType: "text/html"},
+
+		{Method: "POST", Path: "/api/signup", Tag: "Auth", Summary: "Create a user", Body: SignupRequest{}, Status: http.StatusCreated, Response: MessageResponse{}},
+		{Method: "POST", Path: "/api/login", Tag: "Auth", Summary: "Log in; sets the refresh token cookie", Body: LoginRequest{}, Response: TokenResponse{}},
+		{Method: "POST", Path: "/api/refresh", Tag: "Auth", Summary: "Get a new access token from the refresh token cookie", Response: TokenResponse{}},
+
+		{Method: "POST", Path: "/api/inventory", Tag: "Inventory", Summary: "Record an inventory action", Body: InventoryInput{}, Status: http.StatusCreated, Response: InventoryCreatedResponse{}},
+		{Method: "GET", Path: "/api/inventory", Tag: "Inventory", Summary: "List inventory actions", Response: []models.InventoryAction{}},
+		{Method: "PUT", Path: "/api/inventory/:id", Tag: "Inventory", Summary: "Update an inventory action", Body: InventoryInput{}, Response: InventoryUpdatedResponse{}},
+		{Method: "DELETE", Path: "/api/inventory/:id", Tag: "Inventory", Summary: "Delete an inventory action", Response: MessageResponse{}},
+	}
+	routes = append(routes, attachmentRoutes("/api/inventory/:id/attachments", "Inventory")...)
+	routes = append(routes, []openapi.Route{
+		{Method: "GET", Path: "/api/options/:type", Tag: "Options", Summary: "List options of a type: species, eggcolor, eggsize, coop or storageunit", Response: []models.OptionBase{}},
+		{Method: "POST", Path: "/api/options/:type", Tag: "Options", Summary: "Add an option", Body: OptionInput{}, Status: http.StatusCreated, Response: IDResponse{}},
+		{Method: "PUT", Path: "/api/options/:type/:id", Tag: "Options", Summary: "Rename an option", Body: OptionInput{}, Response: MessageResponse{}},
+		{Method: "POST", Path: "/api/options/:type/:id/deactivate", Tag: "Options", Summary: "Deactivate an option", Response: MessageResponse{}},
+		{Method: "POST", Path: "/api/options/:type/:id/reactivate", Tag: "Options", Summary: "Reactivate an option", Response: MessageResponse{}},
+
+		{Method: "GET", Path: "/api/settings", Tag: "Settings", Summary: "Get farm settings", Response: models.Settings{}},
+		{Method: "PUT", Path: "/api/settings", Tag: "Settings", Summary: "Update farm settings", Body: SettingsInput{}, Response: MessageResponse{}},
+		{Method: "GET", Path: "/api/settings/logo", Tag: "Settings", Summary: "Get the farm logo", ContentType: "image/*"},
+		{Method: "POST", Path: "/api/settings/logo", Tag: "Settings", Summary: "Upload the farm logo", Form: []openapi.Param{{Name: "logo", Type: "file", Required: true}}, Response: MessageResponse{}},
+
+		{Method: "GET", Path: "/api/sales/:id/receipt.pdf", Tag: "Sales", Summary: "PDF receipt for a sale", ContentType: "application/pdf"},
+		{Method: "GET", Path: "/api/invoices/:customer/:month/invoice.pdf", Tag: "Sales", Summary: "Monthly PDF invoice for a customer; month is YYYY-MM", ContentType: "application/pdf"},
+
+		{Method: "POST", Path: "/api/birds", Tag: "Birds", Summary: "Add a bird", Body: BirdInput{}, Status: http.StatusCreated, Response: IDResponse{}},
+		{Method: "GET", Path: "/api/birds", Tag: "Birds", Summary: "List birds", Response: []models.Bird{},
+			Query: []openapi.Param{{Name: "status"}, {Name: "coop"}, {Name: "species"}}},
+		{Method: "GET", Path: "/api/birds/:id", Tag: "Birds", Summary: "Get a bird", Response: models.Bird{}},
+		{Method: "PUT", Path: "/api/birds/:id", Tag: "Birds", Summary: "Update a bird", Body: BirdInput{}, Response: MessageResponse{}},
+		{Method: "DELETE", Path: "/api/birds/:id", Tag: "Birds", Summary: "Delete a bird", Response: MessageResponse{}},
+		{Method: "POST", Path: "/api/birds/:id/transfer", Tag: "Birds", Summary: "Move a bird to another coop", Body: BirdTransferInput{}, Status: http.StatusCreated, Response: IDResponse{}},
+		{Method: "GET", Path: "/api/birds/:id/transfers", Tag: "Birds", Summary: "List a bird's transfers", Response: []models.BirdTransfer{}},
+	}...)
+	routes = append(routes, attachmentRoutes("/api/birds/:id/attachments", "Birds")...)
+	routes = append(routes, []openapi.Route{
+		{Method: "POST", Path: "/api/health-events", Tag: "Health", Summary: "Record a health event", Body: HealthEventInput{}, Status: http.StatusCreated, Response: IDResponse{}},
+		{Method: "GET", Path: "/api/health-events", Tag: "Health", Summary: "List health events", Response: []models.HealthEvent{},
+			Query: []openapi.Param{{Name: "bird_id", Type: "integer"}, {Name: "coop"}, {Name: "event_type"}}},
+		{Method: "PUT", Path: "/api/health-events/:id", Tag: "Health", Summary: "Update a health event", Body: HealthEventInput{}, Response: MessageResponse{}},
+		{Method: "DELETE", Path: "/api/health-events/:id", Tag: "Health", Summary: "Delete a health event", Response: MessageResponse{}},
+		{Method: "GET", Path: "/api/withdrawals", Tag: "Health", Summary: "Coops under an egg withdrawal period", Response: []CoopWithdrawal{},
+			Query: []openapi.Param{{Name: "date", Type: "date", Description: "Default today."}}},
+
+		{Method: "POST", Path: "/api/status-periods", Tag: "Birds", Summary: "Record a molting or broody period", Body: StatusPeriodInput{}, Status: http.StatusCreated, Response: IDResponse{}},
+		{Method: "GET", Path: "/api/status-periods", Tag: "Birds", Summary: "List status periods", Response: []models.BirdStatusPeriod{},
+			Query: []openapi.Param{{Name: "bird_id", Type: "integer"}, {Name: "coop"}, {Name: "status"}, {Name: "active", Type: "boolean", Description: "true for periods that have not ended."}}},
+		{Method: "PUT", Path: "/api/status-periods/:id", Tag: "Birds", Summary: "Update a status period", Body: StatusPeriodInput{}, Response: MessageResponse{}},
+		{Method: "DELETE", Path: "/api/status-periods/:id", Tag: "Birds", Summary: "Delete a status period", Response: MessageResponse{}},
+
+		{Method: "GET", Path: "/api/incubation/profiles", Tag: "Incubation", Summary: "List incubation profiles per species", Response: []models.IncubationProfile{}},
+		{Method: "PUT", Path: "/api/incubation/profiles/:species", Tag: "Incubation", Summary: "Update a species' incubation profile", Body: IncubationProfileInput{}, Response: MessageResponse{}},
+		{Method: "POST", Path: "/api/incubation/batches", Tag: "Incubation", Summary: "Set eggs to incubate", Body: IncubationBatchInput{}, Status: http.StatusCreated, Response: IncubationBatchCreated{}},
+		{Method: "GET", Path: "/api/incubation/batches", Tag: "Incubation", Summary: "List incubation batches", Response: []models.IncubationBatch{},
+			Query: []openapi.Param{{Name: "status"}, {Name: "species"}}},
+		{Method: "GET", Path: "/api/incubation/batches/:id", Tag: "Incubation", Summary: "Get an incubation batch", Response: models.IncubationBatch{}},
+		{Method: "DELETE", Path: "/api/incubation/batches/:id", Tag: "Incubation", Summary: "Delete an incubation batch", Response: MessageResponse{}},
+		{Method: "POST", Path: "/api/incubation/batches/:id/candling", Tag: "Incubation", Summary: "Record a candling", Body: CandlingInput{}, Status: http.StatusCreated, Response: IDResponse{}},
+		{Method: "POST", Path: "/api/incubation/batches/:id/hatch", Tag: "Incubation", Summary: "Record a hatch, adding the chicks as birds", Body: HatchInput{}, Response: HatchResult{}},
+
+		{Method: "GET", Path: "/api/trace/:lot", Tag: "Lots", Summary: "Trace where a lot's eggs went", Response: models.LotTrace{}},
+		{Method: "GET", Path: "/api/lots", Tag: "Lots", Summary: "List lots on hand, soonest to expire first", Response: []models.LotStatus{},
+			Query: []openapi.Param{{Name: "species"}, {Name: "expired", Type: "boolean"}}},
+		{Method: "GET", Path: "/api/lots/suggestions", Tag: "Lots", Summary: "Suggest lots to fill a quantity", Response: LotSuggestions{},
+			Query: []openapi.Param{{Name: "species", Required: true}, {Name: "quantity", Type: "integer", Required: true}}},
+		{Method: "POST", Path: "/api/lots/expire", Tag: "Lots", Summary: "Flag or spoil lots past their shelf life", Response: ExpiryResult{}},
+
+		{Method: "GET", Path: "/api/storage", Tag: "Storage", Summary: "List storage units with their safe ranges", Response: []models.StorageUnit{}},
+		{Method: "PUT", Path: "/api/storage/:id/range", Tag: "Storage", Summary: "Set a unit's safe temperature range", Body: StorageRangeInput{}, Response: MessageResponse{}},
+		{Method: "POST", Path: "/api/storage/:id/readings", Tag: "Storage", Summary: "Record a temperature reading", Body: StorageReadingInput{}, Status: http.StatusCreated, Response: StorageReadingCreated{}},
+		{Method: "GET", Path: "/api/storage/:id/readings", Tag: "Storage", Summary: "List a unit's readings; default the last seven days", Response: []models.StorageReading{}, Query: dateRange},
+		{Method: "GET", Path: "/api/storage/:id/excursions", Tag: "Storage", Summary: "List a unit's excursions; default the last seven days", Response: []models.Excursion{}, Query: dateRange},
+
+		{Method: "POST", Path: "/api/tasks", Tag: "Tasks", Summary: "Create a recurring task", Body: TaskInput{}, Status: http.StatusCreated, Response: IDResponse{}},
+		{Method: "GET", Path: "/api/tasks", Tag: "Tasks", Summary: "List tasks with their next due date", Response: []models.TaskDue{},
+			Query: []openapi.Param{{Name: "coop"}, {Name: "user_id", Type: "integer"}, {Name: "active", Type: "boolean"}, {Name: "overdue", Type: "boolean"}}},
+		{Method: "GET", Path: "/api/tasks/today", Tag: "Tasks", Summary: "Tasks due on a day or overdue", Response: []models.TaskDue{},
+			Query: []openapi.Param{{Name: "date", Type: "date", Description: "Default today."}, {Name: "user_id", Type: "integer"}, {Name: "coop"}}},
+		{Method: "GET", Path: "/api/tasks/:id", Tag: "Tasks", Summary: "Get a task with its completions", Response: TaskDetail{}},
+		{Method: "PUT", Path: "/api/tasks/:id", Tag: "Tasks", Summary: "Update a task", Body: TaskInput{}, Response: MessageResponse{}},
+		{Method: "DELETE", Path: "/api/tasks/:id", Tag: "Tasks", Summary: "Delete a task", Response: MessageResponse{}},
+		{Method: "POST", Path: "/api/tasks/:id/complete", Tag: "Tasks", Summary: "Complete a task occurrence", Body: TaskCompletionInput{}, Status: http.StatusCreated, Response: TaskCompleted{}},
+		{Method: "GET", Path: "/api/tasks/:id/completions", Tag: "Tasks", Summary: "List a task's completions", Response: []models.TaskCompletion{}},
+
+		{Method: "GET", Path: "/api/shelf-life", Tag: "Lots", Summary: "List shelf lives per species and storage", Response: []models.ShelfLife{}},
+		{Method: "PUT", Path: "/api/shelf-life/:species/:storage", Tag: "Lots", Summary: "Set a shelf life", Body: ShelfLifeInput{}, Response: MessageResponse{}},
+
+		{Method: "POST", Path: "/api/egg-weight-bands", Tag: "Egg weights", Summary: "Add a weight band", Body: EggWeightBandInput{}, Status: http.StatusCreated, Response: IDResponse{}},
+		{Method: "GET", Path: "/api/egg-weight-bands", Tag: "Egg weights", Summary: "List weight bands", Response: []models.EggWeightBand{},
+			Query: []openapi.Param{{Name: "species"}}},
+		{Method: "PUT", Path: "/api/egg-weight-bands/:id", Tag: "Egg weights", Summary: "Update a weight band", Body: EggWeightBandInput{}, Response: MessageResponse{}},
+		{Method: "DELETE", Path: "/api/egg-weight-bands/:id", Tag: "Egg weights", Summary: "Delete a weight band", Response: MessageResponse{}},
+		{Method: "POST", Path: "/api/egg-weight-bands/presets/:standard", Tag: "Egg weights", Summary: "Replace a species' bands with a grading standard, usda or eu", Response: MessageResponse{},
+			Query: []openapi.Param{{Name: "species", Description: "Default Chicken."}}},
+
+		{Method: "POST", Path: "/api/feed/types", Tag: "Feed", Summary: "Add a feed type", Body: FeedTypeInput{}, Status: http.StatusCreated, Response: IDResponse{}},
+		{Method: "GET", Path: "/api/feed/types", Tag: "Feed", Summary: "List feed types", Response: []models.FeedType{}},
+		{Method: "PUT", Path: "/api/feed/types/:id", Tag: "Feed", Summary: "Update a feed type", Body: FeedTypeInput{}, Response: MessageResponse{}},
+		{Method: "DELETE", Path: "/api/feed/types/:id", Tag: "Feed", Summary: "Delete a feed type without purchases or usage", Response: MessageResponse{}},
+		{Method: "POST", Path: "/api/feed/purchases", Tag: "Feed", Summary: "Record a feed purchase", Body: FeedPurchaseInput{}, Status: http.StatusCreated, Response: FeedPurchaseCreated{}},
+		{Method: "GET", Path: "/api/feed/purchases", Tag: "Feed", Summary: "List feed purchases", Response: []models.FeedPurchase{},
+			Query: []openapi.Param{{Name: "feed_type_id", Type: "integer"}}},
+		{Method: "DELETE", Path: "/api/feed/purchases/:id", Tag: "Feed", Summary: "Delete a feed purchase", Response: MessageResponse{}},
+	}...)
+	routes = append(routes, attachmentRoutes("/api/feed/purchases/:id/attachments", "Feed")...)
+	routes = append(routes, []openapi.Route{
+		{Method: "POST", Path: "/api/feed/usage", Tag: "Feed", Summary: "Record feed used", Body: FeedUsageInput{}, Status: http.StatusCreated, Response: FeedUsageCreated{}},
+		{Method: "GET", Path: "/api/feed/usage", Tag: "Feed", Summary: "List feed usage", Response: []models.FeedUsage{},
+			Query: []openapi.Param{{Name: "feed_type_id", Type: "integer"}, {Name: "coop"}}},
+		{Method: "DELETE", Path: "/api/feed/usage/:id", Tag: "Feed", Summary: "Delete a feed usage log", Response: MessageResponse{}},
+		{Method: "GET", Path: "/api/feed/balance", Tag: "Feed", Summary: "Feed on hand per type", Response: []models.FeedBalance{},
+			Query: []openapi.Param{{Name: "low_stock", Type: "boolean"}}},
+
+		{Method: "POST", Path: "/api/weather/import", Tag: "Weather", Summary: "Import daily weather from a file or the raw body", Response: WeatherImportResult{},
+			Form: []openapi.Param{{Name: "file", Type: "file", Required: true}},
+			Query: []openapi.Param{
+				{Name: "format", Enum: []string{"ghcn", "csv", "json"}, Description: "Guessed from the file name otherwise."},
+				{Name: "units", Enum: []string{"metric", "imperial"}, Description: "For CSV and JSON, default metric."},
+				{Name: "station", Description: "For rows that do not name one."},
+			}},
+		{Method: "GET", Path: "/api/weather", Tag: "Weather", Summary: "List imported days; default the last seven days", Response: []models.WeatherDay{},
+			Query: with(dateRange, openapi.Param{Name: "station"})},
+
+		{Method: "GET", Path: "/api/daylight", Tag: "Lighting", Summary: "Sunrise, sunset and day length; default the next seven days", Response: []models.Daylight{}, Query: dateRange},
+		{Method: "POST", Path: "/api/lighting", Tag: "Lighting", Summary: "Add a lighting schedule", Body: LightingScheduleInput{}, Status: http.StatusCreated, Response: IDResponse{}},
+		{Method: "GET", Path: "/api/lighting", Tag: "Lighting", Summary: "List lighting schedules", Response: []models.LightingSchedule{},
+			Query: []openapi.Param{{Name: "coop"}}},
+		{Method: "PUT", Path: "/api/lighting/:id", Tag: "Lighting", Summary: "Update a lighting schedule", Body: LightingScheduleInput{}, Response: MessageResponse{}},
+		{Method: "DELETE", Path: "/api/lighting/:id", Tag: "Lighting", Summary: "Delete a lighting schedule", Response: MessageResponse{}},
+
+		{Method: "GET", Path: "/api/attachments/:id/file", Tag: "Attachments", Summary: "Download an attachment", ContentType: "application/octet-stream"},
+		{Method: "GET", Path: "/api/attachments/:id/thumbnail", Tag: "Attachments", Summary: "Download an image attachment's thumbnail", ContentType: "image/jpeg"},
+		{Method: "DELETE", Path: "/api/attachments/:id", Tag: "Attachments", Summary: "Delete an attachment", Response: MessageResponse{}},
+
+		{Method: "GET", Path: "/api/reports", Tag: "Reports", Summary: "Series for the reports page", Response: ReportsResponse{}},
+		{Method: "GET", Path: "/api/reports/flock", Tag: "Reports", Summary: "Birds per coop per day", Response: FlockReport{}, Query: dateRange},
+		{Method: "GET", Path: "/api/reports/lay-rate", Tag: "Reports", Summary: "Hen-day lay rates", Response: LayRateReport{},
+			Query: with(dateRange, reportBy, reportPeriod)},
+		{Method: "GET", Path: "/api/reports/production-status", Tag: "Reports", Summary: "Molting and broody periods over collection trends", Response: ProductionStatusReport{},
+			Query: with(dateRange, reportPeriod)},
+		{Method: "GET", Path: "/api/reports/incubation", Tag: "Reports", Summary: "Fertility and hatch rates", Response: IncubationReport{},
+			Query: []openapi.Param{{Name: "by", Enum: []string{"species", "breed", "breeder_coop"}, Description: "Default species."}}},
+		{Method: "GET", Path: "/api/reports/feed-conversion", Tag: "Reports", Summary: "Feed per dozen eggs by coop and month", Response: FeedConversionReport{}, Query: dateRange},
+		{Method: "GET", Path: "/api/reports/egg-weight", Tag: "Reports", Summary: "Average egg weight trends", Response: EggWeightReport{},
+			Query: with(dateRange, reportBy, reportPeriod)},
+		{Method: "GET", Path: "/api/reports/storage-excursions", Tag: "Reports", Summary: "Temperature excursions with the lots affected", Response: StorageExcursionReport{}, Query: dateRange},
+		{Method: "GET", Path: "/api/reports/weather-production", Tag: "Reports", Summary: "Eggs collected by temperature", Response: WeatherProductionReport{},
+			Query: with(dateRange,
+				openapi.Param{Name: "temp", Enum: []string{"tmax", "tmin", "tavg"}, Description: "Default tmax."},
+				openapi.Param{Name: "band", Type: "number", Description: "Width of the temperature bands in °C, default 5."})},
+		{Method: "GET", Path: "/api/reports/light-lay-rate", Tag: "Reports", Summary: "Light hours against lay rates", Response: LightLayRateReport{},
+			Query: with(dateRange, reportPeriod)},
+		{Method: "GET", Path: "/api/reports/forecast", Tag: "Reports", Summary: "Forecast collections", Response: ForecastReport{},
+			Query: with(forecastBy, openapi.Param{Name: "weeks", Type: "integer", Description: "How far ahead, default 4."})},
+		{Method: "GET", Path: "/api/reports/forecast-accuracy", Tag: "Reports", Summary: "Score recorded forecasts", Response: ForecastAccuracyReport{}, Query: forecastBy},
+		{Method: "POST", Path: "/api/forecasts", Tag: "Reports", Summary: "Record a forecast to track its accuracy", Status: http.StatusCreated, Response: IDResponse{},
+			Query: with(forecastBy, openapi.Param{Name: "weeks", Type: "integer", Description: "How far ahead, default 4."})},
+
+		{Method: "GET", Path: "/api/alerts", Tag: "Alerts", Summary: "List alerts", Response: []models.Alert{},
+			Query: with(dateRange, openapi.Param{Name: "coop"}, openapi.Param{Name: "species"}, openapi.Param{Name: "acknowledged", Type: "boolean"})},
+		{Method: "POST", Path: "/api/alerts/detect", Tag: "Alerts", Summary: "Run anomaly detection for a day", Response: DetectionResult{},
+			Query: []openapi.Param{{Name: "date", Type: "date", Description: "Default yesterday."}}},
+		{Method: "POST", Path: "/api/alerts/:id/acknowledge", Tag: "Alerts", Summary: "Acknowledge an alert", Response: MessageResponse{}},
+
+		{Method: "POST", Path: "/api/etl/full", Tag: "System", Summary: "Rebuild the analytics database", Response: MessageResponse{}},
+		{Method: "POST", Path: "/api/backup", Tag: "System", Summary: "Back up the databases", Response: BackupResponse{}},
+
+		{Method: "GET", Path: "/api/users/:id/notification-preferences", Tag: "Notifications", Summary: "Get a user's notification preferences", Response: models.NotificationPreferences{}},
+		{Method: "PUT", Path: "/api/users/:id/notification-preferences", Tag: "Notifications", Summary: "Update a user's notification preferences", Body: NotificationPreferencesInput{}, Response: MessageResponse{}},
+		{Method: "POST", Path: "/api/users/:id/notifications/test", Tag: "Notifications", Summary: "Send a user a test notification", Status: http.StatusAccepted, Response: MessageResponse{}},
+		{Method: "GET", Path: "/api/notifications/deliveries", Tag: "Notifications", Summary: "Notification delivery log", Response: []models.NotificationDelivery{},
+			Query: []openapi.Param{{Name: "status", Enum: []string{"pending", "sent", "failed"}}, {Name: "kind"}, {Name: "user_id", Type: "integer"}, limitParam("100")}},
+
+		{Method: "GET", Path: "/api/events", Tag: "Events", Summary: "Live events as Server-Sent Events", ContentType: "text/event-stream", Auth: true,
+			Query: []openapi.Param{
+				{Name: "types", Description: "Comma-separated event types; inventory.* for a group."},
+				{Name: "coop", Description: "Inventory events for one coop only."},
+				{Name: "last_event_id", Type: "integer", Description: "Replay events after this one, like the Last-Event-ID header."},
+				{Name: "access_token", Description: "For clients that cannot set the Authorization header."},
+			}},
+
+		{Method: "GET", Path: "/api/mqtt/mappings", Tag: "Sensors", Summary: "List MQTT mappings", Response: []models.MQTTMapping{}},
+		{Method: "POST", Path: "/api/mqtt/mappings", Tag: "Sensors", Summary: "Add an MQTT mapping", Body: MQTTMappingInput{}, Status: http.StatusCreated, Response: IDResponse{}},
+		{Method: "PUT", Path: "/api/mqtt/mappings/:id", Tag: "Sensors", Summary: "Update an MQTT mapping", Body: MQTTMappingInput{}, Response: MessageResponse{}},
+		{Method: "DELETE", Path: "/api/mqtt/mappings/:id", Tag: "Sensors", Summary: "Delete an MQTT mapping", Response: MessageResponse{}},
+		{Method: "POST", Path: "/api/mqtt/mappings/:id/preview", Tag: "Sensors", Summary: "Render a mapping against a sample message", Body: MQTTPreviewInput{}, Response: MQTTPreview{}},
+		{Method: "GET", Path: "/api/coop-readings", Tag: "Sensors", Summary: "List coop sensor readings", Response: []models.CoopReading{},
+			Query: with(dateRange, openapi.Param{Name: "coop"}, openapi.Param{Name: "metric"}, limitParam("500"))},
+
+		{Method: "GET", Path: "/api/webhooks", Tag: "Webhooks", Summary: "List webhooks", Response: []models.Webhook{}},
+		{Method: "POST", Path: "/api/webhooks", Tag: "Webhooks", Summary: "Add a webhook; the secret is only returned here", Body: WebhookInput{}, Status: http.StatusCreated, Response: WebhookCreated{}},
+		{Method: "GET", Path: "/api/webhooks/:id", Tag: "Webhooks", Summary: "Get a webhook", Response: models.Webhook{}},
+		{Method: "PUT", Path: "/api/webhooks/:id", Tag: "Webhooks", Summary: "Update a webhook", Body: WebhookInput{}, Response: MessageResponse{}},
+		{Method: "DELETE", Path: "/api/webhooks/:id", Tag: "Webhooks", Summary: "Delete a webhook", Response: MessageResponse{}},
+		{Method: "GET", Path: "/api/webhooks/:id/deliveries", Tag: "Webhooks", Summary: "A webhook's delivery history", Response: []models.WebhookDelivery{},
+			Query: []openapi.Param{{Name: "status", Enum: []string{"pending", "delivered", "failed"}}, {Name: "event_type"}, limitParam("100")}},
+		{Method: "POST", Path: "/api/webhooks/:id/deliveries/:delivery_id/redeliver", Tag: "Webhooks", Summary: "Send a delivery's event again", Status: http.StatusAccepted, Response: IDResponse{}},
+	}...)
+	return routes
+}
@@ -2,9 +2,12 @@ package handlers
 
 import (
 	"database/sql"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
 
+	"egg-tracker/backend/logging"
 	"egg-tracker/backend/models"
 
 	"github.com/gin-gonic/gin"
@@ -24,22 +27,40 @@ func getOptionTable(optionType string) (string, bool) {
 		return "egg_sizes", true
 	case "coop":
 		return "coops", true
+	case "storageunit":
+		return "storage_units", true
 	default:
 		return "", false
 	}
 }
 
+// publishOptionEvent publishes an option event with the option's current
+// name, unless the option does not exist.
+func publishOptionEvent(db *sql.DB, eventType, table, optionType, id string) {
+	var optionID int64
+	var name string
+	err := db.QueryRow("SELECT id, name FROM "+table+" WHERE id = ?", id).Scan(&optionID, &name)
+	if err == sql.ErrNoRows {
+		return
+	}
+	if err != nil {
+		slog.Error("Could not load option", "component", "publishOptionEvent", "option_type", optionType, "id", id, logging.Err(err))
+		return
+	}
+	publishEvent(db, eventType, gin.H{"type": strings.ToLower(optionType), "id": optionID, "name": name})
+}
+
 func ListOptionsHandler(db *sql.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		typeStr := c.Param("type")
 		table, ok := getOptionTable(typeStr)
 		if !ok {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid option type"})
+			respondError(c, http.StatusBadRequest, "invalid option type")
 			return
 		}
 		rows, err := db.Query("SELECT id, name, active, created_at, updated_at FROM " + table + " ORDER BY name ASC")
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+			respondServerError(c, err)
 			return
 		}
 		defer rows.Close()
@@ -47,7 +68,7 @@ func ListOptionsHandler(db *sql.DB) gin.HandlerFunc {
 		for rows.Next() {
 			var opt models.OptionBase
 			if err := rows.Scan(&opt.ID, &opt.Name, &opt.Active, &opt.CreatedAt, &opt.UpdatedAt); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+				respondServerError(c, err)
 				return
 			}
 			options = append(options, opt)
@@ -61,21 +82,22 @@ func AddOptionHandler(db *sql.DB) gin.HandlerFunc {
 		typeStr := c.Param("type")
 		table, ok := getOptionTable(typeStr)
 		if !ok {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid option type"})
+			respondError(c, http.StatusBadRequest, "invalid option type")
 			return
 		}
 		var input OptionInput
 		if err := c.ShouldBindJSON(&input); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+			respondBindError(c, err)
 			return
 		}
 		res, err := db.Exec("INSERT INTO "+table+" (name, active) VALUES (?, 1)", input.Name)
 		if err != nil {
-			c.JSON(http.StatusConflict, gin.H{"error": "option already exists"})
+			respondWriteError(c, err, "option already exists")
 			return
 		}
 		id, _ := res.LastInsertId()
-		c.JSON(http.StatusCreated, gin.H{"id": id})
+		publishOptionEvent(db, eventOptionCreated, table, typeStr, strconv.FormatInt(id, 10))
+		c.JSON(http.StatusCreated, IDResponse{ID: id})
 	}
 }
 
@@ -84,21 +106,22 @@ func EditOptionHandler(db *sql.DB) gin.HandlerFunc {
 		typeStr := c.Param("type")
 		table, ok := getOptionTable(typeStr)
 		if !ok {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid option type"})
+			respondError(c, http.StatusBadRequest, "invalid option type")
 			return
 		}
 		id := c.Param("id")
 		var input OptionInput
 		if err := c.ShouldBindJSON(&input); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+			respondBindError(c, err)
 			return
 		}
 		_, err := db.Exec("UPDATE "+table+" SET name = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", input.Name, id)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+			respondServerError(c, err)
 			return
 		}
-		c.JSON(http.StatusOK, gin.H{"message": "updated"})
+		publishOptionEvent(db, eventOptionUpdated, table, typeStr, id)
+		c.JSON(http.StatusOK, MessageResponse{Message: "updated"})
 	}
 }
 
@@ -107,16 +130,17 @@ func DeactivateOptionHandler(db *sql.DB) gin.HandlerFunc {
 		typeStr := c.Param("type")
 		table, ok := getOptionTable(typeStr)
 		if !ok {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid option type"})
+			respondError(c, http.StatusBadRequest, "invalid option type")
 			return
 		}
 		id := c.Param("id")
 		_, err := db.Exec("UPDATE "+table+" SET active = 0, updated_at = CURRENT_TIMESTAMP WHERE id = ?", id)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+			respondServerError(c, err)
 			return
 		}
-		c.JSON(http.StatusOK, gin.H{"message": "deactivated"})
+		publishOptionEvent(db, eventOptionDeactivated, table, typeStr, id)
+		c.JSON(http.StatusOK, MessageResponse{Message: "deactivated"})
 	}
 }
 
@@ -125,15 +149,16 @@ func ReactivateOptionHandler(db *sql.DB) gin.HandlerFunc {
 		typeStr := c.Param("type")
 		table, ok := getOptionTable(typeStr)
 		if !ok {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid option type"})
+			respondError(c, http.StatusBadRequest, "invalid option type")
 			return
 		}
 		id := c.Param("id")
 		_, err := db.Exec("UPDATE "+table+" SET active = 1, updated_at = CURRENT_TIMESTAMP WHERE id = ?", id)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+			respondServerError(c, err)
 			return
 		}
-		c.JSON(http.StatusOK, gin.H{"message": "reactivated"})
+		publishOptionEvent(db, eventOptionReactivated, table, typeStr, id)
+		c.JSON(http.StatusOK, MessageResponse{Message: "reactivated"})
 	}
 }
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"egg-tracker/backend/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ProductionStatus struct {
+	Period        string   `json:"period"`
+	Coop          string   `json:"coop"`
+	Eggs          int      `json:"eggs"`
+	BirdDays      int      `json:"bird_days"`
+	MoltingDays   int      `json:"molting_days"`
+	BroodyDays    int      `json:"broody_days"`
+	OutOfLayShare *float64 `json:"out_of_lay_share"` // fraction of bird-days molting or broody; null without birds
+}
+
+type StatusPeriodSpan struct {
+	BirdID    *int64  `json:"bird_id,omitempty"`
+	Coop      string  `json:"coop"`
+	Status    string  `json:"status"`
+	StartDate string  `json:"start_date"`
+	EndDate   *string `json:"end_date,omitempty"`
+}
+
+// queryProductionStatus overlays molting and broody periods on collections per
+// coop. A period without a bird applies to every bird in its coop.
+func queryProductionStatus(duck *sql.DB, from, to time.Time, period string) ([]ProductionStatus, error) {
+	format := periodFormats[period]
+	query := birdDaysSQL + `,
+	sp AS (
+		SELECT bird_id, coop, status, CAST(start_date AS DATE) AS s,
+			COALESCE(CAST(end_date AS DATE), DATE '9999-12-31') AS e
+		FROM bird_status_periods
+		WHERE status IN ('molting', 'broody')
+	),
+	flags AS (
+		SELECT bd.day, bd.coop, bd.bird_id,
+			MAX(CASE WHEN sp.status = 'molting' THEN 1 ELSE 0 END) AS molting,
+			MAX(CASE WHEN sp.status = 'broody' THEN 1 ELSE 0 END) AS broody
+		FROM bird_days bd
+		LEFT JOIN sp ON (sp.bird_id = bd.bird_id OR (sp.bird_id IS NULL AND sp.coop = bd.coop))
+			AND bd.day BETWEEN sp.s AND sp.e
+		GROUP BY 1, 2, 3
+	),
+	agg AS (
+		SELECT strftime(day, '` + format + `') AS period, coop, COUNT(*) AS bird_days,
+			SUM(molting) AS molting_days, SUM(broody) AS broody_days,
+			SUM(GREATEST(molting, broody)) AS out_days
+		FROM flags GROUP BY 1, 2
+	),
+	eggs AS (
+		SELECT strftime(CAST(date AS DATE), '` + format + `') AS period, COALESCE(coop, '') AS coop, SUM(quantity) AS eggs
+		FROM inventory_actions
+		WHERE action = 'collected' AND CAST(date AS DATE) BETWEEN CAST(? AS DATE) AND CAST(? AS DATE)
+		GROUP BY 1, 2
+	)
+	SELECT COALESCE(a.period, e.period) AS period, COALESCE(a.coop, e.coop) AS coop,
+		CAST(COALESCE(e.eggs, 0) AS BIGINT), COALESCE(a.bird_days, 0),
+		CAST(COALESCE(a.molting_days, 0) AS BIGINT), CAST(COALESCE(a.broody_days, 0) AS BIGINT),
+		CAST(COALESCE(a.out_days, 0) AS BIGINT)
+	FROM agg a FULL OUTER JOIN eggs e ON a.period = e.period AND a.coop = e.coop
+	ORDER BY period ASC, coop ASC
+	`
+	fromStr, toStr := from.Format("2006-01-02"), to.Format("2006-01-02")
+	rows, err := duck.Query(query, fromStr, toStr, fromStr, toStr)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	trend := []ProductionStatus{}
+	for rows.Next() {
+		var ps ProductionStatus
+		var outDays int
+		if err := rows.Scan(&ps.Period, &ps.Coop, &ps.Eggs, &ps.BirdDays, &ps.MoltingDays, &ps.BroodyDays, &outDays); err != nil {
+			return nil, err
+		}
+		if ps.BirdDays > 0 {
+			share := float64(outDays) / float64(ps.BirdDays)
+			ps.OutOfLayShare = &share
+		}
+		trend = append(trend, ps)
+	}
+	return trend, rows.Err()
+}
+
+// queryStatusSpans returns the status periods overlapping [from, to] so the
+// client can shade them on the collection chart.
+func queryStatusSpans(duck *sql.DB, from, to time.Time) ([]StatusPeriodSpan, error) {
+	rows, err := duck.Query(`
+		SELECT bird_id, coop, status, strftime(CAST(start_date AS DATE), '%Y-%m-%d'),
+			CASE WHEN end_date IS NULL THEN NULL ELSE strftime(CAST(end_date AS DATE), '%Y-%m-%d') END
+		FROM bird_status_periods
+		WHERE CAST(start_date AS DATE) <= CAST(? AS DATE)
+			AND (end_date IS NULL OR CAST(end_date AS DATE) >= CAST(? AS DATE))
+		ORDER BY start_date ASC, id ASC
+	`, to.Format("2006-01-02"), from.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	spans := []StatusPeriodSpan{}
+	for rows.Next() {
+		var s StatusPeriodSpan
+		var birdID sql.NullInt64
+		var end sql.NullString
+		if err := rows.Scan(&birdID, &s.Coop, &s.Status, &s.StartDate, &end); err != nil {
+			return nil, err
+		}
+		if birdID.Valid {
+			s.BirdID = &birdID.Int64
+		}
+		if end.Valid {
+			s.EndDate = &end.String
+		}
+		spans = append(spans, s)
+	}
+	return spans, rows.Err()
+}
+
+// ProductionStatusReport is the response of ProductionStatusReportHandler.
+type ProductionStatusReport struct {
+	Period        string             `json:"period"`
+	Trend         []ProductionStatus `json:"trend"`
+	StatusPeriods []StatusPeriodSpan `json:"statusPeriods"`
+}
+
+// ProductionStatusReportHandler overlays molting/broody periods on collection
+// trends per coop. Query parameters: period=day|week|month (default week),
+// from/to as YYYY-MM-DD (default: first collection to today).
+func ProductionStatusReportHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		period := c.DefaultQuery("period", "week")
+		if _, ok := periodFormats[period]; !ok {
+			respondError(c, http.StatusBadRequest, "period must be day, week or month")
+			return
+		}
+
+		duckdb, err := sql.Open("duckdb", analyticsDBPath)
+		if err != nil {
+			requestLogger(c, "ProductionStatusReportHandler").Error("Failed to open DuckDB", logging.Err(err))
+			respondError(c, http.StatusInternalServerError, "failed to open DuckDB")
+			return
+		}
+		defer duckdb.Close()
+
+		from, to, err := reportRange(c, func() (time.Time, error) { return firstCollectionDate(duckdb) })
+		if err != nil {
+			respondInputError(c, err)
+			return
+		}
+		trend, err := queryProductionStatus(duckdb, from, to, period)
+		if err != nil {
+			requestLogger(c, "ProductionStatusReportHandler").Error("Production status query failed", logging.Err(err))
+			respondError(c, http.StatusInternalServerError, "production status query failed")
+			return
+		}
+		spans, err := queryStatusSpans(duckdb, from, to)
+		if err != nil {
+			requestLogger(c, "ProductionStatusReportHandler").Error("Status periods query failed", logging.Err(err))
+			respondError(c, http.StatusInternalServerError, "status periods query failed")
+			return
+		}
+		c.JSON(http.StatusOK, ProductionStatusReport{Period: period, Trend: trend, StatusPeriods: spans})
+	}
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestProductionStatusReport_OverlaysPeriods(t *testing.T) {
+	cleanup := setupAnalyticsTestDB(t, func(dbase *sql.DB) {
+		mustExec(t, dbase, `INSERT INTO birds (id, name, species, coop, status) VALUES
+			(1, 'A', 'Chicken', 'Main Coop', 'active'),
+			(2, 'B', 'Chicken', 'Main Coop', 'active'),
+			(3, 'C', 'Chicken', 'Back Barn', 'active')`)
+		mustExec(t, dbase, `INSERT INTO bird_transfers (bird_id, from_coop, to_coop, date) VALUES
+			(1, NULL, 'Main Coop', '2025-05-01'),
+			(2, NULL, 'Main Coop', '2025-05-01'),
+			(3, NULL, 'Back Barn', '2025-05-01')`)
+		// Bird 1 broody for both days, whole Back Barn molting on day two
+		mustExec(t, dbase, `INSERT INTO bird_status_periods (bird_id, coop, status, start_date, end_date) VALUES
+			(1, 'Main Coop', 'broody', '2025-05-01', NULL),
+			(NULL, 'Back Barn', 'molting', '2025-05-02', '2025-05-02'),
+			(2, 'Main Coop', 'laying', '2025-04-01', NULL)`)
+		mustExec(t, dbase, `INSERT INTO inventory_actions (quantity, species, coop, action, date) VALUES
+			(2, 'Chicken', 'Main Coop', 'collected', '2025-05-01'),
+			(1, 'Chicken', 'Back Barn', 'collected', '2025-05-01')`)
+	})
+	defer cleanup()
+
+	router := gin.Default()
+	router.GET("/api/reports/production-status", ProductionStatusReportHandler())
+	req, _ := http.NewRequest("GET", "/api/reports/production-status?period=month&from=2025-05-01&to=2025-05-02", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Trend         []ProductionStatus `json:"trend"`
+		StatusPeriods []StatusPeriodSpan `json:"statusPeriods"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Trend) != 2 {
+		t.Fatalf("expected 2 rows, got %+v", resp.Trend)
+	}
+	back, main := resp.Trend[0], resp.Trend[1]
+	if back.Coop != "Back Barn" || back.BirdDays != 2 || back.MoltingDays != 1 || back.BroodyDays != 0 || *back.OutOfLayShare != 0.5 {
+		t.Errorf("unexpected Back Barn row: %+v", back)
+	}
+	if main.Coop != "Main Coop" || main.Eggs != 2 || main.BirdDays != 4 || main.BroodyDays != 2 || *main.OutOfLayShare != 0.5 {
+		t.Errorf("unexpected Main Coop row: %+v", main)
+	}
+	if len(resp.StatusPeriods) != 3 {
+		t.Errorf("expected 3 overlapping status periods, got %+v", resp.StatusPeriods)
+	}
+}
@@ -2,27 +2,49 @@ package handlers
 
 import (
 	"database/sql"
-	"log"
+	"log/slog"
 	"net/http"
 
+	"egg-tracker/backend/logging"
+
 	"github.com/gin-gonic/gin"
 	_ "github.com/marcboeker/go-duckdb"
 )
 
+// analyticsDBPath is the DuckDB file populated by the ETL and read by the reports.
+var analyticsDBPath = "/app/data/eggtracker.duckdb"
+
+// requestLogger returns the logger for the request, tagged with the
+// component writing to it.
+func requestLogger(c *gin.Context, component string) *slog.Logger {
+	return logging.FromContext(c.Request.Context()).With("component", component)
+}
+
+// ReportsResponse holds the series shown on the reports page.
+type ReportsResponse struct {
+	EggsOverTime       []map[string]interface{} `json:"eggsOverTime"`
+	InventoryTrends    []map[string]interface{} `json:"inventoryTrends"`
+	AvgEggsPerCoop     []map[string]interface{} `json:"avgEggsPerCoop"`
+	EggsByWeek         []map[string]interface{} `json:"eggsByWeek"`
+	InventoryBySpecies []map[string]interface{} `json:"inventoryBySpecies"`
+	TopSpecies         []map[string]interface{} `json:"topSpecies"`
+	NetTotals          []map[string]interface{} `json:"netTotals"`
+}
+
 // ReportsHandler returns analytics from DuckDB for the reports page.
 func ReportsHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		log.Println("[ReportsHandler] Opening DuckDB connection...")
-		duckdb, err := sql.Open("duckdb", "/app/data/eggtracker.duckdb")
+		logger := requestLogger(c, "ReportsHandler")
+		duckdb, err := sql.Open("duckdb", analyticsDBPath)
 		if err != nil {
-			log.Printf("[ReportsHandler] Failed to open DuckDB: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to open DuckDB"})
+			logger.Error("Failed to open DuckDB", logging.Err(err))
+			respondError(c, http.StatusInternalServerError, "failed to open DuckDB")
 			return
 		}
 		defer duckdb.Close()
 
 		// 1. Eggs over time by species (from inventory_actions, action = 'collected')
-		log.Println("[ReportsHandler] Querying eggs over time by species from inventory_actions...")
+		logger.Debug("Querying eggs over time by species")
 		eggsRows, err := duckdb.Query(`
 			SELECT date, species, SUM(quantity) as count
 			FROM inventory_actions
@@ -31,8 +53,8 @@ func ReportsHandler() gin.HandlerFunc {
 			ORDER BY date ASC
 		`)
 		if err != nil {
-			log.Printf("[ReportsHandler] Eggs query failed: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "eggs query failed"})
+			logger.Error("Eggs query failed", logging.Err(err))
+			respondError(c, http.StatusInternalServerError, "eggs query failed")
 			return
 		}
 		eggsMap := map[string]map[string]int{}
@@ -47,7 +69,7 @@ func ReportsHandler() gin.HandlerFunc {
 			eggsMap[date][species] = count
 			rowCount++
 		}
-		log.Printf("[ReportsHandler] Eggs query returned %d rows", rowCount)
+		logger.Debug("Eggs query returned", "rows", rowCount)
 		eggsRows.Close()
 		var eggsOverTime []map[string]interface{}
 		for date, speciesCounts := range eggsMap {
@@ -59,7 +81,7 @@ func ReportsHandler() gin.HandlerFunc {
 		}
 
 		// 2. Inventory trends by action
-		log.Println("[ReportsHandler] Querying inventory trends by action...")
+		logger.Debug("Querying inventory trends by action")
 		invRows, err := duckdb.Query(`
 			SELECT date, action, SUM(quantity) as qty
 			FROM inventory_actions
@@ -67,8 +89,8 @@ func ReportsHandler() gin.HandlerFunc {
 			ORDER BY date ASC
 		`)
 		if err != nil {
-			log.Printf("[ReportsHandler] Inventory query failed: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "inventory query failed"})
+			logger.Error("Inventory query failed", logging.Err(err))
+			respondError(c, http.StatusInternalServerError, "inventory query failed")
 			return
 		}
 		invMap := map[string]map[string]int{}
@@ -83,7 +105,7 @@ func ReportsHandler() gin.HandlerFunc {
 			invMap[date][action] = qty
 			invRowCount++
 		}
-		log.Printf("[ReportsHandler] Inventory query returned %d rows", invRowCount)
+		logger.Debug("Inventory query returned", "rows", invRowCount)
 		invRows.Close()
 		var inventoryTrends []map[string]interface{}
 		for date, actions := range invMap {
@@ -95,7 +117,7 @@ func ReportsHandler() gin.HandlerFunc {
 		}
 
 		// 3. Average eggs/day per coop (from inventory_actions, action = 'collected')
-		log.Println("[ReportsHandler] Querying average eggs/day per coop from inventory_actions...")
+		logger.Debug("Querying average eggs/day per coop")
 		avgRows, err := duckdb.Query(`
 			SELECT coop, AVG(cnt) as avg
 			FROM (
@@ -107,8 +129,8 @@ func ReportsHandler() gin.HandlerFunc {
 			GROUP BY coop
 		`)
 		if err != nil {
-			log.Printf("[ReportsHandler] Avg eggs/coop query failed: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "avg eggs/coop query failed"})
+			logger.Error("Avg eggs/coop query failed", logging.Err(err))
+			respondError(c, http.StatusInternalServerError, "avg eggs/coop query failed")
 			return
 		}
 		var avgEggsPerCoop []map[string]interface{}
@@ -123,11 +145,11 @@ func ReportsHandler() gin.HandlerFunc {
 			})
 			avgRowCount++
 		}
-		log.Printf("[ReportsHandler] Avg eggs/coop query returned %d rows", avgRowCount)
+		logger.Debug("Avg eggs/coop query returned", "rows", avgRowCount)
 		avgRows.Close()
 
 		// 4. Eggs collected by week (all species)
-		log.Println("[ReportsHandler] Querying eggs collected by week...")
+		logger.Debug("Querying eggs collected by week")
 		weeklyRows, err := duckdb.Query(`
 			SELECT strftime(date, '%Y-%W') as week, SUM(quantity) as count
 			FROM inventory_actions
@@ -136,8 +158,8 @@ func ReportsHandler() gin.HandlerFunc {
 			ORDER BY week ASC
 		`)
 		if err != nil {
-			log.Printf("[ReportsHandler] Weekly eggs query failed: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "weekly eggs query failed"})
+			logger.Error("Weekly eggs query failed", logging.Err(err))
+			respondError(c, http.StatusInternalServerError, "weekly eggs query failed")
 			return
 		}
 		var eggsByWeek []map[string]interface{}
@@ -153,15 +175,15 @@ func ReportsHandler() gin.HandlerFunc {
 		weeklyRows.Close()
 
 		// 5. Inventory actions by species
-		log.Println("[ReportsHandler] Querying inventory actions by species...")
+		logger.Debug("Querying inventory actions by species")
 		speciesRows, err := duckdb.Query(`
 			SELECT species, action, SUM(quantity) as qty
 			FROM inventory_actions
 			GROUP BY species, action
 		`)
 		if err != nil {
-			log.Printf("[ReportsHandler] Inventory by species query failed: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "inventory by species query failed"})
+			logger.Error("Inventory by species query failed", logging.Err(err))
+			respondError(c, http.StatusInternalServerError, "inventory by species query failed")
 			return
 		}
 		speciesActionMap := map[string]map[string]int{}
@@ -193,12 +215,13 @@ func ReportsHandler() gin.HandlerFunc {
 			net -= actions["consumed"]
 			net -= actions["gifted"]
 			net -= actions["spoiled"]
+			net -= actions["incubated"]
 			row := map[string]interface{}{"species": species, "net": net}
 			netTotals = append(netTotals, row)
 		}
 
 		// 6. Top producing species (total eggs collected per species)
-		log.Println("[ReportsHandler] Querying top producing species...")
+		logger.Debug("Querying top producing species")
 		topRows, err := duckdb.Query(`
 			SELECT species, SUM(quantity) as total
 			FROM inventory_actions
@@ -207,8 +230,8 @@ func ReportsHandler() gin.HandlerFunc {
 			ORDER BY total DESC
 		`)
 		if err != nil {
-			log.Printf("[ReportsHandler] Top species query failed: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "top species query failed"})
+			logger.Error("Top species query failed", logging.Err(err))
+			respondError(c, http.StatusInternalServerError, "top species query failed")
 			return
 		}
 		var topSpecies []map[string]interface{}
@@ -223,15 +246,14 @@ func ReportsHandler() gin.HandlerFunc {
 		}
 		topRows.Close()
 
-		log.Println("[ReportsHandler] Returning JSON response.")
-		c.JSON(http.StatusOK, gin.H{
-			"eggsOverTime":       eggsOverTime,
-			"inventoryTrends":    inventoryTrends,
-			"avgEggsPerCoop":     avgEggsPerCoop,
-			"eggsByWeek":         eggsByWeek,
-			"inventoryBySpecies": inventoryBySpecies,
-			"topSpecies":         topSpecies,
-			"netTotals":          netTotals,
+		c.JSON(http.StatusOK, ReportsResponse{
+			EggsOverTime:       eggsOverTime,
+			InventoryTrends:    inventoryTrends,
+			AvgEggsPerCoop:     avgEggsPerCoop,
+			EggsByWeek:         eggsByWeek,
+			InventoryBySpecies: inventoryBySpecies,
+			TopSpecies:         topSpecies,
+			NetTotals:          netTotals,
 		})
 	}
 }
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"egg-tracker/backend/ingest"
+	"egg-tracker/backend/openapi"
+)
+
+// IDResponse is returned when a record is created.
+type IDResponse struct {
+	ID int64 `json:"id"`
+}
+
+// MessageResponse confirms an update, deletion or other action, e.g.
+// "updated" or "deleted".
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// ErrorResponse is the body of every error. Code is one of the Code
+// constants; Details says what is wrong with each field, when that is known.
+type ErrorResponse struct {
+	Error   string            `json:"error"`
+	Code    string            `json:"code"`
+	Details []openapi.Problem `json:"details,omitempty"`
+}
+
+// TokenResponse carries a new access token; the refresh token is set as
+// an HTTP-only cookie.
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+}
+
+// HealthResponse reports whether the server is up. Status is "degraded"
+// when a configured MQTT broker is unreachable.
+type HealthResponse struct {
+	Status string         `json:"status"`
+	MQTT   *ingest.Health `json:"mqtt,omitempty"`
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"egg-tracker/backend/pdf"
+
+	"github.com/gin-gonic/gin"
+)
+
+// loadFarm builds the PDF letterhead from the stored settings.
+func loadFarm(db *sql.DB) (pdf.Farm, error) {
+	values, err := loadSettings(db)
+	if err != nil {
+		return pdf.Farm{}, err
+	}
+	farm := pdf.Farm{
+		Name:    values[settingFarmName],
+		Address: values[settingFarmAddress],
+	}
+	if farm.Name == "" {
+		farm.Name = "Egg Tracker Farm"
+	}
+	if logo := values[settingFarmLogo]; logo != "" {
+		if data, err := base64.StdEncoding.DecodeString(logo); err == nil {
+			farm.Logo = data
+		}
+	}
+	return farm, nil
+}
+
+func saleDescription(species, size, color string) string {
+	parts := []string{}
+	for _, p := range []string{size, color, species} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, " ") + " eggs"
+}
+
+func writePDF(c *gin.Context, filename string, farm pdf.Farm, doc pdf.Document) {
+	var buf bytes.Buffer
+	if err := pdf.Render(&buf, farm, doc); err != nil {
+		respondServerError(c, err)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", filename))
+	c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+}
+
+// SaleReceiptHandler renders a PDF receipt for a single "sold" inventory action.
+func SaleReceiptHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var (
+			saleID                  int64
+			quantity                int
+			species                 string
+			eggColor, eggSize, cust sql.NullString
+			unitPrice               sql.NullFloat64
+			date                    time.Time
+		)
+		err := db.QueryRow(
+			"SELECT id, quantity, species, egg_color, egg_size, customer, unit_price, date FROM inventory_actions WHERE id = ? AND action = 'sold'",
+			id,
+		).Scan(&saleID, &quantity, &species, &eggColor, &eggSize, &cust, &unitPrice, &date)
+		if err == sql.ErrNoRows {
+			respondError(c, http.StatusNotFound, "sale not found")
+			return
+		} else if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		farm, err := loadFarm(db)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		var lots sql.NullString
+		err = db.QueryRow(
+			`SELECT group_concat(a.lot_code, ', ') FROM lot_draws d
+			JOIN inventory_actions a ON a.id = d.lot_action_id
+			WHERE d.action_id = ?`,
+			saleID,
+		).Scan(&lots)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		item := pdf.LineItem{
+			Date:        date,
+			Description: saleDescription(species, eggSize.String, eggColor.String),
+			Quantity:    quantity,
+		}
+		// Printing lot codes lets customers report problem eggs precisely.
+		if lots.Valid && lots.String != "" {
+			item.Description += " (lot " + lots.String + ")"
+		}
+		if unitPrice.Valid {
+			item.UnitPrice = &unitPrice.Float64
+		}
+		doc := pdf.Document{
+			Title:    "Receipt",
+			Number:   fmt.Sprintf("R-%06d", saleID),
+			Date:     date,
+			Customer: cust.String,
+			Items:    []pdf.LineItem{item},
+		}
+		writePDF(c, fmt.Sprintf("receipt-%d.pdf", saleID), farm, doc)
+	}
+}
+
+// CustomerInvoiceHandler renders a monthly PDF invoice of every sale to a customer.
+// The month parameter is formatted as YYYY-MM.
+func CustomerInvoiceHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		customer := c.Param("customer")
+		month, err := time.Parse("2006-01", c.Param("month"))
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid month")
+			return
+		}
+		rows, err := db.Query(
+			`SELECT quantity, species, egg_color, egg_size, unit_price, date FROM inventory_actions
+			WHERE action = 'sold' AND customer = ? AND date(date) >= date(?) AND date(date) < date(?)
+			ORDER BY date ASC, id ASC`,
+			customer, month.Format("2006-01-02"), month.AddDate(0, 1, 0).Format("2006-01-02"),
+		)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		defer rows.Close()
+		var items []pdf.LineItem
+		for rows.Next() {
+			var (
+				item              pdf.LineItem
+				species           string
+				eggColor, eggSize sql.NullString
+				unitPrice         sql.NullFloat64
+			)
+			if err := rows.Scan(&item.Quantity, &species, &eggColor, &eggSize, &unitPrice, &item.Date); err != nil {
+				respondServerError(c, err)
+				return
+			}
+			item.Description = saleDescription(species, eggSize.String, eggColor.String)
+			if unitPrice.Valid {
+				price := unitPrice.Float64
+				item.UnitPrice = &price
+			}
+			items = append(items, item)
+		}
+		if len(items) == 0 {
+			respondError(c, http.StatusNotFound, "no sales for customer in month")
+			return
+		}
+		farm, err := loadFarm(db)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		doc := pdf.Document{
+			Title:    "Invoice",
+			Number:   "INV-" + month.Format("200601") + "-" + slug(customer),
+			Date:     month.AddDate(0, 1, -1),
+			Customer: customer,
+			Items:    items,
+		}
+		writePDF(c, fmt.Sprintf("invoice-%s-%s.pdf", slug(customer), month.Format("2006-01")), farm, doc)
+	}
+}
+
+// slug reduces a free-text name to a filename-safe identifier.
+func slug(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case b.Len() > 0 && !strings.HasSuffix(b.String(), "-"):
+			b.WriteByte('-')
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"egg-tracker/backend/db"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupSalesTestDB() (*sql.DB, func()) {
+	testDBPath := "test_sales.db"
+	dbase, _ := sql.Open("sqlite3", testDBPath)
+	db.Migrate(dbase)
+	return dbase, func() {
+		dbase.Close()
+		os.Remove(testDBPath)
+	}
+}
+
+func insertSale(t *testing.T, dbase *sql.DB, action, customer string, qty int, price float64, date string) int64 {
+	d, _ := time.Parse("2006-01-02", date)
+	res, err := dbase.Exec(
+		"INSERT INTO inventory_actions (quantity, species, coop, egg_color, egg_size, action, date, customer, unit_price) VALUES (?, 'Chicken', 'Main Coop', 'Brown', 'Large', ?, ?, ?, ?)",
+		qty, action, d, customer, price,
+	)
+	if err != nil {
+		t.Fatalf("insert sale: %v", err)
+	}
+	id, _ := res.LastInsertId()
+	return id
+}
+
+func TestSaleReceipt(t *testing.T) {
+	dbase, cleanup := setupSalesTestDB()
+	defer cleanup()
+	router := gin.Default()
+	router.GET("/api/sales/:id/receipt.pdf", SaleReceiptHandler(dbase))
+
+	soldID := insertSale(t, dbase, "sold", "Jane Doe", 12, 0.5, "2025-05-03")
+	collectedID := insertSale(t, dbase, "collected", "", 12, 0, "2025-05-03")
+
+	req, _ := http.NewRequest("GET", "/api/sales/"+itoa(soldID)+"/receipt.pdf", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/pdf" {
+		t.Errorf("expected application/pdf, got %s", ct)
+	}
+	if !bytes.HasPrefix(w.Body.Bytes(), []byte("%PDF-")) {
+		t.Errorf("response is not a PDF")
+	}
+
+	// Non-sale actions have no receipt
+	req, _ = http.NewRequest("GET", "/api/sales/"+itoa(collectedID)+"/receipt.pdf", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for collected action, got %d", w.Code)
+	}
+}
+
+func TestCustomerInvoice(t *testing.T) {
+	dbase, cleanup := setupSalesTestDB()
+	defer cleanup()
+	router := gin.Default()
+	router.GET("/api/invoices/:customer/:month/invoice.pdf", CustomerInvoiceHandler(dbase))
+
+	insertSale(t, dbase, "sold", "Jane Doe", 12, 0.5, "2025-05-03")
+	insertSale(t, dbase, "sold", "Jane Doe", 6, 0.5, "2025-05-20")
+
+	req, _ := http.NewRequest("GET", "/api/invoices/Jane%20Doe/2025-05/invoice.pdf", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !bytes.HasPrefix(w.Body.Bytes(), []byte("%PDF-")) {
+		t.Errorf("response is not a PDF")
+	}
+
+	req, _ = http.NewRequest("GET", "/api/invoices/Jane%20Doe/2025-06/invoice.pdf", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for month without sales, got %d", w.Code)
+	}
+
+	// Dates stored as plain text by imports count too, including the 1st
+	mustExec(t, dbase, "INSERT INTO inventory_actions (quantity, species, action, date, customer) VALUES (6, 'Chicken', 'sold', '2025-07-01', 'Jane Doe')")
+	req, _ = http.NewRequest("GET", "/api/invoices/Jane%20Doe/2025-07/invoice.pdf", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 for a sale on the 1st, got %d: %s", w.Code, w.Body.String())
+	}
+
+	req, _ = http.NewRequest("GET", "/api/invoices/Jane%20Doe/May/invoice.pdf", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid month, got %d", w.Code)
+	}
+}
+
+func itoa(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
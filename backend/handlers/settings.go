@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"egg-tracker/backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	settingFarmName    = "farm_name"
+	settingFarmAddress = "farm_address"
+	settingFarmLogo    = "farm_logo"      // base64-encoded PNG or JPEG
+	settingExpiredLots = "expired_lots"   // what the daily expiry job does: "flag" (default) or "spoil"
+	settingLatitude    = "farm_latitude"  // decimal degrees, north positive
+	settingLongitude   = "farm_longitude" // decimal degrees, east positive
+	settingTimezone    = "farm_timezone"  // IANA name used for sunrise and lighting times, default UTC
+
+	maxLogoBytes = 1 << 20
+)
+
+type SettingsInput struct {
+	FarmName    *string  `json:"farm_name"`
+	FarmAddress *string  `json:"farm_address"`
+	ExpiredLots *string  `json:"expired_lots"`
+	Latitude    *float64 `json:"latitude" binding:"omitempty,min=-90,max=90"`
+	Longitude   *float64 `json:"longitude" binding:"omitempty,min=-180,max=180"`
+	Timezone    *string  `json:"timezone"`
+}
+
+// loadSettings reads all settings rows into a key/value map.
+func loadSettings(db *sql.DB) (map[string]string, error) {
+	rows, err := db.Query("SELECT key, value FROM settings")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	values := map[string]string{}
+	for rows.Next() {
+		var k, v string
+		if err := rows.Scan(&k, &v); err != nil {
+			return nil, err
+		}
+		values[k] = v
+	}
+	return values, rows.Err()
+}
+
+func saveSetting(db *sql.DB, key, value string) error {
+	_, err := db.Exec(
+		"INSERT INTO settings (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = CURRENT_TIMESTAMP",
+		key, value,
+	)
+	return err
+}
+
+func GetSettingsHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		values, err := loadSettings(db)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		settings := models.Settings{
+			FarmName:    values[settingFarmName],
+			FarmAddress: values[settingFarmAddress],
+			HasLogo:     values[settingFarmLogo] != "",
+			ExpiredLots: expiredLotPolicy(values),
+			Timezone:    farmTimezone(values).String(),
+		}
+		if lat, lon, ok := farmPosition(values); ok {
+			settings.Latitude, settings.Longitude = &lat, &lon
+		}
+		c.JSON(http.StatusOK, settings)
+	}
+}
+
+func UpdateSettingsHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input SettingsInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondBindError(c, err)
+			return
+		}
+		if input.ExpiredLots != nil && *input.ExpiredLots != "flag" && *input.ExpiredLots != "spoil" {
+			respondError(c, http.StatusBadRequest, "expired_lots must be flag or spoil")
+			return
+		}
+		if input.Timezone != nil {
+			if _, err := time.LoadLocation(*input.Timezone); err != nil || *input.Timezone == "" {
+				respondError(c, http.StatusBadRequest, "unknown timezone")
+				return
+			}
+		}
+		updates := map[string]*string{
+			settingFarmName:    input.FarmName,
+			settingFarmAddress: input.FarmAddress,
+			settingExpiredLots: input.ExpiredLots,
+			settingTimezone:    input.Timezone,
+		}
+		for key, value := range map[string]*float64{settingLatitude: input.Latitude, settingLongitude: input.Longitude} {
+			if value != nil {
+				v := strconv.FormatFloat(*value, 'f', -1, 64)
+				updates[key] = &v
+			}
+		}
+		for key, value := range updates {
+			if value == nil {
+				continue
+			}
+			if err := saveSetting(db, key, *value); err != nil {
+				respondServerError(c, err)
+				return
+			}
+		}
+		c.JSON(http.StatusOK, MessageResponse{Message: "updated"})
+	}
+}
+
+// UploadLogoHandler stores the farm logo from a multipart "logo" field.
+func UploadLogoHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		file, err := c.FormFile("logo")
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "missing logo file")
+			return
+		}
+		if file.Size > maxLogoBytes {
+			respondError(c, http.StatusRequestEntityTooLarge, "logo too large")
+			return
+		}
+		f, err := file.Open()
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "could not read logo")
+			return
+		}
+		defer f.Close()
+		data, err := io.ReadAll(io.LimitReader(f, maxLogoBytes))
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "could not read logo")
+			return
+		}
+		switch http.DetectContentType(data) {
+		case "image/png", "image/jpeg":
+		default:
+			respondError(c, http.StatusUnsupportedMediaType, "logo must be PNG or JPEG")
+			return
+		}
+		if err := saveSetting(db, settingFarmLogo, base64.StdEncoding.EncodeToString(data)); err != nil {
+			respondServerError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, MessageResponse{Message: "logo updated"})
+	}
+}
+
+func GetLogoHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		values, err := loadSettings(db)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		data, err := base64.StdEncoding.DecodeString(values[settingFarmLogo])
+		if err != nil || len(data) == 0 {
+			respondError(c, http.StatusNotFound, "no logo")
+			return
+		}
+		c.Data(http.StatusOK, http.DetectContentType(data), data)
+	}
+}
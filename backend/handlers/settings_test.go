@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"egg-tracker/backend/db"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupSettingsTestDB() (*sql.DB, func()) {
+	testDBPath := "test_settings.db"
+	dbase, _ := sql.Open("sqlite3", testDBPath)
+	db.Migrate(dbase)
+	return dbase, func() {
+		dbase.Close()
+		os.Remove(testDBPath)
+	}
+}
+
+func testPNG() []byte {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4)))
+	return buf.Bytes()
+}
+
+func TestSettingsUpdateAndGet(t *testing.T) {
+	dbase, cleanup := setupSettingsTestDB()
+	defer cleanup()
+	router := gin.Default()
+	router.GET("/api/settings", GetSettingsHandler(dbase))
+	router.PUT("/api/settings", UpdateSettingsHandler(dbase))
+
+	body, _ := json.Marshal(map[string]interface{}{"farm_name": "Sunny Acres", "farm_address": "1 Barn Rd"})
+	req, _ := http.NewRequest("PUT", "/api/settings", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	// Partial update keeps other fields
+	body, _ = json.Marshal(map[string]interface{}{"farm_address": "2 Barn Rd"})
+	req, _ = http.NewRequest("PUT", "/api/settings", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on partial update, got %d", w.Code)
+	}
+
+	req, _ = http.NewRequest("GET", "/api/settings", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var resp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp["farm_name"] != "Sunny Acres" || resp["farm_address"] != "2 Barn Rd" {
+		t.Errorf("unexpected settings: %v", resp)
+	}
+	if resp["has_logo"] != false {
+		t.Errorf("expected has_logo false, got %v", resp["has_logo"])
+	}
+}
+
+func TestSettingsLogoUpload(t *testing.T) {
+	dbase, cleanup := setupSettingsTestDB()
+	defer cleanup()
+	router := gin.Default()
+	router.POST("/api/settings/logo", UploadLogoHandler(dbase))
+	router.GET("/api/settings/logo", GetLogoHandler(dbase))
+
+	upload := func(data []byte) int {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, _ := mw.CreateFormFile("logo", "logo.png")
+		fw.Write(data)
+		mw.Close()
+		req, _ := http.NewRequest("POST", "/api/settings/logo", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := upload([]byte("not an image")); code != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected 415 for non-image, got %d", code)
+	}
+	if code := upload(testPNG()); code != http.StatusOK {
+		t.Fatalf("expected 200 for png, got %d", code)
+	}
+
+	req, _ := http.NewRequest("GET", "/api/settings/logo", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 fetching logo, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "image/png" {
+		t.Errorf("expected image/png, got %s", ct)
+	}
+}
@@ -20,13 +20,13 @@ func SignupHandler(db *sql.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req SignupRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+			respondBindError(c, err)
 			return
 		}
 
 		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not hash password"})
+			respondServerError(c, err)
 			return
 		}
 
@@ -35,10 +35,10 @@ func SignupHandler(db *sql.DB) gin.HandlerFunc {
 			req.Email, string(hash),
 		)
 		if err != nil {
-			c.JSON(http.StatusConflict, gin.H{"error": "user already exists"})
+			respondWriteError(c, err, "user already exists")
 			return
 		}
 
-		c.JSON(http.StatusCreated, gin.H{"message": "user created"})
+		c.JSON(http.StatusCreated, MessageResponse{Message: "user created"})
 	}
 }
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"egg-tracker/backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type StatusPeriodInput struct {
+	BirdID    *int64  `json:"bird_id"`
+	Coop      string  `json:"coop"` // defaults to the bird's coop for bird periods
+	Status    string  `json:"status" binding:"required"`
+	StartDate string  `json:"start_date" binding:"required"` // ISO8601 date
+	EndDate   string  `json:"end_date"`                      // ISO8601 date, empty while ongoing
+	Notes     *string `json:"notes"`
+}
+
+var birdProductionStatuses = map[string]bool{"molting": true, "broody": true, "laying": true}
+
+const statusPeriodColumns = "id, bird_id, coop, status, start_date, end_date, notes, created_at, updated_at"
+
+func scanStatusPeriod(row rowScanner) (models.BirdStatusPeriod, error) {
+	var p models.BirdStatusPeriod
+	var birdID sql.NullInt64
+	var endDate sql.NullTime
+	var notes sql.NullString
+	if err := row.Scan(&p.ID, &birdID, &p.Coop, &p.Status, &p.StartDate, &endDate, &notes, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return p, err
+	}
+	if birdID.Valid {
+		p.BirdID = &birdID.Int64
+	}
+	if endDate.Valid {
+		p.EndDate = &endDate.Time
+	}
+	if notes.Valid {
+		p.Notes = &notes.String
+	}
+	return p, nil
+}
+
+// resolveStatusPeriod validates the input and fills in the coop from the bird.
+func resolveStatusPeriod(db *sql.DB, input *StatusPeriodInput) (time.Time, *time.Time, int, string) {
+	if !birdProductionStatuses[input.Status] {
+		return time.Time{}, nil, http.StatusBadRequest, "invalid status"
+	}
+	start, err := time.Parse("2006-01-02", input.StartDate)
+	if err != nil {
+		return time.Time{}, nil, http.StatusBadRequest, "invalid start_date"
+	}
+	end, err := parseOptionalDate(input.EndDate)
+	if err != nil {
+		return time.Time{}, nil, http.StatusBadRequest, "invalid end_date"
+	}
+	if end != nil && end.Before(start) {
+		return time.Time{}, nil, http.StatusBadRequest, "end_date must not be before start_date"
+	}
+	if input.BirdID != nil && input.Coop == "" {
+		var coop sql.NullString
+		err := db.QueryRow("SELECT coop FROM birds WHERE id = ?", *input.BirdID).Scan(&coop)
+		if err == sql.ErrNoRows {
+			return time.Time{}, nil, http.StatusBadRequest, "bird not found"
+		} else if err != nil {
+			return time.Time{}, nil, http.StatusInternalServerError, "db error"
+		}
+		input.Coop = coop.String
+	}
+	if input.Coop == "" {
+		return time.Time{}, nil, http.StatusBadRequest, "bird_id or coop is required"
+	}
+	return start, end, 0, ""
+}
+
+func CreateStatusPeriodHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input StatusPeriodInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondBindError(c, err)
+			return
+		}
+		start, end, status, msg := resolveStatusPeriod(db, &input)
+		if msg != "" {
+			respondError(c, status, msg)
+			return
+		}
+		res, err := db.Exec(
+			"INSERT INTO bird_status_periods (bird_id, coop, status, start_date, end_date, notes) VALUES (?, ?, ?, ?, ?, ?)",
+			input.BirdID, input.Coop, input.Status, start, end, input.Notes,
+		)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		id, _ := res.LastInsertId()
+		c.JSON(http.StatusCreated, IDResponse{ID: id})
+	}
+}
+
+// ListStatusPeriodsHandler lists periods, optionally filtered by ?bird_id=,
+// ?coop=, ?status= and ?active=true for periods that have not ended.
+func ListStatusPeriodsHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := "SELECT " + statusPeriodColumns + " FROM bird_status_periods WHERE 1 = 1"
+		var args []interface{}
+		for _, f := range []string{"bird_id", "coop", "status"} {
+			if v := c.Query(f); v != "" {
+				query += " AND " + f + " = ?"
+				args = append(args, v)
+			}
+		}
+		if c.Query("active") == "true" {
+			query += " AND (end_date IS NULL OR date(end_date) >= date(?))"
+			args = append(args, today().Format("2006-01-02"))
+		}
+		rows, err := db.Query(query+" ORDER BY start_date DESC, id DESC", args...)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		defer rows.Close()
+		periods := []models.BirdStatusPeriod{}
+		for rows.Next() {
+			p, err := scanStatusPeriod(rows)
+			if err != nil {
+				respondServerError(c, err)
+				return
+			}
+			periods = append(periods, p)
+		}
+		c.JSON(http.StatusOK, periods)
+	}
+}
+
+func UpdateStatusPeriodHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var input StatusPeriodInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondBindError(c, err)
+			return
+		}
+		start, end, status, msg := resolveStatusPeriod(db, &input)
+		if msg != "" {
+			respondError(c, status, msg)
+			return
+		}
+		_, err := db.Exec(
+			"UPDATE bird_status_periods SET bird_id = ?, coop = ?, status = ?, start_date = ?, end_date = ?, notes = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?",
+			input.BirdID, input.Coop, input.Status, start, end, input.Notes, id,
+		)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, MessageResponse{Message: "updated"})
+	}
+}
+
+func DeleteStatusPeriodHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_, err := db.Exec("DELETE FROM bird_status_periods WHERE id = ?", c.Param("id"))
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, MessageResponse{Message: "deleted"})
+	}
+}
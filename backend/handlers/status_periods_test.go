@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"testing"
+
+	"egg-tracker/backend/db"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupStatusPeriodsTestDB() (*sql.DB, func()) {
+	testDBPath := "test_status_periods.db"
+	dbase, _ := sql.Open("sqlite3", testDBPath)
+	db.Migrate(dbase)
+	return dbase, func() {
+		dbase.Close()
+		os.Remove(testDBPath)
+	}
+}
+
+func TestStatusPeriodsCRUD(t *testing.T) {
+	dbase, cleanup := setupStatusPeriodsTestDB()
+	defer cleanup()
+	router := gin.Default()
+	router.POST("/api/status-periods", CreateStatusPeriodHandler(dbase))
+	router.GET("/api/status-periods", ListStatusPeriodsHandler(dbase))
+	router.PUT("/api/status-periods/:id", UpdateStatusPeriodHandler(dbase))
+	router.DELETE("/api/status-periods/:id", DeleteStatusPeriodHandler(dbase))
+	mustExec(t, dbase, "INSERT INTO birds (id, name, species, coop, status) VALUES (1, 'Hen', 'Chicken', 'Main Coop', 'active')")
+
+	w := doJSON(router, "POST", "/api/status-periods", map[string]interface{}{
+		"bird_id": 1, "status": "broody", "start_date": "2025-06-01",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	idStr := strconv.Itoa(int(resp["id"].(float64)))
+
+	// Ongoing period is active
+	w = doJSON(router, "GET", "/api/status-periods?active=true", nil)
+	var periods []map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &periods)
+	if len(periods) != 1 || periods[0]["coop"] != "Main Coop" {
+		t.Fatalf("expected one active period in Main Coop, got %v", periods)
+	}
+
+	// Close it
+	w = doJSON(router, "PUT", "/api/status-periods/"+idStr, map[string]interface{}{
+		"bird_id": 1, "status": "broody", "start_date": "2025-06-01", "end_date": "2025-06-20",
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on update, got %d: %s", w.Code, w.Body.String())
+	}
+	w = doJSON(router, "GET", "/api/status-periods?active=true", nil)
+	periods = nil
+	json.Unmarshal(w.Body.Bytes(), &periods)
+	if len(periods) != 0 {
+		t.Fatalf("expected no active periods, got %v", periods)
+	}
+
+	w = doJSON(router, "DELETE", "/api/status-periods/"+idStr, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 on delete, got %d", w.Code)
+	}
+
+	for i, payload := range []map[string]interface{}{
+		{"coop": "Main Coop", "status": "sleeping", "start_date": "2025-06-01"},
+		{"status": "molting", "start_date": "2025-06-01"},
+		{"coop": "Main Coop", "status": "molting", "start_date": "2025-06-10", "end_date": "2025-06-01"},
+	} {
+		w = doJSON(router, "POST", "/api/status-periods", payload)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("case %d: expected 400, got %d", i, w.Code)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"egg-tracker/backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Default safe range for egg refrigerators: above freezing and below 7 °C (45 °F).
+const (
+	defaultMinTempC = 1.0
+	defaultMaxTempC = 7.0
+)
+
+type StorageRangeInput struct {
+	MinTempC *float64 `json:"min_temp_c" binding:"required"`
+	MaxTempC *float64 `json:"max_temp_c" binding:"required"`
+}
+
+type StorageReadingInput struct {
+	TempC      *float64 `json:"temp_c"` // give either temp_c or temp_f
+	TempF      *float64 `json:"temp_f"`
+	RecordedAt string   `json:"recorded_at"` // RFC3339, defaults to now
+	Source     string   `json:"source"`      // "manual" (default) or "sensor"
+	Notes      *string  `json:"notes"`
+}
+
+const storageUnitColumns = "id, name, active, COALESCE(min_temp_c, ?), COALESCE(max_temp_c, ?)"
+
+func scanStorageUnit(row rowScanner) (models.StorageUnit, error) {
+	var u models.StorageUnit
+	err := row.Scan(&u.ID, &u.Name, &u.Active, &u.MinTempC, &u.MaxTempC)
+	return u, err
+}
+
+func loadStorageUnit(q sqlExecutor, id interface{}) (models.StorageUnit, error) {
+	return scanStorageUnit(q.QueryRow("SELECT "+storageUnitColumns+" FROM storage_units WHERE id = ?", defaultMinTempC, defaultMaxTempC, id))
+}
+
+// detectExcursions groups consecutive out-of-range readings, which must be
+// ordered by time, into excursions.
+func detectExcursions(unit models.StorageUnit, readings []models.StorageReading) []models.Excursion {
+	excursions := []models.Excursion{}
+	var cur *models.Excursion
+	for _, r := range readings {
+		kind := ""
+		switch {
+		case r.TempC > unit.MaxTempC:
+			kind = "high"
+		case r.TempC < unit.MinTempC:
+			kind = "low"
+		}
+		if cur != nil && kind != cur.Kind {
+			excursions = append(excursions, *cur)
+			cur = nil
+		}
+		if kind == "" {
+			continue
+		}
+		if cur == nil {
+			cur = &models.Excursion{StorageUnitID: unit.ID, StorageUnit: unit.Name, Kind: kind, Start: r.RecordedAt, PeakTempC: r.TempC}
+		}
+		cur.End = r.RecordedAt
+		cur.Readings++
+		if (kind == "high" && r.TempC > cur.PeakTempC) || (kind == "low" && r.TempC < cur.PeakTempC) {
+			cur.PeakTempC = r.TempC
+		}
+	}
+	if cur != nil {
+		excursions = append(excursions, *cur)
+	}
+	return excursions
+}
+
+// loadReadings returns a unit's readings in [from, to), oldest first.
+func loadReadings(q sqlExecutor, unitID int64, from, to time.Time) ([]models.StorageReading, error) {
+	rows, err := q.Query(
+		`SELECT id, storage_unit_id, recorded_at, temp_c, source, notes, created_at FROM storage_readings
+		WHERE storage_unit_id = ? AND recorded_at >= ? AND recorded_at < ?
+		ORDER BY recorded_at ASC, id ASC`,
+		unitID, from.UTC(), to.UTC(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	readings := []models.StorageReading{}
+	for rows.Next() {
+		var r models.StorageReading
+		var notes sql.NullString
+		if err := rows.Scan(&r.ID, &r.StorageUnitID, &r.RecordedAt, &r.TempC, &r.Source, &notes, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		if notes.Valid {
+			r.Notes = &notes.String
+		}
+		readings = append(readings, r)
+	}
+	return readings, rows.Err()
+}
+
+// readingRange parses ?from= and ?to= as dates; to is inclusive. The range
+// defaults to the last seven days.
+func readingRange(c *gin.Context) (time.Time, time.Time, bool) {
+	to := today().AddDate(0, 0, 1)
+	from := to.AddDate(0, 0, -7)
+	if s := c.Query("from"); s != "" {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			return from, to, false
+		}
+		from = d
+	}
+	if s := c.Query("to"); s != "" {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			return from, to, false
+		}
+		to = d.AddDate(0, 0, 1)
+	}
+	return from, to, true
+}
+
+// unitFromParam loads the :id storage unit, writing the error response and
+// returning false when it cannot be used.
+func unitFromParam(c *gin.Context, db *sql.DB) (models.StorageUnit, bool) {
+	unit, err := loadStorageUnit(db, c.Param("id"))
+	if err == sql.ErrNoRows {
+		respondError(c, http.StatusNotFound, "storage unit not found")
+		return unit, false
+	} else if err != nil {
+		respondServerError(c, err)
+		return unit, false
+	}
+	return unit, true
+}
+
+// ListStorageUnitsHandler lists storage units with their effective safe ranges.
+func ListStorageUnitsHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rows, err := db.Query("SELECT "+storageUnitColumns+" FROM storage_units ORDER BY name ASC", defaultMinTempC, defaultMaxTempC)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		defer rows.Close()
+		units := []models.StorageUnit{}
+		for rows.Next() {
+			u, err := scanStorageUnit(rows)
+			if err != nil {
+				respondServerError(c, err)
+				return
+			}
+			units = append(units, u)
+		}
+		c.JSON(http.StatusOK, units)
+	}
+}
+
+func UpdateStorageRangeHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input StorageRangeInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondBindError(c, err)
+			return
+		}
+		if *input.MinTempC >= *input.MaxTempC {
+			respondError(c, http.StatusBadRequest, "min_temp_c must be below max_temp_c")
+			return
+		}
+		res, err := db.Exec(
+			"UPDATE storage_units SET min_temp_c = ?, max_temp_c = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?",
+			*input.MinTempC, *input.MaxTempC, c.Param("id"),
+		)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			respondError(c, http.StatusNotFound, "storage unit not found")
+			return
+		}
+		c.JSON(http.StatusOK, MessageResponse{Message: "updated"})
+	}
+}
+
+// StorageReadingCreated is returned for a new reading, in Celsius, and
+// whether it is outside the unit's range.
+type StorageReadingCreated struct {
+	ID        int64   `json:"id"`
+	TempC     float64 `json:"temp_c"`
+	Excursion bool    `json:"excursion"`
+}
+
+// CreateStorageReadingHandler records a temperature typed in by hand or pushed
+// by a sensor. The response says whether the reading is outside the safe range.
+func CreateStorageReadingHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input StorageReadingInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondBindError(c, err)
+			return
+		}
+		if (input.TempC == nil) == (input.TempF == nil) {
+			respondError(c, http.StatusBadRequest, "give exactly one of temp_c or temp_f")
+			return
+		}
+		tempC := input.TempC
+		if tempC == nil {
+			v := (*input.TempF - 32) * 5 / 9
+			tempC = &v
+		}
+		if input.Source == "" {
+			input.Source = "manual"
+		}
+		if input.Source != "manual" && input.Source != "sensor" {
+			respondError(c, http.StatusBadRequest, "source must be manual or sensor")
+			return
+		}
+		recordedAt := time.Now()
+		if input.RecordedAt != "" {
+			t, err := time.Parse(time.RFC3339, input.RecordedAt)
+			if err != nil {
+				respondError(c, http.StatusBadRequest, "invalid recorded_at")
+				return
+			}
+			recordedAt = t
+		}
+		recordedAt = recordedAt.UTC().Truncate(time.Second)
+		unit, ok := unitFromParam(c, db)
+		if !ok {
+			return
+		}
+		res, err := db.Exec(
+			"INSERT INTO storage_readings (storage_unit_id, recorded_at, temp_c, source, notes) VALUES (?, ?, ?, ?, ?)",
+			unit.ID, recordedAt, *tempC, input.Source, input.Notes,
+		)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		id, _ := res.LastInsertId()
+		excursion := *tempC < unit.MinTempC || *tempC > unit.MaxTempC
+		c.JSON(http.StatusCreated, StorageReadingCreated{ID: id, TempC: *tempC, Excursion: excursion})
+	}
+}
+
+// ListStorageReadingsHandler lists a unit's readings between ?from= and ?to=
+// (YYYY-MM-DD, default the last seven days).
+func ListStorageReadingsHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		from, to, ok := readingRange(c)
+		if !ok {
+			respondError(c, http.StatusBadRequest, "invalid date")
+			return
+		}
+		unit, ok := unitFromParam(c, db)
+		if !ok {
+			return
+		}
+		readings, err := loadReadings(db, unit.ID, from, to)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, readings)
+	}
+}
+
+// ListStorageExcursionsHandler lists a unit's excursions between ?from= and
+// ?to= (YYYY-MM-DD, default the last seven days).
+func ListStorageExcursionsHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		from, to, ok := readingRange(c)
+		if !ok {
+			respondError(c, http.StatusBadRequest, "invalid date")
+			return
+		}
+		unit, ok := unitFromParam(c, db)
+		if !ok {
+			return
+		}
+		readings, err := loadReadings(db, unit.ID, from, to)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, detectExcursions(unit, readings))
+	}
+}
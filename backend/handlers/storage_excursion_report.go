@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"egg-tracker/backend/logging"
+	"egg-tracker/backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ExcursionWithLots struct {
+	models.Excursion
+	Lots []models.ExcursionLot `json:"lots"`
+}
+
+// excursionLots returns the lots in a unit that still had eggs when an
+// excursion began and had been collected by the time it ended.
+func excursionLots(duck *sql.DB, e models.Excursion) ([]models.ExcursionLot, error) {
+	start, end := e.Start.Format("2006-01-02"), e.End.Format("2006-01-02")
+	rows, err := duck.Query(`
+		SELECT lot_code, species, CAST(date AS DATE), on_hand FROM (
+			SELECT a.lot_code, a.species, a.date,
+				a.quantity - COALESCE((
+					SELECT SUM(d.quantity) FROM lot_draws d JOIN inventory_actions o ON o.id = d.action_id
+					WHERE d.lot_action_id = a.id AND CAST(o.date AS DATE) < CAST(? AS DATE)
+				), 0) AS on_hand
+			FROM inventory_actions a
+			WHERE a.action = 'collected' AND a.lot_code IS NOT NULL AND a.storage_unit_id = ?
+				AND CAST(a.date AS DATE) <= CAST(? AS DATE)
+		)
+		WHERE on_hand > 0
+		ORDER BY date ASC, lot_code ASC
+	`, start, e.StorageUnitID, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	lots := []models.ExcursionLot{}
+	for rows.Next() {
+		var l models.ExcursionLot
+		if err := rows.Scan(&l.LotCode, &l.Species, &l.CollectedOn, &l.OnHand); err != nil {
+			return nil, err
+		}
+		lots = append(lots, l)
+	}
+	return lots, rows.Err()
+}
+
+// queryExcursions detects excursions for every storage unit in [from, to) and
+// attaches the lots exposed to each one.
+func queryExcursions(duck *sql.DB, from, to time.Time) ([]ExcursionWithLots, error) {
+	rows, err := duck.Query("SELECT "+storageUnitColumns+" FROM storage_units ORDER BY name ASC", defaultMinTempC, defaultMaxTempC)
+	if err != nil {
+		return nil, err
+	}
+	var units []models.StorageUnit
+	for rows.Next() {
+		u, err := scanStorageUnit(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		units = append(units, u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	results := []ExcursionWithLots{}
+	for _, u := range units {
+		rows, err := duck.Query(`
+			SELECT recorded_at, temp_c FROM storage_readings
+			WHERE storage_unit_id = ? AND recorded_at >= CAST(? AS TIMESTAMP) AND recorded_at < CAST(? AS TIMESTAMP)
+			ORDER BY recorded_at ASC, id ASC
+		`, u.ID, from.Format("2006-01-02 15:04:05"), to.Format("2006-01-02 15:04:05"))
+		if err != nil {
+			return nil, err
+		}
+		var readings []models.StorageReading
+		for rows.Next() {
+			var r models.StorageReading
+			if err := rows.Scan(&r.RecordedAt, &r.TempC); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			readings = append(readings, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		for _, e := range detectExcursions(u, readings) {
+			lots, err := excursionLots(duck, e)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, ExcursionWithLots{Excursion: e, Lots: lots})
+		}
+	}
+	return results, nil
+}
+
+// StorageExcursionReport is the response of StorageExcursionReportHandler.
+type StorageExcursionReport struct {
+	Excursions []ExcursionWithLots `json:"excursions"`
+}
+
+// StorageExcursionReportHandler lists temperature excursions per storage unit
+// with the lots stored during each one. Query parameters: from/to as
+// YYYY-MM-DD (default: the last seven days).
+func StorageExcursionReportHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		from, to, ok := readingRange(c)
+		if !ok {
+			respondError(c, http.StatusBadRequest, "invalid date")
+			return
+		}
+
+		duckdb, err := sql.Open("duckdb", analyticsDBPath)
+		if err != nil {
+			requestLogger(c, "StorageExcursionReportHandler").Error("Failed to open DuckDB", logging.Err(err))
+			respondError(c, http.StatusInternalServerError, "failed to open DuckDB")
+			return
+		}
+		defer duckdb.Close()
+
+		excursions, err := queryExcursions(duckdb, from, to)
+		if err != nil {
+			requestLogger(c, "StorageExcursionReportHandler").Error("Excursion query failed", logging.Err(err))
+			respondError(c, http.StatusInternalServerError, "excursion query failed")
+			return
+		}
+		c.JSON(http.StatusOK, StorageExcursionReport{Excursions: excursions})
+	}
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestStorageExcursionReport_LotsExposed(t *testing.T) {
+	cleanup := setupAnalyticsTestDB(t, func(dbase *sql.DB) {
+		mustExec(t, dbase, "INSERT INTO storage_units (id, name, active) VALUES (1, 'Stand Fridge', 1)")
+		mustExec(t, dbase, `INSERT INTO storage_readings (storage_unit_id, recorded_at, temp_c) VALUES
+			(1, '2025-05-03 08:00:00', 4),
+			(1, '2025-05-03 09:00:00', 9.5),
+			(1, '2025-05-03 10:00:00', 10),
+			(1, '2025-05-03 11:00:00', 5)`)
+		// Lot 1 is sold out the day before, lot 2 is on hand, lot 3 is collected afterwards
+		mustExec(t, dbase, `INSERT INTO inventory_actions (id, quantity, species, coop, action, date, lot_code, storage_unit_id) VALUES
+			(1, 6, 'Chicken', 'Main Coop', 'collected', '2025-05-01', '20250501-MAIN-COOP-01', 1),
+			(2, 6, 'Chicken', 'Main Coop', 'collected', '2025-05-02', '20250502-MAIN-COOP-01', 1),
+			(3, 6, 'Chicken', 'Main Coop', 'collected', '2025-05-04', '20250504-MAIN-COOP-01', 1),
+			(4, 8, 'Chicken', 'Main Coop', 'sold', '2025-05-02', NULL, NULL)`)
+		mustExec(t, dbase, "INSERT INTO lot_draws (action_id, lot_action_id, quantity) VALUES (4, 1, 6), (4, 2, 2)")
+	})
+	defer cleanup()
+
+	router := gin.Default()
+	router.GET("/api/reports/storage-excursions", StorageExcursionReportHandler())
+	req, _ := http.NewRequest("GET", "/api/reports/storage-excursions?from=2025-05-01&to=2025-05-05", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Excursions []ExcursionWithLots `json:"excursions"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Excursions) != 1 {
+		t.Fatalf("expected one excursion, got %+v", resp.Excursions)
+	}
+	e := resp.Excursions[0]
+	if e.Readings != 2 || e.PeakTempC != 10 || e.StorageUnit != "Stand Fridge" {
+		t.Errorf("unexpected excursion: %+v", e.Excursion)
+	}
+	if len(e.Lots) != 1 || e.Lots[0].LotCode != "20250502-MAIN-COOP-01" || e.Lots[0].OnHand != 4 {
+		t.Errorf("expected only the on-hand lot with 4 eggs, got %+v", e.Lots)
+	}
+}
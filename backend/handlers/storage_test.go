@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"math"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"egg-tracker/backend/db"
+	"egg-tracker/backend/models"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupStorageTestDB() (*sql.DB, func()) {
+	testDBPath := "test_storage.db"
+	dbase, _ := sql.Open("sqlite3", testDBPath)
+	db.Migrate(dbase)
+	return dbase, func() {
+		dbase.Close()
+		os.Remove(testDBPath)
+	}
+}
+
+func TestDetectExcursions(t *testing.T) {
+	unit := models.StorageUnit{ID: 1, Name: "Stand Fridge", MinTempC: 1, MaxTempC: 7}
+	base := time.Date(2025, 5, 1, 8, 0, 0, 0, time.UTC)
+	var readings []models.StorageReading
+	for i, temp := range []float64{4, 8, 11, 9, 5, 0.5, -1, 3} {
+		readings = append(readings, models.StorageReading{RecordedAt: base.Add(time.Duration(i) * time.Hour), TempC: temp})
+	}
+	got := detectExcursions(unit, readings)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 excursions, got %+v", got)
+	}
+	if got[0].Kind != "high" || got[0].Readings != 3 || got[0].PeakTempC != 11 || !got[0].End.Equal(base.Add(3*time.Hour)) {
+		t.Errorf("unexpected high excursion: %+v", got[0])
+	}
+	if got[1].Kind != "low" || got[1].PeakTempC != -1 {
+		t.Errorf("unexpected low excursion: %+v", got[1])
+	}
+}
+
+func TestStorageReadingsAndLotTrace(t *testing.T) {
+	dbase, cleanup := setupStorageTestDB()
+	defer cleanup()
+	router := gin.Default()
+	router.PUT("/api/storage/:id/range", UpdateStorageRangeHandler(dbase))
+	router.POST("/api/storage/:id/readings", CreateStorageReadingHandler(dbase))
+	router.GET("/api/storage/:id/excursions", ListStorageExcursionsHandler(dbase))
+	router.POST("/api/inventory", CreateInventoryHandler(dbase))
+	router.GET("/api/trace/:lot", TraceLotHandler(dbase))
+	mustExec(t, dbase, "INSERT INTO storage_units (id, name, active) VALUES (1, 'Stand Fridge', 1)")
+
+	if w := doJSON(router, "PUT", "/api/storage/1/range", map[string]interface{}{"min_temp_c": 2, "max_temp_c": 6}); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 setting range, got %d: %s", w.Code, w.Body.String())
+	}
+
+	collect := lotAction("collected", "2025-05-01", 12)
+	collect["storage_unit_id"] = 1
+	w := doJSON(router, "POST", "/api/inventory", collect)
+	var created map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	lot, _ := created["lot_code"].(string)
+	collect["storage_unit_id"] = 99
+	if w = doJSON(router, "POST", "/api/inventory", collect); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unknown storage unit, got %d", w.Code)
+	}
+
+	// 46.4 °F is 8 °C, above the 6 °C limit
+	w = doJSON(router, "POST", "/api/storage/1/readings", map[string]interface{}{"temp_f": 46.4, "recorded_at": "2025-05-02T14:00:00Z", "source": "sensor"})
+	var reading map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &reading)
+	if w.Code != http.StatusCreated || reading["excursion"] != true || math.Abs(reading["temp_c"].(float64)-8) > 1e-9 {
+		t.Fatalf("expected an excursion at 8 C, got %d: %s", w.Code, w.Body.String())
+	}
+	doJSON(router, "POST", "/api/storage/1/readings", map[string]interface{}{"temp_c": 4, "recorded_at": "2025-05-02T15:00:00Z"})
+	if w = doJSON(router, "POST", "/api/storage/1/readings", map[string]interface{}{"temp_c": 4, "temp_f": 39}); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 with both units, got %d", w.Code)
+	}
+	if w = doJSON(router, "POST", "/api/storage/2/readings", map[string]interface{}{"temp_c": 4}); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown unit, got %d", w.Code)
+	}
+
+	w = doJSON(router, "GET", "/api/storage/1/excursions?from=2025-05-01&to=2025-05-03", nil)
+	var excursions []models.Excursion
+	json.Unmarshal(w.Body.Bytes(), &excursions)
+	if len(excursions) != 1 || excursions[0].Kind != "high" {
+		t.Errorf("expected one high excursion, got %s", w.Body.String())
+	}
+
+	w = doJSON(router, "GET", "/api/trace/"+lot, nil)
+	var trace models.LotTrace
+	json.Unmarshal(w.Body.Bytes(), &trace)
+	if trace.StorageUnit == nil || *trace.StorageUnit != "Stand Fridge" || len(trace.Excursions) != 1 {
+		t.Errorf("expected the lot trace to show the excursion, got %s", w.Body.String())
+	}
+}
@@ -0,0 +1,471 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	"egg-tracker/backend/models"
+	"egg-tracker/backend/recur"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TaskInput struct {
+	Title          string  `json:"title" binding:"required"`
+	Description    *string `json:"description"`
+	RRule          string  `json:"rrule" binding:"required"`      // RFC 5545 subset, see package recur
+	StartDate      string  `json:"start_date" binding:"required"` // ISO8601 date of the first occurrence
+	Coop           *string `json:"coop"`
+	AssignedUserID *int64  `json:"assigned_user_id"`
+	Active         *bool   `json:"active"` // defaults to true
+}
+
+type TaskCompletionInput struct {
+	Date    string  `json:"date"`     // ISO8601, defaults to today
+	DueDate string  `json:"due_date"` // occurrence being completed, see CompleteTaskHandler
+	UserID  *int64  `json:"user_id"`  // defaults to the signed-in user, if any
+	Notes   *string `json:"notes"`
+}
+
+// taskColumns selects a task along with the due date of its latest completion.
+const taskColumns = `t.id, t.title, t.description, t.rrule, t.start_date, t.coop, t.assigned_user_id, t.active, t.created_at, t.updated_at,
+	(SELECT MAX(date(c.due_date)) FROM task_completions c WHERE c.task_id = t.id)`
+
+func scanTask(row rowScanner) (models.Task, error) {
+	var t models.Task
+	var description, coop, lastDone sql.NullString
+	var userID sql.NullInt64
+	if err := row.Scan(&t.ID, &t.Title, &description, &t.RRule, &t.StartDate, &coop, &userID, &t.Active, &t.CreatedAt, &t.UpdatedAt, &lastDone); err != nil {
+		return t, err
+	}
+	if description.Valid {
+		t.Description = &description.String
+	}
+	if coop.Valid {
+		t.Coop = &coop.String
+	}
+	if userID.Valid {
+		t.AssignedUserID = &userID.Int64
+	}
+	if lastDone.Valid {
+		d, err := time.Parse("2006-01-02", lastDone.String)
+		if err != nil {
+			return t, err
+		}
+		t.LastCompleted = &d
+	}
+	return t, nil
+}
+
+// taskStatus works out where a task stands on day. Occurrences after the last
+// completion that fall before day are missed; completing a later occurrence
+// catches up on the ones before it.
+func taskStatus(t models.Task, day time.Time) models.TaskDue {
+	status := models.TaskDue{Task: t}
+	rule, err := recur.Parse(t.RRule)
+	if err != nil {
+		return status
+	}
+	from := t.StartDate
+	if t.LastCompleted != nil {
+		from = t.LastCompleted.AddDate(0, 0, 1)
+	}
+	if next, ok := rule.Next(t.StartDate, from); ok {
+		status.NextDue = &next
+	}
+	if !t.Active {
+		return status
+	}
+	for _, d := range rule.Between(t.StartDate, from, day) {
+		if d.Equal(day) {
+			status.DueToday = true
+			continue
+		}
+		if status.OverdueSince == nil {
+			since := d
+			status.OverdueSince = &since
+		}
+		status.Missed++
+	}
+	status.Overdue = status.Missed > 0
+	if !status.DueToday && rule.Includes(t.StartDate, day) {
+		// Today's occurrence is already covered by a completion.
+		status.DueToday, status.Completed = true, true
+	}
+	return status
+}
+
+// validateTask checks the rule, start date and assignee of a task. Inputs
+// the client has to correct are *inputError.
+func validateTask(db *sql.DB, input *TaskInput) (time.Time, error) {
+	if _, err := recur.Parse(input.RRule); err != nil {
+		return time.Time{}, &inputError{http.StatusBadRequest, "invalid rrule: " + err.Error()}
+	}
+	start, err := time.Parse("2006-01-02", input.StartDate)
+	if err != nil {
+		return start, &inputError{http.StatusBadRequest, "invalid date"}
+	}
+	if input.Coop != nil && strings.TrimSpace(*input.Coop) == "" {
+		input.Coop = nil
+	}
+	if input.AssignedUserID != nil {
+		var n int
+		if err := db.QueryRow("SELECT COUNT(*) FROM users WHERE id = ?", *input.AssignedUserID).Scan(&n); err != nil {
+			return start, err
+		}
+		if n == 0 {
+			return start, &inputError{http.StatusBadRequest, "user not found"}
+		}
+	}
+	return start, nil
+}
+
+func CreateTaskHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input TaskInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondBindError(c, err)
+			return
+		}
+		start, err := validateTask(db, &input)
+		if err != nil {
+			respondInputError(c, err)
+			return
+		}
+		active := input.Active == nil || *input.Active
+		res, err := db.Exec(
+			"INSERT INTO tasks (title, description, rrule, start_date, coop, assigned_user_id, active) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			input.Title, input.Description, input.RRule, start, input.Coop, input.AssignedUserID, active,
+		)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		id, _ := res.LastInsertId()
+		c.JSON(http.StatusCreated, IDResponse{ID: id})
+	}
+}
+
+// ListTasksHandler lists tasks with their next due date. Query parameters:
+// coop, user_id, active=true|false, overdue=true.
+func ListTasksHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := "SELECT " + taskColumns + " FROM tasks t WHERE 1 = 1"
+		var args []interface{}
+		if v := c.Query("coop"); v != "" {
+			query += " AND t.coop = ?"
+			args = append(args, v)
+		}
+		if v := c.Query("user_id"); v != "" {
+			query += " AND t.assigned_user_id = ?"
+			args = append(args, v)
+		}
+		if v := c.Query("active"); v != "" {
+			query += " AND t.active = ?"
+			args = append(args, v == "true")
+		}
+		rows, err := db.Query(query+" ORDER BY t.title ASC, t.id ASC", args...)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		defer rows.Close()
+		day := today()
+		tasks := []models.TaskDue{}
+		for rows.Next() {
+			t, err := scanTask(rows)
+			if err != nil {
+				respondServerError(c, err)
+				return
+			}
+			status := taskStatus(t, day)
+			if c.Query("overdue") == "true" && !status.Overdue {
+				continue
+			}
+			tasks = append(tasks, status)
+		}
+		c.JSON(http.StatusOK, tasks)
+	}
+}
+
+// tasksDueOn returns the active tasks due on day or overdue, overdue ones
+// first, optionally limited to one coop or assignee.
+func tasksDueOn(db *sql.DB, day time.Time, coop, userID string) ([]models.TaskDue, error) {
+	query := "SELECT " + taskColumns + " FROM tasks t WHERE t.active = 1 AND t.start_date <= ?"
+	args := []interface{}{day}
+	if coop != "" {
+		query += " AND t.coop = ?"
+		args = append(args, coop)
+	}
+	if userID != "" {
+		query += " AND t.assigned_user_id = ?"
+		args = append(args, userID)
+	}
+	rows, err := db.Query(query+" ORDER BY t.title ASC, t.id ASC", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var overdue, due []models.TaskDue
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		status := taskStatus(t, day)
+		switch {
+		case status.Overdue:
+			overdue = append(overdue, status)
+		case status.DueToday:
+			due = append(due, status)
+		}
+	}
+	return append(append([]models.TaskDue{}, overdue...), due...), rows.Err()
+}
+
+// TasksTodayHandler lists the tasks due on ?date= (default today) or overdue,
+// overdue ones first, for the dashboard. Filter with ?user_id= and ?coop=.
+func TasksTodayHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		day := today()
+		if s := c.Query("date"); s != "" {
+			d, err := time.Parse("2006-01-02", s)
+			if err != nil {
+				respondError(c, http.StatusBadRequest, "invalid date")
+				return
+			}
+			day = d
+		}
+		tasks, err := tasksDueOn(db, day, c.Query("coop"), c.Query("user_id"))
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, tasks)
+	}
+}
+
+// TaskDetail is the response of GetTaskHandler.
+type TaskDetail struct {
+	Task        models.TaskDue          `json:"task"`
+	Completions []models.TaskCompletion `json:"completions"`
+}
+
+// GetTaskHandler returns a task with its status and completion history.
+func GetTaskHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		t, err := scanTask(db.QueryRow("SELECT "+taskColumns+" FROM tasks t WHERE t.id = ?", c.Param("id")))
+		if err == sql.ErrNoRows {
+			respondError(c, http.StatusNotFound, "task not found")
+			return
+		} else if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		completions, err := loadTaskCompletions(db, t.ID)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, TaskDetail{Task: taskStatus(t, today()), Completions: completions})
+	}
+}
+
+func UpdateTaskHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input TaskInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondBindError(c, err)
+			return
+		}
+		start, err := validateTask(db, &input)
+		if err != nil {
+			respondInputError(c, err)
+			return
+		}
+		active := input.Active == nil || *input.Active
+		res, err := db.Exec(
+			`UPDATE tasks SET title = ?, description = ?, rrule = ?, start_date = ?, coop = ?, assigned_user_id = ?, active = ?,
+				updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
+			input.Title, input.Description, input.RRule, start, input.Coop, input.AssignedUserID, active, c.Param("id"),
+		)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			respondError(c, http.StatusNotFound, "task not found")
+			return
+		}
+		c.JSON(http.StatusOK, MessageResponse{Message: "updated"})
+	}
+}
+
+// DeleteTaskHandler deletes a task and its completion history.
+func DeleteTaskHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tx, err := db.Begin()
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		defer tx.Rollback()
+		if _, err := tx.Exec("DELETE FROM task_completions WHERE task_id = ?", c.Param("id")); err != nil {
+			respondServerError(c, err)
+			return
+		}
+		if _, err := tx.Exec("DELETE FROM tasks WHERE id = ?", c.Param("id")); err != nil {
+			respondServerError(c, err)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			respondServerError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, MessageResponse{Message: "deleted"})
+	}
+}
+
+// TaskCompleted is returned for a new completion with the occurrence it
+// completed.
+type TaskCompleted struct {
+	ID      int64  `json:"id"`
+	DueDate string `json:"due_date"`
+}
+
+// CompleteTaskHandler logs a task as done. Without a due_date the completion
+// covers the latest occurrence on or before the completion date, which also
+// clears any earlier missed ones; when nothing is pending yet, it covers the
+// next occurrence so chores can be done early.
+func CompleteTaskHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input TaskCompletionInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondBindError(c, err)
+			return
+		}
+		t, err := scanTask(db.QueryRow("SELECT "+taskColumns+" FROM tasks t WHERE t.id = ?", c.Param("id")))
+		if err == sql.ErrNoRows {
+			respondError(c, http.StatusNotFound, "task not found")
+			return
+		} else if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		rule, err := recur.Parse(t.RRule)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		date := today()
+		if input.Date != "" {
+			if date, err = time.Parse("2006-01-02", input.Date); err != nil {
+				respondError(c, http.StatusBadRequest, "invalid date")
+				return
+			}
+		}
+
+		var due time.Time
+		if input.DueDate != "" {
+			if due, err = time.Parse("2006-01-02", input.DueDate); err != nil {
+				respondError(c, http.StatusBadRequest, "invalid due_date")
+				return
+			}
+			if !rule.Includes(t.StartDate, due) {
+				respondError(c, http.StatusBadRequest, "due_date is not an occurrence of the task")
+				return
+			}
+		} else {
+			from := t.StartDate
+			if t.LastCompleted != nil {
+				from = t.LastCompleted.AddDate(0, 0, 1)
+			}
+			if pending := rule.Between(t.StartDate, from, date); len(pending) > 0 {
+				due = pending[len(pending)-1]
+			} else if next, ok := rule.Next(t.StartDate, from); ok {
+				due = next
+			} else {
+				respondError(c, http.StatusBadRequest, "task has no occurrences left")
+				return
+			}
+		}
+
+		userID := input.UserID
+		if userID == nil {
+			if v, ok := c.Get("user_id"); ok {
+				if id, ok := v.(int64); ok {
+					userID = &id
+				}
+			}
+		}
+		var exists int
+		if err := db.QueryRow("SELECT COUNT(*) FROM task_completions WHERE task_id = ? AND due_date = ?", t.ID, due).Scan(&exists); err != nil {
+			respondServerError(c, err)
+			return
+		}
+		if exists > 0 {
+			respondError(c, http.StatusConflict, "occurrence already completed")
+			return
+		}
+		res, err := db.Exec(
+			"INSERT INTO task_completions (task_id, due_date, completed_on, user_id, notes) VALUES (?, ?, ?, ?, ?)",
+			t.ID, due, date, userID, input.Notes,
+		)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		id, _ := res.LastInsertId()
+		c.JSON(http.StatusCreated, TaskCompleted{ID: id, DueDate: due.Format("2006-01-02")})
+	}
+}
+
+func loadTaskCompletions(db *sql.DB, taskID int64) ([]models.TaskCompletion, error) {
+	rows, err := db.Query(
+		"SELECT id, task_id, due_date, completed_on, user_id, notes, created_at FROM task_completions WHERE task_id = ? ORDER BY due_date DESC, id DESC",
+		taskID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	completions := []models.TaskCompletion{}
+	for rows.Next() {
+		var tc models.TaskCompletion
+		var userID sql.NullInt64
+		var notes sql.NullString
+		if err := rows.Scan(&tc.ID, &tc.TaskID, &tc.DueDate, &tc.CompletedOn, &userID, &notes, &tc.CreatedAt); err != nil {
+			return nil, err
+		}
+		if userID.Valid {
+			tc.UserID = &userID.Int64
+		}
+		if notes.Valid {
+			tc.Notes = &notes.String
+		}
+		completions = append(completions, tc)
+	}
+	return completions, rows.Err()
+}
+
+// ListTaskCompletionsHandler lists a task's completions, latest occurrence first.
+func ListTaskCompletionsHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var t models.Task
+		if err := db.QueryRow("SELECT id FROM tasks WHERE id = ?", c.Param("id")).Scan(&t.ID); err == sql.ErrNoRows {
+			respondError(c, http.StatusNotFound, "task not found")
+			return
+		} else if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		completions, err := loadTaskCompletions(db, t.ID)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, completions)
+	}
+}
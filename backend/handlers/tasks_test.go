@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+
+	"egg-tracker/backend/db"
+	"egg-tracker/backend/models"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupTasksTestDB() (*sql.DB, func()) {
+	testDBPath := "test_tasks.db"
+	dbase, _ := sql.Open("sqlite3", testDBPath)
+	db.Migrate(dbase)
+	return dbase, func() {
+		dbase.Close()
+		os.Remove(testDBPath)
+	}
+}
+
+func tasksRouter(dbase *sql.DB) *gin.Engine {
+	router := gin.Default()
+	router.POST("/api/tasks", CreateTaskHandler(dbase))
+	router.GET("/api/tasks/today", TasksTodayHandler(dbase))
+	router.POST("/api/tasks/:id/complete", CompleteTaskHandler(dbase))
+	router.GET("/api/tasks/:id/completions", ListTaskCompletionsHandler(dbase))
+	return router
+}
+
+func tasksDue(t *testing.T, router *gin.Engine, query string) []models.TaskDue {
+	t.Helper()
+	w := doJSON(router, "GET", "/api/tasks/today?"+query, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var due []models.TaskDue
+	json.Unmarshal(w.Body.Bytes(), &due)
+	return due
+}
+
+func TestTaskValidation(t *testing.T) {
+	dbase, cleanup := setupTasksTestDB()
+	defer cleanup()
+	router := tasksRouter(dbase)
+
+	if w := doJSON(router, "POST", "/api/tasks", map[string]interface{}{"title": "Scrub waterers", "rrule": "FREQ=FORTNIGHTLY", "start_date": "2025-05-01"}); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unsupported rule, got %d", w.Code)
+	}
+	if w := doJSON(router, "POST", "/api/tasks", map[string]interface{}{"title": "Scrub waterers", "rrule": "FREQ=DAILY", "start_date": "2025-05-01", "assigned_user_id": 42}); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown user, got %d", w.Code)
+	}
+	// A failed user lookup is not the client's fault
+	mustExec(t, dbase, "ALTER TABLE users RENAME TO users_gone")
+	if w := doJSON(router, "POST", "/api/tasks", map[string]interface{}{"title": "Scrub waterers", "rrule": "FREQ=DAILY", "start_date": "2025-05-01", "assigned_user_id": 1}); w.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500 when users cannot be read, got %d", w.Code)
+	}
+}
+
+func TestTasksTodayOverdueAndCompletion(t *testing.T) {
+	dbase, cleanup := setupTasksTestDB()
+	defer cleanup()
+	router := tasksRouter(dbase)
+	mustExec(t, dbase, "INSERT INTO users (id, email, password_hash) VALUES (1, 'sam@example.com', 'x')")
+
+	doJSON(router, "POST", "/api/tasks", map[string]interface{}{"title": "Collect and check waterers", "rrule": "FREQ=DAILY", "start_date": "2025-05-01", "coop": "Main Coop", "assigned_user_id": 1})
+	// 2025-05-03 is a Saturday
+	doJSON(router, "POST", "/api/tasks", map[string]interface{}{"title": "Change bedding", "rrule": "FREQ=WEEKLY;BYDAY=SA", "start_date": "2025-05-01", "coop": "Back Barn"})
+
+	due := tasksDue(t, router, "date=2025-05-04")
+	if len(due) != 2 {
+		t.Fatalf("expected both tasks, got %+v", due)
+	}
+	daily := due[1]
+	if due[0].Title != "Change bedding" || !due[0].Overdue || due[0].DueToday || due[0].Missed != 1 {
+		t.Errorf("expected the bedding change to be overdue from Saturday, got %+v", due[0])
+	}
+	if !daily.Overdue || !daily.DueToday || daily.Missed != 3 || daily.OverdueSince.Format("2006-01-02") != "2025-05-01" {
+		t.Errorf("expected the daily task overdue since the 1st, got %+v", daily)
+	}
+	if got := tasksDue(t, router, "date=2025-05-04&user_id=1"); len(got) != 1 || got[0].ID != daily.ID {
+		t.Errorf("expected only the assigned task, got %+v", got)
+	}
+
+	// Completing today catches up on the missed days
+	w := doJSON(router, "POST", "/api/tasks/1/complete", map[string]interface{}{"date": "2025-05-04", "user_id": 1})
+	var done map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &done)
+	if w.Code != http.StatusCreated || done["due_date"] != "2025-05-04" {
+		t.Fatalf("expected the 4th to be completed, got %d: %s", w.Code, w.Body.String())
+	}
+	if w = doJSON(router, "POST", "/api/tasks/1/complete", map[string]interface{}{"due_date": "2025-05-04"}); w.Code != http.StatusConflict {
+		t.Errorf("expected 409 completing the same occurrence twice, got %d", w.Code)
+	}
+	if w = doJSON(router, "POST", "/api/tasks/2/complete", map[string]interface{}{"due_date": "2025-05-05"}); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a day the task is not due, got %d", w.Code)
+	}
+	due = tasksDue(t, router, "date=2025-05-04&coop=Main Coop")
+	if len(due) != 1 || due[0].Overdue || !due[0].Completed || due[0].NextDue.Format("2006-01-02") != "2025-05-05" {
+		t.Errorf("expected the daily task done for today, got %+v", due)
+	}
+
+	// Doing the weekly chore early covers the next Saturday
+	w = doJSON(router, "POST", "/api/tasks/2/complete", map[string]interface{}{"date": "2025-05-02"})
+	json.Unmarshal(w.Body.Bytes(), &done)
+	if done["due_date"] != "2025-05-03" {
+		t.Errorf("expected the early completion to cover the 3rd, got %s", w.Body.String())
+	}
+	w = doJSON(router, "GET", "/api/tasks/2/completions", nil)
+	var completions []models.TaskCompletion
+	json.Unmarshal(w.Body.Bytes(), &completions)
+	if len(completions) != 1 || completions[0].CompletedOn.Format("2006-01-02") != "2025-05-02" {
+		t.Errorf("unexpected completions: %s", w.Body.String())
+	}
+}
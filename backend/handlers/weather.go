@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"egg-tracker/backend/logging"
+	"egg-tracker/backend/models"
+	"egg-tracker/backend/weather"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxWeatherImportBytes = 20 << 20
+
+// weatherFormat picks the parser for an upload from ?format=, then the file
+// extension, then the content type, falling back to CSV.
+func weatherFormat(c *gin.Context, filename string) string {
+	if f := c.Query("format"); f != "" {
+		return f
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".dly":
+		return "ghcn"
+	case ".json":
+		return "json"
+	case ".csv":
+		return "csv"
+	}
+	if strings.HasPrefix(c.ContentType(), "application/json") {
+		return "json"
+	}
+	return "csv"
+}
+
+// WeatherImportResult is the response of ImportWeatherHandler. Days
+// already recorded are skipped.
+type WeatherImportResult struct {
+	Imported int    `json:"imported"`
+	Skipped  int    `json:"skipped"`
+	Format   string `json:"format"`
+}
+
+// ImportWeatherHandler loads daily weather from a multipart "file" upload or
+// the raw request body. Query parameters: format=ghcn|csv|json (guessed from
+// the file name otherwise), units=metric|imperial for CSV and JSON (default
+// metric), station= for rows that do not name one. Days already imported for
+// the same station are updated; values missing from the new file are kept.
+func ImportWeatherHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxWeatherImportBytes)
+		var r io.Reader = c.Request.Body
+		filename := ""
+		if strings.HasPrefix(c.ContentType(), "multipart/") {
+			file, err := c.FormFile("file")
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					respondError(c, http.StatusRequestEntityTooLarge, "file too large")
+					return
+				}
+				respondError(c, http.StatusBadRequest, "missing file")
+				return
+			}
+			f, err := file.Open()
+			if err != nil {
+				respondError(c, http.StatusBadRequest, "could not read file")
+				return
+			}
+			defer f.Close()
+			r, filename = f, file.Filename
+		}
+
+		units := weather.Units(c.DefaultQuery("units", string(weather.Metric)))
+		if units != weather.Metric && units != weather.Imperial {
+			respondError(c, http.StatusBadRequest, "units must be metric or imperial")
+			return
+		}
+		format := weatherFormat(c, filename)
+		var days []weather.Day
+		var err error
+		switch format {
+		case "ghcn":
+			days, err = weather.ParseGHCN(r)
+		case "csv":
+			days, err = weather.ParseCSV(r, units)
+		case "json":
+			days, err = weather.ParseJSON(r, units)
+		default:
+			respondError(c, http.StatusBadRequest, "format must be ghcn, csv or json")
+			return
+		}
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				respondError(c, http.StatusRequestEntityTooLarge, "file too large")
+				return
+			}
+			respondError(c, http.StatusBadRequest, "invalid "+format+" file: "+err.Error())
+			return
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		defer tx.Rollback()
+		station := c.Query("station")
+		imported := 0
+		for _, d := range days {
+			if d.TminC == nil && d.TmaxC == nil && d.TavgC == nil && d.PrecipMM == nil {
+				continue
+			}
+			if d.Station == "" {
+				d.Station = station
+			}
+			_, err := tx.Exec(
+				`INSERT INTO weather_daily (date, station, tmin_c, tmax_c, tavg_c, precip_mm, source) VALUES (?, ?, ?, ?, ?, ?, ?)
+				ON CONFLICT(station, date) DO UPDATE SET
+					tmin_c = COALESCE(excluded.tmin_c, tmin_c), tmax_c = COALESCE(excluded.tmax_c, tmax_c),
+					tavg_c = COALESCE(excluded.tavg_c, tavg_c), precip_mm = COALESCE(excluded.precip_mm, precip_mm),
+					source = excluded.source, updated_at = CURRENT_TIMESTAMP`,
+				d.Date, d.Station, d.TminC, d.TmaxC, d.TavgC, d.PrecipMM, format,
+			)
+			if err != nil {
+				requestLogger(c, "ImportWeatherHandler").Error("Insert failed", "date", d.Date.Format("2006-01-02"), logging.Err(err))
+				respondError(c, http.StatusInternalServerError, "db error")
+				return
+			}
+			imported++
+		}
+		if err := tx.Commit(); err != nil {
+			respondServerError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, WeatherImportResult{Imported: imported, Skipped: len(days) - imported, Format: format})
+	}
+}
+
+// ListWeatherHandler lists imported days between ?from= and ?to= (YYYY-MM-DD,
+// default the last seven days), optionally for one ?station=.
+func ListWeatherHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		from, to, ok := readingRange(c)
+		if !ok {
+			respondError(c, http.StatusBadRequest, "invalid date")
+			return
+		}
+		query := "SELECT id, date, station, tmin_c, tmax_c, tavg_c, precip_mm, source FROM weather_daily WHERE date >= ? AND date < ?"
+		args := []interface{}{from, to}
+		if s, ok := c.GetQuery("station"); ok {
+			query += " AND station = ?"
+			args = append(args, s)
+		}
+		rows, err := db.Query(query+" ORDER BY date ASC, station ASC", args...)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		defer rows.Close()
+		days := []models.WeatherDay{}
+		for rows.Next() {
+			var d models.WeatherDay
+			var tmin, tmax, tavg, precip sql.NullFloat64
+			if err := rows.Scan(&d.ID, &d.Date, &d.Station, &tmin, &tmax, &tavg, &precip, &d.Source); err != nil {
+				respondServerError(c, err)
+				return
+			}
+			if tmin.Valid {
+				d.TminC = &tmin.Float64
+			}
+			if tmax.Valid {
+				d.TmaxC = &tmax.Float64
+			}
+			if tavg.Valid {
+				d.TavgC = &tavg.Float64
+			}
+			if precip.Valid {
+				d.PrecipMM = &precip.Float64
+			}
+			days = append(days, d)
+		}
+		c.JSON(http.StatusOK, days)
+	}
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"database/sql"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"egg-tracker/backend/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// weatherTempSQL is the daily temperature each ?temp= choice reads, averaged
+// across stations. The mean falls back to the midpoint of min and max.
+var weatherTempSQL = map[string]string{
+	"tmax": "tmax_c",
+	"tmin": "tmin_c",
+	"tavg": "COALESCE(tavg_c, (tmin_c + tmax_c) / 2)",
+}
+
+type TemperatureBand struct {
+	Coop       string  `json:"coop"`
+	MinC       float64 `json:"min_c"` // inclusive
+	MaxC       float64 `json:"max_c"` // exclusive
+	Days       int     `json:"days"`
+	Eggs       int     `json:"eggs"`
+	EggsPerDay float64 `json:"eggs_per_day"`
+}
+
+type WeatherCorrelation struct {
+	Coop string   `json:"coop"`
+	Days int      `json:"days"`
+	R    *float64 `json:"r"` // Pearson correlation of temperature and eggs; null when either never varies
+}
+
+// weatherDailySQL pairs each coop's daily collections with the day's
+// temperature. A coop counts from its first to its last collection in the
+// range, with zero eggs on days in between that have no collection.
+func weatherDailySQL(tempExpr string) string {
+	return `
+	WITH w AS (
+		SELECT CAST(date AS DATE) AS d, AVG(` + tempExpr + `) AS t
+		FROM weather_daily
+		WHERE CAST(date AS DATE) BETWEEN CAST(? AS DATE) AND CAST(? AS DATE)
+		GROUP BY 1
+		HAVING AVG(` + tempExpr + `) IS NOT NULL
+	),
+	eggs AS (
+		SELECT CAST(date AS DATE) AS d, COALESCE(coop, '') AS coop, SUM(quantity) AS n
+		FROM inventory_actions
+		WHERE action = 'collected' AND CAST(date AS DATE) BETWEEN CAST(? AS DATE) AND CAST(? AS DATE)
+		GROUP BY 1, 2
+	),
+	span AS (SELECT coop, MIN(d) AS first_day, MAX(d) AS last_day FROM eggs GROUP BY 1),
+	daily AS (
+		SELECT s.coop, w.d, w.t, CAST(COALESCE(e.n, 0) AS DOUBLE) AS n
+		FROM span s
+		JOIN w ON w.d BETWEEN s.first_day AND s.last_day
+		LEFT JOIN eggs e ON e.coop = s.coop AND e.d = w.d
+	)`
+}
+
+// queryWeatherProduction groups days into temperature bands of width degrees
+// per coop and correlates temperature with eggs collected.
+func queryWeatherProduction(duck *sql.DB, tempExpr string, width float64, from, to time.Time) ([]TemperatureBand, []WeatherCorrelation, error) {
+	f, t := from.Format("2006-01-02"), to.Format("2006-01-02")
+	rows, err := duck.Query(weatherDailySQL(tempExpr)+`
+	SELECT coop, CAST(FLOOR(t / ?) * ? AS DOUBLE) AS band, CAST(COUNT(*) AS BIGINT), CAST(SUM(n) AS BIGINT)
+	FROM daily GROUP BY 1, 2 ORDER BY 1, 2`, f, t, f, t, width, width)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	bands := []TemperatureBand{}
+	for rows.Next() {
+		var b TemperatureBand
+		if err := rows.Scan(&b.Coop, &b.MinC, &b.Days, &b.Eggs); err != nil {
+			return nil, nil, err
+		}
+		b.MaxC = b.MinC + width
+		b.EggsPerDay = float64(b.Eggs) / float64(b.Days)
+		bands = append(bands, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	rows.Close()
+
+	rows, err = duck.Query(weatherDailySQL(tempExpr)+`
+	SELECT coop, CAST(COUNT(*) AS BIGINT), CAST(corr(t, n) AS DOUBLE)
+	FROM daily GROUP BY 1 ORDER BY 1`, f, t, f, t)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	correlations := []WeatherCorrelation{}
+	for rows.Next() {
+		var wc WeatherCorrelation
+		var r sql.NullFloat64
+		if err := rows.Scan(&wc.Coop, &wc.Days, &r); err != nil {
+			return nil, nil, err
+		}
+		if r.Valid && !math.IsNaN(r.Float64) {
+			wc.R = &r.Float64
+		}
+		correlations = append(correlations, wc)
+	}
+	return bands, correlations, rows.Err()
+}
+
+// WeatherProductionReport is the response of WeatherProductionReportHandler.
+type WeatherProductionReport struct {
+	Bands        []TemperatureBand    `json:"bands"`
+	Correlations []WeatherCorrelation `json:"correlations"`
+}
+
+// WeatherProductionReportHandler relates daily temperature to eggs collected
+// per coop from DuckDB. Query parameters: from/to as YYYY-MM-DD (default:
+// first collection to today), temp=tmax|tmin|tavg (default tmax), band=width
+// of the temperature bands in °C (default 5).
+func WeatherProductionReportHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tempExpr, ok := weatherTempSQL[c.DefaultQuery("temp", "tmax")]
+		if !ok {
+			respondError(c, http.StatusBadRequest, "temp must be tmax, tmin or tavg")
+			return
+		}
+		width, err := strconv.ParseFloat(c.DefaultQuery("band", "5"), 64)
+		if err != nil || width <= 0 {
+			respondError(c, http.StatusBadRequest, "band must be a positive number of degrees")
+			return
+		}
+		duckdb, err := sql.Open("duckdb", analyticsDBPath)
+		if err != nil {
+			requestLogger(c, "WeatherProductionReportHandler").Error("Failed to open DuckDB", logging.Err(err))
+			respondError(c, http.StatusInternalServerError, "failed to open DuckDB")
+			return
+		}
+		defer duckdb.Close()
+
+		from, to, err := reportRange(c, func() (time.Time, error) { return firstCollectionDate(duckdb) })
+		if err != nil {
+			respondInputError(c, err)
+			return
+		}
+		bands, correlations, err := queryWeatherProduction(duckdb, tempExpr, width, from, to)
+		if err != nil {
+			requestLogger(c, "WeatherProductionReportHandler").Error("Weather query failed", logging.Err(err))
+			respondError(c, http.StatusInternalServerError, "weather query failed")
+			return
+		}
+		c.JSON(http.StatusOK, WeatherProductionReport{Bands: bands, Correlations: correlations})
+	}
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestWeatherProductionReport_BandsAndCorrelation(t *testing.T) {
+	cleanup := setupAnalyticsTestDB(t, func(dbase *sql.DB) {
+		mustExec(t, dbase, `INSERT INTO weather_daily (date, station, tmin_c, tmax_c, source) VALUES
+			('2025-07-01', 'farm', 12, 20, 'csv'), ('2025-07-02', 'farm', 13, 22, 'csv'),
+			('2025-07-03', 'farm', 21, 33, 'csv'), ('2025-07-04', 'farm', 24, 35, 'csv')`)
+		mustExec(t, dbase, `INSERT INTO inventory_actions (quantity, species, coop, action, date) VALUES
+			(10, 'Chicken', 'Main Coop', 'collected', '2025-07-01'),
+			(10, 'Chicken', 'Main Coop', 'collected', '2025-07-02'),
+			(6, 'Chicken', 'Main Coop', 'collected', '2025-07-03'),
+			(4, 'Chicken', 'Main Coop', 'collected', '2025-07-04'),
+			(3, 'Goose', 'Back Barn', 'collected', '2025-07-02'),
+			(2, 'Chicken', 'Main Coop', 'sold', '2025-07-03')`)
+	})
+	defer cleanup()
+
+	router := gin.Default()
+	router.GET("/api/reports/weather-production", WeatherProductionReportHandler())
+	req, _ := http.NewRequest("GET", "/api/reports/weather-production?from=2025-07-01&to=2025-07-31", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Bands        []TemperatureBand    `json:"bands"`
+		Correlations []WeatherCorrelation `json:"correlations"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+
+	var main []TemperatureBand
+	for _, b := range resp.Bands {
+		if b.Coop == "Main Coop" {
+			main = append(main, b)
+		}
+	}
+	if len(main) != 3 || main[0].MinC != 20 || main[0].Days != 2 || main[0].EggsPerDay != 10 || main[2].MinC != 35 || main[2].Eggs != 4 {
+		t.Errorf("unexpected bands: %+v", main)
+	}
+	if len(resp.Correlations) != 2 {
+		t.Fatalf("expected a correlation per coop, got %+v", resp.Correlations)
+	}
+	barn, mainCoop := resp.Correlations[0], resp.Correlations[1]
+	if barn.Coop != "Back Barn" || barn.Days != 1 || barn.R != nil {
+		t.Errorf("expected no correlation from a single day, got %+v", barn)
+	}
+	if mainCoop.Days != 4 || mainCoop.R == nil || *mainCoop.R > -0.9 {
+		t.Errorf("expected a strong negative correlation, got %+v", mainCoop)
+	}
+
+	req, _ = http.NewRequest("GET", "/api/reports/weather-production?temp=humidity", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown temperature, got %d", w.Code)
+	}
+}
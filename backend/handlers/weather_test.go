@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"egg-tracker/backend/db"
+	"egg-tracker/backend/models"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupWeatherTestDB() (*sql.DB, func()) {
+	testDBPath := "test_weather.db"
+	dbase, _ := sql.Open("sqlite3", testDBPath)
+	db.Migrate(dbase)
+	return dbase, func() {
+		dbase.Close()
+		os.Remove(testDBPath)
+	}
+}
+
+func TestImportWeather(t *testing.T) {
+	dbase, cleanup := setupWeatherTestDB()
+	defer cleanup()
+	router := gin.Default()
+	router.POST("/api/weather/import", ImportWeatherHandler(dbase))
+	router.GET("/api/weather", ListWeatherHandler(dbase))
+
+	csvBody := "date,tmax,tmin,precip\n2025-07-01,95,68,0.5\n2025-07-02,86,,\n2025-07-03,,,\n"
+	req, _ := http.NewRequest("POST", "/api/weather/import?units=imperial&station=farm", bytes.NewBufferString(csvBody))
+	req.Header.Set("Content-Type", "text/csv")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var resp map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || resp["imported"] != 2.0 || resp["skipped"] != 1.0 {
+		t.Fatalf("expected 2 days imported and an empty one skipped, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// A JSON upload adds the missing minimum without losing the maximum
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("file", "july.json")
+	fw.Write([]byte(`[{"date": "2025-07-02", "station": "farm", "tmin_c": 18}]`))
+	mw.Close()
+	req, _ = http.NewRequest("POST", "/api/weather/import", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 for the JSON upload, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = doJSON(router, "GET", "/api/weather?from=2025-07-01&to=2025-07-31&station=farm", nil)
+	var days []models.WeatherDay
+	json.Unmarshal(w.Body.Bytes(), &days)
+	if len(days) != 2 || days[1].TmaxC == nil || *days[1].TmaxC != 30 || days[1].TminC == nil || *days[1].TminC != 18 || days[1].Source != "json" {
+		t.Errorf("unexpected weather: %s", w.Body.String())
+	}
+
+	req, _ = http.NewRequest("POST", "/api/weather/import?format=csv", bytes.NewBufferString("when,tmax\n2025-07-01,30\n"))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a CSV without dates, got %d", w.Code)
+	}
+}
@@ -0,0 +1,399 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"egg-tracker/backend/logging"
+	"egg-tracker/backend/models"
+	"egg-tracker/backend/webhook"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookInput struct {
+	URL         string   `json:"url" binding:"required"`
+	EventTypes  []string `json:"event_types" binding:"required"` // ["*"] for all
+	Secret      *string  `json:"secret"`                         // generated on create if omitted, kept on update
+	Description *string  `json:"description"`
+	Active      *bool    `json:"active"` // default true
+}
+
+// validate checks the input and returns the event types as stored, or a
+// message for the client.
+func (in WebhookInput) validate() (string, string) {
+	u, err := url.Parse(in.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", "url must be an absolute http or https URL"
+	}
+	if in.Secret != nil && *in.Secret == "" {
+		return "", "secret must not be empty"
+	}
+	if len(in.EventTypes) == 0 {
+		return "", "event_types must not be empty"
+	}
+	var types []string
+	for _, t := range in.EventTypes {
+		if t == "*" {
+			return "*", ""
+		}
+		if !containsString(eventTypes, t) {
+			return "", "unknown event type: " + t
+		}
+		if !containsString(types, t) {
+			types = append(types, t)
+		}
+	}
+	return strings.Join(types, ","), ""
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func newWebhookSecret() (string, error) {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+func loadWebhooks(db *sql.DB, where string, args ...interface{}) ([]models.Webhook, error) {
+	rows, err := db.Query("SELECT id, url, event_types, description, active, created_at, updated_at FROM webhooks "+where+" ORDER BY id ASC", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	hooks := []models.Webhook{}
+	for rows.Next() {
+		var w models.Webhook
+		var types string
+		var description sql.NullString
+		if err := rows.Scan(&w.ID, &w.URL, &types, &description, &w.Active, &w.CreatedAt, &w.UpdatedAt); err != nil {
+			return nil, err
+		}
+		w.EventTypes = splitKinds(types)
+		if description.Valid {
+			w.Description = &description.String
+		}
+		hooks = append(hooks, w)
+	}
+	return hooks, rows.Err()
+}
+
+func ListWebhooksHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		hooks, err := loadWebhooks(db, "")
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, hooks)
+	}
+}
+
+func GetWebhookHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		hooks, err := loadWebhooks(db, "WHERE id = ?", c.Param("id"))
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		if len(hooks) == 0 {
+			respondError(c, http.StatusNotFound, "webhook not found")
+			return
+		}
+		c.JSON(http.StatusOK, hooks[0])
+	}
+}
+
+// WebhookCreated is returned for a new webhook. The secret is shown only
+// here.
+type WebhookCreated struct {
+	ID     int64  `json:"id"`
+	Secret string `json:"secret"`
+}
+
+// CreateWebhookHandler subscribes a URL to events. The response carries the
+// signing secret, which is not shown again.
+func CreateWebhookHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input WebhookInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondBindError(c, err)
+			return
+		}
+		types, msg := input.validate()
+		if msg != "" {
+			respondError(c, http.StatusBadRequest, msg)
+			return
+		}
+		var secret string
+		if input.Secret != nil {
+			secret = *input.Secret
+		} else {
+			s, err := newWebhookSecret()
+			if err != nil {
+				respondServerError(c, err)
+				return
+			}
+			secret = s
+		}
+		active := input.Active == nil || *input.Active
+		res, err := db.Exec(
+			"INSERT INTO webhooks (url, secret, event_types, description, active) VALUES (?, ?, ?, ?, ?)",
+			input.URL, secret, types, input.Description, active,
+		)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		id, _ := res.LastInsertId()
+		c.JSON(http.StatusCreated, WebhookCreated{ID: id, Secret: secret})
+	}
+}
+
+// UpdateWebhookHandler replaces a webhook's settings, keeping its secret
+// unless a new one is given. Deactivating it gives up on its pending
+// deliveries; they can still be redelivered later.
+func UpdateWebhookHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		var input WebhookInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			respondBindError(c, err)
+			return
+		}
+		types, msg := input.validate()
+		if msg != "" {
+			respondError(c, http.StatusBadRequest, msg)
+			return
+		}
+		active := input.Active == nil || *input.Active
+		res, err := db.Exec(
+			`UPDATE webhooks SET url = ?, secret = COALESCE(?, secret), event_types = ?, description = ?, active = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE id = ?`,
+			input.URL, input.Secret, types, input.Description, active, id,
+		)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			respondError(c, http.StatusNotFound, "webhook not found")
+			return
+		}
+		if !active {
+			if _, err := db.Exec("UPDATE webhook_deliveries SET status = 'failed', last_error = 'webhook deactivated' WHERE webhook_id = ? AND status = 'pending'", id); err != nil {
+				respondServerError(c, err)
+				return
+			}
+		}
+		c.JSON(http.StatusOK, MessageResponse{Message: "updated"})
+	}
+}
+
+func DeleteWebhookHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.Param("id")
+		if _, err := db.Exec("DELETE FROM webhook_deliveries WHERE webhook_id = ?", id); err != nil {
+			respondServerError(c, err)
+			return
+		}
+		res, err := db.Exec("DELETE FROM webhooks WHERE id = ?", id)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			respondError(c, http.StatusNotFound, "webhook not found")
+			return
+		}
+		c.JSON(http.StatusOK, MessageResponse{Message: "deleted"})
+	}
+}
+
+// ListWebhookDeliveriesHandler returns the :id webhook's delivery history,
+// newest first. Query parameters: status=pending|delivered|failed,
+// event_type, limit (default 100).
+func ListWebhookDeliveriesHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var exists int
+		if err := db.QueryRow("SELECT COUNT(*) FROM webhooks WHERE id = ?", c.Param("id")).Scan(&exists); err != nil {
+			respondServerError(c, err)
+			return
+		}
+		if exists == 0 {
+			respondError(c, http.StatusNotFound, "webhook not found")
+			return
+		}
+		query := `SELECT d.id, d.webhook_id, d.event_id, e.type, d.status, d.attempts, d.next_attempt_at, d.response_status, d.last_error, d.delivered_at, d.created_at
+			FROM webhook_deliveries d JOIN events e ON e.id = d.event_id WHERE d.webhook_id = ?`
+		args := []interface{}{c.Param("id")}
+		if v := c.Query("status"); v != "" {
+			query += " AND d.status = ?"
+			args = append(args, v)
+		}
+		if v := c.Query("event_type"); v != "" {
+			query += " AND e.type = ?"
+			args = append(args, v)
+		}
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+		if err != nil || limit < 1 {
+			respondError(c, http.StatusBadRequest, "limit must be a positive number")
+			return
+		}
+		rows, err := db.Query(query+" ORDER BY d.created_at DESC, d.id DESC LIMIT ?", append(args, limit)...)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		defer rows.Close()
+		deliveries := []models.WebhookDelivery{}
+		for rows.Next() {
+			var d models.WebhookDelivery
+			var status sql.NullInt64
+			var lastError sql.NullString
+			var deliveredAt sql.NullTime
+			if err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Status, &d.Attempts, &d.NextAttemptAt, &status, &lastError, &deliveredAt, &d.CreatedAt); err != nil {
+				respondServerError(c, err)
+				return
+			}
+			if status.Valid {
+				code := int(status.Int64)
+				d.ResponseStatus = &code
+			}
+			if lastError.Valid {
+				d.LastError = &lastError.String
+			}
+			if deliveredAt.Valid {
+				d.DeliveredAt = &deliveredAt.Time
+			}
+			deliveries = append(deliveries, d)
+		}
+		c.JSON(http.StatusOK, deliveries)
+	}
+}
+
+// RedeliverWebhookHandler queues the event of a past delivery to be sent
+// again to the same webhook, as a new delivery.
+func RedeliverWebhookHandler(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var eventID int64
+		var active bool
+		err := db.QueryRow(
+			"SELECT d.event_id, w.active FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id WHERE d.id = ? AND d.webhook_id = ?",
+			c.Param("delivery_id"), c.Param("id"),
+		).Scan(&eventID, &active)
+		if err == sql.ErrNoRows {
+			respondError(c, http.StatusNotFound, "delivery not found")
+			return
+		}
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		if !active {
+			respondError(c, http.StatusConflict, "webhook is not active")
+			return
+		}
+		res, err := db.Exec(
+			"INSERT INTO webhook_deliveries (webhook_id, event_id, next_attempt_at) VALUES (?, ?, ?)",
+			c.Param("id"), eventID, time.Now().UTC(),
+		)
+		if err != nil {
+			respondServerError(c, err)
+			return
+		}
+		id, _ := res.LastInsertId()
+		c.JSON(http.StatusAccepted, IDResponse{ID: id})
+	}
+}
+
+// DeliverWebhooks posts pending deliveries that are due to active webhooks.
+// Failed ones are retried with exponential backoff until
+// webhook.MaxAttempts. It returns the number delivered.
+func DeliverWebhooks(db *sql.DB, client *http.Client, now time.Time) (int, error) {
+	rows, err := db.Query(`
+	SELECT d.id, d.attempts, d.next_attempt_at, w.url, w.secret, e.id, e.type, e.payload, e.created_at
+	FROM webhook_deliveries d
+	JOIN webhooks w ON w.id = d.webhook_id
+	JOIN events e ON e.id = d.event_id
+	WHERE d.status = 'pending' AND w.active = 1
+	ORDER BY d.next_attempt_at ASC, d.id ASC`)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	type pending struct {
+		id          int64
+		attempts    int
+		due         time.Time
+		url, secret string
+		event       models.Event
+	}
+	var queue []pending
+	for rows.Next() {
+		var p pending
+		var payload string
+		if err := rows.Scan(&p.id, &p.attempts, &p.due, &p.url, &p.secret, &p.event.ID, &p.event.Type, &payload, &p.event.CreatedAt); err != nil {
+			return 0, err
+		}
+		p.event.Data = json.RawMessage(payload)
+		if !p.due.After(now) {
+			queue = append(queue, p)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	delivered := 0
+	for _, p := range queue {
+		body, err := json.Marshal(p.event)
+		if err != nil {
+			return delivered, err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		code, err := webhook.Post(ctx, client, webhook.Request{URL: p.url, Secret: p.secret, Event: p.event.Type, DeliveryID: p.id, Body: body}, now)
+		cancel()
+		var status interface{}
+		if code != 0 {
+			status = code
+		}
+		attempts := p.attempts + 1
+		switch {
+		case err == nil:
+			_, err = db.Exec("UPDATE webhook_deliveries SET status = 'delivered', attempts = ?, response_status = ?, last_error = NULL, delivered_at = ? WHERE id = ?", attempts, status, now.UTC(), p.id)
+			delivered++
+		case attempts >= webhook.MaxAttempts:
+			slog.Warn("Giving up on delivery", "component", "DeliverWebhooks", "delivery_id", p.id, "url", p.url, logging.Err(err))
+			_, err = db.Exec("UPDATE webhook_deliveries SET status = 'failed', attempts = ?, response_status = ?, last_error = ? WHERE id = ?", attempts, status, err.Error(), p.id)
+		default:
+			_, err = db.Exec(
+				"UPDATE webhook_deliveries SET attempts = ?, response_status = ?, last_error = ?, next_attempt_at = ? WHERE id = ?",
+				attempts, status, err.Error(), now.Add(webhook.Backoff(attempts)).UTC(), p.id,
+			)
+		}
+		if err != nil {
+			return delivered, err
+		}
+	}
+	return delivered, nil
+}
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"egg-tracker/backend/db"
+	"egg-tracker/backend/models"
+	"egg-tracker/backend/webhook"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupWebhooksTestDB() (*sql.DB, func()) {
+	testDBPath := "test_webhooks.db"
+	dbase, _ := sql.Open("sqlite3", testDBPath)
+	db.Migrate(dbase)
+	return dbase, func() {
+		dbase.Close()
+		os.Remove(testDBPath)
+	}
+}
+
+func webhooksRouter(dbase *sql.DB) *gin.Engine {
+	router := gin.Default()
+	router.POST("/api/inventory", CreateInventoryHandler(dbase))
+	router.DELETE("/api/inventory/:id", DeleteInventoryHandler(dbase))
+	router.POST("/api/options/:type/:id/deactivate", DeactivateOptionHandler(dbase))
+	router.GET("/api/webhooks", ListWebhooksHandler(dbase))
+	router.POST("/api/webhooks", CreateWebhookHandler(dbase))
+	router.PUT("/api/webhooks/:id", UpdateWebhookHandler(dbase))
+	router.DELETE("/api/webhooks/:id", DeleteWebhookHandler(dbase))
+	router.GET("/api/webhooks/:id/deliveries", ListWebhookDeliveriesHandler(dbase))
+	router.POST("/api/webhooks/:id/deliveries/:delivery_id/redeliver", RedeliverWebhookHandler(dbase))
+	return router
+}
+
+// receivedHook is a request the test receiver accepted as correctly signed.
+type receivedHook struct {
+	event    string
+	delivery string
+	body     models.Event
+}
+
+func webhookReceiver(t *testing.T, secret string, failures *int) (*httptest.Server, *[]receivedHook) {
+	var received []receivedHook
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if *failures > 0 {
+			*failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		ts, _ := strconv.ParseInt(r.Header.Get("X-Webhook-Timestamp"), 10, 64)
+		if !webhook.Verify(secret, ts, body, r.Header.Get("X-Webhook-Signature")) {
+			t.Errorf("bad signature on %s", body)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var e models.Event
+		if err := json.Unmarshal(body, &e); err != nil {
+			t.Errorf("decode event: %v", err)
+		}
+		received = append(received, receivedHook{r.Header.Get("X-Webhook-Event"), r.Header.Get("X-Webhook-Delivery"), e})
+	}))
+	t.Cleanup(server.Close)
+	return server, &received
+}
+
+func webhookDeliveries(t *testing.T, router *gin.Engine, id interface{}) []models.WebhookDelivery {
+	t.Helper()
+	w := doJSON(router, "GET", fmt.Sprintf("/api/webhooks/%v/deliveries", id), nil)
+	var deliveries []models.WebhookDelivery
+	if err := json.Unmarshal(w.Body.Bytes(), &deliveries); err != nil {
+		t.Fatalf("decode deliveries: %v: %s", err, w.Body.String())
+	}
+	return deliveries
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	dbase, cleanup := setupWebhooksTestDB()
+	defer cleanup()
+	router := webhooksRouter(dbase)
+	failures := 0
+	server, received := webhookReceiver(t, "s3cret", &failures)
+
+	for _, body := range []gin.H{
+		{"url": "ftp://example.com", "event_types": []string{"*"}},
+		{"url": server.URL, "event_types": []string{"inventory.exploded"}},
+		{"url": server.URL, "event_types": []string{}},
+	} {
+		if w := doJSON(router, "POST", "/api/webhooks", body); w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %v, got %d", body, w.Code)
+		}
+	}
+	w := doJSON(router, "POST", "/api/webhooks", gin.H{"url": server.URL, "secret": "s3cret", "event_types": []string{"inventory.created", "option.deactivated"}})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create webhook: %d %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		ID     int64  `json:"id"`
+		Secret string `json:"secret"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	// A second subscriber to everything gets its own generated secret.
+	w = doJSON(router, "POST", "/api/webhooks", gin.H{"url": server.URL + "/all", "event_types": []string{"*"}, "active": false})
+	var all struct {
+		ID     int64  `json:"id"`
+		Secret string `json:"secret"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &all)
+	if len(all.Secret) != 48 {
+		t.Errorf("expected a generated secret, got %q", all.Secret)
+	}
+	w = doJSON(router, "GET", "/api/webhooks", nil)
+	var hooks []map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &hooks)
+	if len(hooks) != 2 || hooks[0]["secret"] != nil {
+		t.Fatalf("expected 2 webhooks without secrets, got %s", w.Body.String())
+	}
+
+	w = doJSON(router, "POST", "/api/inventory", gin.H{"quantity": 6, "species": "Chicken", "coop": "North", "egg_color": "Brown", "egg_size": "Large", "action": "collected", "date": "2025-06-01"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create inventory: %d %s", w.Code, w.Body.String())
+	}
+	mustExec(t, dbase, "INSERT INTO coops (name, active) VALUES ('North', 1)")
+	doJSON(router, "POST", "/api/options/coop/1/deactivate", nil)
+
+	// The receiver is down for the first attempt at both events.
+	failures = 2
+	now := time.Now()
+	if n, err := DeliverWebhooks(dbase, server.Client(), now); err != nil || n != 0 {
+		t.Fatalf("expected no deliveries, got %d, %v", n, err)
+	}
+	deliveries := webhookDeliveries(t, router, created.ID)
+	if len(deliveries) != 2 {
+		t.Fatalf("expected 2 deliveries, got %+v", deliveries)
+	}
+	for _, d := range deliveries {
+		if d.Status != "pending" || d.Attempts != 1 || d.ResponseStatus == nil || *d.ResponseStatus != http.StatusServiceUnavailable || !d.NextAttemptAt.After(now) {
+			t.Errorf("unexpected delivery after a failure: %+v", d)
+		}
+	}
+	if n, _ := DeliverWebhooks(dbase, server.Client(), now); n != 0 {
+		t.Errorf("retried %d deliveries before their backoff", n)
+	}
+	if n, err := DeliverWebhooks(dbase, server.Client(), now.Add(webhook.Backoff(1))); err != nil || n != 2 {
+		t.Fatalf("expected 2 deliveries after backoff, got %d, %v", n, err)
+	}
+	if len(*received) != 2 {
+		t.Fatalf("expected 2 requests, got %+v", *received)
+	}
+	first := (*received)[0]
+	var data map[string]interface{}
+	json.Unmarshal(first.body.Data, &data)
+	if first.event != "inventory.created" || first.body.Type != first.event || data["quantity"] != float64(6) || data["coop"] != "North" {
+		t.Errorf("unexpected inventory event %+v %v", first, data)
+	}
+	second := (*received)[1]
+	json.Unmarshal(second.body.Data, &data)
+	if second.event != "option.deactivated" || data["type"] != "coop" || data["name"] != "North" {
+		t.Errorf("unexpected option event %+v %v", second, data)
+	}
+	for _, d := range webhookDeliveries(t, router, created.ID) {
+		if d.Status != "delivered" || d.Attempts != 2 || d.DeliveredAt == nil || d.LastError != nil {
+			t.Errorf("unexpected delivery after success: %+v", d)
+		}
+	}
+	// The inactive subscriber to everything was not queued anything.
+	if d := webhookDeliveries(t, router, all.ID); len(d) != 0 {
+		t.Errorf("expected no deliveries to an inactive webhook, got %+v", d)
+	}
+
+	// Redelivery sends the same event again as a new delivery.
+	w = doJSON(router, "POST", fmt.Sprintf("/api/webhooks/%d/deliveries/%d/redeliver", created.ID, deliveries[1].ID), nil)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("redeliver: %d %s", w.Code, w.Body.String())
+	}
+	if n, _ := DeliverWebhooks(dbase, server.Client(), time.Now()); n != 1 {
+		t.Fatalf("expected the redelivery to be sent, got %d", n)
+	}
+	again := (*received)[2]
+	if again.body.ID != first.body.ID || again.delivery == first.delivery {
+		t.Errorf("expected event %d under a new delivery id, got %+v", first.body.ID, again)
+	}
+	if w := doJSON(router, "POST", fmt.Sprintf("/api/webhooks/%d/deliveries/%d/redeliver", all.ID, deliveries[1].ID), nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 redelivering another webhook's delivery, got %d", w.Code)
+	}
+
+	if w := doJSON(router, "DELETE", fmt.Sprintf("/api/webhooks/%d", created.ID), nil); w.Code != http.StatusOK {
+		t.Errorf("delete webhook: %d", w.Code)
+	}
+	if w := doJSON(router, "GET", fmt.Sprintf("/api/webhooks/%d/deliveries", created.ID), nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a deleted webhook's deliveries, got %d", w.Code)
+	}
+}
+
+func TestWebhookGivesUp(t *testing.T) {
+	dbase, cleanup := setupWebhooksTestDB()
+	defer cleanup()
+	router := webhooksRouter(dbase)
+	failures := webhook.MaxAttempts
+	server, received := webhookReceiver(t, "s3cret", &failures)
+
+	doJSON(router, "POST", "/api/webhooks", gin.H{"url": server.URL, "secret": "s3cret", "event_types": []string{"*"}})
+	publishEvent(dbase, eventETLCompleted, gin.H{"duration_ms": 12})
+	now := time.Now()
+	for i := 0; i < webhook.MaxAttempts; i++ {
+		if _, err := DeliverWebhooks(dbase, server.Client(), now); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(time.Hour)
+	}
+	deliveries := webhookDeliveries(t, router, 1)
+	if len(deliveries) != 1 || deliveries[0].Status != "failed" || deliveries[0].Attempts != webhook.MaxAttempts || deliveries[0].EventType != "etl.completed" {
+		t.Fatalf("expected a failed delivery, got %+v", deliveries)
+	}
+	if n, _ := DeliverWebhooks(dbase, server.Client(), now); n != 0 || len(*received) != 0 {
+		t.Errorf("expected no more attempts, got %d", n)
+	}
+
+	// Deactivating a webhook abandons what is still pending for it.
+	publishEvent(dbase, eventETLCompleted, gin.H{"duration_ms": 15})
+	doJSON(router, "PUT", "/api/webhooks/1", gin.H{"url": server.URL, "event_types": []string{"*"}, "active": false})
+	deliveries = webhookDeliveries(t, router, 1)
+	if deliveries[0].Status != "failed" || deliveries[0].LastError == nil || *deliveries[0].LastError != "webhook deactivated" {
+		t.Errorf("expected the pending delivery to fail, got %+v", deliveries[0])
+	}
+	if w := doJSON(router, "POST", fmt.Sprintf("/api/webhooks/1/deliveries/%d/redeliver", deliveries[0].ID), nil); w.Code != http.StatusConflict {
+		t.Errorf("expected 409 redelivering to an inactive webhook, got %d", w.Code)
+	}
+}
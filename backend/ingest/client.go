@@ -0,0 +1,208 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// ErrDuplicate is returned by a Handler for a message it has already ingested.
+var ErrDuplicate = errors.New("duplicate message")
+
+// Handler ingests one message.
+type Handler func(topic string, payload []byte) error
+
+// Config is how to reach the broker.
+type Config struct {
+	Broker   string // e.g. tcp://mosquitto:1883
+	ClientID string
+	Username string
+	Password string
+}
+
+// Health is the state of the connection and what has come through it.
+type Health struct {
+	Broker        string     `json:"broker"`
+	Connected     bool       `json:"connected"`
+	Subscriptions []string   `json:"subscriptions"`
+	Received      int64      `json:"received"`
+	Ingested      int64      `json:"ingested"`
+	Duplicates    int64      `json:"duplicates"`
+	Failed        int64      `json:"failed"`
+	LastMessageAt *time.Time `json:"last_message_at,omitempty"`
+	LastError     *string    `json:"last_error,omitempty"`
+	LastErrorAt   *time.Time `json:"last_error_at,omitempty"`
+}
+
+// Client subscribes to the topics it is given and passes their messages to
+// a Handler. It keeps its session on the broker, so QoS 1 messages sent
+// while it was away arrive once it reconnects.
+type Client struct {
+	cfg     Config
+	handler Handler
+	client  mqtt.Client
+
+	mu     sync.Mutex
+	health Health
+	topics map[string]bool
+}
+
+func NewClient(cfg Config, handler Handler) *Client {
+	if cfg.ClientID == "" {
+		cfg.ClientID = "egg-tracker"
+	}
+	c := &Client{cfg: cfg, handler: handler, topics: map[string]bool{}}
+	c.health.Broker = cfg.Broker
+	opts := mqtt.NewClientOptions().
+		AddBroker(cfg.Broker).
+		SetClientID(cfg.ClientID).
+		SetUsername(cfg.Username).
+		SetPassword(cfg.Password).
+		SetCleanSession(false).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(10 * time.Second).
+		SetOnConnectHandler(c.onConnect).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			c.setConnected(false)
+			c.fail("connection lost: " + err.Error())
+		})
+	c.client = mqtt.NewClient(opts)
+	return c
+}
+
+// Run connects and keeps the subscriptions in line with topics, checked
+// every refresh, until ctx is cancelled.
+func (c *Client) Run(ctx context.Context, refresh time.Duration, topics func() ([]string, error)) {
+	c.client.Connect() // retried in the background until it succeeds
+	c.sync(topics)
+	ticker := time.NewTicker(refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			c.client.Disconnect(250)
+			c.setConnected(false)
+			return
+		case <-ticker.C:
+			c.sync(topics)
+		}
+	}
+}
+
+func (c *Client) Health() Health {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	h := c.health
+	h.Subscriptions = []string{}
+	for t := range c.topics {
+		h.Subscriptions = append(h.Subscriptions, t)
+	}
+	sort.Strings(h.Subscriptions)
+	return h
+}
+
+// sync subscribes to new topics and drops ones no longer wanted.
+func (c *Client) sync(topics func() ([]string, error)) {
+	want, err := topics()
+	if err != nil {
+		c.fail("load topics: " + err.Error())
+		return
+	}
+	wanted := map[string]bool{}
+	for _, t := range want {
+		wanted[t] = true
+	}
+	c.mu.Lock()
+	var add, drop []string
+	for t := range wanted {
+		if !c.topics[t] {
+			add = append(add, t)
+		}
+	}
+	for t := range c.topics {
+		if !wanted[t] {
+			drop = append(drop, t)
+		}
+	}
+	c.topics = wanted
+	connected := c.health.Connected
+	c.mu.Unlock()
+	if !connected {
+		return // onConnect subscribes to everything
+	}
+	if len(drop) > 0 {
+		c.wait(c.client.Unsubscribe(drop...), "unsubscribe")
+	}
+	for _, t := range add {
+		c.subscribe(t)
+	}
+}
+
+func (c *Client) onConnect(mqtt.Client) {
+	c.setConnected(true)
+	slog.Info("Connected to broker", "component", "ingest", "broker", c.cfg.Broker)
+	c.mu.Lock()
+	var topics []string
+	for t := range c.topics {
+		topics = append(topics, t)
+	}
+	c.mu.Unlock()
+	for _, t := range topics {
+		c.subscribe(t)
+	}
+}
+
+func (c *Client) subscribe(topic string) {
+	c.wait(c.client.Subscribe(topic, 1, func(_ mqtt.Client, m mqtt.Message) {
+		c.receive(m.Topic(), m.Payload())
+	}), "subscribe to "+topic)
+}
+
+func (c *Client) wait(t mqtt.Token, what string) {
+	if !t.WaitTimeout(10 * time.Second) {
+		c.fail(what + ": timed out")
+	} else if err := t.Error(); err != nil {
+		c.fail(what + ": " + err.Error())
+	}
+}
+
+func (c *Client) receive(topic string, payload []byte) {
+	err := c.handler(topic, payload)
+	now := time.Now()
+	c.mu.Lock()
+	c.health.Received++
+	c.health.LastMessageAt = &now
+	switch {
+	case err == nil:
+		c.health.Ingested++
+	case errors.Is(err, ErrDuplicate):
+		c.health.Duplicates++
+	default:
+		c.health.Failed++
+	}
+	c.mu.Unlock()
+	if err != nil && !errors.Is(err, ErrDuplicate) {
+		c.fail(topic + ": " + err.Error())
+	}
+}
+
+func (c *Client) setConnected(connected bool) {
+	c.mu.Lock()
+	c.health.Connected = connected
+	c.mu.Unlock()
+}
+
+func (c *Client) fail(msg string) {
+	slog.Warn("MQTT error", "component", "ingest", "error", msg)
+	now := time.Now()
+	c.mu.Lock()
+	c.health.LastError = &msg
+	c.health.LastErrorAt = &now
+	c.mu.Unlock()
+}
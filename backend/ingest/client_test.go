@@ -0,0 +1,78 @@
+package ingest
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// TestClientAgainstBroker needs a broker, e.g.
+// docker run -p 1883:1883 eclipse-mosquitto mosquitto -c /mosquitto-no-auth.conf
+// and MQTT_TEST_BROKER=tcp://localhost:1883.
+func TestClientAgainstBroker(t *testing.T) {
+	broker := os.Getenv("MQTT_TEST_BROKER")
+	if broker == "" {
+		t.Skip("MQTT_TEST_BROKER not set")
+	}
+	var mu sync.Mutex
+	seen := map[string]bool{}
+	got := make(chan string, 10)
+	client := NewClient(Config{Broker: broker, ClientID: "egg-tracker-test"}, func(topic string, payload []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if seen[string(payload)] {
+			return ErrDuplicate
+		}
+		seen[string(payload)] = true
+		got <- topic + " " + string(payload)
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go client.Run(ctx, time.Hour, func() ([]string, error) { return []string{"egg-tracker-test/+/nest"}, nil })
+
+	deadline := time.Now().Add(10 * time.Second)
+	for !client.Health().Connected {
+		if time.Now().After(deadline) {
+			t.Fatalf("not connected: %+v", client.Health())
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	time.Sleep(200 * time.Millisecond) // let the subscription settle
+
+	pub := mqtt.NewClient(mqtt.NewClientOptions().AddBroker(broker).SetClientID("egg-tracker-test-pub"))
+	if tok := pub.Connect(); tok.Wait() && tok.Error() != nil {
+		t.Fatal(tok.Error())
+	}
+	defer pub.Disconnect(100)
+	for _, p := range []struct{ topic, payload string }{
+		{"egg-tracker-test/north/nest", `{"count":1,"seq":1}`},
+		{"egg-tracker-test/north/nest", `{"count":1,"seq":1}`},
+		{"egg-tracker-test/north/door", `open`},
+		{"egg-tracker-test/south/nest", `{"count":2,"seq":2}`},
+	} {
+		pub.Publish(p.topic, 1, false, p.payload).Wait()
+	}
+	for _, want := range []string{`egg-tracker-test/north/nest {"count":1,"seq":1}`, `egg-tracker-test/south/nest {"count":2,"seq":2}`} {
+		select {
+		case m := <-got:
+			if m != want {
+				t.Errorf("got %q, want %q", m, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %q", want)
+		}
+	}
+	h := client.Health()
+	for i := 0; i < 20 && h.Received < 3; i++ { // counted after the handler returns
+		time.Sleep(50 * time.Millisecond)
+		h = client.Health()
+	}
+	if h.Received != 3 || h.Ingested != 2 || h.Duplicates != 1 || len(h.Subscriptions) != 1 {
+		t.Errorf("unexpected health %+v", h)
+	}
+}
@@ -0,0 +1,76 @@
+// Package ingest receives readings from farm devices over MQTT.
+package ingest
+
+import (
+	"encoding/json"
+	"strings"
+	"text/template"
+)
+
+// Message is what payload templates are executed against.
+type Message struct {
+	Topic    string
+	Segments []string    // Topic split on "/", so {{index .Segments 1}} is its second level
+	Payload  interface{} // the payload decoded as JSON, or nil if it is not JSON
+	Raw      string      // the payload as text
+}
+
+func NewMessage(topic string, payload []byte) Message {
+	m := Message{Topic: topic, Segments: strings.Split(topic, "/"), Raw: string(payload)}
+	var v interface{}
+	if json.Unmarshal(payload, &v) == nil {
+		m.Payload = v
+	}
+	return m
+}
+
+// Parse checks a template, such as "{{.Payload.count}}", for use with Render.
+func Parse(text string) (*template.Template, error) {
+	return template.New("").Option("missingkey=error").Parse(text)
+}
+
+// Render executes a template against m, trimming surrounding space.
+func Render(text string, m Message) (string, error) {
+	t, err := Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, m); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// Match reports whether topic matches an MQTT subscription filter, in which
+// "+" stands for one level and a final "#" for any number of them.
+func Match(filter, topic string) bool {
+	f := strings.Split(filter, "/")
+	t := strings.Split(topic, "/")
+	for i, level := range f {
+		if level == "#" {
+			return i == len(f)-1
+		}
+		if i >= len(t) || (level != "+" && level != t[i]) {
+			return false
+		}
+	}
+	return len(f) == len(t)
+}
+
+// ValidFilter reports whether filter is a well-formed subscription filter.
+func ValidFilter(filter string) bool {
+	if filter == "" {
+		return false
+	}
+	levels := strings.Split(filter, "/")
+	for i, level := range levels {
+		if strings.ContainsAny(level, "+#") && level != "+" && level != "#" {
+			return false
+		}
+		if level == "#" && i != len(levels)-1 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package ingest
+
+import "testing"
+
+func TestRender(t *testing.T) {
+	m := NewMessage("farm/north/nest3", []byte(`{"count": 4, "sensor": {"temp_c": 21.5}, "id": "a1"}`))
+	cases := map[string]string{
+		"{{.Payload.count}}":          "4",
+		"{{.Payload.sensor.temp_c}}":  "21.5",
+		"{{index .Segments 1}}":       "north",
+		" {{.Topic}}-{{.Payload.id}}": "farm/north/nest3-a1",
+		"Chicken":                     "Chicken",
+	}
+	for text, want := range cases {
+		if got, err := Render(text, m); err != nil || got != want {
+			t.Errorf("Render(%q) = %q, %v, want %q", text, got, err, want)
+		}
+	}
+	if _, err := Render("{{.Payload.missing}}", m); err == nil {
+		t.Error("expected an error for a missing payload field")
+	}
+	if _, err := Render("{{.Payload.count", m); err == nil {
+		t.Error("expected an error for a malformed template")
+	}
+
+	raw := NewMessage("farm/door", []byte("open"))
+	if got, _ := Render("{{.Raw}}", raw); got != "open" || raw.Payload != nil {
+		t.Errorf("expected the raw payload, got %q and %v", got, raw.Payload)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		filter, topic string
+		want          bool
+	}{
+		{"farm/north/nest", "farm/north/nest", true},
+		{"farm/+/nest", "farm/north/nest", true},
+		{"farm/+/nest", "farm/north/door", false},
+		{"farm/#", "farm/north/nest", true},
+		{"farm/#", "farm", true},
+		{"farm/+", "farm/north/nest", false},
+		{"farm/north/nest", "farm/north", false},
+	}
+	for _, tc := range cases {
+		if got := Match(tc.filter, tc.topic); got != tc.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tc.filter, tc.topic, got, tc.want)
+		}
+	}
+	for filter, want := range map[string]bool{"farm/+/nest": true, "#": true, "farm/#/nest": false, "farm/no+de": false, "": false} {
+		if got := ValidFilter(filter); got != want {
+			t.Errorf("ValidFilter(%q) = %v, want %v", filter, got, want)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+// Package jobs runs background maintenance tasks alongside the API server.
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"egg-tracker/backend/logging"
+)
+
+// nextRun returns the next time at the given hour (local time) after now.
+func nextRun(now time.Time, hour int) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// RunDaily calls fn once a day at the given hour until ctx is cancelled.
+// Failures are logged and retried at the next scheduled run.
+func RunDaily(ctx context.Context, name string, hour int, fn func(now time.Time) error) {
+	for {
+		next := nextRun(time.Now(), hour)
+		slog.Info("Job scheduled", "component", "jobs", "job", name, "next_run", next.Format(time.RFC3339))
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case now := <-timer.C:
+			if err := fn(now); err != nil {
+				slog.Error("Job failed", "component", "jobs", "job", name, logging.Err(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextRun(t *testing.T) {
+	cases := []struct {
+		now, want string
+	}{
+		{"2025-05-01T00:30:00Z", "2025-05-01T02:00:00Z"},
+		{"2025-05-01T02:00:00Z", "2025-05-02T02:00:00Z"},
+		{"2025-05-31T23:00:00Z", "2025-06-01T02:00:00Z"},
+	}
+	for _, tc := range cases {
+		now, _ := time.Parse(time.RFC3339, tc.now)
+		if got := nextRun(now, 2).Format(time.RFC3339); got != tc.want {
+			t.Errorf("nextRun(%s) = %s, want %s", tc.now, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"egg-tracker/backend/logging"
+)
+
+// RunEvery calls fn at each interval until ctx is cancelled. Failures are
+// logged and the next tick runs as usual.
+func RunEvery(ctx context.Context, name string, interval time.Duration, fn func(now time.Time) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := fn(now); err != nil {
+				slog.Error("Job failed", "component", "jobs", "job", name, logging.Err(err))
+			}
+		}
+	}
+}
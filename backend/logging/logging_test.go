@@ -0,0 +1,84 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "json", "warn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("hidden")
+	logger.Warn("shown", Err(errors.New("boom")))
+	var line map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("expected one JSON line, got %q", buf.String())
+	}
+	if line["msg"] != "shown" || line["error"] != "boom" {
+		t.Errorf("unexpected line %v", line)
+	}
+
+	if _, err := New(&buf, "xml", ""); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+	if _, err := New(&buf, "", "loud"); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	logger, _ := New(&buf, "json", "info")
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(logger)
+
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/api/items/:id", func(c *gin.Context) {
+		FromContext(c.Request.Context()).Error("Lookup failed", Err(errors.New("no such item")))
+		c.Status(http.StatusNotFound)
+	})
+
+	for _, tc := range []struct{ sent, want string }{
+		{"abc-123", "abc-123"},
+		{"bad id\n", ""},
+		{"", ""},
+	} {
+		buf.Reset()
+		req := httptest.NewRequest("GET", "/api/items/7", nil)
+		if tc.sent != "" {
+			req.Header.Set(RequestIDHeader, tc.sent)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		id := w.Header().Get(RequestIDHeader)
+		if tc.want != "" && id != tc.want || tc.want == "" && len(id) != 16 {
+			t.Errorf("sent %q, got request ID %q", tc.sent, id)
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("expected a handler line and a request line, got %q", buf.String())
+		}
+		var handler, request map[string]interface{}
+		json.Unmarshal([]byte(lines[0]), &handler)
+		json.Unmarshal([]byte(lines[1]), &request)
+		if handler["request_id"] != id || handler["error"] != "no such item" {
+			t.Errorf("unexpected handler line %v", handler)
+		}
+		if request["request_id"] != id || request["level"] != "WARN" || request["route"] != "/api/items/:id" || request["status"] != float64(404) {
+			t.Errorf("unexpected request line %v", request)
+		}
+	}
+}
@@ -1,13 +1,24 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"egg-tracker/backend/auth"
 	"egg-tracker/backend/db"
 	"egg-tracker/backend/handlers"
-	"log"
+	"egg-tracker/backend/ingest"
+	"egg-tracker/backend/jobs"
+	"egg-tracker/backend/logging"
+	"egg-tracker/backend/metrics"
+	"egg-tracker/backend/notify"
+	"egg-tracker/backend/openapi"
+	"log/slog"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"time"
+	_ "time/tzdata" // farm timezones must resolve in minimal containers
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -55,34 +66,20 @@ func ensureDatabasesWithSampleData() error {
 	return nil
 }
 
-func main() {
-	if err := ensureDatabasesWithSampleData(); err != nil {
-		log.Fatalf("failed to initialize databases: %v", err)
-	}
-
-	database, err := db.InitDB("/app/data/eggtracker.db")
-	if err != nil {
-		log.Fatalf("failed to initialize database: %v", err)
-	}
-	defer database.Close()
-
-	// Initialize the database schema and seed data
-	if err := db.InitializeDatabase("/app/data/eggtracker.db"); err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
-	}
-
-	router := gin.Default()
-
-	// Public routes
-	router.POST("/api/signup", handlers.SignupHandler(database))
-
-	// --- CORS middleware (must be first) ---
+// setupRouter registers every route. New routes must also be documented in
+// handlers.OpenAPISpec.
+func setupRouter(database *sql.DB, sensors *ingest.Client) *gin.Engine {
+	// Requests are logged by logging.Middleware, tagged with their ID.
+	router := gin.New()
+	router.Use(logging.Middleware(), gin.Recovery(), metrics.Middleware())
+	// --- CORS middleware (before validation and routes, so their errors carry CORS headers) ---
 	router.Use(cors.New(cors.Config{
 		AllowOriginFunc: func(origin string) bool {
 			return strings.HasPrefix(origin, "http://localhost:") || strings.HasPrefix(origin, "http://127.0.0.1:")
 		},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", logging.RequestIDHeader},
+		ExposeHeaders:    []string{logging.RequestIDHeader},
 		AllowCredentials: true,
 		AllowOrigins: []string{
 			"http://frontend:3000",
@@ -93,9 +90,27 @@ func main() {
 			// "http://192.168.1.42:8080",
 		},
 	}))
+	// JSON bodies are checked against the OpenAPI document before handlers run.
+	router.Use(handlers.OpenAPISpec().Middleware(func(c *gin.Context, err *openapi.ValidationError) {
+		handlers.RespondValidationError(c, err.Problems)
+	}))
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+	router.GET("/api/openapi.json", handlers.OpenAPIHandler())
+	router.GET("/api/docs", handlers.APIDocsHandler())
+
+	// Public routes
+	router.POST("/api/signup", handlers.SignupHandler(database))
 
 	router.GET("/api/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+		resp := handlers.HealthResponse{Status: "ok"}
+		if sensors != nil {
+			h := sensors.Health()
+			resp.MQTT = &h
+			if !h.Connected {
+				resp.Status = "degraded"
+			}
+		}
+		c.JSON(http.StatusOK, resp)
 	})
 
 	router.POST("/api/login", handlers.LoginHandler(database))
@@ -107,6 +122,8 @@ func main() {
 		inv.GET("", handlers.ListInventoryHandler(database))
 		inv.PUT("/:id", handlers.UpdateInventoryHandler(database))
 		inv.DELETE("/:id", handlers.DeleteInventoryHandler(database))
+		inv.POST("/:id/attachments", handlers.UploadAttachmentHandler(database, "inventory_action"))
+		inv.GET("/:id/attachments", handlers.ListAttachmentsHandler(database, "inventory_action"))
 	}
 
 	// Register /api/options endpoints
@@ -119,14 +136,319 @@ func main() {
 		options.POST("/:type/:id/reactivate", handlers.ReactivateOptionHandler(database))
 	}
 
-	// Register /api/reports endpoint
+	// Register /api/settings endpoints
+	settings := router.Group("/api/settings")
+	{
+		settings.GET("", handlers.GetSettingsHandler(database))
+		settings.PUT("", handlers.UpdateSettingsHandler(database))
+		settings.GET("/logo", handlers.GetLogoHandler(database))
+		settings.POST("/logo", handlers.UploadLogoHandler(database))
+	}
+
+	// Register sales document endpoints
+	router.GET("/api/sales/:id/receipt.pdf", handlers.SaleReceiptHandler(database))
+	router.GET("/api/invoices/:customer/:month/invoice.pdf", handlers.CustomerInvoiceHandler(database))
+
+	// Register /api/birds endpoints
+	birds := router.Group("/api/birds")
+	{
+		birds.POST("", handlers.CreateBirdHandler(database))
+		birds.GET("", handlers.ListBirdsHandler(database))
+		birds.GET("/:id", handlers.GetBirdHandler(database))
+		birds.PUT("/:id", handlers.UpdateBirdHandler(database))
+		birds.DELETE("/:id", handlers.DeleteBirdHandler(database))
+		birds.POST("/:id/transfer", handlers.TransferBirdHandler(database))
+		birds.GET("/:id/transfers", handlers.ListBirdTransfersHandler(database))
+		birds.POST("/:id/attachments", handlers.UploadAttachmentHandler(database, "bird"))
+		birds.GET("/:id/attachments", handlers.ListAttachmentsHandler(database, "bird"))
+	}
+
+	// Register /api/health-events endpoints
+	health := router.Group("/api/health-events")
+	{
+		health.POST("", handlers.CreateHealthEventHandler(database))
+		health.GET("", handlers.ListHealthEventsHandler(database))
+		health.PUT("/:id", handlers.UpdateHealthEventHandler(database))
+		health.DELETE("/:id", handlers.DeleteHealthEventHandler(database))
+	}
+	router.GET("/api/withdrawals", handlers.ActiveWithdrawalsHandler(database))
+
+	// Register /api/status-periods endpoints
+	statusPeriods := router.Group("/api/status-periods")
+	{
+		statusPeriods.POST("", handlers.CreateStatusPeriodHandler(database))
+		statusPeriods.GET("", handlers.ListStatusPeriodsHandler(database))
+		statusPeriods.PUT("/:id", handlers.UpdateStatusPeriodHandler(database))
+		statusPeriods.DELETE("/:id", handlers.DeleteStatusPeriodHandler(database))
+	}
+
+	// Register /api/incubation endpoints
+	incubation := router.Group("/api/incubation")
+	{
+		incubation.GET("/profiles", handlers.ListIncubationProfilesHandler(database))
+		incubation.PUT("/profiles/:species", handlers.UpdateIncubationProfileHandler(database))
+		incubation.POST("/batches", handlers.CreateIncubationBatchHandler(database))
+		incubation.GET("/batches", handlers.ListIncubationBatchesHandler(database))
+		incubation.GET("/batches/:id", handlers.GetIncubationBatchHandler(database))
+		incubation.DELETE("/batches/:id", handlers.DeleteIncubationBatchHandler(database))
+		incubation.POST("/batches/:id/candling", handlers.RecordCandlingHandler(database))
+		incubation.POST("/batches/:id/hatch", handlers.RecordHatchHandler(database))
+	}
+
+	router.GET("/api/trace/:lot", handlers.TraceLotHandler(database))
+
+	// Register /api/lots endpoints
+	lots := router.Group("/api/lots")
+	{
+		lots.GET("", handlers.ListLotsHandler(database))
+		lots.GET("/suggestions", handlers.LotSuggestionsHandler(database))
+		lots.POST("/expire", handlers.ExpireLotsHandler(database))
+	}
+	// Register /api/storage endpoints
+	storage := router.Group("/api/storage")
+	{
+		storage.GET("", handlers.ListStorageUnitsHandler(database))
+		storage.PUT("/:id/range", handlers.UpdateStorageRangeHandler(database))
+		storage.POST("/:id/readings", handlers.CreateStorageReadingHandler(database))
+		storage.GET("/:id/readings", handlers.ListStorageReadingsHandler(database))
+		storage.GET("/:id/excursions", handlers.ListStorageExcursionsHandler(database))
+	}
+
+	// Register /api/tasks endpoints
+	tasks := router.Group("/api/tasks")
+	{
+		tasks.POST("", handlers.CreateTaskHandler(database))
+		tasks.GET("", handlers.ListTasksHandler(database))
+		tasks.GET("/today", handlers.TasksTodayHandler(database))
+		tasks.GET("/:id", handlers.GetTaskHandler(database))
+		tasks.PUT("/:id", handlers.UpdateTaskHandler(database))
+		tasks.DELETE("/:id", handlers.DeleteTaskHandler(database))
+		tasks.POST("/:id/complete", handlers.CompleteTaskHandler(database))
+		tasks.GET("/:id/completions", handlers.ListTaskCompletionsHandler(database))
+	}
+
+	router.GET("/api/shelf-life", handlers.ListShelfLifeHandler(database))
+	router.PUT("/api/shelf-life/:species/:storage", handlers.UpdateShelfLifeHandler(database))
+
+	// Register /api/egg-weight-bands endpoints
+	bands := router.Group("/api/egg-weight-bands")
+	{
+		bands.POST("", handlers.CreateEggWeightBandHandler(database))
+		bands.GET("", handlers.ListEggWeightBandsHandler(database))
+		bands.PUT("/:id", handlers.UpdateEggWeightBandHandler(database))
+		bands.DELETE("/:id", handlers.DeleteEggWeightBandHandler(database))
+		bands.POST("/presets/:standard", handlers.ApplyEggWeightPresetHandler(database))
+	}
+
+	// Register /api/feed endpoints
+	feed := router.Group("/api/feed")
+	{
+		feed.POST("/types", handlers.CreateFeedTypeHandler(database))
+		feed.GET("/types", handlers.ListFeedTypesHandler(database))
+		feed.PUT("/types/:id", handlers.UpdateFeedTypeHandler(database))
+		feed.DELETE("/types/:id", handlers.DeleteFeedTypeHandler(database))
+		feed.POST("/purchases", handlers.CreateFeedPurchaseHandler(database))
+		feed.GET("/purchases", handlers.ListFeedPurchasesHandler(database))
+		feed.DELETE("/purchases/:id", handlers.DeleteFeedPurchaseHandler(database))
+		feed.POST("/purchases/:id/attachments", handlers.UploadAttachmentHandler(database, "feed_purchase"))
+		feed.GET("/purchases/:id/attachments", handlers.ListAttachmentsHandler(database, "feed_purchase"))
+		feed.POST("/usage", handlers.CreateFeedUsageHandler(database))
+		feed.GET("/usage", handlers.ListFeedUsageHandler(database))
+		feed.DELETE("/usage/:id", handlers.DeleteFeedUsageHandler(database))
+		feed.GET("/balance", handlers.FeedBalanceHandler(database))
+	}
+
+	// Register /api/weather endpoints
+	router.POST("/api/weather/import", handlers.ImportWeatherHandler(database))
+	router.GET("/api/weather", handlers.ListWeatherHandler(database))
+
+	// Register daylight and lighting endpoints
+	router.GET("/api/daylight", handlers.DaylightHandler(database))
+	lighting := router.Group("/api/lighting")
+	{
+		lighting.POST("", handlers.CreateLightingScheduleHandler(database))
+		lighting.GET("", handlers.ListLightingSchedulesHandler(database))
+		lighting.PUT("/:id", handlers.UpdateLightingScheduleHandler(database))
+		lighting.DELETE("/:id", handlers.DeleteLightingScheduleHandler(database))
+	}
+
+	// Register /api/attachments endpoints
+	attachments := router.Group("/api/attachments")
+	{
+		attachments.GET("/:id/file", handlers.GetAttachmentFileHandler(database))
+		attachments.GET("/:id/thumbnail", handlers.GetAttachmentThumbnailHandler(database))
+		attachments.DELETE("/:id", handlers.DeleteAttachmentHandler(database))
+	}
+
+	// Register /api/reports endpoints
 	router.GET("/api/reports", handlers.ReportsHandler())
+	router.GET("/api/reports/flock", handlers.FlockReportHandler())
+	router.GET("/api/reports/lay-rate", handlers.LayRateReportHandler())
+	router.GET("/api/reports/production-status", handlers.ProductionStatusReportHandler())
+	router.GET("/api/reports/incubation", handlers.IncubationReportHandler())
+	router.GET("/api/reports/feed-conversion", handlers.FeedConversionReportHandler())
+	router.GET("/api/reports/egg-weight", handlers.EggWeightReportHandler())
+	router.GET("/api/reports/storage-excursions", handlers.StorageExcursionReportHandler())
+	router.GET("/api/reports/weather-production", handlers.WeatherProductionReportHandler())
+	router.GET("/api/reports/light-lay-rate", handlers.LightLayRateReportHandler(database))
+	router.GET("/api/reports/forecast", handlers.ForecastReportHandler())
+	router.GET("/api/reports/forecast-accuracy", handlers.ForecastAccuracyHandler(database))
+	router.POST("/api/forecasts", handlers.RecordForecastHandler(database))
+
+	// Register /api/alerts endpoints
+	alerts := router.Group("/api/alerts")
+	{
+		alerts.GET("", handlers.ListAlertsHandler(database))
+		alerts.POST("/detect", handlers.DetectAnomaliesHandler(database))
+		alerts.POST("/:id/acknowledge", handlers.AcknowledgeAlertHandler(database))
+	}
 
 	// Register ETL full refresh endpoint
 	router.POST("/api/etl/full", handlers.FullETLHandler(database))
 
 	// Register backup endpoint
-	router.POST("/api/backup", handlers.BackupHandler())
+	router.POST("/api/backup", handlers.BackupHandler(database))
+
+	// Register notification endpoints
+	router.GET("/api/users/:id/notification-preferences", handlers.GetNotificationPreferencesHandler(database))
+	router.PUT("/api/users/:id/notification-preferences", handlers.UpdateNotificationPreferencesHandler(database))
+	router.POST("/api/users/:id/notifications/test", handlers.SendTestNotificationHandler(database))
+	router.GET("/api/notifications/deliveries", handlers.ListNotificationDeliveriesHandler(database))
+
+	// Register the live event stream
+	router.GET("/api/events", auth.AuthMiddleware(), handlers.EventStreamHandler(database))
+
+	// Register MQTT ingestion endpoints
+	mqttMappings := router.Group("/api/mqtt/mappings")
+	{
+		mqttMappings.GET("", handlers.ListMQTTMappingsHandler(database))
+		mqttMappings.POST("", handlers.CreateMQTTMappingHandler(database))
+		mqttMappings.PUT("/:id", handlers.UpdateMQTTMappingHandler(database))
+		mqttMappings.DELETE("/:id", handlers.DeleteMQTTMappingHandler(database))
+		mqttMappings.POST("/:id/preview", handlers.PreviewMQTTMappingHandler(database))
+	}
+	router.GET("/api/coop-readings", handlers.ListCoopReadingsHandler(database))
+
+	// Register /api/webhooks endpoints
+	webhooks := router.Group("/api/webhooks")
+	{
+		webhooks.GET("", handlers.ListWebhooksHandler(database))
+		webhooks.POST("", handlers.CreateWebhookHandler(database))
+		webhooks.GET("/:id", handlers.GetWebhookHandler(database))
+		webhooks.PUT("/:id", handlers.UpdateWebhookHandler(database))
+		webhooks.DELETE("/:id", handlers.DeleteWebhookHandler(database))
+		webhooks.GET("/:id/deliveries", handlers.ListWebhookDeliveriesHandler(database))
+		webhooks.POST("/:id/deliveries/:delivery_id/redeliver", handlers.RedeliverWebhookHandler(database))
+	}
+
+	return router
+}
+
+// fatal logs err and exits.
+func fatal(msg string, err error) {
+	slog.Error(msg, logging.Err(err))
+	os.Exit(1)
+}
+
+func main() {
+	logging.Setup()
+	if err := ensureDatabasesWithSampleData(); err != nil {
+		fatal("Failed to initialize databases", err)
+	}
+
+	database, err := db.InitDB("/app/data/eggtracker.db")
+	if err != nil {
+		fatal("Failed to open database", err)
+	}
+	defer database.Close()
+
+	// Initialize the database schema and seed data
+	if err := db.InitializeDatabase("/app/data/eggtracker.db"); err != nil {
+		fatal("Failed to initialize database", err)
+	}
+
+	// Email goes through SMTP when SMTP_HOST is set, otherwise to the server log.
+	var email notify.Notifier = notify.LogNotifier{}
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		port, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
+		if err != nil {
+			port = 587
+		}
+		email = &notify.SMTP{
+			Host:     host,
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("SMTP_FROM"),
+		}
+	}
+	channels := map[string]notify.Notifier{"email": email}
+
+	// Device readings come in over MQTT when MQTT_BROKER is set.
+	var sensors *ingest.Client
+	if broker := os.Getenv("MQTT_BROKER"); broker != "" {
+		sensors = ingest.NewClient(ingest.Config{
+			Broker:   broker,
+			ClientID: os.Getenv("MQTT_CLIENT_ID"),
+			Username: os.Getenv("MQTT_USERNAME"),
+			Password: os.Getenv("MQTT_PASSWORD"),
+		}, func(topic string, payload []byte) error {
+			return handlers.IngestMQTTMessage(database, topic, payload, time.Now())
+		})
+		go sensors.Run(context.Background(), time.Minute, func() ([]string, error) {
+			return handlers.MQTTTopics(database)
+		})
+	}
+
+	metrics.RegisterDB(database, "sqlite")
+	metrics.Registry.MustRegister(handlers.NewFarmCollector(database))
+	router := setupRouter(database, sensors)
+
+	// Background jobs
+	go jobs.RunDaily(context.Background(), "expire-lots", 1, func(now time.Time) error {
+		_, err := handlers.ExpireLots(database, now)
+		return err
+	})
+	go jobs.RunDaily(context.Background(), "prune-events", 3, func(now time.Time) error {
+		_, err := handlers.PruneEvents(database, now)
+		return err
+	})
+	go jobs.RunDaily(context.Background(), "prune-mqtt-messages", 3, func(now time.Time) error {
+		_, err := handlers.PruneMQTTMessages(database, now)
+		return err
+	})
+	go jobs.RunDaily(context.Background(), "detect-anomalies", 6, func(now time.Time) error {
+		_, err := handlers.DetectAnomalies(database, now.AddDate(0, 0, -1))
+		return err
+	})
+	go jobs.RunDaily(context.Background(), "notify-tasks-due", 7, func(now time.Time) error {
+		day, _ := time.Parse("2006-01-02", now.Format("2006-01-02"))
+		_, err := handlers.NotifyTasksDue(database, day)
+		return err
+	})
+	go jobs.RunEvery(context.Background(), "deliver-notifications", time.Minute, func(now time.Time) error {
+		_, err := handlers.DeliverNotifications(database, channels, now)
+		return err
+	})
+	webhookClient := &http.Client{Timeout: 30 * time.Second}
+	go jobs.RunEvery(context.Background(), "deliver-webhooks", 15*time.Second, func(now time.Time) error {
+		_, err := handlers.DeliverWebhooks(database, webhookClient, now)
+		return err
+	})
+	// Record a weekly forecast each Monday so its accuracy can be tracked.
+	go jobs.RunDaily(context.Background(), "record-forecasts", 2, func(now time.Time) error {
+		if now.Weekday() != time.Monday {
+			return nil
+		}
+		asOf, _ := time.Parse("2006-01-02", now.Format("2006-01-02"))
+		for _, by := range []string{"coop", "species"} {
+			opts := handlers.ForecastOptions{By: by, Period: "week", Weeks: 4, Level: 80, AsOf: asOf}
+			if _, err := handlers.RecordForecast(database, opts); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 
 	router.Run("0.0.0.0:8080")
 }
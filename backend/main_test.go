@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"egg-tracker/backend/db"
+	"egg-tracker/backend/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupMainTestDB(t *testing.T) *sql.DB {
+	testDBPath := "test_main.db"
+	dbase, err := sql.Open("sqlite3", testDBPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Migrate(dbase); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		dbase.Close()
+		os.Remove(testDBPath)
+	})
+	return dbase
+}
+
+// TestRoutesDocumented fails when a route is registered without being added
+// to the OpenAPI document, or documented without being registered.
+func TestRoutesDocumented(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := setupRouter(setupMainTestDB(t), nil)
+	spec := handlers.OpenAPISpec()
+
+	registered := map[string]bool{}
+	for _, r := range router.Routes() {
+		registered[r.Method+" "+r.Path] = true
+		if !spec.Has(r.Method, r.Path) {
+			t.Errorf("%s %s is not in the OpenAPI document", r.Method, r.Path)
+		}
+	}
+	for path, item := range spec.Paths {
+		for method := range item {
+			ginPath := path
+			for _, p := range strings.Split(path, "/") {
+				if strings.HasPrefix(p, "{") {
+					ginPath = strings.Replace(ginPath, p, ":"+strings.Trim(p, "{}"), 1)
+				}
+			}
+			if !registered[strings.ToUpper(method)+" "+ginPath] {
+				t.Errorf("%s %s is documented but not registered", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
+
+func TestOpenAPIRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := setupRouter(setupMainTestDB(t), nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var doc struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.OpenAPI != "3.0.3" || doc.Paths["/api/birds/{id}"]["get"] == nil {
+		t.Errorf("unexpected document %s", w.Body.String()[:200])
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/docs", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "/api/openapi.json") {
+		t.Errorf("expected the docs page, got %d", w.Code)
+	}
+}
+
+func TestRequestValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := setupRouter(setupMainTestDB(t), nil)
+
+	req := httptest.NewRequest("POST", "/api/signup", bytes.NewBufferString(`{"email": "not-an-email", "password": 12345678}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Origin", "http://localhost:3000")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Header().Get("Access-Control-Allow-Origin") != "http://localhost:3000" {
+		t.Errorf("expected validation errors to carry CORS headers, got %v", w.Header())
+	}
+	var resp handlers.ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusBadRequest || resp.Code != handlers.CodeValidationFailed || len(resp.Details) != 1 || resp.Details[0].Field != "password" {
+		t.Errorf("expected a validation error on password, got %d %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest("POST", "/api/signup", bytes.NewBufferString(`{"email": "hen@example.com", "password": "longenough"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Errorf("expected the body to reach the handler, got %d %s", w.Code, w.Body.String())
+	}
+}
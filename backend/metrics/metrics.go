@@ -0,0 +1,137 @@
+// Package metrics collects Prometheus metrics for the backend and serves
+// them from one registry.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "eggtracker"
+
+// Registry holds every metric served by Handler. Register further
+// collectors, such as database pool stats, on it.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	etlRunDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "etl",
+		Name:      "run_duration_seconds",
+		Help:      "Duration of ETL runs from SQLite to DuckDB.",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 10),
+	}, []string{"mode", "result"})
+	etlRowsCopied = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "etl",
+		Name:      "rows_copied_total",
+		Help:      "Rows copied or upserted into DuckDB by table.",
+	}, []string{"table"})
+	etlLastSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "etl",
+		Name:      "last_success_timestamp_seconds",
+		Help:      "When the last successful ETL run finished.",
+	})
+
+	backupDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "backup",
+		Name:      "duration_seconds",
+		Help:      "Duration of backups.",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 10),
+	}, []string{"result"})
+	backupSize = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "backup",
+		Name:      "size_bytes",
+		Help:      "Total size of the files in the last successful backup.",
+	})
+	backupLastSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "backup",
+		Name:      "last_success_timestamp_seconds",
+		Help:      "When the last successful backup finished.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequestDuration,
+		etlRunDuration, etlRowsCopied, etlLastSuccess,
+		backupDuration, backupSize, backupLastSuccess,
+	)
+}
+
+// RegisterDB adds connection pool stats for db, labelled db_name=name.
+func RegisterDB(db *sql.DB, name string) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Middleware times each request under its route pattern, such as
+// /api/inventory/:id, so that IDs do not each get their own series.
+// Requests matching no route are counted as "unmatched".
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		httpRequestDuration.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Observe(time.Since(start).Seconds())
+	}
+}
+
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
+
+// ObserveETL records an ETL run of mode ("full" or "incremental") that
+// began at started and ended with err.
+func ObserveETL(mode string, started time.Time, err error) {
+	etlRunDuration.WithLabelValues(mode, result(err)).Observe(time.Since(started).Seconds())
+	if err == nil {
+		etlLastSuccess.SetToCurrentTime()
+	}
+}
+
+// AddETLRows counts rows written to table by the ETL.
+func AddETLRows(table string, n int) {
+	etlRowsCopied.WithLabelValues(table).Add(float64(n))
+}
+
+// ObserveBackup records a backup that began at started and wrote size
+// bytes, or failed with err.
+func ObserveBackup(started time.Time, size int64, err error) {
+	backupDuration.WithLabelValues(result(err)).Observe(time.Since(started).Seconds())
+	if err == nil {
+		backupSize.Set(float64(size))
+		backupLastSuccess.SetToCurrentTime()
+	}
+}
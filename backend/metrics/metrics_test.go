@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMiddleware(t *testing.T) {
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/api/items/:id", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	router.GET("/metrics", gin.WrapH(Handler()))
+	for _, path := range []string{"/api/items/1", "/api/items/2", "/nowhere"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	if n := testutil.CollectAndCount(httpRequestDuration); n != 2 {
+		t.Errorf("expected one series per route and status, got %d", n)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	for _, want := range []string{
+		`eggtracker_http_request_duration_seconds_count{method="GET",route="/api/items/:id",status="204"} 2`,
+		`eggtracker_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics output lacks %q", want)
+		}
+	}
+}
+
+func TestObserve(t *testing.T) {
+	started := time.Now().Add(-time.Second)
+	ObserveETL("full", started, nil)
+	ObserveETL("full", started, errors.New("boom"))
+	AddETLRows("eggs", 3)
+	AddETLRows("eggs", 2)
+	ObserveBackup(started, 2048, nil)
+	ObserveBackup(started, 0, errors.New("disk full"))
+
+	if got := testutil.ToFloat64(etlRowsCopied.WithLabelValues("eggs")); got != 5 {
+		t.Errorf("expected 5 rows copied, got %v", got)
+	}
+	if got := testutil.ToFloat64(backupSize); got != 2048 {
+		t.Errorf("a failed backup should keep the last size, got %v", got)
+	}
+	if n := testutil.CollectAndCount(etlRunDuration); n != 2 {
+		t.Errorf("expected success and error series, got %d", n)
+	}
+	if testutil.ToFloat64(etlLastSuccess) == 0 || testutil.ToFloat64(backupLastSuccess) == 0 {
+		t.Error("expected last success timestamps to be set")
+	}
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Alert is a day on which a coop's collections of one species fell well
+// below or rose well above their recent baseline.
+type Alert struct {
+	ID             int64      `json:"id"`
+	Date           time.Time  `json:"date"`
+	Coop           string     `json:"coop"`
+	Species        string     `json:"species"`
+	Kind           string     `json:"kind"` // "drop" or "spike"
+	Eggs           int        `json:"eggs"`
+	Baseline       float64    `json:"baseline"` // median daily collection over the baseline window
+	MAD            float64    `json:"mad"`
+	Score          *float64   `json:"score"` // modified z-score; null when the baseline never varied
+	NotifiedAt     *time.Time `json:"notified_at,omitempty"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
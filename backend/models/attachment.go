@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Attachment is a photo or document uploaded against an inventory action,
+// bird or feed purchase.
+type Attachment struct {
+	ID           int64     `json:"id"`
+	EntityType   string    `json:"entity_type"` // "inventory_action", "bird" or "feed_purchase"
+	EntityID     int64     `json:"entity_id"`
+	Filename     string    `json:"filename"`
+	ContentType  string    `json:"content_type"`
+	SizeBytes    int64     `json:"size_bytes"`
+	HasThumbnail bool      `json:"has_thumbnail"`
+	Caption      *string   `json:"caption,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
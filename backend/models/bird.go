@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+type Bird struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	BandID     *string    `json:"band_id,omitempty"`
+	Species    string     `json:"species"`
+	Breed      string     `json:"breed"`
+	HatchDate  *time.Time `json:"hatch_date,omitempty"`
+	Coop       string     `json:"coop"`
+	Status     string     `json:"status"` // "active", "sold" or "deceased"
+	StatusDate *time.Time `json:"status_date,omitempty"`
+	Notes      *string    `json:"notes,omitempty"`
+	// IncubationBatchID links birds hatched on the farm to their batch.
+	IncubationBatchID *int64    `json:"incubation_batch_id,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// BirdTransfer records a bird joining or moving between coops.
+type BirdTransfer struct {
+	ID        int64     `json:"id"`
+	BirdID    int64     `json:"bird_id"`
+	FromCoop  *string   `json:"from_coop,omitempty"`
+	ToCoop    string    `json:"to_coop"`
+	Date      time.Time `json:"date"`
+	Notes     *string   `json:"notes,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Daylight is the natural light on one date at the farm.
+type Daylight struct {
+	Date           string     `json:"date"`
+	Sunrise        *time.Time `json:"sunrise,omitempty"` // absent during polar day or night
+	Sunset         *time.Time `json:"sunset,omitempty"`
+	DayLengthHours float64    `json:"day_length_hours"`
+	Polar          string     `json:"polar,omitempty"` // "day" or "night"
+}
+
+// LightingSchedule is supplemental lighting run in a coop every day between
+// StartDate and EndDate.
+type LightingSchedule struct {
+	ID        int64      `json:"id"`
+	Coop      string     `json:"coop"`
+	StartDate time.Time  `json:"start_date"`
+	EndDate   *time.Time `json:"end_date,omitempty"` // inclusive; null while still in use
+	LightsOn  string     `json:"lights_on"`          // HH:MM, farm timezone
+	LightsOff string     `json:"lights_off"`
+	Notes     *string    `json:"notes,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
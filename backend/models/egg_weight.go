@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// EggWeightBand maps a weight range for one species to an egg size.
+type EggWeightBand struct {
+	ID        int64     `json:"id"`
+	Species   string    `json:"species"`
+	EggSize   string    `json:"egg_size"`
+	MinGrams  float64   `json:"min_grams"`           // inclusive
+	MaxGrams  *float64  `json:"max_grams,omitempty"` // exclusive; nil for the heaviest band
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
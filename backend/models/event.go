@@ -0,0 +1,14 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Event is something that happened in the tracker, as sent to webhooks.
+type Event struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+type FeedType struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	BagWeightKg *float64  `json:"bag_weight_kg,omitempty"`
+	LowStockKg  float64   `json:"low_stock_kg"`
+	Notes       *string   `json:"notes,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type FeedPurchase struct {
+	ID         int64     `json:"id"`
+	FeedTypeID int64     `json:"feed_type_id"`
+	Date       time.Time `json:"date"`
+	Quantity   int       `json:"quantity"` // bags
+	WeightKg   float64   `json:"weight_kg"`
+	Cost       *float64  `json:"cost,omitempty"`
+	Supplier   *string   `json:"supplier,omitempty"`
+	Notes      *string   `json:"notes,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type FeedUsage struct {
+	ID         int64     `json:"id"`
+	FeedTypeID int64     `json:"feed_type_id"`
+	Coop       string    `json:"coop"`
+	Date       time.Time `json:"date"`
+	WeightKg   float64   `json:"weight_kg"`
+	Notes      *string   `json:"notes,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// FeedBalance is the feed on hand for one feed type.
+type FeedBalance struct {
+	FeedTypeID  int64   `json:"feed_type_id"`
+	Name        string  `json:"name"`
+	PurchasedKg float64 `json:"purchased_kg"`
+	UsedKg      float64 `json:"used_kg"`
+	OnHandKg    float64 `json:"on_hand_kg"`
+	LowStockKg  float64 `json:"low_stock_kg"`
+	LowStock    bool    `json:"low_stock"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// HealthEvent is an illness, treatment, vaccination or death affecting a
+// single bird or a whole coop.
+type HealthEvent struct {
+	ID             int64     `json:"id"`
+	BirdID         *int64    `json:"bird_id,omitempty"`
+	Coop           string    `json:"coop"`
+	EventType      string    `json:"event_type"`
+	Date           time.Time `json:"date"`
+	Description    *string   `json:"description,omitempty"`
+	Medication     *string   `json:"medication,omitempty"`
+	WithdrawalDays int       `json:"withdrawal_days"`
+	// WithdrawalEnds is the first day eggs may be sold again; nil without a withdrawal period.
+	WithdrawalEnds *time.Time `json:"withdrawal_ends,omitempty"`
+	Notes          *string    `json:"notes,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
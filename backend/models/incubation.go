@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// IncubationProfile holds per-species incubation settings.
+type IncubationProfile struct {
+	Species        string `json:"species"`
+	IncubationDays int    `json:"incubation_days"`
+	CandlingDays   []int  `json:"candling_days"` // days after setting
+}
+
+type IncubationBatch struct {
+	ID                int64      `json:"id"`
+	Species           string     `json:"species"`
+	Breed             string     `json:"breed"`
+	BreederCoop       string     `json:"breeder_coop"`
+	SetDate           time.Time  `json:"set_date"`
+	EggsSet           int        `json:"eggs_set"`
+	ExpectedHatchDate time.Time  `json:"expected_hatch_date"`
+	InventoryActionID *int64     `json:"inventory_action_id,omitempty"`
+	Status            string     `json:"status"` // "incubating", "hatched" or "failed"
+	HatchDate         *time.Time `json:"hatch_date,omitempty"`
+	HatchedCount      *int       `json:"hatched_count,omitempty"`
+	Notes             *string    `json:"notes,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+
+	Candlings []CandlingResult  `json:"candlings,omitempty"`
+	Schedule  []CandlingPlanned `json:"candling_schedule,omitempty"`
+}
+
+type CandlingResult struct {
+	ID        int64     `json:"id"`
+	BatchID   int64     `json:"batch_id"`
+	Day       int       `json:"day"`
+	Date      time.Time `json:"date"`
+	Fertile   int       `json:"fertile"`
+	Infertile int       `json:"infertile"`
+	Dead      int       `json:"dead"`
+	Notes     *string   `json:"notes,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CandlingPlanned is a candling due for a batch according to its species profile.
+type CandlingPlanned struct {
+	Day  int       `json:"day"`
+	Date time.Time `json:"date"`
+	Done bool      `json:"done"`
+}
@@ -12,6 +12,14 @@ type InventoryAction struct {
 	Action    string    `json:"action"` // e.g., "collected", "sold", etc.
 	Notes     *string   `json:"notes,omitempty"`
 	Date      time.Time `json:"date"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	Customer  *string   `json:"customer,omitempty"`
+	UnitPrice *float64  `json:"unit_price,omitempty"`
+	// WeightGrams is the average weight per egg, recorded on collection.
+	WeightGrams *float64 `json:"weight_grams,omitempty"`
+	// LotCode identifies the eggs of a collected action for traceability.
+	LotCode       *string   `json:"lot_code,omitempty"`
+	Storage       *string   `json:"storage,omitempty"` // collected actions: "refrigerated" or "counter"
+	StorageUnitID *int64    `json:"storage_unit_id,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
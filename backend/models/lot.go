@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// LotDraw is the part of an outgoing action taken from one lot.
+type LotDraw struct {
+	LotCode  string `json:"lot_code"`
+	Quantity int    `json:"quantity"`
+}
+
+// LotMovement is one outgoing action that drew eggs from a lot.
+type LotMovement struct {
+	ActionID int64     `json:"action_id"`
+	Date     time.Time `json:"date"`
+	Action   string    `json:"action"`
+	Quantity int       `json:"quantity"`
+	Customer *string   `json:"customer,omitempty"`
+}
+
+// LotCustomer totals the eggs from a lot that went to one customer.
+type LotCustomer struct {
+	Customer  string    `json:"customer"`
+	Quantity  int       `json:"quantity"`
+	FirstSale time.Time `json:"first_sale"`
+	LastSale  time.Time `json:"last_sale"`
+}
+
+// LotTrace follows a lot from its collection to everyone who received it.
+type LotTrace struct {
+	LotCode string          `json:"lot_code"`
+	Source  InventoryAction `json:"source"`
+	Birds   []Bird          `json:"birds"` // birds housed in the source coop on the collection day
+	// StorageUnit and Excursions cover where the lot was kept while it had eggs left.
+	StorageUnit *string       `json:"storage_unit,omitempty"`
+	Excursions  []Excursion   `json:"excursions"`
+	History     []LotMovement `json:"history"`
+	Remaining   int           `json:"remaining"`
+	Customers   []LotCustomer `json:"customers"`
+}
+
+// LotStatus is the age and shelf life of a lot that still has eggs on hand.
+type LotStatus struct {
+	LotCode       string     `json:"lot_code"`
+	ActionID      int64      `json:"action_id"`
+	Species       string     `json:"species"`
+	Coop          string     `json:"coop"`
+	Storage       string     `json:"storage"`
+	CollectedOn   time.Time  `json:"collected_on"`
+	Remaining     int        `json:"remaining"`
+	AgeDays       int        `json:"age_days"`
+	ShelfLifeDays int        `json:"shelf_life_days"`
+	ExpiresOn     time.Time  `json:"expires_on"`
+	DaysLeft      int        `json:"days_left"` // negative once expired
+	Expired       bool       `json:"expired"`
+	FlaggedOn     *time.Time `json:"flagged_on,omitempty"` // when the expiry job flagged the lot
+}
+
+// ShelfLife is how long eggs of a species keep in one kind of storage.
+type ShelfLife struct {
+	Species string `json:"species"`
+	Storage string `json:"storage"`
+	Days    int    `json:"days"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// MQTTMapping turns messages on matching topics into collected inventory
+// actions or coop readings. Its string fields are text/template strings
+// executed against each message.
+type MQTTMapping struct {
+	ID        int64     `json:"id"`
+	Topic     string    `json:"topic"`
+	Kind      string    `json:"kind"` // "collection" or "reading"
+	Coop      string    `json:"coop"`
+	Species   *string   `json:"species,omitempty"`
+	Quantity  *string   `json:"quantity,omitempty"`
+	EggColor  *string   `json:"egg_color,omitempty"`
+	EggSize   *string   `json:"egg_size,omitempty"`
+	Metric    *string   `json:"metric,omitempty"`
+	Value     *string   `json:"value,omitempty"`
+	Timestamp *string   `json:"timestamp,omitempty"`
+	DedupeKey *string   `json:"dedupe_key,omitempty"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CoopReading is one sensor value from a coop, such as a temperature or
+// whether the door is open.
+type CoopReading struct {
+	ID         int64     `json:"id"`
+	Coop       string    `json:"coop"`
+	Metric     string    `json:"metric"`
+	Value      float64   `json:"value"`
+	RecordedAt time.Time `json:"recorded_at"`
+	Source     string    `json:"source"`
+	CreatedAt  time.Time `json:"created_at"`
+}
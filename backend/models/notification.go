@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// NotificationPreferences are one user's notification choices. Users without
+// saved preferences get every kind, at any hour.
+type NotificationPreferences struct {
+	UserID     int64    `json:"user_id"`
+	Enabled    bool     `json:"enabled"`
+	Kinds      []string `json:"kinds"`                 // null for all kinds
+	QuietStart *string  `json:"quiet_start,omitempty"` // HH:MM in the farm timezone
+	QuietEnd   *string  `json:"quiet_end,omitempty"`
+}
+
+// NotificationDelivery is one message to one person, kept as a delivery log.
+type NotificationDelivery struct {
+	ID            int64      `json:"id"`
+	UserID        int64      `json:"user_id"`
+	Channel       string     `json:"channel"`
+	Recipient     string     `json:"recipient"`
+	Kind          string     `json:"kind"`
+	Subject       string     `json:"subject"`
+	Body          string     `json:"body"`
+	Status        string     `json:"status"` // "pending", "sent" or "failed"
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	LastError     *string    `json:"last_error,omitempty"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
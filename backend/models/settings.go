@@ -0,0 +1,12 @@
+package models
+
+// Settings holds farm-wide configuration editable from the UI.
+type Settings struct {
+	FarmName    string   `json:"farm_name"`
+	FarmAddress string   `json:"farm_address"`
+	HasLogo     bool     `json:"has_logo"`
+	ExpiredLots string   `json:"expired_lots"` // "flag" or "spoil"
+	Latitude    *float64 `json:"latitude"`     // null until the farm's position is set
+	Longitude   *float64 `json:"longitude"`
+	Timezone    string   `json:"timezone"` // IANA name, e.g. "America/Chicago"
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// BirdStatusPeriod records a bird or whole coop molting, going broody or
+// returning to lay.
+type BirdStatusPeriod struct {
+	ID        int64      `json:"id"`
+	BirdID    *int64     `json:"bird_id,omitempty"`
+	Coop      string     `json:"coop"`
+	Status    string     `json:"status"` // "molting", "broody" or "laying"
+	StartDate time.Time  `json:"start_date"`
+	EndDate   *time.Time `json:"end_date,omitempty"` // inclusive; nil while ongoing
+	Notes     *string    `json:"notes,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
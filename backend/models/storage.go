@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// StorageUnit is a refrigerator or cooler with its safe temperature range.
+type StorageUnit struct {
+	ID       int64   `json:"id"`
+	Name     string  `json:"name"`
+	Active   bool    `json:"active"`
+	MinTempC float64 `json:"min_temp_c"`
+	MaxTempC float64 `json:"max_temp_c"`
+}
+
+type StorageReading struct {
+	ID            int64     `json:"id"`
+	StorageUnitID int64     `json:"storage_unit_id"`
+	RecordedAt    time.Time `json:"recorded_at"`
+	TempC         float64   `json:"temp_c"`
+	Source        string    `json:"source"` // "manual" or "sensor"
+	Notes         *string   `json:"notes,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Excursion is a run of consecutive readings outside a unit's safe range.
+type Excursion struct {
+	StorageUnitID int64     `json:"storage_unit_id"`
+	StorageUnit   string    `json:"storage_unit"`
+	Kind          string    `json:"kind"` // "high" or "low"
+	Start         time.Time `json:"start"`
+	End           time.Time `json:"end"` // last out-of-range reading
+	Readings      int       `json:"readings"`
+	PeakTempC     float64   `json:"peak_temp_c"` // furthest from the safe range
+}
+
+// ExcursionLot is a lot that was in a storage unit during an excursion.
+type ExcursionLot struct {
+	LotCode     string    `json:"lot_code"`
+	Species     string    `json:"species"`
+	CollectedOn time.Time `json:"collected_on"`
+	OnHand      int       `json:"on_hand"` // eggs left in the lot when the excursion began
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Task is a recurring chore such as cleaning a coop or changing bedding.
+type Task struct {
+	ID             int64      `json:"id"`
+	Title          string     `json:"title"`
+	Description    *string    `json:"description,omitempty"`
+	RRule          string     `json:"rrule"` // e.g. FREQ=WEEKLY;BYDAY=SA
+	StartDate      time.Time  `json:"start_date"`
+	Coop           *string    `json:"coop,omitempty"`
+	AssignedUserID *int64     `json:"assigned_user_id,omitempty"`
+	Active         bool       `json:"active"`
+	LastCompleted  *time.Time `json:"last_completed,omitempty"` // latest occurrence marked done
+	NextDue        *time.Time `json:"next_due,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+type TaskCompletion struct {
+	ID          int64     `json:"id"`
+	TaskID      int64     `json:"task_id"`
+	DueDate     time.Time `json:"due_date"`
+	CompletedOn time.Time `json:"completed_on"`
+	UserID      *int64    `json:"user_id,omitempty"`
+	Notes       *string   `json:"notes,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TaskDue is a task that needs attention on a given day: due that day,
+// overdue from earlier occurrences, or both.
+type TaskDue struct {
+	Task
+	DueToday     bool       `json:"due_today"`
+	Completed    bool       `json:"completed"` // today's occurrence is done
+	Overdue      bool       `json:"overdue"`
+	OverdueSince *time.Time `json:"overdue_since,omitempty"` // earliest missed occurrence
+	Missed       int        `json:"missed"`                  // occurrences missed since the last completion
+}
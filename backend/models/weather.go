@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// WeatherDay is one day of imported weather observations. Temperatures are
+// in °C and precipitation in mm.
+type WeatherDay struct {
+	ID       int64     `json:"id"`
+	Date     time.Time `json:"date"`
+	Station  string    `json:"station"`
+	TminC    *float64  `json:"tmin_c,omitempty"`
+	TmaxC    *float64  `json:"tmax_c,omitempty"`
+	TavgC    *float64  `json:"tavg_c,omitempty"`
+	PrecipMM *float64  `json:"precip_mm,omitempty"`
+	Source   string    `json:"source"`
+}
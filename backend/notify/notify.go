@@ -0,0 +1,44 @@
+// Package notify delivers messages about things that need attention, such as
+// production anomalies or failed backups, to the people looking after the
+// flock. Channels implement Notifier; the templates turn events into messages.
+package notify
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Message is one notification.
+type Message struct {
+	Kind    string // what raised it, one of Kinds or KindTest
+	Subject string
+	Body    string
+}
+
+// Notifier delivers messages over one channel, such as email.
+type Notifier interface {
+	// Notify sends m to the channel address to, e.g. an email address.
+	Notify(ctx context.Context, to string, m Message) error
+}
+
+// LogNotifier writes messages to the server log. It stands in for email when
+// no SMTP server is configured.
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(ctx context.Context, to string, m Message) error {
+	slog.Info("Notification", "component", "notify", "to", to, "kind", m.Kind, "subject", m.Subject)
+	return nil
+}
+
+// MaxAttempts is how many times a delivery is tried before it is given up.
+const MaxAttempts = 6
+
+// Backoff is the wait before retrying a delivery that has failed attempts
+// times: one minute, doubling each time.
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	return time.Minute << (attempts - 1)
+}
@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	alert := struct {
+		Species, Coop, Kind string
+		Date                time.Time
+		Eggs                int
+		Baseline            float64
+		WindowDays          int
+	}{"Chicken", "", "drop", time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC), 3, 10.5, 28}
+	m, err := Render(KindAnomaly, alert)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if m.Kind != KindAnomaly || m.Subject != "Chicken collections dropped in no coop on 2025-06-30" {
+		t.Errorf("unexpected subject %q", m.Subject)
+	}
+	if m.Body != "3 eggs were collected against a usual 10.5 a day over the previous 28 days. Check for predators, illness or hidden nests." {
+		t.Errorf("unexpected body %q", m.Body)
+	}
+
+	coop := "Main Coop"
+	since := time.Date(2025, 6, 28, 0, 0, 0, 0, time.UTC)
+	type task struct {
+		Title        string
+		Coop         *string
+		Overdue      bool
+		OverdueSince *time.Time
+	}
+	m, err = Render(KindTasksDue, map[string]interface{}{
+		"Date":  "2025-06-30",
+		"Tasks": []task{{"Clean waterers", &coop, true, &since}, {"Order feed", nil, false, nil}},
+	})
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if m.Subject != "2 tasks to do on 2025-06-30" || m.Body != "- Clean waterers (Main Coop), overdue since 2025-06-28\n- Order feed" {
+		t.Errorf("unexpected tasks message %+v", m)
+	}
+
+	if _, err := Render("weather", nil); err == nil || !strings.Contains(err.Error(), "unknown") {
+		t.Errorf("expected an unknown kind error, got %v", err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	want := []time.Duration{time.Minute, time.Minute, 2 * time.Minute, 4 * time.Minute, 16 * time.Minute}
+	for i, attempts := range []int{0, 1, 2, 3, 5} {
+		if got := Backoff(attempts); got != want[i] {
+			t.Errorf("Backoff(%d) = %s, want %s", attempts, got, want[i])
+		}
+	}
+}
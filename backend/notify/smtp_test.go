@@ -0,0 +1,111 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTP is a minimal SMTP server that records one message per session.
+// reject makes it refuse recipients.
+type fakeSMTP struct {
+	ln       net.Listener
+	reject   bool
+	messages chan string
+}
+
+func startFakeSMTP(t *testing.T, reject bool) *fakeSMTP {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &fakeSMTP{ln: ln, reject: reject, messages: make(chan string, 10)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	reply("220 fake ESMTP")
+	var data strings.Builder
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"):
+			reply("250-fake")
+			reply("250 8BITMIME")
+		case strings.HasPrefix(cmd, "RCPT") && s.reject:
+			reply("550 no such user")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			s.messages <- data.String()
+			reply("250 queued")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func (s *fakeSMTP) notifier() *SMTP {
+	addr := s.ln.Addr().(*net.TCPAddr)
+	return &SMTP{Host: "127.0.0.1", Port: addr.Port, From: "tracker@farm.test", Timeout: 5 * time.Second}
+}
+
+func TestSMTPNotify(t *testing.T) {
+	server := startFakeSMTP(t, false)
+	m := Message{Kind: KindTest, Subject: "Ørsted coop check", Body: "line one\n.\nline three"}
+	if err := server.notifier().Notify(context.Background(), "keeper@farm.test", m); err != nil {
+		t.Fatalf("notify: %v", err)
+	}
+	got := <-server.messages
+	for _, want := range []string{
+		"From: tracker@farm.test\r\n",
+		"To: keeper@farm.test\r\n",
+		"Subject: =?utf-8?q?=C3=98rsted_coop_check?=\r\n",
+		"Content-Type: text/plain; charset=utf-8\r\n",
+		"\r\n\r\nline one\r\n..\r\nline three\r\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("message missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestSMTPNotifyRejected(t *testing.T) {
+	server := startFakeSMTP(t, true)
+	err := server.notifier().Notify(context.Background(), "nobody@farm.test", Message{Subject: "x"})
+	if err == nil || !strings.Contains(err.Error(), "550") {
+		t.Errorf("expected the 550 rejection, got %v", err)
+	}
+}
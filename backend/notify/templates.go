@@ -0,0 +1,76 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// Kinds of notification people can subscribe to.
+const (
+	KindAnomaly      = "anomaly"
+	KindLowStock     = "low_stock"
+	KindTasksDue     = "tasks_due"
+	KindBackupFailed = "backup_failed"
+	KindETLFailed    = "etl_failed"
+
+	// KindTest is sent on request to check a channel; it ignores subscriptions.
+	KindTest = "test"
+)
+
+// Kinds lists the kinds people can subscribe to.
+var Kinds = []string{KindAnomaly, KindLowStock, KindTasksDue, KindBackupFailed, KindETLFailed}
+
+type messageTemplate struct {
+	subject, body *template.Template
+}
+
+func mustTemplate(kind, subject, body string) messageTemplate {
+	return messageTemplate{
+		subject: template.Must(template.New(kind + " subject").Parse(subject)),
+		body:    template.Must(template.New(kind + " body").Parse(body)),
+	}
+}
+
+// templates render each kind from the data its source passes to Render:
+// models.Alert plus WindowDays for anomalies, models.FeedBalance for low
+// stock, Date and Tasks for tasks due, and Time and Error for failures.
+var templates = map[string]messageTemplate{
+	KindAnomaly: mustTemplate(KindAnomaly,
+		`{{.Species}} collections {{if eq .Kind "drop"}}dropped{{else}}spiked{{end}} in {{or .Coop "no coop"}} on {{.Date.Format "2006-01-02"}}`,
+		`{{.Eggs}} eggs were collected against a usual {{printf "%.1f" .Baseline}} a day over the previous {{.WindowDays}} days.
+{{- if eq .Kind "drop"}} Check for predators, illness or hidden nests.{{end}}`),
+	KindLowStock: mustTemplate(KindLowStock,
+		`{{.Name}} feed is running low`,
+		`{{printf "%.1f" .OnHandKg}} kg of {{.Name}} is left, below the {{printf "%.1f" .LowStockKg}} kg warning level.`),
+	KindTasksDue: mustTemplate(KindTasksDue,
+		`{{len .Tasks}} {{if eq (len .Tasks) 1}}task{{else}}tasks{{end}} to do on {{.Date}}`,
+		`{{range .Tasks}}- {{.Title}}{{with .Coop}} ({{.}}){{end}}{{if .Overdue}}, overdue since {{.OverdueSince.Format "2006-01-02"}}{{end}}
+{{end}}`),
+	KindBackupFailed: mustTemplate(KindBackupFailed,
+		`Backup failed`,
+		`The backup started at {{.Time.Format "2006-01-02 15:04 MST"}} failed: {{.Error}}`),
+	KindETLFailed: mustTemplate(KindETLFailed,
+		`Analytics refresh failed`,
+		`The analytics refresh started at {{.Time.Format "2006-01-02 15:04 MST"}} failed: {{.Error}}
+Reports may show stale figures until it succeeds.`),
+	KindTest: mustTemplate(KindTest,
+		`Test notification`,
+		`Notifications from the egg tracker reach you here.`),
+}
+
+// Render builds the message for kind from data.
+func Render(kind string, data interface{}) (Message, error) {
+	t, ok := templates[kind]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown notification kind %q", kind)
+	}
+	var subject, body strings.Builder
+	if err := t.subject.Execute(&subject, data); err != nil {
+		return Message{}, fmt.Errorf("render %s subject: %w", kind, err)
+	}
+	if err := t.body.Execute(&body, data); err != nil {
+		return Message{}, fmt.Errorf("render %s body: %w", kind, err)
+	}
+	return Message{Kind: kind, Subject: subject.String(), Body: strings.TrimSpace(body.String())}, nil
+}
@@ -0,0 +1,425 @@
+// Package openapi builds an OpenAPI 3 document from Go types and checks
+// JSON request bodies against it.
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Document is an OpenAPI 3.0 document.
+type Document struct {
+	OpenAPI    string                  `json:"openapi"`
+	Info       Info                    `json:"info"`
+	Paths      map[string]PathItem     `json:"paths"`
+	Components Components              `json:"components"`
+	Tags       []Tag                   `json:"tags,omitempty"`
+	routes     map[string]*Operation   // keyed by method and gin path
+	names      map[reflect.Type]string // component names of named structs
+	errorRef   string
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Tag struct {
+	Name string `json:"name"`
+}
+
+// PathItem maps lower-case HTTP methods to operations.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme,omitempty"`
+}
+
+// Schema is the subset of JSON Schema used by OpenAPI 3.0.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     bool               `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     bool               `json:"exclusiveMaximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// Param describes a query parameter for Route.
+type Param struct {
+	Name        string
+	Type        string // "string" (default), "integer", "number", "boolean", "date" or "file"
+	Description string
+	Required    bool
+	Enum        []string
+}
+
+func (p Param) schema() *Schema {
+	s := &Schema{Type: p.Type, Enum: p.Enum}
+	switch p.Type {
+	case "":
+		s.Type = "string"
+	case "date":
+		s.Type, s.Format = "string", "date"
+	case "file":
+		s.Type, s.Format = "string", "binary"
+	}
+	return s
+}
+
+// Route describes one operation for Add.
+type Route struct {
+	Method  string
+	Path    string // in gin syntax, e.g. /api/birds/:id
+	Summary string
+	Tag     string
+	Query   []Param
+	// Body is a value of the JSON request type; Form lists multipart
+	// fields instead, with Type "file" for uploads.
+	Body any
+	Form []Param
+	// Status is the success status, 200 unless set. Response is a value of
+	// the JSON response type, or ContentType names another kind of body.
+	Status      int
+	Response    any
+	ContentType string
+	// Auth marks routes that need a bearer token.
+	Auth bool
+}
+
+// New returns an empty document. Every operation added to it can fail
+// with errorBody, a value of the JSON error type.
+func New(title, version, description string, errorBody any) *Document {
+	d := &Document{
+		OpenAPI: "3.0.3",
+		Info:    Info{Title: title, Version: version, Description: description},
+		Paths:   map[string]PathItem{},
+		Components: Components{
+			Schemas:         map[string]*Schema{},
+			SecuritySchemes: map[string]SecurityScheme{"bearerAuth": {Type: "http", Scheme: "bearer"}},
+		},
+		routes: map[string]*Operation{},
+		names:  map[reflect.Type]string{},
+	}
+	d.errorRef = d.Schema(errorBody).Ref
+	return d
+}
+
+// Path converts a gin path to OpenAPI syntax: /api/birds/:id becomes
+// /api/birds/{id}.
+func Path(ginPath string) string {
+	parts := strings.Split(ginPath, "/")
+	for i, p := range parts {
+		if strings.HasPrefix(p, ":") || strings.HasPrefix(p, "*") {
+			parts[i] = "{" + p[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+func pathParams(ginPath string) []string {
+	var names []string
+	for _, p := range strings.Split(ginPath, "/") {
+		if strings.HasPrefix(p, ":") || strings.HasPrefix(p, "*") {
+			names = append(names, p[1:])
+		}
+	}
+	return names
+}
+
+// Add documents a route. It panics if the route was already added, as
+// that is a mistake in the caller's table.
+func (d *Document) Add(r Route) {
+	key := r.Method + " " + r.Path
+	if d.routes[key] != nil {
+		panic("openapi: route added twice: " + key)
+	}
+	op := &Operation{
+		OperationID: operationID(r.Method, r.Path),
+		Summary:     r.Summary,
+		Responses:   map[string]Response{},
+	}
+	if r.Tag != "" {
+		op.Tags = []string{r.Tag}
+		d.addTag(r.Tag)
+	}
+	for _, name := range pathParams(r.Path) {
+		op.Parameters = append(op.Parameters, Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+	}
+	for _, q := range r.Query {
+		op.Parameters = append(op.Parameters, Parameter{Name: q.Name, In: "query", Description: q.Description, Required: q.Required, Schema: q.schema()})
+	}
+	switch {
+	case r.Body != nil:
+		op.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{"application/json": {Schema: d.Schema(r.Body)}}}
+	case len(r.Form) > 0:
+		form := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		for _, f := range r.Form {
+			form.Properties[f.Name] = f.schema()
+			form.Properties[f.Name].Description = f.Description
+			if f.Required {
+				form.Required = append(form.Required, f.Name)
+			}
+		}
+		op.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{"multipart/form-data": {Schema: form}}}
+	}
+	status := r.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	resp := Response{Description: http.StatusText(status)}
+	switch {
+	case r.Response != nil:
+		resp.Content = map[string]MediaType{"application/json": {Schema: d.Schema(r.Response)}}
+	case r.ContentType != "":
+		resp.Content = map[string]MediaType{r.ContentType: {Schema: &Schema{Type: "string", Format: "binary"}}}
+	}
+	op.Responses[strconv.Itoa(status)] = resp
+	op.Responses["default"] = Response{
+		Description: "Error",
+		Content:     map[string]MediaType{"application/json": {Schema: &Schema{Ref: d.errorRef}}},
+	}
+	if r.Auth {
+		op.Security = []map[string][]string{{"bearerAuth": {}}}
+	}
+
+	path := Path(r.Path)
+	if d.Paths[path] == nil {
+		d.Paths[path] = PathItem{}
+	}
+	d.Paths[path][strings.ToLower(r.Method)] = op
+	d.routes[key] = op
+}
+
+func (d *Document) addTag(name string) {
+	for _, t := range d.Tags {
+		if t.Name == name {
+			return
+		}
+	}
+	d.Tags = append(d.Tags, Tag{Name: name})
+}
+
+// Has reports whether the route, in gin syntax, has been added.
+func (d *Document) Has(method, ginPath string) bool {
+	return d.routes[method+" "+ginPath] != nil
+}
+
+// operationID turns POST /api/birds/:id/transfer into postBirdsIdTransfer.
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(strings.TrimPrefix(path, "/api"), func(r rune) bool {
+		return r == '/' || r == ':' || r == '-' || r == '_' || r == '.' || r == '*'
+	}) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Schema returns the schema for v's type. Named structs are added to the
+// components and referred to by name.
+func (d *Document) Schema(v any) *Schema {
+	return d.schemaOf(reflect.TypeOf(v))
+}
+
+func (d *Document) schemaOf(t reflect.Type) *Schema {
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Pointer:
+		s := *d.schemaOf(t.Elem())
+		if s.Ref != "" {
+			// 3.0 ignores siblings of $ref, so wrap it to allow null.
+			return &Schema{Nullable: true, AllOf: []*Schema{{Ref: s.Ref}}}
+		}
+		s.Nullable = true
+		return &s
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			if t.Name() == "RawMessage" {
+				return &Schema{Description: "Any JSON value."}
+			}
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: d.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.structSchema(t)
+		}
+		name, ok := d.names[t]
+		if !ok {
+			name = t.Name()
+			if _, taken := d.Components.Schemas[name]; taken {
+				pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+				name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+			}
+			d.names[t] = name // before the fields, for recursive types
+			d.Components.Schemas[name] = d.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	return &Schema{}
+}
+
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	d.addFields(s, t)
+	return s
+}
+
+func (d *Document) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" || !f.IsExported() && !f.Anonymous {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				d.addFields(s, ft)
+				continue
+			}
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fs := d.schemaOf(f.Type)
+		if applyBinding(fs, f.Tag.Get("binding")) {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = fs
+	}
+}
+
+// applyBinding adds the gin binding rules in tag to s, so the document
+// says what the handlers enforce. It reports whether the field is required.
+func applyBinding(s *Schema, tag string) bool {
+	required := false
+	target := s
+	for _, rule := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = true
+		case "dive":
+			if target.Items != nil {
+				target = target.Items
+			}
+		case "email":
+			target.Format = "email"
+		case "oneof":
+			target.Enum = strings.Fields(arg)
+		case "min", "max", "gt", "gte", "lt", "lte":
+			n, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				continue
+			}
+			limit(target, name, n)
+		}
+	}
+	return required
+}
+
+func limit(s *Schema, rule string, n float64) {
+	if s.Type == "string" || s.Type == "array" {
+		i := int(n)
+		if rule == "gt" {
+			i++
+		}
+		switch {
+		case s.Type == "string" && (rule == "min" || rule == "gt" || rule == "gte"):
+			s.MinLength = &i
+		case s.Type == "string":
+			s.MaxLength = &i
+		case rule == "min" || rule == "gt" || rule == "gte":
+			s.MinItems = &i
+		}
+		return
+	}
+	switch rule {
+	case "min", "gte":
+		s.Minimum = &n
+	case "gt":
+		s.Minimum, s.ExclusiveMinimum = &n, true
+	case "max", "lte":
+		s.Maximum = &n
+	case "lt":
+		s.Maximum, s.ExclusiveMaximum = &n, true
+	}
+}
@@ -0,0 +1,194 @@
+package openapi
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type testError struct {
+	Error string `json:"error"`
+}
+
+type testLot struct {
+	Code     string `json:"code" binding:"required"`
+	Quantity int    `json:"quantity" binding:"required,gt=0"`
+}
+
+type testBase struct {
+	ID int64 `json:"id"`
+}
+
+type testInput struct {
+	testBase
+	Species string     `json:"species" binding:"required,oneof=Chicken Duck"`
+	Notes   *string    `json:"notes" binding:"omitempty,max=5"`
+	Date    *time.Time `json:"date"`
+	Lots    []testLot  `json:"lots" binding:"omitempty,min=1,dive"`
+	Weights []float64  `json:"weights" binding:"dive,gte=0,lte=200"`
+	Parent  *testLot   `json:"parent"`
+	skipped bool
+}
+
+func TestPath(t *testing.T) {
+	if got := Path("/api/webhooks/:id/deliveries/:delivery_id"); got != "/api/webhooks/{id}/deliveries/{delivery_id}" {
+		t.Errorf("got %s", got)
+	}
+	if got := operationID("POST", "/api/birds/:id/transfer"); got != "postBirdsIdTransfer" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestSchema(t *testing.T) {
+	d := New("Test", "1", "", testError{})
+	ref := d.Schema(testInput{})
+	if ref.Ref != "#/components/schemas/testInput" {
+		t.Fatalf("expected a reference, got %+v", ref)
+	}
+	s := d.Components.Schemas["testInput"]
+	if _, ok := s.Properties["id"]; !ok {
+		t.Error("expected the embedded struct's fields")
+	}
+	if _, ok := s.Properties["skipped"]; ok {
+		t.Error("unexported fields should be skipped")
+	}
+	if strings.Join(s.Required, ",") != "species" {
+		t.Errorf("unexpected required fields %v", s.Required)
+	}
+	if got := s.Properties["species"].Enum; strings.Join(got, ",") != "Chicken,Duck" {
+		t.Errorf("unexpected enum %v", got)
+	}
+	notes := s.Properties["notes"]
+	if !notes.Nullable || notes.MaxLength == nil || *notes.MaxLength != 5 {
+		t.Errorf("unexpected notes schema %+v", notes)
+	}
+	if date := s.Properties["date"]; date.Format != "date-time" || !date.Nullable {
+		t.Errorf("unexpected date schema %+v", date)
+	}
+	lots := s.Properties["lots"]
+	if lots.MinItems == nil || *lots.MinItems != 1 || lots.Items.Ref != "#/components/schemas/testLot" {
+		t.Errorf("unexpected lots schema %+v", lots)
+	}
+	if w := s.Properties["weights"].Items; w.Minimum == nil || *w.Minimum != 0 || w.Maximum == nil || *w.Maximum != 200 {
+		t.Errorf("dive rules should apply to the items, got %+v", w)
+	}
+	if p := s.Properties["parent"]; !p.Nullable || len(p.AllOf) != 1 {
+		t.Errorf("a nullable reference should be wrapped, got %+v", p)
+	}
+	lot := d.Components.Schemas["testLot"]
+	if q := lot.Properties["quantity"]; q.Minimum == nil || !q.ExclusiveMinimum {
+		t.Errorf("unexpected quantity schema %+v", q)
+	}
+}
+
+func TestAdd(t *testing.T) {
+	d := New("Test", "1", "", testError{})
+	d.Add(Route{Method: "PUT", Path: "/api/lots/:id", Tag: "Lots", Body: testLot{}, Response: testLot{},
+		Query: []Param{{Name: "from", Type: "date"}}})
+	d.Add(Route{Method: "POST", Path: "/api/lots/:id/files", Form: []Param{{Name: "file", Type: "file", Required: true}}, Status: http.StatusCreated})
+
+	if !d.Has("PUT", "/api/lots/:id") || d.Has("GET", "/api/lots/:id") {
+		t.Error("Has does not match the routes added")
+	}
+	op := d.Paths["/api/lots/{id}"]["put"]
+	if len(op.Parameters) != 2 || op.Parameters[0].In != "path" || op.Parameters[1].Schema.Format != "date" {
+		t.Errorf("unexpected parameters %+v", op.Parameters)
+	}
+	if op.Responses["200"].Content == nil || op.Responses["default"].Content == nil {
+		t.Errorf("unexpected responses %+v", op.Responses)
+	}
+	form := d.Paths["/api/lots/{id}/files"]["post"].RequestBody.Content["multipart/form-data"].Schema
+	if form.Properties["file"].Format != "binary" || form.Required[0] != "file" {
+		t.Errorf("unexpected form %+v", form)
+	}
+	if _, ok := d.Paths["/api/lots/{id}/files"]["post"].Responses["201"]; !ok {
+		t.Error("expected a 201 response")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("adding a route twice should panic")
+		}
+	}()
+	d.Add(Route{Method: "PUT", Path: "/api/lots/:id"})
+}
+
+func TestValidateBody(t *testing.T) {
+	d := New("Test", "1", "", testError{})
+	d.Add(Route{Method: "POST", Path: "/api/input", Body: testInput{}})
+
+	for _, tc := range []struct {
+		body string
+		want string
+	}{
+		{`{"species": "Chicken"}`, ""},
+		{`{"species": "Chicken", "notes": null, "date": "2024-05-01T08:00:00Z", "lots": [{"code": "A", "quantity": 2}]}`, ""},
+		{`{"species": "Chicken", "extra": true}`, ""},
+		{`{}`, "species is required"},
+		{`{"species": null}`, "species is required"},
+		{`{"species": "Goose"}`, "species must be one of Chicken, Duck"},
+		{`{"species": "Chicken", "notes": "too long"}`, "notes must be at most 5 characters"},
+		{`{"species": "Chicken", "date": "2024-05-01"}`, "date must be an RFC 3339 date-time"},
+		{`{"species": "Chicken", "lots": []}`, "lots must have at least 1 items"},
+		{`{"species": "Chicken", "lots": [{"code": "A", "quantity": 0}]}`, "lots[0].quantity must be greater than 0"},
+		{`{"species": "Chicken", "lots": [{"quantity": 1.5}]}`, "lots[0].code is required; lots[0].quantity must be a whole number"},
+		{`{"species": "Chicken", "weights": [201]}`, "weights[0] must be at most 200"},
+		{`{"species": 1}`, "species must be a string"},
+		{`[]`, "must be an object"},
+		{`{`, "body must be valid JSON"},
+	} {
+		err := d.ValidateBody("POST", "/api/input", []byte(tc.body))
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tc.want {
+			t.Errorf("%s: expected %q, got %q", tc.body, tc.want, got)
+		}
+	}
+	if err := d.ValidateBody("POST", "/api/other", []byte(`{`)); err != nil {
+		t.Errorf("undocumented routes should not be checked, got %v", err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	d := New("Test", "1", "", testError{})
+	d.Add(Route{Method: "POST", Path: "/api/lots/:id", Body: testLot{}})
+
+	router := gin.New()
+	router.Use(d.Middleware(func(c *gin.Context, err *ValidationError) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "problems": err.Problems})
+	}))
+	router.POST("/api/lots/:id", func(c *gin.Context) {
+		var lot testLot
+		if err := c.ShouldBindJSON(&lot); err != nil {
+			c.Status(http.StatusTeapot)
+			return
+		}
+		c.String(http.StatusOK, lot.Code)
+	})
+
+	send := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/lots/1", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	if w := send(`{"code": "A", "quantity": 1}`); w.Code != http.StatusOK || w.Body.String() != "A" {
+		t.Errorf("expected the body to reach the handler, got %d %q", w.Code, w.Body.String())
+	}
+	if w := send(`{"quantity": 1}`); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"field":"code"`) {
+		t.Errorf("expected a validation error, got %d %s", w.Code, w.Body.String())
+	}
+	big := `{"code": "` + strings.Repeat("A", MaxBodyBytes) + `", "quantity": 1}`
+	if w := send(big); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "larger than") {
+		t.Errorf("expected an oversized body to be rejected, got %d %.200s", w.Code, w.Body.String())
+	}
+}
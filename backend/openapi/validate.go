@@ -0,0 +1,233 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Problem is one way a request body does not match its schema.
+type Problem struct {
+	Field   string `json:"field"` // e.g. "lots[0].quantity"; empty for the body itself
+	Message string `json:"message"`
+}
+
+// ValidationError lists everything wrong with a request body.
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		if p.Field == "" {
+			msgs[i] = p.Message
+		} else {
+			msgs[i] = p.Field + " " + p.Message
+		}
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ValidateBody checks a JSON request body for the route, in gin syntax,
+// against its schema. Routes without a JSON body accept anything. The
+// checks never go beyond what decoding and gin's binding rules enforce;
+// they only say up front, and by field, what the handler would reject.
+func (d *Document) ValidateBody(method, ginPath string, body []byte) error {
+	op := d.routes[method+" "+ginPath]
+	if op == nil || op.RequestBody == nil {
+		return nil
+	}
+	media, ok := op.RequestBody.Content["application/json"]
+	if !ok {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return &ValidationError{Problems: []Problem{{Message: "body must be valid JSON"}}}
+	}
+	var problems []Problem
+	d.validate(media.Schema, v, "", &problems)
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// MaxBodyBytes caps the JSON bodies Middleware reads; no documented route
+// takes anything close to it.
+const MaxBodyBytes = 1 << 20
+
+// Middleware validates JSON request bodies of documented routes before
+// their handlers run, and calls invalid instead of the handler when one
+// does not match or is larger than MaxBodyBytes.
+func (d *Document) Middleware(invalid func(c *gin.Context, err *ValidationError)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		op := d.routes[c.Request.Method+" "+c.FullPath()]
+		if op == nil || op.RequestBody == nil || c.Request.Body == nil || c.ContentType() != "application/json" {
+			c.Next()
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, MaxBodyBytes))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				invalid(c, &ValidationError{Problems: []Problem{{Message: fmt.Sprintf("body must not be larger than %d bytes", MaxBodyBytes)}}})
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		if err := d.ValidateBody(c.Request.Method, c.FullPath(), body); err != nil {
+			invalid(c, err.(*ValidationError))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func (d *Document) resolve(s *Schema) *Schema {
+	for s.Ref != "" {
+		s = d.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	return s
+}
+
+func field(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+func (d *Document) validate(s *Schema, v any, at string, problems *[]Problem) {
+	s = d.resolve(s)
+	for _, sub := range s.AllOf {
+		d.validate(sub, v, at, problems)
+	}
+	if v == nil {
+		// encoding/json leaves the Go value untouched for null, so null is
+		// only a problem for required fields, checked by their parent.
+		return
+	}
+	fail := func(format string, args ...any) {
+		*problems = append(*problems, Problem{Field: at, Message: fmt.Sprintf(format, args...)})
+	}
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			fail("must be an object")
+			return
+		}
+		for _, name := range s.Required {
+			if obj[name] == nil {
+				*problems = append(*problems, Problem{Field: field(at, name), Message: "is required"})
+			}
+		}
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if ps, ok := s.Properties[name]; ok {
+				d.validate(ps, obj[name], field(at, name), problems)
+			} else if s.AdditionalProperties != nil {
+				d.validate(s.AdditionalProperties, obj[name], field(at, name), problems)
+			}
+		}
+	case "array":
+		arr, ok := v.([]any)
+		if !ok {
+			fail("must be an array")
+			return
+		}
+		if s.MinItems != nil && len(arr) < *s.MinItems {
+			fail("must have at least %d items", *s.MinItems)
+		}
+		for i, item := range arr {
+			d.validate(s.Items, item, fmt.Sprintf("%s[%d]", at, i), problems)
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			fail("must be a string")
+			return
+		}
+		if len(s.Enum) > 0 && !contains(s.Enum, str) {
+			fail("must be one of %s", strings.Join(s.Enum, ", "))
+		}
+		n := utf8.RuneCountInString(str)
+		if s.MinLength != nil && n < *s.MinLength {
+			fail("must be at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			fail("must be at most %d characters", *s.MaxLength)
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, str); err != nil {
+				fail("must be an RFC 3339 date-time")
+			}
+		}
+	case "integer", "number":
+		num, ok := v.(json.Number)
+		if !ok {
+			fail("must be a number")
+			return
+		}
+		if s.Type == "integer" {
+			if _, err := strconv.ParseInt(string(num), 10, 64); err != nil {
+				fail("must be a whole number")
+				return
+			}
+		}
+		f, err := num.Float64()
+		if err != nil {
+			fail("must be a number")
+			return
+		}
+		if s.Minimum != nil && (f < *s.Minimum || s.ExclusiveMinimum && f == *s.Minimum) {
+			if s.ExclusiveMinimum {
+				fail("must be greater than %v", *s.Minimum)
+			} else {
+				fail("must be at least %v", *s.Minimum)
+			}
+		}
+		if s.Maximum != nil && (f > *s.Maximum || s.ExclusiveMaximum && f == *s.Maximum) {
+			if s.ExclusiveMaximum {
+				fail("must be less than %v", *s.Maximum)
+			} else {
+				fail("must be at most %v", *s.Maximum)
+			}
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			fail("must be true or false")
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// Farm holds the letterhead printed at the top of every document.
+type Farm struct {
+	Name    string
+	Address string
+	Logo    []byte // PNG or JPEG, optional
+}
+
+// LineItem is one row of a receipt or invoice.
+type LineItem struct {
+	Date        time.Time
+	Description string
+	Quantity    int
+	UnitPrice   *float64 // nil when no price was recorded for the sale
+}
+
+// Document describes a receipt or invoice to render.
+type Document struct {
+	Title    string // e.g. "Receipt" or "Invoice"
+	Number   string
+	Date     time.Time
+	Customer string
+	Items    []LineItem
+}
+
+// Total returns the sum of all priced line items.
+func (d Document) Total() float64 {
+	total := 0.0
+	for _, it := range d.Items {
+		if it.UnitPrice != nil {
+			total += *it.UnitPrice * float64(it.Quantity)
+		}
+	}
+	return total
+}
+
+// Render writes the document as a single A4 PDF to w.
+func Render(w io.Writer, farm Farm, doc Document) error {
+	p := fpdf.New("P", "mm", "A4", "")
+	p.SetCreationDate(doc.Date)
+	p.SetTitle(doc.Title+" "+doc.Number, true)
+	tr := p.UnicodeTranslatorFromDescriptor("")
+	p.AddPage()
+
+	// Letterhead
+	if len(farm.Logo) > 0 {
+		imgType := logoType(farm.Logo)
+		if imgType == "" {
+			return fmt.Errorf("unsupported logo format")
+		}
+		opts := fpdf.ImageOptions{ImageType: imgType, ReadDpi: true}
+		p.RegisterImageOptionsReader("logo", opts, bytes.NewReader(farm.Logo))
+		if err := p.Error(); err != nil {
+			return fmt.Errorf("load logo: %w", err)
+		}
+		p.ImageOptions("logo", 150, 10, 45, 0, false, opts, 0, "")
+	}
+	p.SetFont("Helvetica", "B", 18)
+	p.CellFormat(130, 9, tr(farm.Name), "", 1, "L", false, 0, "")
+	p.SetFont("Helvetica", "", 10)
+	for _, line := range strings.Split(farm.Address, "\n") {
+		p.CellFormat(130, 5, tr(line), "", 1, "L", false, 0, "")
+	}
+	p.Ln(12)
+
+	// Document header
+	p.SetFont("Helvetica", "B", 14)
+	p.CellFormat(0, 8, tr(doc.Title), "", 1, "L", false, 0, "")
+	p.SetFont("Helvetica", "", 10)
+	p.CellFormat(0, 5, tr("Number: "+doc.Number), "", 1, "L", false, 0, "")
+	p.CellFormat(0, 5, "Date: "+doc.Date.Format("2006-01-02"), "", 1, "L", false, 0, "")
+	if doc.Customer != "" {
+		p.CellFormat(0, 5, tr("Customer: "+doc.Customer), "", 1, "L", false, 0, "")
+	}
+	p.Ln(6)
+
+	// Line items
+	widths := []float64{28, 82, 20, 30, 30}
+	p.SetFont("Helvetica", "B", 10)
+	p.SetFillColor(230, 230, 230)
+	for i, h := range []string{"Date", "Description", "Qty", "Unit price", "Amount"} {
+		align := "L"
+		if i >= 2 {
+			align = "R"
+		}
+		p.CellFormat(widths[i], 7, h, "B", 0, align, true, 0, "")
+	}
+	p.Ln(-1)
+	p.SetFont("Helvetica", "", 10)
+	for _, it := range doc.Items {
+		unit, amount := "-", "-"
+		if it.UnitPrice != nil {
+			unit = formatMoney(*it.UnitPrice)
+			amount = formatMoney(*it.UnitPrice * float64(it.Quantity))
+		}
+		p.CellFormat(widths[0], 6, it.Date.Format("2006-01-02"), "", 0, "L", false, 0, "")
+		p.CellFormat(widths[1], 6, tr(it.Description), "", 0, "L", false, 0, "")
+		p.CellFormat(widths[2], 6, fmt.Sprintf("%d", it.Quantity), "", 0, "R", false, 0, "")
+		p.CellFormat(widths[3], 6, unit, "", 0, "R", false, 0, "")
+		p.CellFormat(widths[4], 6, amount, "", 1, "R", false, 0, "")
+	}
+	p.SetFont("Helvetica", "B", 10)
+	p.CellFormat(widths[0]+widths[1]+widths[2]+widths[3], 8, "Total", "T", 0, "R", false, 0, "")
+	p.CellFormat(widths[4], 8, formatMoney(doc.Total()), "T", 1, "R", false, 0, "")
+
+	p.Ln(10)
+	p.SetFont("Helvetica", "I", 9)
+	p.CellFormat(0, 5, tr("Thank you for buying from "+farm.Name+"!"), "", 1, "C", false, 0, "")
+
+	return p.Output(w)
+}
+
+// logoType maps sniffed image content to the fpdf image type name.
+func logoType(data []byte) string {
+	switch http.DetectContentType(data) {
+	case "image/png":
+		return "PNG"
+	case "image/jpeg":
+		return "JPG"
+	default:
+		return ""
+	}
+}
+
+func formatMoney(v float64) string {
+	return fmt.Sprintf("%.2f", v)
+}
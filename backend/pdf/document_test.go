@@ -0,0 +1,43 @@
+package pdf
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+	"time"
+)
+
+func TestRender_ProducesPDF(t *testing.T) {
+	var logo bytes.Buffer
+	png.Encode(&logo, image.NewRGBA(image.Rect(0, 0, 8, 8)))
+	price := 0.45
+	doc := Document{
+		Title:    "Receipt",
+		Number:   "R-000001",
+		Date:     time.Date(2025, 5, 3, 0, 0, 0, 0, time.UTC),
+		Customer: "Zoë",
+		Items: []LineItem{
+			{Date: time.Date(2025, 5, 3, 0, 0, 0, 0, time.UTC), Description: "Large Brown Chicken eggs", Quantity: 12, UnitPrice: &price},
+			{Date: time.Date(2025, 5, 3, 0, 0, 0, 0, time.UTC), Description: "Goose eggs", Quantity: 2},
+		},
+	}
+	var out bytes.Buffer
+	if err := Render(&out, Farm{Name: "Sunny Acres", Address: "1 Barn Rd\nSpringfield", Logo: logo.Bytes()}, doc); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if !bytes.HasPrefix(out.Bytes(), []byte("%PDF-")) {
+		t.Fatalf("output is not a PDF")
+	}
+	if got := doc.Total(); got < 5.39 || got > 5.41 {
+		t.Errorf("expected total 5.40, got %.2f", got)
+	}
+}
+
+func TestRender_RejectsUnknownLogo(t *testing.T) {
+	var out bytes.Buffer
+	err := Render(&out, Farm{Name: "Farm", Logo: []byte("GIF89a")}, Document{Title: "Receipt"})
+	if err == nil {
+		t.Fatalf("expected error for unsupported logo")
+	}
+}
@@ -0,0 +1,202 @@
+// Package recur expands the subset of iCalendar (RFC 5545) recurrence rules
+// used for farm chores. Occurrences are whole days; times of day are ignored.
+//
+// Supported parts: FREQ (DAILY, WEEKLY, MONTHLY, YEARLY), INTERVAL, BYDAY
+// (weekly rules only, e.g. MO,TH), BYMONTHDAY (monthly rules only, negative
+// values count from the end of the month), COUNT and UNTIL (YYYYMMDD).
+package recur
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rule is a parsed recurrence rule.
+type Rule struct {
+	Freq       string
+	Interval   int
+	ByDay      []time.Weekday
+	ByMonthDay []int
+	Count      int
+	Until      *time.Time
+}
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// maxPeriods bounds expansion so that a rule which can never produce another
+// occurrence (e.g. BYMONTHDAY=31 every 12 months from February) terminates.
+const maxPeriods = 100000
+
+// Parse reads a rule such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH". A leading
+// "RRULE:" is accepted.
+func Parse(s string) (Rule, error) {
+	r := Rule{Interval: 1}
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return r, fmt.Errorf("empty rule")
+	}
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return r, fmt.Errorf("invalid rule part %q", part)
+		}
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			r.Freq = strings.ToUpper(value)
+			switch r.Freq {
+			case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+			default:
+				return r, fmt.Errorf("unsupported FREQ %q", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return r, fmt.Errorf("invalid INTERVAL %q", value)
+			}
+			r.Interval = n
+		case "BYDAY":
+			for _, d := range strings.Split(strings.ToUpper(value), ",") {
+				wd, ok := weekdays[d]
+				if !ok {
+					return r, fmt.Errorf("invalid BYDAY %q", d)
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			for _, d := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(d)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return r, fmt.Errorf("invalid BYMONTHDAY %q", d)
+				}
+				r.ByMonthDay = append(r.ByMonthDay, n)
+			}
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return r, fmt.Errorf("invalid COUNT %q", value)
+			}
+			r.Count = n
+		case "UNTIL":
+			t, err := time.Parse("20060102", value[:min(len(value), 8)])
+			if err != nil {
+				return r, fmt.Errorf("invalid UNTIL %q", value)
+			}
+			r.Until = &t
+		default:
+			return r, fmt.Errorf("unsupported rule part %q", key)
+		}
+	}
+	if r.Freq == "" {
+		return r, fmt.Errorf("FREQ is required")
+	}
+	if len(r.ByDay) > 0 && r.Freq != "WEEKLY" {
+		return r, fmt.Errorf("BYDAY is only supported with FREQ=WEEKLY")
+	}
+	if len(r.ByMonthDay) > 0 && r.Freq != "MONTHLY" {
+		return r, fmt.Errorf("BYMONTHDAY is only supported with FREQ=MONTHLY")
+	}
+	if r.Count > 0 && r.Until != nil {
+		return r, fmt.Errorf("COUNT and UNTIL cannot both be set")
+	}
+	return r, nil
+}
+
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// period returns the candidate days of the n-th period after start, sorted.
+func (r Rule) period(start time.Time, n int) []time.Time {
+	step := n * r.Interval
+	switch r.Freq {
+	case "DAILY":
+		return []time.Time{start.AddDate(0, 0, step)}
+	case "WEEKLY":
+		if len(r.ByDay) == 0 {
+			return []time.Time{start.AddDate(0, 0, 7*step)}
+		}
+		// Weeks start on Monday, as with the RFC 5545 default WKST=MO.
+		monday := start.AddDate(0, 0, -((int(start.Weekday())+6)%7)+7*step)
+		var days []time.Time
+		for _, wd := range r.ByDay {
+			days = append(days, monday.AddDate(0, 0, (int(wd)+6)%7))
+		}
+		sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+		return days
+	case "MONTHLY":
+		first := time.Date(start.Year(), start.Month()+time.Month(step), 1, 0, 0, 0, 0, time.UTC)
+		last := first.AddDate(0, 1, -1).Day()
+		monthDays := r.ByMonthDay
+		if len(monthDays) == 0 {
+			monthDays = []int{start.Day()}
+		}
+		var days []time.Time
+		for _, d := range monthDays {
+			if d < 0 {
+				d = last + d + 1
+			}
+			// Months without the day are skipped, as RFC 5545 requires.
+			if d >= 1 && d <= last {
+				days = append(days, first.AddDate(0, 0, d-1))
+			}
+		}
+		sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+		return days
+	default: // YEARLY
+		d := time.Date(start.Year()+step, start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+		if d.Month() != start.Month() { // 29 February in a common year
+			return nil
+		}
+		return []time.Time{d}
+	}
+}
+
+// Between returns the occurrences of a rule starting on start that fall on or
+// after from and on or before to, oldest first.
+func (r Rule) Between(start, from, to time.Time) []time.Time {
+	start, from, to = day(start), day(from), day(to)
+	var out []time.Time
+	seen := 0
+	for n := 0; n < maxPeriods; n++ {
+		for _, d := range r.period(start, n) {
+			if d.Before(start) {
+				continue
+			}
+			if d.After(to) || (r.Until != nil && d.After(*r.Until)) {
+				return out
+			}
+			seen++
+			if r.Count > 0 && seen > r.Count {
+				return out
+			}
+			if !d.Before(from) {
+				out = append(out, d)
+			}
+		}
+	}
+	return out
+}
+
+// Includes reports whether d is an occurrence of a rule starting on start.
+func (r Rule) Includes(start, d time.Time) bool {
+	return len(r.Between(start, d, d)) == 1
+}
+
+// Next returns the first occurrence on or after from, if any.
+func (r Rule) Next(start, from time.Time) (time.Time, bool) {
+	// Look ahead a little over a period at the rule's coarsest spacing, then
+	// further for rules that skip periods, such as 29 February every year,
+	// which can go eight years without an occurrence.
+	for years := r.Interval + 1; years <= 8*(r.Interval+1); years *= 2 {
+		if occ := r.Between(start, from, day(from).AddDate(years, 0, 0)); len(occ) > 0 {
+			return occ[0], true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,83 @@
+package recur
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func dates(ts []time.Time) string {
+	var s []string
+	for _, t := range ts {
+		s = append(s, t.Format("2006-01-02"))
+	}
+	return strings.Join(s, ",")
+}
+
+func TestBetween(t *testing.T) {
+	d := func(s string) time.Time {
+		t, _ := time.Parse("2006-01-02", s)
+		return t
+	}
+	cases := []struct {
+		rule, start, from, to, want string
+	}{
+		{"FREQ=DAILY;INTERVAL=3", "2025-05-01", "2025-05-01", "2025-05-10", "2025-05-01,2025-05-04,2025-05-07,2025-05-10"},
+		// 2025-05-01 is a Thursday; the Monday of its week is skipped
+		{"FREQ=WEEKLY;BYDAY=MO,TH", "2025-05-01", "2025-05-01", "2025-05-12", "2025-05-01,2025-05-05,2025-05-08,2025-05-12"},
+		{"FREQ=WEEKLY;INTERVAL=2", "2025-05-01", "2025-05-10", "2025-06-01", "2025-05-15,2025-05-29"},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", "2025-01-15", "2025-01-01", "2025-04-30", "2025-01-31,2025-02-28,2025-03-31,2025-04-30"},
+		{"FREQ=MONTHLY", "2025-01-31", "2025-01-01", "2025-05-31", "2025-01-31,2025-03-31,2025-05-31"},
+		{"FREQ=YEARLY", "2024-02-29", "2024-01-01", "2028-12-31", "2024-02-29,2028-02-29"},
+		{"FREQ=DAILY;COUNT=3", "2025-05-01", "2025-05-02", "2025-05-31", "2025-05-02,2025-05-03"},
+		{"RRULE:FREQ=DAILY;UNTIL=20250503", "2025-05-01", "2025-05-01", "2025-05-31", "2025-05-01,2025-05-02,2025-05-03"},
+	}
+	for _, tc := range cases {
+		r, err := Parse(tc.rule)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tc.rule, err)
+		}
+		if got := dates(r.Between(d(tc.start), d(tc.from), d(tc.to))); got != tc.want {
+			t.Errorf("%s from %s: got %s, want %s", tc.rule, tc.start, got, tc.want)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	d := func(s string) time.Time {
+		t, _ := time.Parse("2006-01-02", s)
+		return t
+	}
+	cases := []struct {
+		rule, start, from, want string
+	}{
+		{"FREQ=WEEKLY;BYDAY=MO", "2025-05-01", "2025-05-06", "2025-05-12"},
+		{"FREQ=YEARLY", "2024-02-29", "2024-03-01", "2028-02-29"},
+		// 2100 is not a leap year
+		{"FREQ=YEARLY", "2096-02-29", "2096-03-01", "2104-02-29"},
+		{"FREQ=MONTHLY;INTERVAL=12;BYMONTHDAY=31", "2025-02-01", "2025-02-01", ""},
+		{"FREQ=DAILY;COUNT=2", "2025-05-01", "2025-05-03", ""},
+	}
+	for _, tc := range cases {
+		r, err := Parse(tc.rule)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tc.rule, err)
+		}
+		next, ok := r.Next(d(tc.start), d(tc.from))
+		got := ""
+		if ok {
+			got = next.Format("2006-01-02")
+		}
+		if got != tc.want {
+			t.Errorf("%s from %s after %s: got %q, want %q", tc.rule, tc.start, tc.from, got, tc.want)
+		}
+	}
+}
+
+func TestParseRejectsUnsupportedRules(t *testing.T) {
+	for _, rule := range []string{"", "INTERVAL=2", "FREQ=HOURLY", "FREQ=DAILY;BYDAY=MO", "FREQ=WEEKLY;BYDAY=XX", "FREQ=DAILY;COUNT=2;UNTIL=20250101", "FREQ=DAILY;BYSETPOS=1"} {
+		if _, err := Parse(rule); err == nil {
+			t.Errorf("expected %q to be rejected", rule)
+		}
+	}
+}
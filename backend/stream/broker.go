@@ -0,0 +1,61 @@
+// Package stream fans events out to live subscribers such as open
+// Server-Sent Events connections.
+package stream
+
+import (
+	"sync"
+
+	"egg-tracker/backend/models"
+)
+
+// Broker delivers published events to every current subscriber. It never
+// blocks the publisher: a subscriber that falls behind by more than its
+// buffer is dropped, and its channel closed, so that it can reconnect and
+// catch up from the event log instead.
+type Broker struct {
+	mu   sync.Mutex
+	subs map[chan models.Event]struct{}
+}
+
+func NewBroker() *Broker {
+	return &Broker{subs: map[chan models.Event]struct{}{}}
+}
+
+// Subscribe returns a channel of events published from now on and a
+// function that ends the subscription.
+func (b *Broker) Subscribe(buffer int) (<-chan models.Event, func()) {
+	ch := make(chan models.Event, buffer)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+	return ch, func() { b.remove(ch) }
+}
+
+func (b *Broker) remove(ch chan models.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[ch]; ok {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
+
+func (b *Broker) Publish(e models.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribers returns the number of current subscribers.
+func (b *Broker) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
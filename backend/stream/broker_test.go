@@ -0,0 +1,43 @@
+package stream
+
+import (
+	"testing"
+
+	"egg-tracker/backend/models"
+)
+
+func TestBroker(t *testing.T) {
+	b := NewBroker()
+	fast, stopFast := b.Subscribe(4)
+	slow, _ := b.Subscribe(1)
+	if b.Subscribers() != 2 {
+		t.Fatalf("expected 2 subscribers, got %d", b.Subscribers())
+	}
+
+	b.Publish(models.Event{ID: 1, Type: "inventory.created"})
+	b.Publish(models.Event{ID: 2, Type: "inventory.deleted"})
+	if e := <-fast; e.ID != 1 {
+		t.Errorf("expected event 1, got %d", e.ID)
+	}
+	if e := <-fast; e.ID != 2 {
+		t.Errorf("expected event 2, got %d", e.ID)
+	}
+	// The slow subscriber's buffer was full for event 2, so it was dropped
+	// after receiving event 1.
+	if e, ok := <-slow; !ok || e.ID != 1 {
+		t.Errorf("expected event 1 for the slow subscriber, got %d, %v", e.ID, ok)
+	}
+	if _, ok := <-slow; ok {
+		t.Error("expected the slow subscriber's channel to be closed")
+	}
+	if b.Subscribers() != 1 {
+		t.Errorf("expected 1 subscriber, got %d", b.Subscribers())
+	}
+
+	stopFast()
+	stopFast() // ending twice is harmless
+	if _, ok := <-fast; ok {
+		t.Error("expected the channel to be closed after unsubscribing")
+	}
+	b.Publish(models.Event{ID: 3})
+}
@@ -0,0 +1,67 @@
+// Package sun computes sunrise, sunset and day length from latitude and
+// longitude using the sunrise equation, without any network lookups. Results
+// are accurate to a minute or two, which is plenty for photoperiod tracking.
+package sun
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	j2000        = 2451545.0 // Julian date of 2000-01-01 12:00 UTC
+	unixEpochJD  = 2440587.5 // Julian date of 1970-01-01 00:00 UTC
+	obliquity    = 23.4397   // degrees
+	horizonAngle = -0.833    // sun's centre at sunrise, allowing for refraction and its radius
+)
+
+// Day is the daylight on one calendar date.
+type Day struct {
+	Date      time.Time
+	Sunrise   *time.Time // nil during polar day or night
+	Sunset    *time.Time
+	DayLength time.Duration
+	Polar     string // "day" or "night" when the sun never sets or never rises
+}
+
+func rad(deg float64) float64 { return deg * math.Pi / 180 }
+func deg(r float64) float64   { return r * 180 / math.Pi }
+
+func fromJulian(j float64) time.Time {
+	secs := (j - unixEpochJD) * 86400
+	return time.Unix(int64(math.Round(secs)), 0).UTC()
+}
+
+// Times returns the daylight for the calendar date of date at the given
+// position (degrees, north and east positive), with times in loc.
+func Times(date time.Time, lat, lon float64, loc *time.Location) Day {
+	y, m, d := date.Date()
+	day := Day{Date: time.Date(y, m, d, 0, 0, 0, 0, loc)}
+
+	noonUTC := time.Date(y, m, d, 12, 0, 0, 0, time.UTC)
+	n := float64(noonUTC.Unix())/86400 + unixEpochJD - j2000
+	meanNoon := n - lon/360
+
+	anomaly := math.Mod(357.5291+0.98560028*meanNoon, 360)
+	center := 1.9148*math.Sin(rad(anomaly)) + 0.02*math.Sin(rad(2*anomaly)) + 0.0003*math.Sin(rad(3*anomaly))
+	eclipticLon := math.Mod(anomaly+center+180+102.9372, 360)
+	transit := j2000 + meanNoon + 0.0053*math.Sin(rad(anomaly)) - 0.0069*math.Sin(rad(2*eclipticLon))
+
+	sinDecl := math.Sin(rad(eclipticLon)) * math.Sin(rad(obliquity))
+	cosDecl := math.Cos(math.Asin(sinDecl))
+	cosHour := (math.Sin(rad(horizonAngle)) - math.Sin(rad(lat))*sinDecl) / (math.Cos(rad(lat)) * cosDecl)
+	switch {
+	case cosHour < -1:
+		day.Polar, day.DayLength = "day", 24*time.Hour
+		return day
+	case cosHour > 1:
+		day.Polar = "night"
+		return day
+	}
+	hourAngle := deg(math.Acos(cosHour))
+	rise := fromJulian(transit - hourAngle/360).In(loc)
+	set := fromJulian(transit + hourAngle/360).In(loc)
+	day.Sunrise, day.Sunset = &rise, &set
+	day.DayLength = set.Sub(rise)
+	return day
+}
@@ -0,0 +1,53 @@
+package sun
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimes(t *testing.T) {
+	london, _ := time.LoadLocation("Europe/London")
+	sydney, _ := time.LoadLocation("Australia/Sydney")
+	cases := []struct {
+		name          string
+		date          string
+		lat, lon      float64
+		loc           *time.Location
+		rise, set     string
+		lengthMinutes float64
+	}{
+		{"London midsummer", "2025-06-21", 51.5074, -0.1278, london, "04:43", "21:21", 998},
+		{"London midwinter", "2025-12-21", 51.5074, -0.1278, london, "08:04", "15:54", 470},
+		{"Sydney midwinter", "2025-06-21", -33.8688, 151.2093, sydney, "07:00", "16:54", 594},
+	}
+	for _, tc := range cases {
+		date, _ := time.Parse("2006-01-02", tc.date)
+		d := Times(date, tc.lat, tc.lon, tc.loc)
+		if d.Sunrise == nil || d.Sunset == nil {
+			t.Fatalf("%s: expected sunrise and sunset, got %+v", tc.name, d)
+		}
+		for _, c := range []struct {
+			got  time.Time
+			want string
+		}{{*d.Sunrise, tc.rise}, {*d.Sunset, tc.set}} {
+			want, _ := time.ParseInLocation("2006-01-02 15:04", tc.date+" "+c.want, tc.loc)
+			if diff := c.got.Sub(want); diff < -2*time.Minute || diff > 2*time.Minute {
+				t.Errorf("%s: got %s, want about %s", tc.name, c.got.Format("15:04"), c.want)
+			}
+		}
+		if diff := d.DayLength.Minutes() - tc.lengthMinutes; diff < -3 || diff > 3 {
+			t.Errorf("%s: day length %.0f minutes, want about %.0f", tc.name, d.DayLength.Minutes(), tc.lengthMinutes)
+		}
+	}
+}
+
+func TestTimesPolar(t *testing.T) {
+	summer, _ := time.Parse("2006-01-02", "2025-06-21")
+	winter, _ := time.Parse("2006-01-02", "2025-12-21")
+	if d := Times(summer, 69.65, 18.96, time.UTC); d.Polar != "day" || d.DayLength != 24*time.Hour || d.Sunrise != nil {
+		t.Errorf("expected midnight sun in Tromsø, got %+v", d)
+	}
+	if d := Times(winter, 69.65, 18.96, time.UTC); d.Polar != "night" || d.DayLength != 0 {
+		t.Errorf("expected polar night in Tromsø, got %+v", d)
+	}
+}
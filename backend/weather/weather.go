@@ -0,0 +1,272 @@
+// Package weather parses daily weather observations for import. It reads the
+// NOAA GHCN-Daily fixed-width .dly format and generic CSV or JSON files with
+// one row per day.
+package weather
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Day is one day of observations. Temperatures are in °C, precipitation in mm;
+// missing values are nil.
+type Day struct {
+	Date     time.Time
+	Station  string
+	TminC    *float64
+	TmaxC    *float64
+	TavgC    *float64
+	PrecipMM *float64
+}
+
+// Units says how temperatures and precipitation are given in generic files.
+type Units string
+
+const (
+	Metric   Units = "metric"   // °C and mm
+	Imperial Units = "imperial" // °F and inches
+)
+
+func (u Units) temp(v float64) float64 {
+	if u == Imperial {
+		return (v - 32) * 5 / 9
+	}
+	return v
+}
+
+func (u Units) precip(v float64) float64 {
+	if u == Imperial {
+		return v * 25.4
+	}
+	return v
+}
+
+// ParseGHCN reads the GHCN-Daily .dly format: one line per station, month and
+// element with 31 day values in tenths of °C or tenths of mm. Values that are
+// missing (-9999) or failed a quality check are skipped, as are elements
+// other than TMIN, TMAX, TAVG and PRCP.
+func ParseGHCN(r io.Reader) ([]Day, error) {
+	days := map[string]*Day{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		if len(text) < 21 {
+			return nil, fmt.Errorf("line %d: too short for GHCN-Daily", line)
+		}
+		station := strings.TrimSpace(text[0:11])
+		year, yerr := strconv.Atoi(text[11:15])
+		month, merr := strconv.Atoi(text[15:17])
+		if yerr != nil || merr != nil || month < 1 || month > 12 {
+			return nil, fmt.Errorf("line %d: invalid year or month", line)
+		}
+		element := text[17:21]
+		switch element {
+		case "TMIN", "TMAX", "TAVG", "PRCP":
+		default:
+			continue
+		}
+		for d := 1; d <= 31; d++ {
+			start := 21 + (d-1)*8
+			if start+8 > len(text) {
+				break
+			}
+			raw, err := strconv.Atoi(strings.TrimSpace(text[start : start+5]))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid value for day %d", line, d)
+			}
+			qflag := text[start+6]
+			if raw == -9999 || qflag != ' ' {
+				continue
+			}
+			date := time.Date(year, time.Month(month), d, 0, 0, 0, 0, time.UTC)
+			if date.Month() != time.Month(month) {
+				continue
+			}
+			key := station + date.Format("20060102")
+			day, ok := days[key]
+			if !ok {
+				day = &Day{Date: date, Station: station}
+				days[key] = day
+			}
+			v := float64(raw) / 10
+			switch element {
+			case "TMIN":
+				day.TminC = &v
+			case "TMAX":
+				day.TmaxC = &v
+			case "TAVG":
+				day.TavgC = &v
+			case "PRCP":
+				day.PrecipMM = &v
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	out := make([]Day, 0, len(days))
+	for _, d := range days {
+		out = append(out, *d)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].Date.Equal(out[j].Date) {
+			return out[i].Date.Before(out[j].Date)
+		}
+		return out[i].Station < out[j].Station
+	})
+	return out, nil
+}
+
+// columnAliases maps accepted CSV headers and JSON keys onto fields. NOAA
+// "daily summaries" CSV exports (STATION, DATE, TMIN, TMAX, TAVG, PRCP) are
+// covered as well.
+var columnAliases = map[string]string{
+	"date": "date", "day": "date", "station": "station",
+	"tmin": "tmin", "tmin_c": "tmin", "tmin_f": "tmin", "min": "tmin", "min_temp": "tmin",
+	"tmax": "tmax", "tmax_c": "tmax", "tmax_f": "tmax", "max": "tmax", "max_temp": "tmax",
+	"tavg": "tavg", "tavg_c": "tavg", "tavg_f": "tavg", "avg": "tavg", "mean_temp": "tavg",
+	"prcp": "precip", "precip": "precip", "precip_mm": "precip", "precip_in": "precip", "precipitation": "precip", "rain": "precip",
+}
+
+// columnUnits lets a unit suffix on a column name (tmax_f, precip_mm, ...)
+// override the units given for the file.
+func columnUnits(name string, units Units) Units {
+	switch {
+	case strings.HasSuffix(name, "_f"), strings.HasSuffix(name, "_in"):
+		return Imperial
+	case strings.HasSuffix(name, "_c"), strings.HasSuffix(name, "_mm"):
+		return Metric
+	}
+	return units
+}
+
+func parseDate(s string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02", "20060102", "2006/01/02"} {
+		if t, err := time.Parse(layout, strings.TrimSpace(s)); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+// setField stores one raw value, converting it from units. Empty values are
+// left missing.
+func setField(d *Day, field, value string, units Units) error {
+	value = strings.TrimSpace(value)
+	if field == "station" {
+		d.Station = value
+		return nil
+	}
+	if field == "date" {
+		t, err := parseDate(value)
+		d.Date = t
+		return err
+	}
+	if value == "" {
+		return nil
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q", field, value)
+	}
+	switch field {
+	case "tmin":
+		v = units.temp(v)
+		d.TminC = &v
+	case "tmax":
+		v = units.temp(v)
+		d.TmaxC = &v
+	case "tavg":
+		v = units.temp(v)
+		d.TavgC = &v
+	case "precip":
+		v = units.precip(v)
+		d.PrecipMM = &v
+	}
+	return nil
+}
+
+// ParseCSV reads a CSV file with a header row naming a date column and any of
+// the temperature and precipitation columns in columnAliases.
+func ParseCSV(r io.Reader, units Units) ([]Day, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	fields := make([]string, len(header))
+	fieldUnits := make([]Units, len(header))
+	hasDate := false
+	for i, h := range header {
+		name := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+		fields[i], fieldUnits[i] = columnAliases[name], columnUnits(name, units)
+		hasDate = hasDate || fields[i] == "date"
+	}
+	if !hasDate {
+		return nil, fmt.Errorf("no date column in header")
+	}
+	var days []Day
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		var d Day
+		for i, v := range rec {
+			if i >= len(fields) || fields[i] == "" {
+				continue
+			}
+			if err := setField(&d, fields[i], v, fieldUnits[i]); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+		days = append(days, d)
+	}
+	return days, nil
+}
+
+// ParseJSON reads an array of objects keyed like the CSV columns, e.g.
+// [{"date": "2025-07-01", "tmin": 14.2, "tmax": 31.5, "precip": 0}].
+func ParseJSON(r io.Reader, units Units) ([]Day, error) {
+	var rows []map[string]interface{}
+	if err := json.NewDecoder(r).Decode(&rows); err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+	days := make([]Day, 0, len(rows))
+	for i, row := range rows {
+		var d Day
+		hasDate := false
+		for k, v := range row {
+			name := strings.ToLower(k)
+			field := columnAliases[name]
+			if field == "" || v == nil {
+				continue
+			}
+			hasDate = hasDate || field == "date"
+			if err := setField(&d, field, fmt.Sprint(v), columnUnits(name, units)); err != nil {
+				return nil, fmt.Errorf("row %d: %w", i+1, err)
+			}
+		}
+		if !hasDate {
+			return nil, fmt.Errorf("row %d: missing date", i+1)
+		}
+		days = append(days, d)
+	}
+	return days, nil
+}
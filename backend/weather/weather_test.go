@@ -0,0 +1,76 @@
+package weather
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+// dlyLine builds a GHCN-Daily record; values beyond those given are missing.
+func dlyLine(element string, qflags string, values ...int) string {
+	line := "USC00301234" + "2025" + "07" + element
+	for d := 0; d < 31; d++ {
+		v, q := -9999, byte(' ')
+		if d < len(values) {
+			v = values[d]
+		}
+		if d < len(qflags) {
+			q = qflags[d]
+		}
+		line += fmt.Sprintf("%5d %c ", v, q)
+	}
+	return line
+}
+
+func near(p *float64, want float64) bool {
+	return p != nil && math.Abs(*p-want) < 1e-9
+}
+
+func TestParseGHCN(t *testing.T) {
+	data := strings.Join([]string{
+		dlyLine("TMAX", "  X", 312, 335, 400),
+		dlyLine("TMIN", "", 141, 150),
+		dlyLine("SNOW", "", 0, 0),
+		dlyLine("PRCP", "", 0, 53),
+	}, "\n")
+	days, err := ParseGHCN(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseGHCN: %v", err)
+	}
+	// The third TMAX failed quality control, leaving no values for the 3rd
+	if len(days) != 2 {
+		t.Fatalf("expected 2 days, got %d", len(days))
+	}
+	d := days[1]
+	if d.Station != "USC00301234" || d.Date.Format("2006-01-02") != "2025-07-02" || !near(d.TmaxC, 33.5) || !near(d.TminC, 15) || !near(d.PrecipMM, 5.3) || d.TavgC != nil {
+		t.Errorf("unexpected day: %+v", d)
+	}
+}
+
+func TestParseCSVAndJSON(t *testing.T) {
+	csvData := "\ufeffSTATION,DATE,PRCP,TMAX,TMIN\nUSW00014739,2025-07-01,0.5,95,68\nUSW00014739,2025-07-02,,86,\n"
+	days, err := ParseCSV(strings.NewReader(csvData), Imperial)
+	if err != nil {
+		t.Fatalf("ParseCSV: %v", err)
+	}
+	if len(days) != 2 || !near(days[0].TmaxC, 35) || !near(days[0].TminC, 20) || !near(days[0].PrecipMM, 12.7) || days[1].TminC != nil || days[1].PrecipMM != nil {
+		t.Errorf("unexpected days: %+v", days)
+	}
+	// A unit suffix wins over the file's units
+	days, err = ParseCSV(strings.NewReader("date,tmax_c\n20250701,30\n"), Imperial)
+	if err != nil || !near(days[0].TmaxC, 30) {
+		t.Errorf("expected 30 C from a _c column, got %+v %v", days, err)
+	}
+	if _, err := ParseCSV(strings.NewReader("when,tmax\n2025-07-01,30\n"), Metric); err == nil {
+		t.Errorf("expected an error without a date column")
+	}
+
+	days, err = ParseJSON(strings.NewReader(`[{"date": "2025-07-01", "tmin": 14.2, "tmax": 31.5, "precip": 0, "humidity": 40}]`), Metric)
+	if err != nil || len(days) != 1 || !near(days[0].TminC, 14.2) || !near(days[0].PrecipMM, 0) {
+		t.Errorf("unexpected JSON days: %+v %v", days, err)
+	}
+	if _, err := ParseJSON(strings.NewReader(`[{"tmax": 30}]`), Metric); err == nil {
+		t.Errorf("expected an error for a row without a date")
+	}
+}
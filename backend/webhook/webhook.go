@@ -0,0 +1,87 @@
+// Package webhook signs and posts event payloads to subscriber URLs.
+//
+// Each request carries X-Webhook-Timestamp (Unix seconds) and
+// X-Webhook-Signature, "sha256=" followed by the hex HMAC-SHA256 of the
+// timestamp, a dot and the raw body, keyed with the subscription secret.
+// Receivers should recompute it with Verify and reject stale timestamps.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// MaxAttempts is how many times a delivery is tried before it is given up.
+const MaxAttempts = 8
+
+// Backoff is the wait before retrying a delivery that has failed attempts
+// times: 30 seconds, doubling each time up to an hour.
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	if attempts > 8 {
+		return time.Hour
+	}
+	d := 30 * time.Second << (attempts - 1)
+	if d > time.Hour {
+		return time.Hour
+	}
+	return d
+}
+
+// Sign returns the X-Webhook-Signature value for body sent at timestamp.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature matches body sent at timestamp.
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// Request is one delivery attempt.
+type Request struct {
+	URL        string
+	Secret     string
+	Event      string // event type, sent as X-Webhook-Event
+	DeliveryID int64  // sent as X-Webhook-Delivery so receivers can drop duplicates
+	Body       []byte
+}
+
+// Post sends r and returns the response status. Any status outside 2xx is
+// returned together with an error.
+func Post(ctx context.Context, client *http.Client, r Request, now time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.URL, bytes.NewReader(r.Body))
+	if err != nil {
+		return 0, err
+	}
+	ts := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "egg-tracker-webhooks")
+	req.Header.Set("X-Webhook-Event", r.Event)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(r.DeliveryID, 10))
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(ts, 10))
+	req.Header.Set("X-Webhook-Signature", Sign(r.Secret, ts, r.Body))
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
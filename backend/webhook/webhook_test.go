@@ -0,0 +1,65 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	body := []byte(`{"type":"inventory.created"}`)
+	// Computed with: printf '1700000000.{"type":"inventory.created"}' | openssl dgst -sha256 -hmac s3cret
+	want := "sha256=a243fa533d4d79f3037a6f7d90af8fab042c38bf43171416a080e9519c23c677"
+	got := Sign("s3cret", 1700000000, body)
+	if got != want {
+		t.Fatalf("Sign() = %q, want %q", got, want)
+	}
+	if !Verify("s3cret", 1700000000, body, got) {
+		t.Error("signature does not verify")
+	}
+	if Verify("other", 1700000000, body, got) || Verify("s3cret", 1700000001, body, got) || Verify("s3cret", 1700000000, append(body, ' '), got) {
+		t.Error("signature verified with a different secret, timestamp or body")
+	}
+}
+
+func TestPost(t *testing.T) {
+	var status = http.StatusNoContent
+	var header http.Header
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	now := time.Unix(1700000000, 0)
+	req := Request{URL: server.URL, Secret: "s3cret", Event: "etl.completed", DeliveryID: 42, Body: []byte(`{"id":1}`)}
+	code, err := Post(context.Background(), server.Client(), req, now)
+	if err != nil || code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d, %v", code, err)
+	}
+	ts, _ := strconv.ParseInt(header.Get("X-Webhook-Timestamp"), 10, 64)
+	if ts != now.Unix() || header.Get("X-Webhook-Event") != "etl.completed" || header.Get("X-Webhook-Delivery") != "42" ||
+		!Verify("s3cret", ts, body, header.Get("X-Webhook-Signature")) {
+		t.Errorf("unexpected request headers %v", header)
+	}
+
+	status = http.StatusBadGateway
+	if code, err := Post(context.Background(), server.Client(), req, now); err == nil || code != http.StatusBadGateway {
+		t.Errorf("expected a 502 error, got %d, %v", code, err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	want := map[int]time.Duration{0: 30 * time.Second, 1: 30 * time.Second, 2: time.Minute, 7: 32 * time.Minute, 8: time.Hour, 20: time.Hour}
+	for attempts, d := range want {
+		if got := Backoff(attempts); got != d {
+			t.Errorf("Backoff(%d) = %s, want %s", attempts, got, d)
+		}
+	}
+}